@@ -0,0 +1,70 @@
+// ledger-opening 账本期初余额迁移命令，将账本上线前已有的钱包余额登记为期初凭证，
+// 须在启用ledger_verify定时核对前执行一次，重复执行不会重复记账
+//
+// 用法:
+//
+//	ledger-opening -config ./configs/config-optimized.yaml
+//
+// 登记完成后执行试算平衡并核对全部钱包，仍有不一致时以状态码2退出
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/internal/services"
+	"task-platform-api/pkg/database"
+)
+
+var (
+	configPath = flag.String("config", "./configs/config-optimized.yaml", "配置文件路径")
+)
+
+func main() {
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("加载配置文件失败: %v", err)
+	}
+	db, err := database.New(database.Config{
+		Host:            cfg.Database.Host,
+		Port:            cfg.Database.Port,
+		Username:        cfg.Database.Username,
+		Password:        cfg.Database.Password,
+		Database:        cfg.Database.Database,
+		Charset:         "utf8mb4",
+		MaxIdleConns:    cfg.Database.MaxIdleConn,
+		MaxOpenConns:    cfg.Database.MaxOpenConn,
+		ConnMaxLifetime: 3600,
+	})
+	if err != nil {
+		log.Fatalf("初始化数据库失败: %v", err)
+	}
+	ledgerService := services.NewLedgerService(db)
+
+	ctx := context.Background()
+	posted, err := ledgerService.PostOpeningBalances(ctx)
+	fmt.Printf("登记期初余额: %d个钱包\n", posted)
+	if err != nil {
+		log.Fatalf("登记期初余额失败: %v", err)
+	}
+
+	if err := ledgerService.CheckTrialBalance(ctx); err != nil {
+		log.Fatalf("试算平衡失败: %v", err)
+	}
+	mismatched, err := ledgerService.VerifyWallets(ctx)
+	if err != nil {
+		log.Fatalf("核对钱包失败: %v", err)
+	}
+	for _, m := range mismatched {
+		fmt.Printf("用户%d 钱包%s/%s 账本%s/%s\n", m.UserID, m.WalletBalance, m.WalletFrozenBalance, m.LedgerBalance, m.LedgerFrozenBalance)
+	}
+	if len(mismatched) > 0 {
+		os.Exit(2)
+	}
+}
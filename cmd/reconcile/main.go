@@ -0,0 +1,135 @@
+// reconcile 渠道对账命令，核对渠道对账单与本地交易，生成对账报告并通知财务
+//
+// 用法:
+//
+//	reconcile -provider shouqianba -date 2026-01-02              通过渠道接口下载对账单
+//	reconcile -provider shouqianba -date 2026-01-02 -file a.csv  导入渠道导出的CSV对账单
+//
+// 未指定日期时核对前一日账单
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/internal/models"
+	"task-platform-api/internal/services"
+	"task-platform-api/pkg/database"
+	"task-platform-api/pkg/logger"
+	"task-platform-api/pkg/payment"
+)
+
+var (
+	configPath = flag.String("config", "./configs/config-optimized.yaml", "配置文件路径")
+	provider   = flag.String("provider", "", "支付渠道，为空时使用默认渠道")
+	billDate   = flag.String("date", "", "账单日，格式2006-01-02，默认前一日")
+	filePath   = flag.String("file", "", "CSV对账单路径，为空时通过渠道接口下载")
+)
+
+func main() {
+	flag.Parse()
+
+	date := time.Now().AddDate(0, 0, -1)
+	if *billDate != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", *billDate, time.Local)
+		if err != nil {
+			log.Fatalf("账单日格式错误: %v", err)
+		}
+		date = parsed
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("加载配置文件失败: %v", err)
+	}
+	zapLogger, err := logger.New(logger.Config{
+		Level:      cfg.Log.Level,
+		Format:     cfg.Log.Format,
+		OutputPath: cfg.Log.FilePath,
+	})
+	if err != nil {
+		log.Fatalf("初始化日志失败: %v", err)
+	}
+	defer zapLogger.Sync()
+
+	db, err := database.New(database.Config{
+		Host:            cfg.Database.Host,
+		Port:            cfg.Database.Port,
+		Username:        cfg.Database.Username,
+		Password:        cfg.Database.Password,
+		Database:        cfg.Database.Database,
+		Charset:         "utf8mb4",
+		MaxIdleConns:    cfg.Database.MaxIdleConn,
+		MaxOpenConns:    cfg.Database.MaxOpenConn,
+		ConnMaxLifetime: 3600,
+	})
+	if err != nil {
+		log.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	gateways, err := payment.NewRegistry(cfg, zapLogger)
+	if err != nil {
+		log.Fatalf("初始化支付渠道失败: %v", err)
+	}
+	reconciliationService := services.NewReconciliationService(db, gateways, cfg.Admin)
+
+	ctx := context.Background()
+	req := &services.ReconcileRequest{
+		Provider: *provider,
+		BillDate: date,
+		Source:   services.StatementSourceAPI,
+	}
+	if *filePath != "" {
+		file, err := os.Open(*filePath)
+		if err != nil {
+			log.Fatalf("打开对账单失败: %v", err)
+		}
+		req.Entries, err = payment.ParseStatementCSV(file)
+		file.Close()
+		if err != nil {
+			log.Fatalf("解析对账单失败: %v", err)
+		}
+		req.Source = services.StatementSourceCSV
+	} else {
+		req.Entries, err = reconciliationService.FetchStatement(ctx, *provider, date)
+		if err != nil {
+			log.Fatalf("下载对账单失败: %v", err)
+		}
+	}
+
+	report, err := reconciliationService.Reconcile(ctx, req)
+	if err != nil {
+		log.Fatalf("对账失败: %v", err)
+	}
+	printReport(os.Stdout, report)
+	if !report.IsBalanced() {
+		os.Exit(2)
+	}
+}
+
+// printReport 输出对账汇总和差异明细
+func printReport(out io.Writer, report *models.ReconciliationReport) {
+	fmt.Fprintf(out, "渠道: %s  账单日: %s  报告ID: %d\n", report.Provider, report.BillDate.Format("2006-01-02"), report.ID)
+	fmt.Fprintf(out, "渠道成功: %d笔 %s元\n", report.RemoteCount, report.RemoteAmount)
+	fmt.Fprintf(out, "本地收款: %d笔 %s元\n", report.LocalCount, report.LocalAmount)
+	fmt.Fprintf(out, "核对一致: %d笔  差异: %d笔\n", report.MatchedCount, report.DiscrepancyCount)
+	if len(report.Discrepancies) == 0 {
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "差异类型\t内部交易号\t第三方交易号\t本地金额\t渠道金额\t本地状态\t渠道状态")
+	for _, d := range report.Discrepancies {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			d.Type, d.InternalNo, d.ThirdPartyNo, d.LocalAmount, d.RemoteAmount, d.LocalStatus, d.RemoteStatus)
+	}
+	w.Flush()
+}
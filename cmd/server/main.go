@@ -14,12 +14,17 @@ import (
 	"go.uber.org/zap"
 
 	"task-platform-api/internal/api/v1/handlers"
+	"task-platform-api/internal/api/v1/middleware"
 	"task-platform-api/internal/api/v1/routes"
 	"task-platform-api/internal/config"
+	"task-platform-api/internal/events"
+	"task-platform-api/internal/scheduler"
 	"task-platform-api/internal/services"
 	"task-platform-api/pkg/logger"
+	"task-platform-api/pkg/payment"
 	"task-platform-api/pkg/database"
 	"task-platform-api/pkg/redis"
+	"task-platform-api/pkg/wechat"
 )
 
 var (
@@ -97,13 +102,107 @@ func main() {
 	}
 
 	// 创建处理器
-	authHandler := handlers.NewAuthHandler(db, rdb, cfg, zapLogger)
-	paymentService := services.NewPaymentService(db, nil) // 暂时不传入支付客户端
+	alipayClient, err := payment.NewAlipayClient(&cfg.Alipay)
+	if err != nil {
+		zapLogger.Warn("支付宝客户端初始化失败，支付宝登录不可用", zap.Error(err))
+	}
+	authHandler := handlers.NewAuthHandler(db, rdb, cfg, wechat.NewOAuthClient(&cfg.Wechat), alipayClient, zapLogger)
+	eventBus := events.NewBus(zapLogger)
+	services.SubscribePaymentEvents(eventBus, db)
+	paymentGateways, err := payment.NewRegistry(cfg, zapLogger)
+	if err != nil {
+		zapLogger.Fatal("初始化支付渠道失败", zap.Error(err))
+	}
+	feeEngine := services.NewFeeEngine(db, cfg.Fee)
+	paymentService := services.NewPaymentService(db, cfg.Payment, paymentGateways, eventBus, feeEngine, cfg.Admin)
 	paymentHandler := handlers.NewPaymentHandler(paymentService)
+	taskService := services.NewTaskService(db, cfg.Task)
+	taskHandler := handlers.NewTaskHandler(taskService)
+	applicationService := services.NewTaskApplicationService(db)
+	applicationHandler := handlers.NewTaskApplicationHandler(applicationService)
+	deliveryService := services.NewTaskDeliveryService(db, cfg.Task)
+	deliveryHandler := handlers.NewTaskDeliveryHandler(deliveryService)
+	withdrawService := services.NewWithdrawService(db, cfg.RiskControl, cfg.Payment.TransferProvider, paymentGateways)
+	withdrawHandler := handlers.NewWithdrawHandler(withdrawService)
+	violationService := services.NewViolationService(db, cfg.Task)
+	violationHandler := handlers.NewViolationHandler(violationService)
+	walletService := services.NewWalletService(db)
+	walletHandler := handlers.NewWalletHandler(walletService)
+	invoiceService := services.NewInvoiceService(db, cfg.Invoice)
+	invoiceHandler := handlers.NewInvoiceHandler(invoiceService)
+	couponService := services.NewCouponService(db)
+	couponHandler := handlers.NewCouponHandler(couponService)
+	authMiddleware := middleware.JWTAuth(&cfg.JWT, zapLogger)
+	adminMiddleware := middleware.RequireAdmin(&cfg.Admin)
+	financeMiddleware := middleware.RequireFinance(&cfg.Admin)
 	
 	// 创建路由
 	router := gin.New()
-	routes.SetupRoutes(router, authHandler, paymentHandler)
+	routes.SetupRoutes(router, authMiddleware, adminMiddleware, financeMiddleware, authHandler, paymentHandler, taskHandler, applicationHandler, deliveryHandler, withdrawHandler, violationHandler, walletHandler, invoiceHandler, couponHandler)
+
+	// 启动定时任务
+	jobScheduler := scheduler.New(zapLogger)
+	jobScheduler.Register("delivery_auto_accept", 10*time.Minute, func(ctx context.Context) error {
+		count, err := deliveryService.AutoAcceptOverdue(ctx)
+		if count > 0 {
+			zapLogger.Info("超时交付自动验收", zap.Int("count", count))
+		}
+		return err
+	})
+	jobScheduler.Register("trade_expire", time.Minute, func(ctx context.Context) error {
+		count, err := paymentService.CloseExpiredTrades(ctx)
+		if count > 0 {
+			zapLogger.Info("关闭过期未支付交易", zap.Int("count", count))
+		}
+		return err
+	})
+	jobScheduler.Register("task_expire", 10*time.Minute, func(ctx context.Context) error {
+		count, err := taskService.ExpireOverdueTasks(ctx)
+		if count > 0 {
+			zapLogger.Info("处理超过截止时间的任务", zap.Int("count", count))
+		}
+		return err
+	})
+	jobScheduler.Register("refund_sync", 5*time.Minute, func(ctx context.Context) error {
+		count, err := paymentService.SyncPendingRefunds(ctx)
+		if count > 0 {
+			zapLogger.Info("同步处理中退款结果", zap.Int("count", count))
+		}
+		return err
+	})
+	jobScheduler.Register("withdraw_sync", 5*time.Minute, func(ctx context.Context) error {
+		count, err := withdrawService.SyncProcessingWithdraws(ctx)
+		if count > 0 {
+			zapLogger.Info("同步处理中提现打款结果", zap.Int("count", count))
+		}
+		return err
+	})
+	// 钱包余额与账本核对，账本上线前已有余额的钱包须先执行ledger-opening命令登记期初余额
+	ledgerService := services.NewLedgerService(db)
+	jobScheduler.Register("ledger_verify", time.Hour, func(ctx context.Context) error {
+		if err := ledgerService.CheckTrialBalance(ctx); err != nil {
+			return err
+		}
+		mismatched, err := ledgerService.VerifyWallets(ctx)
+		for _, m := range mismatched {
+			zapLogger.Error("钱包余额与账本不一致",
+				zap.Uint64("user_id", m.UserID),
+				zap.Stringer("wallet_balance", m.WalletBalance),
+				zap.Stringer("ledger_balance", m.LedgerBalance),
+				zap.Stringer("wallet_frozen_balance", m.WalletFrozenBalance),
+				zap.Stringer("ledger_frozen_balance", m.LedgerFrozenBalance))
+		}
+		return err
+	})
+	reconciliationService := services.NewReconciliationService(db, paymentGateways, cfg.Admin)
+	jobScheduler.Register("reconcile_daily", time.Hour, func(ctx context.Context) error {
+		count, err := reconciliationService.ReconcileDaily(ctx)
+		if count > 0 {
+			zapLogger.Info("完成前一日渠道对账", zap.Int("count", count))
+		}
+		return err
+	})
+	jobScheduler.Start()
 
 	// 创建HTTP服务器
 	srv := &http.Server{
@@ -131,6 +230,8 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	jobScheduler.Stop()
+
 	if err := srv.Shutdown(ctx); err != nil {
 		zapLogger.Fatal("服务器强制关闭", zap.Error(err))
 	}
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+    "errors"
+    "fmt"
     "net/http"
+    "strings"
     "time"
 
     "github.com/gin-gonic/gin"
@@ -13,7 +16,9 @@ import (
     "task-platform-api/internal/config"
     "task-platform-api/internal/api/v1/middleware"
     "task-platform-api/internal/models"
+    "task-platform-api/pkg/payment"
     "task-platform-api/pkg/utils"
+    "task-platform-api/pkg/wechat"
 )
 
 // AuthHandler 认证处理器
@@ -21,15 +26,19 @@ type AuthHandler struct {
     db     *gorm.DB
     rdb    *go_redis.Client
     cfg    *config.Config
+    wechat *wechat.OAuthClient
+    alipay *payment.AlipayClient
     logger *zap.Logger
 }
 
-// NewAuthHandler 创建认证处理器
-func NewAuthHandler(db *gorm.DB, rdb *go_redis.Client, cfg *config.Config, logger *zap.Logger) *AuthHandler {
+// NewAuthHandler 创建认证处理器，alipay为空时支付宝登录不可用
+func NewAuthHandler(db *gorm.DB, rdb *go_redis.Client, cfg *config.Config, wechat *wechat.OAuthClient, alipay *payment.AlipayClient, logger *zap.Logger) *AuthHandler {
     return &AuthHandler{
         db:     db,
         rdb:    rdb,
         cfg:    cfg,
+        wechat: wechat,
+        alipay: alipay,
         logger: logger,
     }
 }
@@ -38,6 +47,7 @@ func NewAuthHandler(db *gorm.DB, rdb *go_redis.Client, cfg *config.Config, logge
 type LoginRequest struct {
     AuthType string `json:"auth_type" binding:"required,oneof=wechat alipay"`
     Code     string `json:"code" binding:"required"`
+    Channel  string `json:"channel" binding:"omitempty,oneof=miniprogram official"` // 微信登录渠道，默认小程序
 }
 
 // LoginResponse 登录响应
@@ -75,7 +85,11 @@ func (h *AuthHandler) WechatLogin(c *gin.Context) {
     ipAddress := c.ClientIP()
 
     // 调用微信API获取用户信息
-    wechatUser, err := h.getWechatUserInfo(req.Code)
+    channel := req.Channel
+    if channel == "" {
+        channel = wechat.ChannelMiniProgram
+    }
+    wechatUser, err := h.getWechatUserInfo(channel, req.Code)
     if err != nil {
         h.logger.Error("获取微信用户信息失败", zap.Error(err))
         utils.ErrorResponse(c, http.StatusUnauthorized, "微信授权失败")
@@ -83,7 +97,7 @@ func (h *AuthHandler) WechatLogin(c *gin.Context) {
     }
 
     // 查找或创建用户
-    user, err := h.findOrCreateUser(wechatUser.OpenID, req.AuthType, wechatUser)
+    user, err := h.findOrCreateUser("wechat", channel, wechatUser.OpenID, wechatUser.UnionID, wechatUser)
     if err != nil {
         h.logger.Error("用户处理失败", zap.Error(err))
         utils.InternalServerErrorResponse(c, "用户处理失败")
@@ -145,7 +159,7 @@ func (h *AuthHandler) AlipayLogin(c *gin.Context) {
     }
 
     // 查找或创建用户
-    user, err := h.findOrCreateUser(alipayUser.UserID, req.AuthType, alipayUser)
+    user, err := h.findOrCreateUser("alipay", models.AuthChannelAlipay, alipayUser.UserID, "", alipayUser)
     if err != nil {
         h.logger.Error("用户处理失败", zap.Error(err))
         utils.InternalServerErrorResponse(c, "用户处理失败")
@@ -253,87 +267,225 @@ type AlipayUser struct {
     Avatar   string `json:"avatar"`
 }
 
-// getWechatUserInfo 获取微信用户信息
-func (h *AuthHandler) getWechatUserInfo(code string) (*WechatUser, error) {
-    // TODO: 调用微信API
-    // 这里应该实现微信小程序或公众号的授权登录逻辑
+// getWechatUserInfo 按登录渠道用code换取微信用户身份，公众号snsapi_userinfo授权时同时拉取昵称头像
+func (h *AuthHandler) getWechatUserInfo(channel, code string) (*WechatUser, error) {
+    if channel == wechat.ChannelOfficial {
+        token, err := h.wechat.OAuth2AccessToken(code)
+        if err != nil {
+            return nil, err
+        }
+        wechatUser := &WechatUser{
+            OpenID:  token.OpenID,
+            UnionID: token.UnionID,
+        }
+        if !strings.Contains(token.Scope, "snsapi_userinfo") {
+            return wechatUser, nil
+        }
+
+        info, err := h.wechat.UserInfo(token.AccessToken, token.OpenID)
+        if err != nil {
+            h.logger.Warn("获取微信用户信息失败，仅使用用户标识登录", zap.Error(err))
+            return wechatUser, nil
+        }
+        wechatUser.Nickname = info.Nickname
+        wechatUser.Avatar = info.HeadImgURL
+        if info.UnionID != "" {
+            wechatUser.UnionID = info.UnionID
+        }
+        return wechatUser, nil
+    }
+
+    session, err := h.wechat.Code2Session(code)
+    if err != nil {
+        return nil, err
+    }
     return &WechatUser{
-        OpenID:   "test_openid_" + code,
-        Nickname: "测试用户",
-        Avatar:   "https://example.com/avatar.jpg",
-        UnionID:  "test_unionid_" + code,
+        OpenID:  session.OpenID,
+        UnionID: session.UnionID,
     }, nil
 }
 
-// getAlipayUserInfo 获取支付宝用户信息
+// getAlipayUserInfo 用授权码换取令牌并获取支付宝用户信息，仅授权auth_base时只返回用户标识
 func (h *AuthHandler) getAlipayUserInfo(code string) (*AlipayUser, error) {
-    // TODO: 调用支付宝API
-    // 这里应该实现支付宝的授权登录逻辑
-    return &AlipayUser{
-        UserID:   "test_userid_" + code,
-        Nickname: "支付宝测试用户",
-        Avatar:   "https://example.com/alipay_avatar.jpg",
-    }, nil
-}
+    if h.alipay == nil {
+        return nil, fmt.Errorf("支付宝未配置")
+    }
 
-// findOrCreateUser 查找或创建用户
-func (h *AuthHandler) findOrCreateUser(openID, authType string, userInfo interface{}) (*models.User, error) {
-    var user models.User
-    
-    // 查找用户
-    err := h.db.Where("openid = ? AND auth_type = ?", openID, authType).First(&user).Error
+    token, err := h.alipay.SystemOAuthToken(code)
     if err != nil {
-        if err == gorm.ErrRecordNotFound {
-            // 创建新用户
-            user = models.User{
-                OpenID:   openID,
-                AuthType: authType,
-                Status:   1, // 正常状态
-            }
+        return nil, err
+    }
+    alipayUser := &AlipayUser{
+        UserID: token.UserID,
+    }
+    if alipayUser.UserID == "" {
+        alipayUser.UserID = token.OpenID
+    }
+    if alipayUser.UserID == "" {
+        return nil, fmt.Errorf("支付宝未返回用户标识")
+    }
 
-            // 设置用户信息
-            switch v := userInfo.(type) {
-            case *WechatUser:
-                user.Nickname = v.Nickname
-                user.Avatar = v.Avatar
-                user.UnionID = v.UnionID
-            case *AlipayUser:
-                user.Nickname = v.Nickname
-                user.Avatar = v.Avatar
-            }
+    info, err := h.alipay.UserInfoShare(token.AccessToken)
+    if err != nil {
+        h.logger.Warn("获取支付宝会员信息失败，仅使用用户标识登录", zap.Error(err))
+        return alipayUser, nil
+    }
+    alipayUser.Nickname = info.NickName
+    alipayUser.Avatar = info.Avatar
+    return alipayUser, nil
+}
 
-            // 创建用户记录
-            if err := h.db.Create(&user).Error; err != nil {
-                return nil, err
+// findOrCreateUser 按渠道身份查找用户，未绑定时按unionid关联已有用户，仍未找到则创建新用户
+func (h *AuthHandler) findOrCreateUser(authType, channel, openID, unionID string, userInfo interface{}) (*models.User, error) {
+    var user models.User
+    err := h.db.Transaction(func(tx *gorm.DB) error {
+        // 已绑定的渠道身份
+        var auth models.UserAuth
+        err := tx.Where("channel = ? AND openid = ?", channel, openID).First(&auth).Error
+        if err == nil {
+            if err := tx.First(&user, auth.UserID).Error; err != nil {
+                return err
             }
+            return h.fillUnionID(tx, &user, &auth, unionID)
+        }
+        if !errors.Is(err, gorm.ErrRecordNotFound) {
+            return err
+        }
 
-            // 创建用户信誉记录
-            credit := models.UserCredit{
-                UserID: user.ID,
-                Score:  5.0,
-                Level:  1,
-            }
-            if err := h.db.Create(&credit).Error; err != nil {
-                h.logger.Error("创建用户信誉记录失败", zap.Error(err))
-            }
+        // 身份表上线前注册的用户，按用户表的openid查找后补绑身份
+        err = tx.Where("openid = ? AND auth_type = ?", openID, authType).First(&user).Error
+        if err == nil {
+            return h.bindUserAuth(tx, &user, authType, channel, openID, unionID)
+        }
+        if !errors.Is(err, gorm.ErrRecordNotFound) {
+            return err
+        }
 
-            // 创建钱包记录
-            wallet := models.Wallet{
-                UserID:  user.ID,
-                Balance: 0,
+        // 同一微信开放平台下的其他渠道已登录过，按unionid关联到同一用户
+        if unionID != "" {
+            linked, err := h.findUserByUnionID(tx, unionID)
+            if err != nil {
+                return err
             }
-            if err := h.db.Create(&wallet).Error; err != nil {
-                h.logger.Error("创建用户钱包失败", zap.Error(err))
+            if linked != nil {
+                user = *linked
+                h.logger.Info("按unionid关联登录渠道", zap.Uint64("user_id", user.ID), zap.String("channel", channel))
+                return h.bindUserAuth(tx, &user, authType, channel, openID, unionID)
             }
+        }
 
-        } else {
-            return nil, err
+        // 创建新用户
+        user = models.User{
+            OpenID:   openID,
+            UnionID:  unionID,
+            AuthType: authType,
+            Status:   1, // 正常状态
         }
+
+        // 设置用户信息
+        switch v := userInfo.(type) {
+        case *WechatUser:
+            user.Nickname = v.Nickname
+            user.Avatar = v.Avatar
+        case *AlipayUser:
+            user.Nickname = v.Nickname
+            user.Avatar = v.Avatar
+        }
+
+        // 创建用户记录
+        if err := tx.Create(&user).Error; err != nil {
+            return err
+        }
+        if err := h.bindUserAuth(tx, &user, authType, channel, openID, unionID); err != nil {
+            return err
+        }
+
+        // 创建用户信誉记录
+        credit := models.UserCredit{
+            UserID: user.ID,
+            Score:  5.0,
+            Level:  1,
+        }
+        if err := tx.Create(&credit).Error; err != nil {
+            h.logger.Error("创建用户信誉记录失败", zap.Error(err))
+        }
+
+        // 创建钱包记录
+        wallet := models.Wallet{
+            UserID:  user.ID,
+            Balance: 0,
+        }
+        if err := tx.Create(&wallet).Error; err != nil {
+            h.logger.Error("创建用户钱包失败", zap.Error(err))
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
     }
 
     return &user, nil
 }
 
+// findUserByUnionID 按unionid查找已绑定的用户，未找到时返回nil
+func (h *AuthHandler) findUserByUnionID(tx *gorm.DB, unionID string) (*models.User, error) {
+    var auth models.UserAuth
+    err := tx.Where("unionid = ?", unionID).Order("auth_id").First(&auth).Error
+    if err == nil {
+        var user models.User
+        if err := tx.First(&user, auth.UserID).Error; err != nil {
+            return nil, err
+        }
+        return &user, nil
+    }
+    if !errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, err
+    }
+
+    var user models.User
+    err = tx.Where("unionid = ?", unionID).Order("user_id").First(&user).Error
+    if err == nil {
+        return &user, nil
+    }
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, nil
+    }
+    return nil, err
+}
+
+// bindUserAuth 为用户绑定渠道身份
+func (h *AuthHandler) bindUserAuth(tx *gorm.DB, user *models.User, authType, channel, openID, unionID string) error {
+    auth := models.UserAuth{
+        UserID:   user.ID,
+        AuthType: authType,
+        Channel:  channel,
+        OpenID:   openID,
+        UnionID:  unionID,
+    }
+    if err := tx.Create(&auth).Error; err != nil {
+        return err
+    }
+    return h.fillUnionID(tx, user, &auth, unionID)
+}
+
+// fillUnionID 渠道首次返回unionid时补写到身份和用户记录，便于后续跨渠道关联
+func (h *AuthHandler) fillUnionID(tx *gorm.DB, user *models.User, auth *models.UserAuth, unionID string) error {
+    if unionID == "" {
+        return nil
+    }
+    if auth.UnionID == "" {
+        if err := tx.Model(auth).Update("unionid", unionID).Error; err != nil {
+            return err
+        }
+    }
+    if user.UnionID == "" {
+        if err := tx.Model(user).Update("unionid", unionID).Error; err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
 // saveUserSession 保存用户会话
 func (h *AuthHandler) saveUserSession(sessionID string, userID uint64, token, deviceInfo, ipAddress string) error {
     expireTime := time.Now().Add(time.Duration(h.cfg.JWT.ExpireTime) * time.Second)
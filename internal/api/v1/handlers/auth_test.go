@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"task-platform-api/internal/models"
+	"task-platform-api/internal/testutil"
+)
+
+var userAuthColumns = []string{"auth_id", "user_id", "auth_type", "channel", "openid", "unionid"}
+
+func TestFindOrCreateUserLinksByUnionID(t *testing.T) {
+	db, stub := testutil.NewGorm(t,
+		testutil.StubRows{
+			Match:   "FROM `user_auths` WHERE unionid",
+			Columns: userAuthColumns,
+			Values:  [][]driver.Value{{int64(1), int64(42), "wechat", models.AuthChannelOfficial, "official-openid", "union-1"}},
+		},
+		testutil.StubRows{
+			Match:   "FROM `users` WHERE `users`.`user_id`",
+			Columns: []string{"user_id", "openid", "unionid", "auth_type"},
+			Values:  [][]driver.Value{{int64(42), "official-openid", "union-1", "wechat"}},
+		},
+	)
+	h := &AuthHandler{db: db, logger: zap.NewNop()}
+
+	user, err := h.findOrCreateUser("wechat", models.AuthChannelMiniProgram, "mini-openid", "union-1", &WechatUser{OpenID: "mini-openid"})
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	if user.ID != 42 {
+		t.Fatalf("应按unionid关联到已有用户42, 实际: %d", user.ID)
+	}
+	if inserts := stub.Executed("INSERT INTO `users`"); len(inserts) > 0 {
+		t.Fatal("按unionid关联时不应创建新用户")
+	}
+	auths := stub.Executed("INSERT INTO `user_auths`")
+	if len(auths) != 1 || !auths[0].HasArgs(int64(42), models.AuthChannelMiniProgram, "mini-openid", "union-1") {
+		t.Fatalf("应为已有用户绑定小程序身份: %+v", auths)
+	}
+	if updates := stub.Executed("SET `unionid`"); len(updates) > 0 {
+		t.Fatal("已有unionid时不应重复写入")
+	}
+}
+
+func TestFindOrCreateUserCreatesWithUnionID(t *testing.T) {
+	db, stub := testutil.NewGorm(t)
+	h := &AuthHandler{db: db, logger: zap.NewNop()}
+
+	user, err := h.findOrCreateUser("wechat", models.AuthChannelMiniProgram, "mini-openid", "union-2", &WechatUser{OpenID: "mini-openid", Nickname: "李四"})
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	if user.UnionID != "union-2" || user.Nickname != "李四" {
+		t.Errorf("新用户信息错误: %+v", user)
+	}
+
+	users := stub.Executed("INSERT INTO `users`")
+	if len(users) != 1 || !users[0].HasArgs("mini-openid", "union-2") {
+		t.Fatalf("未关联到已有用户时应创建带unionid的新用户: %+v", users)
+	}
+	auths := stub.Executed("INSERT INTO `user_auths`")
+	if len(auths) != 1 || !auths[0].HasArgs(int64(user.ID), "union-2") {
+		t.Fatalf("应为新用户绑定登录身份: %+v", auths)
+	}
+}
+
+func TestFindOrCreateUserBackfillsUnionID(t *testing.T) {
+	db, stub := testutil.NewGorm(t,
+		testutil.StubRows{
+			Match:   "FROM `user_auths` WHERE channel",
+			Columns: userAuthColumns,
+			Values:  [][]driver.Value{{int64(5), int64(42), "wechat", models.AuthChannelMiniProgram, "mini-openid", ""}},
+		},
+		testutil.StubRows{
+			Match:   "FROM `users` WHERE `users`.`user_id`",
+			Columns: []string{"user_id", "openid", "unionid", "auth_type"},
+			Values:  [][]driver.Value{{int64(42), "mini-openid", "", "wechat"}},
+		},
+	)
+	h := &AuthHandler{db: db, logger: zap.NewNop()}
+
+	user, err := h.findOrCreateUser("wechat", models.AuthChannelMiniProgram, "mini-openid", "union-3", &WechatUser{OpenID: "mini-openid"})
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	if user.ID != 42 || user.UnionID != "union-3" {
+		t.Fatalf("已绑定身份应登录原用户并补写unionid: %+v", user)
+	}
+	if len(stub.Executed("INSERT")) > 0 {
+		t.Fatal("已绑定身份不应创建记录")
+	}
+	if len(stub.Executed("UPDATE `user_auths` SET `unionid`")) != 1 || len(stub.Executed("UPDATE `users` SET `unionid`")) != 1 {
+		t.Fatal("渠道首次返回unionid时应补写到身份和用户记录")
+	}
+}
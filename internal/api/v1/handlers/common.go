@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"task-platform-api/internal/services"
+	"task-platform-api/pkg/utils"
+)
+
+// getCurrentUserID 从上下文获取当前登录用户ID
+func getCurrentUserID(c *gin.Context) (uint64, bool) {
+	value, exists := c.Get("user_id")
+	if !exists {
+		return 0, false
+	}
+	userID, ok := value.(uint64)
+	if !ok || userID == 0 {
+		return 0, false
+	}
+	return userID, true
+}
+
+// parseIDParam 解析路径中的ID参数
+func parseIDParam(c *gin.Context, name string) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// handleServiceError 将服务层错误转换为响应
+func handleServiceError(c *gin.Context, err error) {
+	var svcErr *services.ServiceError
+	if errors.As(err, &svcErr) {
+		utils.ErrorCodeResponse(c, svcErr.HTTPStatus, svcErr.Code, svcErr.Message)
+		return
+	}
+	utils.InternalServerErrorResponse(c, "服务器内部错误")
+}
+
+// paginationInfo 根据请求的页码和每页数量构造分页信息，与utils.Pagination的默认值保持一致
+func paginationInfo(page, pageSize int, total int64) utils.PaginationInfo {
+	_, limit := utils.Pagination(page, pageSize)
+	if page < 1 {
+		page = 1
+	}
+	return utils.PaginationInfo{
+		Page:       page,
+		PageSize:   limit,
+		Total:      total,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+	}
+}
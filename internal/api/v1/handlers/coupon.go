@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"task-platform-api/internal/models"
+	"task-platform-api/internal/services"
+	"task-platform-api/pkg/money"
+	"task-platform-api/pkg/utils"
+)
+
+// CreateCouponRequest 创建优惠券请求
+type CreateCouponRequest struct {
+	Code            string      `json:"code" binding:"required,max=32"`
+	Name            string      `json:"name" binding:"required,max=100"`
+	CouponType      string      `json:"coupon_type" binding:"required,oneof=first_task_fee fee_percent fixed"`
+	Amount          money.Money `json:"amount" swaggertype:"number"`              // 立减金额，fixed类型必填
+	FeeDiscountRate float64     `json:"fee_discount_rate"`                        // 服务费减免比例，fee_percent类型必填，0.5表示减免一半
+	MaxDiscount     money.Money `json:"max_discount" swaggertype:"number"`        // 单次最高优惠金额，0为不限
+	MinTaskAmount   money.Money `json:"min_task_amount" swaggertype:"number"`     // 任务赏金门槛，0为不限
+	Stackable       bool        `json:"stackable"`                                // 是否可与其他类型的优惠券叠加
+	TotalLimit      int         `json:"total_limit" binding:"min=0"`              // 全局可用次数，0为不限
+	PerUserLimit    *int        `json:"per_user_limit" binding:"omitempty,min=0"` // 每个用户可用次数，为空时为1，0为不限
+	StartTime       *time.Time  `json:"start_time"`
+	EndTime         *time.Time  `json:"end_time"`
+}
+
+// CouponListQuery 优惠券列表查询参数
+type CouponListQuery struct {
+	CouponType string `form:"coupon_type" binding:"omitempty,oneof=first_task_fee fee_percent fixed"`
+	Status     *int8  `form:"status"`
+	Page       int    `form:"page"`
+	PageSize   int    `form:"page_size"`
+}
+
+// CouponHandler 优惠券处理器
+type CouponHandler struct {
+	couponService *services.CouponService
+}
+
+// NewCouponHandler 创建优惠券处理器
+func NewCouponHandler(couponService *services.CouponService) *CouponHandler {
+	return &CouponHandler{
+		couponService: couponService,
+	}
+}
+
+// Create 创建优惠券
+// @Summary 创建优惠券
+// @Description 管理员创建优惠券，支持首单免发布者服务费、服务费按比例减免和预付款立减；优惠码不区分大小写
+// @Tags 优惠券
+// @Accept json
+// @Produce json
+// @Param request body CreateCouponRequest true "优惠券信息"
+// @Success 201 {object} utils.Response{data=models.Coupon}
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/admin/coupons [post]
+func (h *CouponHandler) Create(c *gin.Context) {
+	var req CreateCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	perUserLimit := 1
+	if req.PerUserLimit != nil {
+		perUserLimit = *req.PerUserLimit
+	}
+	coupon, err := h.couponService.CreateCoupon(c.Request.Context(), &services.CreateCouponRequest{
+		Code:            req.Code,
+		Name:            req.Name,
+		CouponType:      req.CouponType,
+		Amount:          req.Amount,
+		FeeDiscountRate: req.FeeDiscountRate,
+		MaxDiscount:     req.MaxDiscount,
+		MinTaskAmount:   req.MinTaskAmount,
+		Stackable:       req.Stackable,
+		TotalLimit:      req.TotalLimit,
+		PerUserLimit:    perUserLimit,
+		StartTime:       req.StartTime,
+		EndTime:         req.EndTime,
+	})
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.CreatedResponse(c, coupon)
+}
+
+// List 优惠券列表
+// @Summary 优惠券列表
+// @Description 管理员分页查询优惠券及已占用次数
+// @Tags 优惠券
+// @Produce json
+// @Param coupon_type query string false "优惠券类型:first_task_fee,fee_percent,fixed"
+// @Param status query int false "状态:0-停用,1-启用"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} utils.Response{data=utils.PageResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /api/v1/admin/coupons [get]
+func (h *CouponHandler) List(c *gin.Context) {
+	var query CouponListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	coupons, total, err := h.couponService.ListCoupons(c.Request.Context(), &services.CouponListRequest{
+		CouponType: query.CouponType,
+		Status:     query.Status,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+	})
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessPageResponse(c, coupons, paginationInfo(query.Page, query.PageSize, total))
+}
+
+// Enable 启用优惠券
+// @Summary 启用优惠券
+// @Description 管理员启用优惠券
+// @Tags 优惠券
+// @Produce json
+// @Param id path int true "优惠券ID"
+// @Success 200 {object} utils.Response{data=models.Coupon}
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/v1/admin/coupons/{id}/enable [post]
+func (h *CouponHandler) Enable(c *gin.Context) {
+	h.updateStatus(c, models.CouponStatusEnabled)
+}
+
+// Disable 停用优惠券
+// @Summary 停用优惠券
+// @Description 管理员停用优惠券，停用后不能再使用，已创建的待支付订单不受影响
+// @Tags 优惠券
+// @Produce json
+// @Param id path int true "优惠券ID"
+// @Success 200 {object} utils.Response{data=models.Coupon}
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/v1/admin/coupons/{id}/disable [post]
+func (h *CouponHandler) Disable(c *gin.Context) {
+	h.updateStatus(c, models.CouponStatusDisabled)
+}
+
+// updateStatus 更新优惠券状态
+func (h *CouponHandler) updateStatus(c *gin.Context, status int8) {
+	couponID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.BadRequestResponse(c, "优惠券ID错误")
+		return
+	}
+
+	coupon, err := h.couponService.UpdateCouponStatus(c.Request.Context(), couponID, status)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, coupon)
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"task-platform-api/internal/services"
+	"task-platform-api/pkg/utils"
+)
+
+// SaveInvoiceProfileRequest 保存开票信息请求
+type SaveInvoiceProfileRequest struct {
+	TitleType   string `json:"title_type" binding:"required,oneof=personal company"` // 抬头类型
+	Title       string `json:"title" binding:"required,max=200"`                     // 发票抬头
+	TaxNo       string `json:"tax_no" binding:"max=32"`                              // 纳税人识别号，企业抬头必填
+	Address     string `json:"address" binding:"max=255"`
+	Phone       string `json:"phone" binding:"max=32"`
+	BankName    string `json:"bank_name" binding:"max=100"`
+	BankAccount string `json:"bank_account" binding:"max=64"`
+	Email       string `json:"email" binding:"omitempty,email,max=100"`
+}
+
+// IssueInvoiceRequest 申请开票请求
+type IssueInvoiceRequest struct {
+	SourceType string `json:"source_type" binding:"required,oneof=prepay platform_fee"` // 开票来源
+	SourceID   uint64 `json:"source_id" binding:"required"`                             // 预付款交易ID或结算ID
+}
+
+// InvoiceListQuery 发票列表查询参数
+type InvoiceListQuery struct {
+	SourceType string `form:"source_type" binding:"omitempty,oneof=prepay platform_fee"`
+	Status     *int8  `form:"status"`
+	Page       int    `form:"page"`
+	PageSize   int    `form:"page_size"`
+}
+
+// VoidInvoiceRequest 作废发票请求
+type VoidInvoiceRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// InvoiceHandler 发票处理器
+type InvoiceHandler struct {
+	invoiceService *services.InvoiceService
+}
+
+// NewInvoiceHandler 创建发票处理器
+func NewInvoiceHandler(invoiceService *services.InvoiceService) *InvoiceHandler {
+	return &InvoiceHandler{
+		invoiceService: invoiceService,
+	}
+}
+
+// GetProfile 我的开票信息
+// @Summary 我的开票信息
+// @Description 查询当前用户保存的发票抬头和税务信息
+// @Tags 发票
+// @Produce json
+// @Success 200 {object} utils.Response{data=models.InvoiceProfile}
+// @Failure 401 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/v1/invoices/profile [get]
+func (h *InvoiceHandler) GetProfile(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	profile, err := h.invoiceService.GetProfile(c.Request.Context(), userID)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, profile)
+}
+
+// SaveProfile 保存开票信息
+// @Summary 保存开票信息
+// @Description 保存当前用户的发票抬头和税务信息，企业抬头须填写纳税人识别号；修改不影响已开具的发票
+// @Tags 发票
+// @Accept json
+// @Produce json
+// @Param request body SaveInvoiceProfileRequest true "开票信息"
+// @Success 200 {object} utils.Response{data=models.InvoiceProfile}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /api/v1/invoices/profile [put]
+func (h *InvoiceHandler) SaveProfile(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	var req SaveInvoiceProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	profile, err := h.invoiceService.SaveProfile(c.Request.Context(), &services.InvoiceProfileRequest{
+		UserID:      userID,
+		TitleType:   req.TitleType,
+		Title:       req.Title,
+		TaxNo:       req.TaxNo,
+		Address:     req.Address,
+		Phone:       req.Phone,
+		BankName:    req.BankName,
+		BankAccount: req.BankAccount,
+		Email:       req.Email,
+	})
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, profile)
+}
+
+// Issue 申请开票
+// @Summary 申请开票
+// @Description 按当前开票信息为已支付的预付款或已结算的平台服务费开具收据，预付款按扣除已退款后的金额开具；同一笔款项只能有一张有效发票
+// @Tags 发票
+// @Accept json
+// @Produce json
+// @Param request body IssueInvoiceRequest true "开票来源"
+// @Success 201 {object} utils.Response{data=models.Invoice}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/invoices [post]
+func (h *InvoiceHandler) Issue(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	var req IssueInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	invoice, err := h.invoiceService.IssueInvoice(c.Request.Context(), &services.IssueInvoiceRequest{
+		UserID:     userID,
+		SourceType: req.SourceType,
+		SourceID:   req.SourceID,
+	})
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.CreatedResponse(c, invoice)
+}
+
+// List 我的发票
+// @Summary 我的发票
+// @Description 分页查询当前用户的发票
+// @Tags 发票
+// @Produce json
+// @Param source_type query string false "开票来源:prepay,platform_fee"
+// @Param status query int false "状态:0-已开具,1-已作废"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} utils.Response{data=utils.PageResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /api/v1/invoices [get]
+func (h *InvoiceHandler) List(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	var query InvoiceListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	invoices, total, err := h.invoiceService.ListInvoices(c.Request.Context(), &services.InvoiceListRequest{
+		UserID:     userID,
+		SourceType: query.SourceType,
+		Status:     query.Status,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+	})
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessPageResponse(c, invoices, paginationInfo(query.Page, query.PageSize, total))
+}
+
+// Detail 发票详情
+// @Summary 发票详情
+// @Description 按发票号码查询当前用户的发票
+// @Tags 发票
+// @Produce json
+// @Param invoice_no path string true "发票号码"
+// @Success 200 {object} utils.Response{data=models.Invoice}
+// @Failure 401 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/v1/invoices/{invoice_no} [get]
+func (h *InvoiceHandler) Detail(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	invoice, err := h.invoiceService.GetInvoice(c.Request.Context(), userID, c.Param("invoice_no"))
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, invoice)
+}
+
+// Receipt 打印收据
+// @Summary 打印收据
+// @Description 按开具时保存的快照渲染可打印的HTML收据，已作废的发票带作废标记
+// @Tags 发票
+// @Produce html
+// @Param invoice_no path string true "发票号码"
+// @Success 200 {string} string "HTML收据"
+// @Failure 401 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/v1/invoices/{invoice_no}/receipt [get]
+func (h *InvoiceHandler) Receipt(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	invoice, err := h.invoiceService.GetInvoice(c.Request.Context(), userID, c.Param("invoice_no"))
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := h.invoiceService.RenderReceipt(&buf, invoice); err != nil {
+		utils.InternalServerErrorResponse(c, "生成收据失败")
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// Void 作废发票
+// @Summary 作废发票
+// @Description 管理员作废已开具的发票，作废后可对同一笔款项重新开票；预付款退款成功时系统自动作废
+// @Tags 发票
+// @Accept json
+// @Produce json
+// @Param id path int true "发票ID"
+// @Param request body VoidInvoiceRequest true "作废原因"
+// @Success 200 {object} utils.Response{data=models.Invoice}
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/admin/invoices/{id}/void [post]
+func (h *InvoiceHandler) Void(c *gin.Context) {
+	invoiceID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.BadRequestResponse(c, "发票ID错误")
+		return
+	}
+
+	var req VoidInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	invoice, err := h.invoiceService.VoidInvoice(c.Request.Context(), invoiceID, req.Reason)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, invoice)
+}
@@ -1,27 +1,45 @@
 package handlers
 
 import (
+	"io"
 	"net/http"
 
 	"task-platform-api/internal/services"
+	"task-platform-api/pkg/money"
 	"task-platform-api/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-// PrePayRequest 预支付请求
+// PrePayRequest 预支付请求，金额由任务赏金和保证金计算
 type PrePayRequest struct {
-	TaskID    uint64  `json:"task_id" binding:"required"`
-	OrderType string  `json:"order_type" binding:"required"`
-	Amount    float64 `json:"amount" binding:"required,min=0.01"`
-	Remark    string  `json:"remark"`
+	TaskID        uint64   `json:"task_id" binding:"required"`
+	Remark        string   `json:"remark"`
+	PaymentMethod string   `json:"payment_method"`                           // 支付渠道：shouqianba、wechat、alipay，为空时使用默认渠道
+	OpenID        string   `json:"openid"`                                   // 微信JSAPI支付的用户标识，为空时使用扫码支付
+	Scene         string   `json:"scene" binding:"omitempty,oneof=page app"` // 支付宝支付场景，默认电脑网站支付
+	CouponCodes   []string `json:"coupon_codes" binding:"max=3"`             // 优惠码，可叠加的优惠券可同时使用多张
+}
+
+// PrePayPreviewRequest 预付款试算请求
+type PrePayPreviewRequest struct {
+	TaskID      uint64   `json:"task_id" binding:"required"`
+	CouponCodes []string `json:"coupon_codes" binding:"max=3"`
 }
 
 // PrePayResponse 预支付响应
 type PrePayResponse struct {
-	TradeNo string `json:"trade_no"`
-	PayURL  string `json:"pay_url"`
-	QRCode  string `json:"qr_code"`
+	TradeNo   string            `json:"trade_no"`
+	PayURL    string            `json:"pay_url"`
+	QRCode    string            `json:"qr_code"`
+	PayParams map[string]string `json:"pay_params,omitempty"`
+}
+
+// RefundRequest 退款请求，金额为空时退还剩余可退金额
+type RefundRequest struct {
+	TradeID uint64      `json:"trade_id" binding:"required"`
+	Amount  money.Money `json:"amount" swaggertype:"number"`
+	Reason  string      `json:"reason" binding:"max=500"`
 }
 
 // PaymentHandler 支付处理器
@@ -38,17 +56,23 @@ func NewPaymentHandler(paymentService *services.PaymentService) *PaymentHandler
 
 // PrePay 预支付
 // @Summary 预支付
-// @Description 创建预支付订单
+// @Description 为草稿任务创建预付款订单，金额为任务赏金加保证金，可使用优惠券抵扣；支付成功后资金冻结托管并自动发布任务，订单未支付关闭后优惠券自动退回；同一任务已有未过期的待支付订单时不能重复下单
 // @Tags 支付
 // @Accept json
 // @Produce json
 // @Param request body PrePayRequest true "预支付请求"
-// @Success 200 {object} utils.ApiResponse{data=PrePayResponse}
-// @Failure 400 {object} utils.ApiResponse
-// @Failure 401 {object} utils.ApiResponse
-// @Failure 500 {object} utils.ApiResponse
+// @Success 200 {object} utils.Response{data=PrePayResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 409 {object} utils.Response
 // @Router /api/v1/pay/prepay [post]
 func (h *PaymentHandler) PrePay(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
 	var req PrePayRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "参数错误")
@@ -57,26 +81,29 @@ func (h *PaymentHandler) PrePay(c *gin.Context) {
 
 	// 构造预支付请求
 	prePayReq := &services.CreatePrePayOrderRequest{
-		UserID:    1, // 暂时使用固定用户ID
-		TaskID:    req.TaskID,
-		OrderType: req.OrderType,
-		Amount:    req.Amount,
-		Remark:    req.Remark,
-		ClientIP:  c.ClientIP(),
+		UserID:        userID,
+		TaskID:        req.TaskID,
+		Remark:        req.Remark,
+		ClientIP:      c.ClientIP(),
+		PaymentMethod: req.PaymentMethod,
+		OpenID:        req.OpenID,
+		Scene:         req.Scene,
+		CouponCodes:   req.CouponCodes,
 	}
 
 	// 调用支付服务
 	trade, prePayResp, err := h.paymentService.CreatePrePayOrder(c.Request.Context(), prePayReq)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "创建预支付订单失败")
+		handleServiceError(c, err)
 		return
 	}
 
 	// 构造响应
 	resp := &PrePayResponse{
-		TradeNo: prePayResp.TradeNo,
-		PayURL:  prePayResp.PayURL,
-		QRCode:  prePayResp.QRCode,
+		TradeNo:   prePayResp.TradeNo,
+		PayURL:    prePayResp.PayURL,
+		QRCode:    prePayResp.QRCode,
+		PayParams: prePayResp.PayParams,
 	}
 
 	utils.SuccessResponse(c, gin.H{
@@ -85,28 +112,68 @@ func (h *PaymentHandler) PrePay(c *gin.Context) {
 	})
 }
 
+// PrePayPreview 预付款试算
+// @Summary 预付款试算
+// @Description 按当前服务费规则和优惠码试算草稿任务的预付款，返回优惠明细和实付金额，不创建订单也不占用优惠券
+// @Tags 支付
+// @Accept json
+// @Produce json
+// @Param request body PrePayPreviewRequest true "试算请求"
+// @Success 200 {object} utils.Response{data=services.CouponQuote}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/pay/prepay/preview [post]
+func (h *PaymentHandler) PrePayPreview(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	var req PrePayPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	quote, err := h.paymentService.PreviewPrePay(c.Request.Context(), userID, req.TaskID, req.CouponCodes)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, quote)
+}
+
 // QueryStatus 查询支付状态
 // @Summary 查询支付状态
-// @Description 查询订单支付状态
+// @Description 查询当前用户订单的支付状态；待支付订单未收到回调时按退避间隔主动向渠道查询并同步结果，客户端可仅轮询该接口
 // @Tags 支付
 // @Accept json
 // @Produce json
 // @Param order_no path string true "订单号"
-// @Success 200 {object} utils.ApiResponse{data=models.Trade}
-// @Failure 400 {object} utils.ApiResponse
-// @Failure 404 {object} utils.ApiResponse
-// @Failure 500 {object} utils.ApiResponse
+// @Success 200 {object} utils.Response{data=models.Trade}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
 // @Router /api/v1/pay/status/{order_no} [get]
 func (h *PaymentHandler) QueryStatus(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
 	orderNo := c.Param("order_no")
 	if orderNo == "" {
 		utils.ErrorResponse(c, http.StatusBadRequest, "订单号不能为空")
 		return
 	}
 
-	trade, err := h.paymentService.QueryPaymentStatus(c.Request.Context(), orderNo)
+	trade, err := h.paymentService.QueryPaymentStatus(c.Request.Context(), userID, orderNo)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "查询支付状态失败")
+		handleServiceError(c, err)
 		return
 	}
 
@@ -115,27 +182,123 @@ func (h *PaymentHandler) QueryStatus(c *gin.Context) {
 
 // PaymentCallback 支付回调
 // @Summary 支付回调
-// @Description 处理支付回调通知
+// @Description 处理支付渠道的异步通知，/callback使用默认渠道，/callback/{provider}按渠道标识处理
 // @Tags 支付
-// @Accept json
-// @Produce json
+// @Accept x-www-form-urlencoded,json
+// @Produce plain
+// @Param provider path string false "支付渠道：shouqianba、wechat、alipay、fake"
 // @Success 200 {string} string "success"
 // @Failure 400 {string} string "fail"
 // @Router /api/v1/pay/callback [post]
+// @Router /api/v1/pay/callback/{provider} [post]
 func (h *PaymentHandler) PaymentCallback(c *gin.Context) {
-	// 解析回调数据
-	data := make(map[string]string)
-	for key, values := range c.Request.PostForm {
-		if len(values) > 0 {
-			data[key] = values[0]
-		}
+	// 签名基于原始报文，由渠道自行解析
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.String(http.StatusBadRequest, "fail")
+		return
 	}
 
-	err := h.paymentService.ProcessPaymentCallback(c.Request.Context(), data)
+	err = h.paymentService.ProcessPaymentCallback(c.Request.Context(), c.Param("provider"), c.Request.Header, body)
 	if err != nil {
 		c.String(http.StatusBadRequest, "fail")
 		return
 	}
 
 	c.String(http.StatusOK, "success")
-}
\ No newline at end of file
+}
+
+// Refund 申请退款
+// @Summary 申请退款
+// @Description 任务取消后对预付款交易申请全额或部分退款，累计退款不超过已解冻退回钱包的预付款，退款金额从钱包可用余额扣除
+// @Tags 支付
+// @Accept json
+// @Produce json
+// @Param request body RefundRequest true "退款请求"
+// @Success 200 {object} utils.Response{data=models.Refund}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Failure 502 {object} utils.Response
+// @Router /api/v1/pay/refunds [post]
+func (h *PaymentHandler) Refund(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	var req RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Amount.IsNegative() {
+		utils.ErrorResponse(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+
+	refund, err := h.paymentService.CreateRefund(c.Request.Context(), &services.CreateRefundRequest{
+		UserID:  userID,
+		TradeID: req.TradeID,
+		Amount:  req.Amount,
+		Reason:  req.Reason,
+	})
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, refund)
+}
+
+// QueryRefund 查询退款状态
+// @Summary 查询退款状态
+// @Description 查询当前用户的退款，处理中的退款会向渠道查询最新结果
+// @Tags 支付
+// @Accept json
+// @Produce json
+// @Param refund_no path string true "退款单号"
+// @Success 200 {object} utils.Response{data=models.Refund}
+// @Failure 401 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/v1/pay/refunds/{refund_no} [get]
+func (h *PaymentHandler) QueryRefund(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	refund, err := h.paymentService.QueryRefund(c.Request.Context(), userID, c.Param("refund_no"))
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, refund)
+}
+
+// RefundCallback 退款回调
+// @Summary 退款回调
+// @Description 处理支付渠道的退款结果通知，/refund/callback使用默认渠道，/refund/callback/{provider}按渠道标识处理
+// @Tags 支付
+// @Accept x-www-form-urlencoded,json
+// @Produce plain
+// @Param provider path string false "支付渠道：shouqianba、wechat、fake"
+// @Success 200 {string} string "success"
+// @Failure 400 {string} string "fail"
+// @Router /api/v1/pay/refund/callback [post]
+// @Router /api/v1/pay/refund/callback/{provider} [post]
+func (h *PaymentHandler) RefundCallback(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.String(http.StatusBadRequest, "fail")
+		return
+	}
+
+	err = h.paymentService.ProcessRefundCallback(c.Request.Context(), c.Param("provider"), c.Request.Header, body)
+	if err != nil {
+		c.String(http.StatusBadRequest, "fail")
+		return
+	}
+
+	c.String(http.StatusOK, "success")
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"task-platform-api/internal/models"
+	"task-platform-api/internal/services"
+	"task-platform-api/pkg/money"
+	"task-platform-api/pkg/utils"
+)
+
+// CreateTaskRequest 创建任务请求
+type CreateTaskRequest struct {
+	Title       string      `json:"title" binding:"required,max=200"`
+	Content     string      `json:"content" binding:"required"`
+	Amount      money.Money `json:"amount" binding:"required,gt=0" swaggertype:"number"`
+	Deadline    time.Time   `json:"deadline" binding:"required"`
+	CategoryID  uint64      `json:"category_id"`
+	Tags        string      `json:"tags"`
+	Attachments string      `json:"attachments"`
+
+	MaxRevisions *int                     `json:"max_revisions" binding:"omitempty,min=0,max=10"`
+	Stages       []CreateTaskStageRequest `json:"stages" binding:"omitempty,max=20,dive"`
+}
+
+// CreateTaskStageRequest 任务阶段定义
+type CreateTaskStageRequest struct {
+	StageName   string     `json:"stage_name" binding:"required,max=100"`
+	AmountRatio float64    `json:"amount_ratio" binding:"required,gt=0,lte=1"`
+	Deadline    *time.Time `json:"deadline"`
+	Description string     `json:"description"`
+}
+
+// TaskTransitionRequest 任务状态操作请求
+type TaskTransitionRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// TaskListQuery 任务列表查询参数
+type TaskListQuery struct {
+	Status      *int8   `form:"status"`
+	CategoryID  *uint64 `form:"category_id"`
+	PublisherID *uint64 `form:"publisher_id"`
+	TakerID     *uint64 `form:"taker_id"`
+	Keyword     string  `form:"keyword"`
+	Page        int     `form:"page"`
+	PageSize    int     `form:"page_size"`
+}
+
+// TaskHandler 任务处理器
+type TaskHandler struct {
+	taskService *services.TaskService
+}
+
+// NewTaskHandler 创建任务处理器
+func NewTaskHandler(taskService *services.TaskService) *TaskHandler {
+	return &TaskHandler{
+		taskService: taskService,
+	}
+}
+
+// List 任务列表
+// @Summary 任务列表
+// @Description 分页查询任务列表
+// @Tags 任务
+// @Produce json
+// @Param status query int false "任务状态"
+// @Param category_id query int false "分类ID"
+// @Param keyword query string false "关键词"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} utils.Response{data=utils.PageResponse}
+// @Router /api/v1/tasks [get]
+func (h *TaskHandler) List(c *gin.Context) {
+	var query TaskListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	tasks, total, err := h.taskService.ListTasks(c.Request.Context(), &services.TaskListRequest{
+		Status:      query.Status,
+		CategoryID:  query.CategoryID,
+		PublisherID: query.PublisherID,
+		TakerID:     query.TakerID,
+		Keyword:     query.Keyword,
+		Page:        query.Page,
+		PageSize:    query.PageSize,
+	})
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	_, limit := utils.Pagination(query.Page, query.PageSize)
+	page := query.Page
+	if page < 1 {
+		page = 1
+	}
+	utils.SuccessPageResponse(c, tasks, utils.PaginationInfo{
+		Page:       page,
+		PageSize:   limit,
+		Total:      total,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+	})
+}
+
+// Detail 任务详情
+// @Summary 任务详情
+// @Description 获取任务详情，包含状态变更历史
+// @Tags 任务
+// @Produce json
+// @Param id path int true "任务ID"
+// @Success 200 {object} utils.Response{data=models.Task}
+// @Failure 404 {object} utils.Response
+// @Router /api/v1/tasks/{id} [get]
+func (h *TaskHandler) Detail(c *gin.Context) {
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.BadRequestResponse(c, "任务ID错误")
+		return
+	}
+
+	task, err := h.taskService.GetTask(c.Request.Context(), taskID)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, task)
+}
+
+// Create 创建任务
+// @Summary 创建任务
+// @Description 创建草稿状态的任务，可定义分阶段交付及各阶段金额比例
+// @Tags 任务
+// @Accept json
+// @Produce json
+// @Param request body CreateTaskRequest true "创建任务请求"
+// @Success 201 {object} utils.Response{data=models.Task}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /api/v1/tasks [post]
+func (h *TaskHandler) Create(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	var req CreateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	stages := make([]services.CreateTaskStageRequest, len(req.Stages))
+	for i, stage := range req.Stages {
+		stages[i] = services.CreateTaskStageRequest{
+			StageName:   stage.StageName,
+			AmountRatio: stage.AmountRatio,
+			Deadline:    stage.Deadline,
+			Description: stage.Description,
+		}
+	}
+
+	task, err := h.taskService.CreateTask(c.Request.Context(), &services.CreateTaskRequest{
+		PublisherID:  userID,
+		Title:        req.Title,
+		Content:      req.Content,
+		Amount:       req.Amount,
+		Deadline:     req.Deadline,
+		CategoryID:   req.CategoryID,
+		Tags:         req.Tags,
+		Attachments:  req.Attachments,
+		MaxRevisions: req.MaxRevisions,
+		Stages:       stages,
+	})
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.CreatedResponse(c, task)
+}
+
+// Publish 发布任务
+// @Summary 发布任务
+// @Description 草稿 → 待接取
+// @Tags 任务
+// @Produce json
+// @Param id path int true "任务ID"
+// @Param request body TaskTransitionRequest false "操作原因"
+// @Success 200 {object} utils.Response{data=models.Task}
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/tasks/{id}/publish [post]
+func (h *TaskHandler) Publish(c *gin.Context) {
+	h.transit(c, h.taskService.PublishTask)
+}
+
+// Take 接取任务
+// @Summary 接取任务
+// @Description 待接取 → 进行中，从接取者钱包冻结任务保证金，任务完成或取消后退还
+// @Tags 任务
+// @Produce json
+// @Param id path int true "任务ID"
+// @Param request body TaskTransitionRequest false "操作原因"
+// @Success 200 {object} utils.Response{data=models.Task}
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/tasks/{id}/take [post]
+func (h *TaskHandler) Take(c *gin.Context) {
+	h.transit(c, h.taskService.TakeTask)
+}
+
+// Cancel 取消任务
+// @Summary 取消任务
+// @Description 草稿/待接取/已逾期 → 已取消，逾期任务接取者的保证金在违规处理后退还
+// @Tags 任务
+// @Produce json
+// @Param id path int true "任务ID"
+// @Param request body TaskTransitionRequest false "操作原因"
+// @Success 200 {object} utils.Response{data=models.Task}
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/tasks/{id}/cancel [post]
+func (h *TaskHandler) Cancel(c *gin.Context) {
+	h.transit(c, h.taskService.CancelTask)
+}
+
+// transit 执行任务状态流转操作
+func (h *TaskHandler) transit(c *gin.Context, action func(ctx context.Context, taskID, userID uint64, reason string) (*models.Task, error)) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.BadRequestResponse(c, "任务ID错误")
+		return
+	}
+
+	var req TaskTransitionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.BadRequestResponse(c, "参数错误")
+			return
+		}
+	}
+
+	task, err := action(c.Request.Context(), taskID, userID, req.Reason)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, task)
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+
+	"task-platform-api/internal/models"
+	"task-platform-api/internal/services"
+	"task-platform-api/pkg/money"
+	"task-platform-api/pkg/utils"
+)
+
+// ApplyTaskRequest 申请任务请求
+type ApplyTaskRequest struct {
+	QuotedPrice money.Money `json:"quoted_price" binding:"min=0" swaggertype:"number"`
+	Message     string      `json:"message" binding:"max=2000"`
+	Attachments string      `json:"attachments"`
+}
+
+// ReviewApplicationRequest 审核申请请求
+type ReviewApplicationRequest struct {
+	Note string `json:"note" binding:"max=500"`
+}
+
+// TaskApplicationHandler 任务申请处理器
+type TaskApplicationHandler struct {
+	applicationService *services.TaskApplicationService
+}
+
+// NewTaskApplicationHandler 创建任务申请处理器
+func NewTaskApplicationHandler(applicationService *services.TaskApplicationService) *TaskApplicationHandler {
+	return &TaskApplicationHandler{
+		applicationService: applicationService,
+	}
+}
+
+// Apply 申请任务
+// @Summary 申请任务
+// @Description 对待接取的任务提交申请及报价
+// @Tags 任务申请
+// @Accept json
+// @Produce json
+// @Param id path int true "任务ID"
+// @Param request body ApplyTaskRequest true "申请请求"
+// @Success 201 {object} utils.Response{data=models.TaskApplication}
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/tasks/{id}/applications [post]
+func (h *TaskApplicationHandler) Apply(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.BadRequestResponse(c, "任务ID错误")
+		return
+	}
+
+	var req ApplyTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	application, err := h.applicationService.Apply(c.Request.Context(), &services.ApplyTaskRequest{
+		TaskID:      taskID,
+		ApplicantID: userID,
+		QuotedPrice: req.QuotedPrice,
+		Message:     req.Message,
+		Attachments: req.Attachments,
+	})
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.CreatedResponse(c, application)
+}
+
+// List 申请列表
+// @Summary 申请列表
+// @Description 发布者查看任务收到的申请
+// @Tags 任务申请
+// @Produce json
+// @Param id path int true "任务ID"
+// @Param status query int false "申请状态"
+// @Success 200 {object} utils.Response{data=[]models.TaskApplication}
+// @Failure 403 {object} utils.Response
+// @Router /api/v1/tasks/{id}/applications [get]
+func (h *TaskApplicationHandler) List(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.BadRequestResponse(c, "任务ID错误")
+		return
+	}
+
+	var query struct {
+		Status *int8 `form:"status"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	applications, err := h.applicationService.ListApplications(c.Request.Context(), taskID, userID, query.Status)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, applications)
+}
+
+// Shortlist 加入候选
+// @Summary 加入候选
+// @Description 发布者将待审核的申请加入候选名单
+// @Tags 任务申请
+// @Accept json
+// @Produce json
+// @Param id path int true "任务ID"
+// @Param application_id path int true "申请ID"
+// @Param request body ReviewApplicationRequest false "审核备注"
+// @Success 200 {object} utils.Response{data=models.TaskApplication}
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/tasks/{id}/applications/{application_id}/shortlist [post]
+func (h *TaskApplicationHandler) Shortlist(c *gin.Context) {
+	h.review(c, h.applicationService.Shortlist)
+}
+
+// Accept 接受申请
+// @Summary 接受申请
+// @Description 发布者接受申请并冻结申请者的任务保证金，任务进入进行中，其余申请自动拒绝
+// @Tags 任务申请
+// @Accept json
+// @Produce json
+// @Param id path int true "任务ID"
+// @Param application_id path int true "申请ID"
+// @Param request body ReviewApplicationRequest false "审核备注"
+// @Success 200 {object} utils.Response{data=models.TaskApplication}
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/tasks/{id}/applications/{application_id}/accept [post]
+func (h *TaskApplicationHandler) Accept(c *gin.Context) {
+	h.review(c, h.applicationService.Accept)
+}
+
+// Reject 拒绝申请
+// @Summary 拒绝申请
+// @Description 发布者拒绝申请
+// @Tags 任务申请
+// @Accept json
+// @Produce json
+// @Param id path int true "任务ID"
+// @Param application_id path int true "申请ID"
+// @Param request body ReviewApplicationRequest false "审核备注"
+// @Success 200 {object} utils.Response{data=models.TaskApplication}
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/tasks/{id}/applications/{application_id}/reject [post]
+func (h *TaskApplicationHandler) Reject(c *gin.Context) {
+	h.review(c, h.applicationService.Reject)
+}
+
+// review 执行申请审核操作
+func (h *TaskApplicationHandler) review(c *gin.Context, action func(ctx context.Context, taskID, applicationID, userID uint64, note string) (*models.TaskApplication, error)) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.BadRequestResponse(c, "任务ID错误")
+		return
+	}
+
+	applicationID, ok := parseIDParam(c, "application_id")
+	if !ok {
+		utils.BadRequestResponse(c, "申请ID错误")
+		return
+	}
+
+	var req ReviewApplicationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.BadRequestResponse(c, "参数错误")
+			return
+		}
+	}
+
+	application, err := action(c.Request.Context(), taskID, applicationID, userID, req.Note)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, application)
+}
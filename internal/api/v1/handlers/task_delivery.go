@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"task-platform-api/internal/services"
+	"task-platform-api/pkg/utils"
+)
+
+// SubmitDeliveryRequest 提交交付请求
+type SubmitDeliveryRequest struct {
+	StageID *uint64 `json:"stage_id"`
+	FileURL string  `json:"file_url" binding:"max=500"`
+	Content string  `json:"content"`
+}
+
+// ReviewDeliveryRequest 验收交付请求
+type ReviewDeliveryRequest struct {
+	Feedback string `json:"feedback"`
+}
+
+// TaskDeliveryHandler 任务交付处理器
+type TaskDeliveryHandler struct {
+	deliveryService *services.TaskDeliveryService
+}
+
+// NewTaskDeliveryHandler 创建任务交付处理器
+func NewTaskDeliveryHandler(deliveryService *services.TaskDeliveryService) *TaskDeliveryHandler {
+	return &TaskDeliveryHandler{
+		deliveryService: deliveryService,
+	}
+}
+
+// Submit 提交交付
+// @Summary 提交交付
+// @Description 接取者提交交付，分阶段任务需指定当前阶段
+// @Tags 任务交付
+// @Accept json
+// @Produce json
+// @Param id path int true "任务ID"
+// @Param request body SubmitDeliveryRequest true "交付内容"
+// @Success 201 {object} utils.Response{data=models.TaskDelivery}
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/tasks/{id}/deliveries [post]
+func (h *TaskDeliveryHandler) Submit(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.BadRequestResponse(c, "任务ID错误")
+		return
+	}
+
+	var req SubmitDeliveryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	delivery, err := h.deliveryService.Submit(c.Request.Context(), &services.SubmitDeliveryRequest{
+		TaskID:  taskID,
+		TakerID: userID,
+		StageID: req.StageID,
+		FileURL: req.FileURL,
+		Content: req.Content,
+	})
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.CreatedResponse(c, delivery)
+}
+
+// List 交付记录
+// @Summary 交付记录
+// @Description 查询任务的交付记录
+// @Tags 任务交付
+// @Produce json
+// @Param id path int true "任务ID"
+// @Success 200 {object} utils.Response{data=[]models.TaskDelivery}
+// @Failure 403 {object} utils.Response
+// @Router /api/v1/tasks/{id}/deliveries [get]
+func (h *TaskDeliveryHandler) List(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.BadRequestResponse(c, "任务ID错误")
+		return
+	}
+
+	deliveries, err := h.deliveryService.ListDeliveries(c.Request.Context(), taskID, userID)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, deliveries)
+}
+
+// Accept 验收交付
+// @Summary 验收交付
+// @Description 发布者验收交付，分阶段任务按阶段生成结算
+// @Tags 任务交付
+// @Accept json
+// @Produce json
+// @Param id path int true "任务ID"
+// @Param delivery_id path int true "交付ID"
+// @Param request body ReviewDeliveryRequest false "验收反馈"
+// @Success 200 {object} utils.Response{data=models.TaskDelivery}
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/tasks/{id}/deliveries/{delivery_id}/accept [post]
+func (h *TaskDeliveryHandler) Accept(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.BadRequestResponse(c, "任务ID错误")
+		return
+	}
+
+	deliveryID, ok := parseIDParam(c, "delivery_id")
+	if !ok {
+		utils.BadRequestResponse(c, "交付ID错误")
+		return
+	}
+
+	var req ReviewDeliveryRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.BadRequestResponse(c, "参数错误")
+			return
+		}
+	}
+
+	delivery, err := h.deliveryService.Accept(c.Request.Context(), taskID, deliveryID, userID, req.Feedback)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, delivery)
+}
+
+// RequestRevision 要求整改
+// @Summary 要求整改
+// @Description 发布者驳回交付并要求整改，任务回到进行中，超过最大整改轮次后不可再驳回
+// @Tags 任务交付
+// @Accept json
+// @Produce json
+// @Param id path int true "任务ID"
+// @Param delivery_id path int true "交付ID"
+// @Param request body ReviewDeliveryRequest true "整改意见"
+// @Success 200 {object} utils.Response{data=models.TaskDelivery}
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/tasks/{id}/deliveries/{delivery_id}/revise [post]
+func (h *TaskDeliveryHandler) RequestRevision(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.BadRequestResponse(c, "任务ID错误")
+		return
+	}
+
+	deliveryID, ok := parseIDParam(c, "delivery_id")
+	if !ok {
+		utils.BadRequestResponse(c, "交付ID错误")
+		return
+	}
+
+	var req ReviewDeliveryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	delivery, err := h.deliveryService.RequestRevision(c.Request.Context(), taskID, deliveryID, userID, req.Feedback)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, delivery)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"task-platform-api/internal/services"
+	"task-platform-api/pkg/money"
+	"task-platform-api/pkg/utils"
+)
+
+// ViolationListQuery 违规列表查询参数
+type ViolationListQuery struct {
+	UserID   *uint64 `form:"user_id"`
+	TaskID   *uint64 `form:"task_id"`
+	Status   *int8   `form:"status"`
+	Page     int     `form:"page"`
+	PageSize int     `form:"page_size"`
+}
+
+// ConfirmViolationRequest 确认违规请求
+type ConfirmViolationRequest struct {
+	Penalty    *money.Money `json:"penalty" swaggertype:"number"` // 违约金，须大于0，为空时使用违规记录的违约金
+	ForfeitAll bool         `json:"forfeit_all"`                  // 没收接取者在该任务的全部剩余保证金，不能与违约金同时指定
+}
+
+// DismissViolationRequest 撤销违规请求
+type DismissViolationRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// ViolationHandler 违规处理器
+type ViolationHandler struct {
+	violationService *services.ViolationService
+}
+
+// NewViolationHandler 创建违规处理器
+func NewViolationHandler(violationService *services.ViolationService) *ViolationHandler {
+	return &ViolationHandler{
+		violationService: violationService,
+	}
+}
+
+// AdminList 违规记录列表
+// @Summary 违规记录列表
+// @Description 管理员分页查询违规记录，status=0为待处理
+// @Tags 违规
+// @Produce json
+// @Param user_id query int false "违规用户ID"
+// @Param task_id query int false "关联任务ID"
+// @Param status query int false "状态:0-待处理,1-已处理,2-已申诉,3-已撤销"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} utils.Response{data=utils.PageResponse}
+// @Failure 403 {object} utils.Response
+// @Router /api/v1/admin/violations [get]
+func (h *ViolationHandler) AdminList(c *gin.Context) {
+	var query ViolationListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	violations, total, err := h.violationService.ListViolations(c.Request.Context(), &services.ViolationListRequest{
+		UserID:   query.UserID,
+		TaskID:   query.TaskID,
+		Status:   query.Status,
+		Page:     query.Page,
+		PageSize: query.PageSize,
+	})
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessPageResponse(c, violations, paginationInfo(query.Page, query.PageSize, total))
+}
+
+// Confirm 确认违规
+// @Summary 确认违规
+// @Description 管理员确认违规，违约金从接取者在该任务冻结的保证金中扣除，部分补偿发布者，其余归平台；违约金须大于0，没收全部保证金须显式指定forfeit_all
+// @Tags 违规
+// @Accept json
+// @Produce json
+// @Param id path int true "违规记录ID"
+// @Param request body ConfirmViolationRequest false "违约金或没收全部保证金"
+// @Success 200 {object} utils.Response{data=models.Violation}
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/admin/violations/{id}/confirm [post]
+func (h *ViolationHandler) Confirm(c *gin.Context) {
+	violationID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.BadRequestResponse(c, "违规记录ID错误")
+		return
+	}
+
+	var req ConfirmViolationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.BadRequestResponse(c, "参数错误")
+			return
+		}
+	}
+
+	violation, err := h.violationService.ConfirmViolation(c.Request.Context(), violationID, req.Penalty, req.ForfeitAll)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, violation)
+}
+
+// Dismiss 撤销违规
+// @Summary 撤销违规
+// @Description 管理员撤销违规记录，不扣除违约金，任务已结束时退还接取者保证金
+// @Tags 违规
+// @Accept json
+// @Produce json
+// @Param id path int true "违规记录ID"
+// @Param request body DismissViolationRequest true "撤销原因"
+// @Success 200 {object} utils.Response{data=models.Violation}
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/admin/violations/{id}/dismiss [post]
+func (h *ViolationHandler) Dismiss(c *gin.Context) {
+	violationID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.BadRequestResponse(c, "违规记录ID错误")
+		return
+	}
+
+	var req DismissViolationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	violation, err := h.violationService.DismissViolation(c.Request.Context(), violationID, req.Reason)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, violation)
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"task-platform-api/internal/services"
+	"task-platform-api/pkg/utils"
+)
+
+// 对账单导出格式
+const (
+	statementFormatCSV  = "csv"
+	statementFormatJSON = "json"
+)
+
+// WalletTransactionQuery 钱包流水查询参数
+type WalletTransactionQuery struct {
+	Type      string  `form:"type" binding:"omitempty,oneof=income expense freeze unfreeze"`
+	StartDate string  `form:"start_date"` // 起始日期，格式2006-01-02
+	EndDate   string  `form:"end_date"`   // 结束日期（含当天），格式2006-01-02
+	TaskID    *uint64 `form:"task_id"`
+	Cursor    uint64  `form:"cursor"`
+	Limit     int     `form:"limit"`
+}
+
+// WalletStatementQuery 对账单导出参数
+type WalletStatementQuery struct {
+	Month  string `form:"month"` // 账单月份，格式2006-01，默认当月
+	Format string `form:"format" binding:"omitempty,oneof=csv json"`
+}
+
+// WalletHandler 钱包处理器
+type WalletHandler struct {
+	walletService *services.WalletService
+}
+
+// NewWalletHandler 创建钱包处理器
+func NewWalletHandler(walletService *services.WalletService) *WalletHandler {
+	return &WalletHandler{
+		walletService: walletService,
+	}
+}
+
+// Detail 我的钱包
+// @Summary 我的钱包
+// @Description 查询当前用户的可用余额、冻结余额、累计收入和累计提现
+// @Tags 钱包
+// @Produce json
+// @Success 200 {object} utils.Response{data=models.Wallet}
+// @Failure 401 {object} utils.Response
+// @Router /api/v1/wallet [get]
+func (h *WalletHandler) Detail(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	wallet, err := h.walletService.GetWallet(c.Request.Context(), userID)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, wallet)
+}
+
+// Transactions 钱包流水
+// @Summary 钱包流水
+// @Description 按时间倒序游标分页查询当前用户的钱包流水，将返回的next_cursor作为cursor获取下一页
+// @Tags 钱包
+// @Produce json
+// @Param type query string false "流水类型:income,expense,freeze,unfreeze"
+// @Param start_date query string false "起始日期，格式2006-01-02"
+// @Param end_date query string false "结束日期（含当天），格式2006-01-02"
+// @Param task_id query int false "关联任务ID"
+// @Param cursor query int false "游标，首页不传"
+// @Param limit query int false "每页数量，默认20，最大100"
+// @Success 200 {object} utils.Response{data=utils.CursorResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /api/v1/wallet/transactions [get]
+func (h *WalletHandler) Transactions(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	var query WalletTransactionQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+	startTime, err := parseDateParam(query.StartDate, 0)
+	if err != nil {
+		utils.BadRequestResponse(c, "起始日期格式错误")
+		return
+	}
+	endTime, err := parseDateParam(query.EndDate, 1)
+	if err != nil {
+		utils.BadRequestResponse(c, "结束日期格式错误")
+		return
+	}
+
+	transactions, next, err := h.walletService.ListTransactions(c.Request.Context(), &services.WalletTransactionListRequest{
+		UserID:    userID,
+		Type:      query.Type,
+		StartTime: startTime,
+		EndTime:   endTime,
+		TaskID:    query.TaskID,
+		Cursor:    query.Cursor,
+		Limit:     query.Limit,
+	})
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessCursorResponse(c, transactions, next)
+}
+
+// Statement 下载我的月度对账单
+// @Summary 下载月度对账单
+// @Description 下载当前用户指定月份的钱包对账单，包含期初期末余额、收支汇总和流水明细
+// @Tags 钱包
+// @Produce text/csv
+// @Produce json
+// @Param month query string false "账单月份，格式2006-01，默认当月"
+// @Param format query string false "导出格式:csv,json，默认csv"
+// @Success 200 {file} file
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /api/v1/wallet/statement [get]
+func (h *WalletHandler) Statement(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+	h.exportStatement(c, userID)
+}
+
+// UserStatement 财务下载用户月度对账单
+// @Summary 财务下载用户月度对账单
+// @Description 管理员或财务人员下载指定用户的月度钱包对账单
+// @Tags 钱包
+// @Produce text/csv
+// @Produce json
+// @Param user_id path int true "用户ID"
+// @Param month query string false "账单月份，格式2006-01，默认当月"
+// @Param format query string false "导出格式:csv,json，默认csv"
+// @Success 200 {file} file
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /api/v1/finance/wallets/{user_id}/statement [get]
+func (h *WalletHandler) UserStatement(c *gin.Context) {
+	userID, ok := parseIDParam(c, "user_id")
+	if !ok {
+		utils.BadRequestResponse(c, "用户ID错误")
+		return
+	}
+	h.exportStatement(c, userID)
+}
+
+// exportStatement 生成对账单并以附件形式返回
+func (h *WalletHandler) exportStatement(c *gin.Context, userID uint64) {
+	var query WalletStatementQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+	month := time.Now()
+	if query.Month != "" {
+		parsed, err := time.ParseInLocation("2006-01", query.Month, time.Local)
+		if err != nil {
+			utils.BadRequestResponse(c, "账单月份格式错误")
+			return
+		}
+		month = parsed
+	}
+
+	statement, err := h.walletService.MonthlyStatement(c.Request.Context(), userID, month)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	contentType := "text/csv; charset=utf-8"
+	format := query.Format
+	if format == "" {
+		format = statementFormatCSV
+	}
+	if format == statementFormatJSON {
+		contentType = "application/json; charset=utf-8"
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(statement)
+	} else {
+		err = statement.WriteCSV(&buf)
+	}
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "生成对账单失败")
+		return
+	}
+
+	filename := fmt.Sprintf("wallet-statement-%d-%s.%s", userID, statement.Month, format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// parseDateParam 解析日期参数，空字符串返回nil，offsetDays用于将结束日期转换为次日零点
+func parseDateParam(value string, offsetDays int) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	date = date.AddDate(0, 0, offsetDays)
+	return &date, nil
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"task-platform-api/internal/services"
+	"task-platform-api/pkg/money"
+	"task-platform-api/pkg/utils"
+)
+
+// CreateWithdrawRequest 提现申请请求
+type CreateWithdrawRequest struct {
+	Amount   money.Money `json:"amount" binding:"required" swaggertype:"number"`
+	Method   string      `json:"method" binding:"required,oneof=alipay wechat bank"` // 提现方式
+	Account  string      `json:"account" binding:"required,max=128"`                 // 支付宝账号、微信OpenID或银行卡号
+	RealName string      `json:"real_name" binding:"max=50"`                         // 收款人姓名
+	BankCode string      `json:"bank_code" binding:"max=20"`                         // 银行代码，银行卡提现必填
+}
+
+// WithdrawListQuery 提现列表查询参数
+type WithdrawListQuery struct {
+	Status   *int8 `form:"status"`
+	Page     int   `form:"page"`
+	PageSize int   `form:"page_size"`
+}
+
+// RejectWithdrawRequest 拒绝提现请求
+type RejectWithdrawRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// WithdrawHandler 提现处理器
+type WithdrawHandler struct {
+	withdrawService *services.WithdrawService
+}
+
+// NewWithdrawHandler 创建提现处理器
+func NewWithdrawHandler(withdrawService *services.WithdrawService) *WithdrawHandler {
+	return &WithdrawHandler{
+		withdrawService: withdrawService,
+	}
+}
+
+// Create 申请提现
+// @Summary 申请提现
+// @Description 从钱包可用余额申请提现，金额立即冻结；大额或有风险的申请进入人工审核，其余直接打款
+// @Tags 提现
+// @Accept json
+// @Produce json
+// @Param request body CreateWithdrawRequest true "提现申请"
+// @Success 201 {object} utils.Response{data=models.WithdrawRequest}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Failure 502 {object} utils.Response
+// @Router /api/v1/wallet/withdrawals [post]
+func (h *WithdrawHandler) Create(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	var req CreateWithdrawRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	withdraw, err := h.withdrawService.CreateWithdraw(c.Request.Context(), &services.CreateWithdrawRequest{
+		UserID: userID,
+		Amount: req.Amount,
+		Method: req.Method,
+		Account: services.WithdrawAccount{
+			Account:  req.Account,
+			RealName: req.RealName,
+			BankCode: req.BankCode,
+		},
+	})
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.CreatedResponse(c, withdraw)
+}
+
+// List 我的提现记录
+// @Summary 我的提现记录
+// @Description 分页查询当前用户的提现申请
+// @Tags 提现
+// @Produce json
+// @Param status query int false "状态:0-待处理,1-处理中,2-已完成,3-已拒绝"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} utils.Response{data=utils.PageResponse}
+// @Failure 401 {object} utils.Response
+// @Router /api/v1/wallet/withdrawals [get]
+func (h *WithdrawHandler) List(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	var query WithdrawListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	withdraws, total, err := h.withdrawService.ListWithdraws(c.Request.Context(), &services.WithdrawListRequest{
+		UserID:   &userID,
+		Status:   query.Status,
+		Page:     query.Page,
+		PageSize: query.PageSize,
+	})
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessPageResponse(c, withdraws, paginationInfo(query.Page, query.PageSize, total))
+}
+
+// Detail 提现详情
+// @Summary 提现详情
+// @Description 查询当前用户的提现申请，打款中的申请会向渠道查询最新结果
+// @Tags 提现
+// @Produce json
+// @Param request_no path string true "提现申请单号"
+// @Success 200 {object} utils.Response{data=models.WithdrawRequest}
+// @Failure 401 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/v1/wallet/withdrawals/{request_no} [get]
+func (h *WithdrawHandler) Detail(c *gin.Context) {
+	userID, ok := getCurrentUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c, "未认证用户")
+		return
+	}
+
+	withdraw, err := h.withdrawService.GetWithdraw(c.Request.Context(), userID, c.Param("request_no"))
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, withdraw)
+}
+
+// AdminList 提现审核列表
+// @Summary 提现审核列表
+// @Description 管理员分页查询提现申请，status=0为待审核
+// @Tags 提现
+// @Produce json
+// @Param status query int false "状态:0-待处理,1-处理中,2-已完成,3-已拒绝"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} utils.Response{data=utils.PageResponse}
+// @Failure 403 {object} utils.Response
+// @Router /api/v1/admin/withdrawals [get]
+func (h *WithdrawHandler) AdminList(c *gin.Context) {
+	var query WithdrawListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	withdraws, total, err := h.withdrawService.ListWithdraws(c.Request.Context(), &services.WithdrawListRequest{
+		Status:   query.Status,
+		Page:     query.Page,
+		PageSize: query.PageSize,
+	})
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessPageResponse(c, withdraws, paginationInfo(query.Page, query.PageSize, total))
+}
+
+// Approve 审核通过提现
+// @Summary 审核通过提现
+// @Description 管理员审核通过待处理的提现申请并提交渠道打款
+// @Tags 提现
+// @Produce json
+// @Param id path int true "提现申请ID"
+// @Success 200 {object} utils.Response{data=models.WithdrawRequest}
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Failure 502 {object} utils.Response
+// @Router /api/v1/admin/withdrawals/{id}/approve [post]
+func (h *WithdrawHandler) Approve(c *gin.Context) {
+	withdrawID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.BadRequestResponse(c, "提现申请ID错误")
+		return
+	}
+
+	withdraw, err := h.withdrawService.ApproveWithdraw(c.Request.Context(), withdrawID)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, withdraw)
+}
+
+// Reject 审核拒绝提现
+// @Summary 审核拒绝提现
+// @Description 管理员拒绝待处理的提现申请，冻结金额退回可用余额
+// @Tags 提现
+// @Accept json
+// @Produce json
+// @Param id path int true "提现申请ID"
+// @Param request body RejectWithdrawRequest true "拒绝原因"
+// @Success 200 {object} utils.Response{data=models.WithdrawRequest}
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/admin/withdrawals/{id}/reject [post]
+func (h *WithdrawHandler) Reject(c *gin.Context) {
+	withdrawID, ok := parseIDParam(c, "id")
+	if !ok {
+		utils.BadRequestResponse(c, "提现申请ID错误")
+		return
+	}
+
+	var req RejectWithdrawRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "参数错误")
+		return
+	}
+
+	withdraw, err := h.withdrawService.RejectWithdraw(c.Request.Context(), withdrawID, req.Reason)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, withdraw)
+}
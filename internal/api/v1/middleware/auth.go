@@ -2,6 +2,7 @@ package middleware
 
 import (
     "net/http"
+    "strconv"
     "strings"
     "time"
 
@@ -105,6 +106,45 @@ func RequireRole(roles ...string) gin.HandlerFunc {
     }
 }
 
+// RequireAdmin 管理员权限中间件，管理员为配置中列出的用户ID，须在JWTAuth之后使用
+func RequireAdmin(cfg *config.AdminConfig) gin.HandlerFunc {
+    return requireUserIDs("admin", cfg.UserIDs)
+}
+
+// RequireFinance 财务权限中间件，允许管理员和财务人员访问
+func RequireFinance(cfg *config.AdminConfig) gin.HandlerFunc {
+    return requireUserIDs("finance", cfg.UserIDs, cfg.FinanceUserIDs)
+}
+
+// requireUserIDs 按配置的用户ID名单授权，通过后将角色写入上下文
+func requireUserIDs(role string, idLists ...[]uint64) gin.HandlerFunc {
+    allowed := make(map[uint64]bool)
+    for _, ids := range idLists {
+        for _, id := range ids {
+            allowed[id] = true
+        }
+    }
+
+    return func(c *gin.Context) {
+        userID, exists := c.Get("user_id")
+        if !exists {
+            utils.ErrorResponse(c, http.StatusUnauthorized, "未认证用户")
+            c.Abort()
+            return
+        }
+
+        id, ok := userID.(uint64)
+        if !ok || !allowed[id] {
+            utils.ErrorResponse(c, http.StatusForbidden, "权限不足")
+            c.Abort()
+            return
+        }
+
+        c.Set("role", role)
+        c.Next()
+    }
+}
+
 // RequireNormalUser 正常用户状态中间件
 func RequireNormalUser(db *gorm.DB) gin.HandlerFunc {
     return func(c *gin.Context) {
@@ -155,7 +195,7 @@ func GenerateToken(user *models.User, cfg *config.JWTConfig) (string, error) {
             IssuedAt:  jwt.NewNumericDate(now),
             NotBefore: jwt.NewNumericDate(now),
             Issuer:    "task-platform",
-            Subject:   string(user.ID),
+            Subject:   strconv.FormatUint(user.ID, 10),
         },
     }
 
@@ -175,7 +215,7 @@ func GenerateRefreshToken(user *models.User, cfg *config.JWTConfig) (string, err
             IssuedAt:  jwt.NewNumericDate(now),
             NotBefore: jwt.NewNumericDate(now),
             Issuer:    "task-platform",
-            Subject:   string(user.ID),
+            Subject:   strconv.FormatUint(user.ID, 10),
         },
     }
 
@@ -9,8 +9,19 @@ import (
 // SetupRoutes 设置路由
 func SetupRoutes(
 	r *gin.Engine,
+	authMiddleware gin.HandlerFunc,
+	adminMiddleware gin.HandlerFunc,
+	financeMiddleware gin.HandlerFunc,
 	authHandler *handlers.AuthHandler,
 	paymentHandler *handlers.PaymentHandler,
+	taskHandler *handlers.TaskHandler,
+	applicationHandler *handlers.TaskApplicationHandler,
+	deliveryHandler *handlers.TaskDeliveryHandler,
+	withdrawHandler *handlers.WithdrawHandler,
+	violationHandler *handlers.ViolationHandler,
+	walletHandler *handlers.WalletHandler,
+	invoiceHandler *handlers.InvoiceHandler,
+	couponHandler *handlers.CouponHandler,
 ) {
 	// 健康检查
 	r.GET("/health", func(c *gin.Context) {
@@ -35,14 +46,66 @@ func SetupRoutes(
 			auth.POST("/logout", func(c *gin.Context) {
 				c.JSON(200, gin.H{"message": "登出接口"})
 			})
+			auth.POST("/wechat/login", authHandler.WechatLogin)
+			auth.POST("/alipay/login", authHandler.AlipayLogin)
 		}
 
 		// 支付相关路由
 		pay := v1.Group("/pay")
 		{
-			pay.POST("/prepay", paymentHandler.PrePay)
-			pay.GET("/status/:order_no", paymentHandler.QueryStatus)
+			pay.POST("/prepay", authMiddleware, paymentHandler.PrePay)
+			pay.POST("/prepay/preview", authMiddleware, paymentHandler.PrePayPreview)
+			pay.GET("/status/:order_no", authMiddleware, paymentHandler.QueryStatus)
 			pay.POST("/callback", paymentHandler.PaymentCallback)
+			pay.POST("/callback/:provider", paymentHandler.PaymentCallback)
+			pay.POST("/refunds", authMiddleware, paymentHandler.Refund)
+			pay.GET("/refunds/:refund_no", authMiddleware, paymentHandler.QueryRefund)
+			pay.POST("/refund/callback", paymentHandler.RefundCallback)
+			pay.POST("/refund/callback/:provider", paymentHandler.RefundCallback)
+		}
+
+		// 钱包相关路由
+		wallet := v1.Group("/wallet", authMiddleware)
+		{
+			wallet.GET("", walletHandler.Detail)
+			wallet.GET("/transactions", walletHandler.Transactions)
+			wallet.GET("/statement", walletHandler.Statement)
+			wallet.POST("/withdrawals", withdrawHandler.Create)
+			wallet.GET("/withdrawals", withdrawHandler.List)
+			wallet.GET("/withdrawals/:request_no", withdrawHandler.Detail)
+		}
+
+		// 发票相关路由
+		invoices := v1.Group("/invoices", authMiddleware)
+		{
+			invoices.GET("/profile", invoiceHandler.GetProfile)
+			invoices.PUT("/profile", invoiceHandler.SaveProfile)
+			invoices.POST("", invoiceHandler.Issue)
+			invoices.GET("", invoiceHandler.List)
+			invoices.GET("/:invoice_no", invoiceHandler.Detail)
+			invoices.GET("/:invoice_no/receipt", invoiceHandler.Receipt)
+		}
+
+		// 管理后台路由
+		admin := v1.Group("/admin", authMiddleware, adminMiddleware)
+		{
+			admin.GET("/withdrawals", withdrawHandler.AdminList)
+			admin.POST("/withdrawals/:id/approve", withdrawHandler.Approve)
+			admin.POST("/withdrawals/:id/reject", withdrawHandler.Reject)
+			admin.GET("/violations", violationHandler.AdminList)
+			admin.POST("/violations/:id/confirm", violationHandler.Confirm)
+			admin.POST("/violations/:id/dismiss", violationHandler.Dismiss)
+			admin.POST("/invoices/:id/void", invoiceHandler.Void)
+			admin.GET("/coupons", couponHandler.List)
+			admin.POST("/coupons", couponHandler.Create)
+			admin.POST("/coupons/:id/enable", couponHandler.Enable)
+			admin.POST("/coupons/:id/disable", couponHandler.Disable)
+		}
+
+		// 财务路由
+		finance := v1.Group("/finance", authMiddleware, financeMiddleware)
+		{
+			finance.GET("/wallets/:user_id/statement", walletHandler.UserStatement)
 		}
 
 		// 用户相关路由
@@ -56,12 +119,25 @@ func SetupRoutes(
 		// 任务相关路由
 		tasks := v1.Group("/tasks")
 		{
-			tasks.GET("", func(c *gin.Context) {
-				c.JSON(200, gin.H{"message": "任务列表接口"})
-			})
-			tasks.GET("/:id", func(c *gin.Context) {
-				c.JSON(200, gin.H{"message": "任务详情接口"})
-			})
+			tasks.GET("", taskHandler.List)
+			tasks.GET("/:id", taskHandler.Detail)
+			tasks.POST("", authMiddleware, taskHandler.Create)
+			tasks.POST("/:id/publish", authMiddleware, taskHandler.Publish)
+			tasks.POST("/:id/take", authMiddleware, taskHandler.Take)
+			tasks.POST("/:id/cancel", authMiddleware, taskHandler.Cancel)
+
+			// 任务申请
+			tasks.POST("/:id/applications", authMiddleware, applicationHandler.Apply)
+			tasks.GET("/:id/applications", authMiddleware, applicationHandler.List)
+			tasks.POST("/:id/applications/:application_id/shortlist", authMiddleware, applicationHandler.Shortlist)
+			tasks.POST("/:id/applications/:application_id/accept", authMiddleware, applicationHandler.Accept)
+			tasks.POST("/:id/applications/:application_id/reject", authMiddleware, applicationHandler.Reject)
+
+			// 任务交付
+			tasks.POST("/:id/deliveries", authMiddleware, deliveryHandler.Submit)
+			tasks.GET("/:id/deliveries", authMiddleware, deliveryHandler.List)
+			tasks.POST("/:id/deliveries/:delivery_id/accept", authMiddleware, deliveryHandler.Accept)
+			tasks.POST("/:id/deliveries/:delivery_id/revise", authMiddleware, deliveryHandler.RequestRevision)
 		}
 
 		// 系统信息
@@ -1,7 +1,13 @@
 package config
 
 import (
+    "fmt"
+    "reflect"
+
+    "github.com/mitchellh/mapstructure"
     "github.com/spf13/viper"
+
+    "task-platform-api/pkg/money"
 )
 
 type Config struct {
@@ -12,6 +18,7 @@ type Config struct {
     Shouqianba   ShouqianbaConfig  `mapstructure:"shouqianba"`
     Wechat       WechatConfig       `mapstructure:"wechat"`
     Alipay       AlipayConfig       `mapstructure:"alipay"`
+    Payment      PaymentConfig      `mapstructure:"payment"`
     Log          LogConfig          `mapstructure:"log"`
     OSS          OSSConfig          `mapstructure:"oss"`
     SMS          SMSConfig          `mapstructure:"sms"`
@@ -19,6 +26,10 @@ type Config struct {
     Security     SecurityConfig     `mapstructure:"security"`
     RiskControl  RiskControlConfig  `mapstructure:"risk_control"`
     Monitoring   MonitoringConfig   `mapstructure:"monitoring"`
+    Task         TaskConfig         `mapstructure:"task"`
+    Fee          FeeConfig          `mapstructure:"fee"`
+    Admin        AdminConfig        `mapstructure:"admin"`
+    Invoice      InvoiceConfig      `mapstructure:"invoice"`
 }
 
 type ServerConfig struct {
@@ -66,17 +77,56 @@ type ShouqianbaConfig struct {
 }
 
 type WechatConfig struct {
-    AppID     string `mapstructure:"app_id"`
-    AppSecret string `mapstructure:"app_secret"`
-    MchID     string `mapstructure:"mch_id"`
-    APIKey    string `mapstructure:"api_key"`
+    AppID                string `mapstructure:"app_id"`                  // 公众号AppID，用于网页授权和JSAPI支付
+    AppSecret            string `mapstructure:"app_secret"`
+    MiniProgramAppID     string `mapstructure:"mini_program_app_id"`     // 小程序AppID
+    MiniProgramAppSecret string `mapstructure:"mini_program_app_secret"` // 小程序AppSecret
+    OAuthBaseURL         string `mapstructure:"oauth_base_url"`          // 登录接口地址，测试时可指向本地桩服务
+    MchID                string `mapstructure:"mch_id"`
+    APIKey               string `mapstructure:"api_key"`                 // APIv3密钥，用于解密回调报文
+    MchSerialNo          string `mapstructure:"mch_serial_no"`           // 商户API证书序列号
+    PrivateKey           string `mapstructure:"private_key"`             // 商户API私钥，PEM内容或文件路径
+    PlatformSerialNo     string `mapstructure:"platform_serial_no"`      // 微信支付平台证书或公钥ID
+    PlatformPublicKey    string `mapstructure:"platform_public_key"`     // 微信支付平台公钥或证书，PEM内容或文件路径
+    PayBaseURL           string `mapstructure:"pay_base_url"`            // 支付接口地址，测试时可指向本地桩服务
+    PayNotifyURL         string `mapstructure:"pay_notify_url"`          // 支付结果通知地址
 }
 
 type AlipayConfig struct {
     AppID       string `mapstructure:"app_id"`
-    PrivateKey  string `mapstructure:"private_key"`
-    PublicKey   string `mapstructure:"public_key"`
-    GatewayURL  string `mapstructure:"gateway_url"`
+    PrivateKey  string `mapstructure:"private_key"` // 应用私钥，PEM内容、文件路径或Base64
+    PublicKey   string `mapstructure:"public_key"`  // 支付宝公钥，PEM内容、文件路径或Base64
+    GatewayURL  string `mapstructure:"gateway_url"` // 开放平台网关，测试时可指向本地桩服务
+    NotifyURL   string `mapstructure:"notify_url"`  // 支付结果异步通知地址
+    ReturnURL   string `mapstructure:"return_url"`  // 电脑网站支付完成后的跳转地址
+}
+
+type PaymentConfig struct {
+    Provider         string            `mapstructure:"provider"`          // 默认支付渠道：shouqianba、wechat、alipay、fake
+    Providers        []string          `mapstructure:"providers"`         // 额外启用的支付渠道，交易可按支付方式选择
+    RefundNotifyURL  string            `mapstructure:"refund_notify_url"` // 退款结果通知地址前缀，实际地址追加渠道标识
+    TransferProvider string            `mapstructure:"transfer_provider"` // 提现打款渠道，为空时按提现方式选择同名渠道，银行卡使用收钱吧
+    StatusQuery      StatusQueryConfig `mapstructure:"status_query"`      // 未收到回调时主动查询支付状态的策略
+    Fake             FakePaymentConfig `mapstructure:"fake"`              // 本地模拟渠道配置
+}
+
+// StatusQueryConfig 支付状态主动查询策略，查询间隔从Interval开始逐次翻倍，不超过MaxInterval
+type StatusQueryConfig struct {
+    Delay       int `mapstructure:"delay"`        // 交易创建后超过该秒数仍待支付才向渠道查询
+    Interval    int `mapstructure:"interval"`     // 首次查询后的最小查询间隔，秒
+    MaxInterval int `mapstructure:"max_interval"` // 最大查询间隔，秒
+}
+
+// FakePaymentConfig 本地模拟支付渠道配置，用于集成测试和预发环境
+type FakePaymentConfig struct {
+    SecretKey       string  `mapstructure:"secret_key"`        // 通知签名密钥
+    NotifyURL       string  `mapstructure:"notify_url"`        // 默认异步通知地址
+    AutoNotify      bool    `mapstructure:"auto_notify"`       // 预支付后是否自动完成支付并发送通知
+    ResponseDelay   int     `mapstructure:"response_delay"`    // 接口响应延迟，毫秒
+    NotifyDelay     int     `mapstructure:"notify_delay"`      // 预支付到发送通知的延迟，毫秒
+    NotifyRetries   int     `mapstructure:"notify_retries"`    // 通知未收到success时的重试次数
+    RequestFailRate float64 `mapstructure:"request_fail_rate"` // 接口调用失败概率，0~1
+    PayFailRate     float64 `mapstructure:"pay_fail_rate"`     // 支付结果为失败的概率，0~1
 }
 
 type LogConfig struct {
@@ -120,10 +170,14 @@ type SecurityConfig struct {
 }
 
 type RiskControlConfig struct {
-    EnableDeviceFingerprint bool `mapstructure:"enable_device_fingerprint"`
-    MaxRegisterPerIP        int  `mapstructure:"max_register_per_ip"`
-    MaxTaskPerUser          int  `mapstructure:"max_task_per_user"`
-    MaxWithdrawPerDay       int  `mapstructure:"max_withdraw_per_day"`
+    EnableDeviceFingerprint   bool    `mapstructure:"enable_device_fingerprint"`
+    MaxRegisterPerIP          int     `mapstructure:"max_register_per_ip"`
+    MaxTaskPerUser            int     `mapstructure:"max_task_per_user"`
+    MaxWithdrawPerDay         money.Money `mapstructure:"max_withdraw_per_day"`           // 单日累计提现金额上限，元，0表示不限
+    MaxWithdrawCountPerDay    int         `mapstructure:"max_withdraw_count_per_day"`     // 单日提现次数上限，0表示不限
+    MinWithdrawAmount         money.Money `mapstructure:"min_withdraw_amount"`            // 单笔最低提现金额，元
+    WithdrawReviewAmount      money.Money `mapstructure:"withdraw_review_amount"`         // 单笔超过该金额转人工审核，元，0表示全部自动打款
+    WithdrawReviewNewUserDays int         `mapstructure:"withdraw_review_new_user_days"` // 注册未满该天数的用户提现转人工审核
 }
 
 type MonitoringConfig struct {
@@ -132,6 +186,39 @@ type MonitoringConfig struct {
     EnableTrace      bool   `mapstructure:"enable_trace"`
 }
 
+type TaskConfig struct {
+    MaxRevisions          int     `mapstructure:"max_revisions"`           // 默认最大整改轮次
+    AutoAcceptDays        int     `mapstructure:"auto_accept_days"`        // 交付后发布者未处理自动验收的天数
+    OverduePenaltyRatio   float64 `mapstructure:"overdue_penalty_ratio"`   // 逾期未交付时接取者违约金占任务金额的比例
+    PenaltyPublisherRatio float64 `mapstructure:"penalty_publisher_ratio"` // 没收的接取者保证金中补偿给发布者的比例，其余归平台
+    ServiceFeeRatio       float64 `mapstructure:"service_fee_ratio"`       // 新建任务记录的服务费比例，未按服务费规则计费时按此比例从赏金中扣除
+    DepositRatio          float64 `mapstructure:"deposit_ratio"`           // 新建任务的保证金占任务金额的比例
+}
+
+// FeeConfig 默认服务费，没有匹配的服务费规则时使用
+type FeeConfig struct {
+    PublisherRate   float64 `mapstructure:"publisher_rate"`    // 发布者服务费率，在赏金之外向发布者收取
+    TakerRate       float64 `mapstructure:"taker_rate"`        // 接取者服务费率，从赏金中扣除
+    MinPublisherFee float64 `mapstructure:"min_publisher_fee"` // 发布者最低服务费（元）
+    MinTakerFee     float64 `mapstructure:"min_taker_fee"`     // 接取者最低服务费（元）
+}
+
+type AdminConfig struct {
+    UserIDs        []uint64 `mapstructure:"user_ids"`         // 管理员用户ID，可审核提现等后台操作
+    FinanceUserIDs []uint64 `mapstructure:"finance_user_ids"` // 财务人员用户ID，接收对账结果通知，可导出用户钱包对账单
+}
+
+// InvoiceConfig 开票配置，销售方信息在开票时快照到发票上
+type InvoiceConfig struct {
+    Prefix            string `mapstructure:"prefix"`              // 发票号码前缀
+    SellerName        string `mapstructure:"seller_name"`         // 销售方名称
+    SellerTaxNo       string `mapstructure:"seller_tax_no"`       // 销售方纳税人识别号
+    SellerAddress     string `mapstructure:"seller_address"`      // 销售方地址
+    SellerPhone       string `mapstructure:"seller_phone"`        // 销售方电话
+    SellerBankName    string `mapstructure:"seller_bank_name"`    // 销售方开户银行
+    SellerBankAccount string `mapstructure:"seller_bank_account"` // 销售方银行账号
+}
+
 // Load 加载配置文件
 func Load(configPath string) (*Config, error) {
     v := viper.New()
@@ -148,10 +235,34 @@ func Load(configPath string) (*Config, error) {
         return nil, err
     }
     
+    // 保留viper默认的解析钩子，并将以元为单位的金额配置解析为money.Money
     var config Config
-    if err := v.Unmarshal(&config); err != nil {
+    if err := v.Unmarshal(&config, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+        mapstructure.StringToTimeDurationHookFunc(),
+        mapstructure.StringToSliceHookFunc(","),
+        moneyDecodeHook,
+    ))); err != nil {
         return nil, err
     }
     
     return &config, nil
+}
+
+// moneyDecodeHook 将配置中以元为单位的数字或字符串解析为money.Money，避免整数被当作分
+func moneyDecodeHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+    if to != reflect.TypeOf(money.Money(0)) {
+        return data, nil
+    }
+    switch from.Kind() {
+    case reflect.String,
+        reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+        reflect.Float32, reflect.Float64:
+        amount, err := money.Parse(fmt.Sprint(data))
+        if err != nil {
+            return nil, fmt.Errorf("金额配置格式错误: %w", err)
+        }
+        return amount, nil
+    }
+    return data, nil
 }
\ No newline at end of file
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"task-platform-api/pkg/money"
+)
+
+func TestLoadParsesMoneyInYuan(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+risk_control:
+  max_withdraw_per_day: 5000
+  min_withdraw_amount: 0.5
+  withdraw_review_amount: "2,000.00"
+  max_withdraw_count_per_day: 3
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	risk := cfg.RiskControl
+	if risk.MaxWithdrawPerDay != money.FromCents(500000) || risk.MinWithdrawAmount != money.FromCents(50) || risk.WithdrawReviewAmount != money.FromCents(200000) {
+		t.Fatalf("金额配置应按元解析: %+v", risk)
+	}
+	if risk.MaxWithdrawCountPerDay != 3 {
+		t.Fatalf("其他配置应正常解析: %+v", risk)
+	}
+}
+
+func TestLoadRejectsInvalidMoney(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("risk_control:\n  min_withdraw_amount: 0.001\n"), 0o600); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatal("超过两位小数的金额配置应返回错误")
+	}
+}
@@ -0,0 +1,90 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Event 领域事件
+type Event interface {
+	EventName() string
+}
+
+// Handler 事件处理函数
+type Handler func(ctx context.Context, event Event) error
+
+// Bus 进程内领域事件总线。
+// 同步处理器在发布方事务内执行，任一返回错误时由发布方回滚事务，保证与业务状态变更同生共死；
+// 异步处理器在发布方事务提交后通过Dispatch触发，失败只记录日志，不影响业务结果
+type Bus struct {
+	mu            sync.RWMutex
+	handlers      map[string][]Handler
+	asyncHandlers map[string][]Handler
+	logger        *zap.Logger
+}
+
+// NewBus 创建事件总线
+func NewBus(logger *zap.Logger) *Bus {
+	return &Bus{
+		handlers:      make(map[string][]Handler),
+		asyncHandlers: make(map[string][]Handler),
+		logger:        logger,
+	}
+}
+
+// Subscribe 注册同步处理器
+func (b *Bus) Subscribe(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[name] = append(b.handlers[name], handler)
+}
+
+// SubscribeAsync 注册异步处理器
+func (b *Bus) SubscribeAsync(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.asyncHandlers[name] = append(b.asyncHandlers[name], handler)
+}
+
+// Publish 依次执行同步处理器，遇到错误立即返回，须在发布方事务内调用
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	handlers := b.handlers[event.EventName()]
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			return fmt.Errorf("处理事件%s失败: %w", event.EventName(), err)
+		}
+	}
+	return nil
+}
+
+// Dispatch 在后台执行异步处理器，须在发布方事务提交后调用
+func (b *Bus) Dispatch(ctx context.Context, event Event) {
+	b.mu.RLock()
+	handlers := b.asyncHandlers[event.EventName()]
+	b.mu.RUnlock()
+
+	// 请求上下文可能随响应结束被取消，异步处理器使用独立上下文
+	ctx = context.WithoutCancel(ctx)
+	for _, handler := range handlers {
+		go b.runAsync(ctx, event, handler)
+	}
+}
+
+// runAsync 执行单个异步处理器，捕获panic并记录错误
+func (b *Bus) runAsync(ctx context.Context, event Event, handler Handler) {
+	defer func() {
+		if r := recover(); r != nil {
+			b.logger.Error("异步事件处理异常", zap.String("event", event.EventName()), zap.Any("panic", r))
+		}
+	}()
+
+	if err := handler(ctx, event); err != nil {
+		b.logger.Error("异步事件处理失败", zap.String("event", event.EventName()), zap.Error(err))
+	}
+}
@@ -0,0 +1,29 @@
+package events
+
+import (
+	"time"
+
+	"task-platform-api/pkg/money"
+)
+
+// 支付相关事件名
+const (
+	PaymentSucceededEvent = "payment.succeeded"
+)
+
+// PaymentSucceeded 支付成功事件，每笔交易只会发布一次
+type PaymentSucceeded struct {
+	TradeID      uint64
+	InternalNo   string
+	ThirdPartyNo string
+	TradeType    string
+	UserID       uint64
+	TaskID       *uint64
+	Amount       money.Money
+	PaidAt       time.Time
+}
+
+// EventName 事件名
+func (e *PaymentSucceeded) EventName() string {
+	return PaymentSucceededEvent
+}
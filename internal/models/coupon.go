@@ -0,0 +1,96 @@
+package models
+
+import (
+    "time"
+
+    "task-platform-api/pkg/money"
+)
+
+// 优惠券类型，叠加使用时按首单免服务费、服务费折扣、立减的顺序计算
+const (
+    CouponTypeFirstTaskFee = "first_task_fee" // 首个任务免发布者服务费
+    CouponTypeFeePercent   = "fee_percent"    // 发布者服务费按比例减免
+    CouponTypeFixed        = "fixed"          // 预付款立减固定金额
+)
+
+// 优惠券状态
+const (
+    CouponStatusDisabled int8 = 0 // 停用
+    CouponStatusEnabled  int8 = 1 // 启用
+)
+
+// 优惠券使用状态
+const (
+    CouponRedemptionReserved int8 = 0 // 已占用，预付款待支付
+    CouponRedemptionUsed     int8 = 1 // 已使用，预付款已支付
+    CouponRedemptionReleased int8 = 2 // 已释放，订单未支付或任务取消后退回
+)
+
+// Coupon 优惠券表，用户支付预付款时输入优惠码使用，优惠金额由平台营销费用承担
+type Coupon struct {
+    ID              uint64      `json:"id" gorm:"primaryKey"`
+    Code            string      `json:"code" gorm:"size:32;uniqueIndex;not null;comment:优惠码"`
+    Name            string      `json:"name" gorm:"size:100;not null;comment:优惠券名称"`
+    CouponType      string      `json:"coupon_type" gorm:"type:enum('first_task_fee','fee_percent','fixed');not null;comment:优惠券类型"`
+    Amount          money.Money `json:"amount" gorm:"type:decimal(10,2);default:0;comment:立减金额,fixed类型使用"`
+    FeeDiscountRate float64     `json:"fee_discount_rate" gorm:"type:decimal(3,2);default:0;comment:服务费减免比例,fee_percent类型使用,0.5表示减免一半"`
+    MaxDiscount     money.Money `json:"max_discount" gorm:"type:decimal(10,2);default:0;comment:单次最高优惠金额,0-不限"`
+    MinTaskAmount   money.Money `json:"min_task_amount" gorm:"type:decimal(10,2);default:0;comment:任务赏金门槛,0-不限"`
+    Stackable       bool        `json:"stackable" gorm:"default:false;comment:是否可与其他类型的优惠券叠加"`
+    TotalLimit      int         `json:"total_limit" gorm:"default:0;comment:全局可用次数,0-不限"`
+    PerUserLimit    int         `json:"per_user_limit" gorm:"not null;comment:每个用户可用次数,0-不限"`
+    UsedCount       int         `json:"used_count" gorm:"default:0;comment:已占用次数,含待支付订单"`
+    StartTime       *time.Time  `json:"start_time" gorm:"comment:生效时间"`
+    EndTime         *time.Time  `json:"end_time" gorm:"comment:失效时间"`
+    Status          int8        `json:"status" gorm:"default:1;comment:状态:0-停用,1-启用"`
+    CreatedAt       time.Time   `json:"created_at"`
+    UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (Coupon) TableName() string {
+    return "coupons"
+}
+
+// IsEnabled 优惠券是否启用
+func (c *Coupon) IsEnabled() bool {
+    return c.Status == CouponStatusEnabled
+}
+
+// IsEffective 优惠券在指定时间是否处于有效期内
+func (c *Coupon) IsEffective(now time.Time) bool {
+    if c.StartTime != nil && now.Before(*c.StartTime) {
+        return false
+    }
+    return c.EndTime == nil || now.Before(*c.EndTime)
+}
+
+// IsExhausted 优惠券全局可用次数是否已用完
+func (c *Coupon) IsExhausted() bool {
+    return c.TotalLimit > 0 && c.UsedCount >= c.TotalLimit
+}
+
+// IsFeeCoupon 是否为减免服务费的优惠券
+func (c *Coupon) IsFeeCoupon() bool {
+    return c.CouponType == CouponTypeFirstTaskFee || c.CouponType == CouponTypeFeePercent
+}
+
+// CouponRedemption 优惠券使用记录表，创建预付款订单时占用，支付失败、订单关闭或任务取消时释放
+type CouponRedemption struct {
+    ID        uint64      `json:"id" gorm:"primaryKey"`
+    CouponID  uint64      `json:"coupon_id" gorm:"index:idx_coupon_user;not null;comment:优惠券ID"`
+    UserID    uint64      `json:"user_id" gorm:"index:idx_coupon_user;not null;comment:用户ID"`
+    TaskID    uint64      `json:"task_id" gorm:"index;not null;comment:任务ID"`
+    TradeID   uint64      `json:"trade_id" gorm:"index;not null;comment:预付款交易ID"`
+    Discount  money.Money `json:"discount" gorm:"type:decimal(10,2);not null;comment:优惠金额"`
+    Status    int8        `json:"status" gorm:"default:0;comment:状态:0-已占用,1-已使用,2-已释放"`
+    CreatedAt time.Time   `json:"created_at"`
+    UpdatedAt time.Time   `json:"updated_at"`
+
+    Coupon *Coupon `json:"coupon,omitempty" gorm:"foreignKey:CouponID"`
+}
+
+// TableName 设置表名
+func (CouponRedemption) TableName() string {
+    return "coupon_redemptions"
+}
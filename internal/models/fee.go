@@ -0,0 +1,45 @@
+package models
+
+import (
+    "time"
+
+    "task-platform-api/pkg/money"
+)
+
+// 服务费规则类型
+const (
+    FeeRuleTypeBase      = "base"      // 基础费率
+    FeeRuleTypePromotion = "promotion" // 促销折扣
+)
+
+// 服务费规则状态
+const (
+    FeeRuleStatusDisabled int8 = 0 // 停用
+    FeeRuleStatusEnabled  int8 = 1 // 启用
+)
+
+// FeeRule 服务费规则表，按任务分类、发布者等级和信用评分匹配，优先级高的规则优先
+type FeeRule struct {
+    ID              uint64      `json:"id" gorm:"primaryKey;column:rule_id"`
+    Name            string      `json:"name" gorm:"size:100;not null;comment:规则名称"`
+    RuleType        string      `json:"rule_type" gorm:"type:enum('base','promotion');not null;comment:规则类型"`
+    CategoryID      *uint64     `json:"category_id" gorm:"index;comment:任务分类ID,为空匹配全部分类"`
+    MinLevel        int         `json:"min_level" gorm:"default:0;comment:发布者最低用户等级,0-不限"`
+    MinCreditScore  float64     `json:"min_credit_score" gorm:"type:decimal(3,1);default:0;comment:发布者最低信用评分,0-不限"`
+    PublisherRate   float64     `json:"publisher_rate" gorm:"type:decimal(5,4);default:0;comment:发布者服务费率"`
+    TakerRate       float64     `json:"taker_rate" gorm:"type:decimal(5,4);default:0;comment:接取者服务费率"`
+    MinPublisherFee money.Money `json:"min_publisher_fee" gorm:"type:decimal(10,2);default:0;comment:发布者最低服务费"`
+    MinTakerFee     money.Money `json:"min_taker_fee" gorm:"type:decimal(10,2);default:0;comment:接取者最低服务费"`
+    Discount        float64     `json:"discount" gorm:"type:decimal(3,2);default:1;comment:促销折扣,0.8表示服务费八折"`
+    Priority        int         `json:"priority" gorm:"default:0;comment:优先级,越大越优先"`
+    StartTime       *time.Time  `json:"start_time" gorm:"comment:生效时间"`
+    EndTime         *time.Time  `json:"end_time" gorm:"comment:失效时间"`
+    Status          int8        `json:"status" gorm:"default:1;comment:状态:0-停用,1-启用"`
+    CreatedAt       time.Time   `json:"created_at"`
+    UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (FeeRule) TableName() string {
+    return "fee_rules"
+}
@@ -0,0 +1,159 @@
+package models
+
+import (
+    "encoding/json"
+    "time"
+
+    "task-platform-api/pkg/money"
+)
+
+// 发票抬头类型
+const (
+    InvoiceTitlePersonal = "personal" // 个人
+    InvoiceTitleCompany  = "company"  // 企业
+)
+
+// 开票来源
+const (
+    InvoiceSourcePrepay      = "prepay"       // 预付款交易
+    InvoiceSourcePlatformFee = "platform_fee" // 结算平台服务费
+)
+
+// 发票状态
+const (
+    InvoiceStatusIssued int8 = 0 // 已开具
+    InvoiceStatusVoided int8 = 1 // 已作废
+)
+
+// InvoiceProfile 开票信息表，每个用户一份，开票时快照到发票上
+type InvoiceProfile struct {
+    ID          uint64    `json:"id" gorm:"primaryKey"`
+    UserID      uint64    `json:"user_id" gorm:"uniqueIndex;not null;comment:用户ID"`
+    TitleType   string    `json:"title_type" gorm:"type:enum('personal','company');not null;comment:抬头类型"`
+    Title       string    `json:"title" gorm:"size:200;not null;comment:发票抬头"`
+    TaxNo       string    `json:"tax_no" gorm:"size:32;comment:纳税人识别号"`
+    Address     string    `json:"address" gorm:"size:255;comment:注册地址"`
+    Phone       string    `json:"phone" gorm:"size:32;comment:注册电话"`
+    BankName    string    `json:"bank_name" gorm:"size:100;comment:开户银行"`
+    BankAccount string    `json:"bank_account" gorm:"size:64;comment:银行账号"`
+    Email       string    `json:"email" gorm:"size:100;comment:接收邮箱"`
+    CreatedAt   time.Time `json:"created_at"`
+    UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (InvoiceProfile) TableName() string {
+    return "invoice_profiles"
+}
+
+// IsCompany 是否为企业抬头
+func (p *InvoiceProfile) IsCompany() bool {
+    return p.TitleType == InvoiceTitleCompany
+}
+
+// InvoiceParty 发票上的购买方或销售方信息
+type InvoiceParty struct {
+    Title       string `json:"title"`
+    TaxNo       string `json:"tax_no,omitempty"`
+    Address     string `json:"address,omitempty"`
+    Phone       string `json:"phone,omitempty"`
+    BankName    string `json:"bank_name,omitempty"`
+    BankAccount string `json:"bank_account,omitempty"`
+}
+
+// InvoiceItem 发票明细行，金额可为负数（如已退款冲减）
+type InvoiceItem struct {
+    Name   string      `json:"name"`
+    Amount money.Money `json:"amount"`
+}
+
+// Invoice 发票表，开具后内容不再修改，收据按保存的快照渲染
+type Invoice struct {
+    ID           uint64    `json:"id" gorm:"primaryKey;column:invoice_id"`
+    InvoiceNo    string    `json:"invoice_no" gorm:"size:32;uniqueIndex;not null;comment:发票号码"`
+    UserID       uint64    `json:"user_id" gorm:"index;not null;comment:购买方用户ID"`
+    SourceType   string    `json:"source_type" gorm:"type:enum('prepay','platform_fee');not null;comment:开票来源"`
+    TradeID      *uint64   `json:"trade_id" gorm:"index;comment:预付款交易ID"`
+    SettlementID *uint64   `json:"settlement_id" gorm:"index;comment:结算ID"`
+    TaskID       *uint64   `json:"task_id" gorm:"index;comment:关联任务ID"`
+    Amount       money.Money `json:"amount" gorm:"type:decimal(10,2);not null;comment:开票金额"`
+    BuyerTitle   string    `json:"buyer_title" gorm:"size:200;not null;comment:购买方抬头"`
+    BuyerInfo    string    `json:"buyer_info" gorm:"type:json;comment:购买方信息快照"`
+    SellerInfo   string    `json:"seller_info" gorm:"type:json;comment:销售方信息快照"`
+    Items        string    `json:"items" gorm:"type:json;comment:开票明细"`
+    Status       int8      `json:"status" gorm:"default:0;comment:状态:0-已开具,1-已作废"`
+    IssueTime    time.Time `json:"issue_time" gorm:"comment:开具时间"`
+    VoidTime     *time.Time `json:"void_time" gorm:"comment:作废时间"`
+    VoidReason   string    `json:"void_reason" gorm:"size:500;comment:作废原因"`
+    CreatedAt    time.Time `json:"created_at"`
+    UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (Invoice) TableName() string {
+    return "invoices"
+}
+
+// IsIssued 发票是否有效
+func (i *Invoice) IsIssued() bool {
+    return i.Status == InvoiceStatusIssued
+}
+
+// IsVoided 发票是否已作废
+func (i *Invoice) IsVoided() bool {
+    return i.Status == InvoiceStatusVoided
+}
+
+// GetItems 解析开票明细
+func (i *Invoice) GetItems() ([]InvoiceItem, error) {
+    var items []InvoiceItem
+    if i.Items == "" {
+        return items, nil
+    }
+    err := json.Unmarshal([]byte(i.Items), &items)
+    return items, err
+}
+
+// GetBuyer 解析购买方信息快照
+func (i *Invoice) GetBuyer() (InvoiceParty, error) {
+    return parseInvoiceParty(i.BuyerInfo)
+}
+
+// GetSeller 解析销售方信息快照
+func (i *Invoice) GetSeller() (InvoiceParty, error) {
+    return parseInvoiceParty(i.SellerInfo)
+}
+
+// parseInvoiceParty 解析发票方信息JSON
+func parseInvoiceParty(raw string) (InvoiceParty, error) {
+    var party InvoiceParty
+    if raw == "" {
+        return party, nil
+    }
+    err := json.Unmarshal([]byte(raw), &party)
+    return party, err
+}
+
+// InvoiceSourceName 开票来源名称
+func InvoiceSourceName(source string) string {
+    switch source {
+    case InvoiceSourcePrepay:
+        return "任务预付款"
+    case InvoiceSourcePlatformFee:
+        return "平台服务费"
+    default:
+        return "未知"
+    }
+}
+
+// InvoiceSequence 发票号码序列表，按期间连续编号
+type InvoiceSequence struct {
+    Period    string    `json:"period" gorm:"primaryKey;size:16;comment:编号期间,如202401"`
+    LastNo    uint64    `json:"last_no" gorm:"not null;default:0;comment:已使用的最大序号"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (InvoiceSequence) TableName() string {
+    return "invoice_sequences"
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+    "fmt"
+    "time"
+
+    "task-platform-api/pkg/money"
+)
+
+// 账户类型
+const (
+    LedgerAccountAsset     = "asset"     // 资产类，借方增加
+    LedgerAccountLiability = "liability" // 负债类，贷方增加
+    LedgerAccountRevenue   = "revenue"   // 收入类，贷方增加
+    LedgerAccountExpense   = "expense"   // 费用类，借方增加
+)
+
+// 记账方向
+const (
+    LedgerDebit  = "debit"  // 借
+    LedgerCredit = "credit" // 贷
+)
+
+// 平台账户编码
+const (
+    LedgerAccountGateway        = "platform:gateway"         // 支付渠道待收/在途资金
+    LedgerAccountEscrow         = "platform:escrow"          // 平台托管资金
+    LedgerAccountFeeRevenue     = "platform:fee_revenue"     // 平台服务费收入
+    LedgerAccountPenaltyRevenue = "platform:penalty_revenue" // 平台违约金收入
+    LedgerAccountMarketing      = "platform:marketing"       // 平台营销费用，承担优惠券抵扣
+)
+
+// LedgerAccount 账本账户表
+type LedgerAccount struct {
+    ID          uint64    `json:"id" gorm:"primaryKey;column:account_id"`
+    Code        string    `json:"code" gorm:"size:64;uniqueIndex;not null;comment:账户编码"`
+    Name        string    `json:"name" gorm:"size:100;not null;comment:账户名称"`
+    AccountType string    `json:"account_type" gorm:"type:enum('asset','liability','revenue','expense');not null;comment:账户类型"`
+    UserID      *uint64   `json:"user_id" gorm:"index;comment:所属用户ID,平台账户为空"`
+    Balance     money.Money `json:"balance" gorm:"type:decimal(12,2);default:0;comment:余额(按账户正常方向)"`
+    CreatedAt   time.Time `json:"created_at"`
+    UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (LedgerAccount) TableName() string {
+    return "ledger_accounts"
+}
+
+// LedgerEntry 记账凭证表，每张凭证的借贷合计相等
+type LedgerEntry struct {
+    ID          uint64    `json:"id" gorm:"primaryKey;column:entry_id"`
+    BizType     string    `json:"biz_type" gorm:"size:32;not null;comment:业务类型"`
+    BizID       uint64    `json:"biz_id" gorm:"comment:业务ID"`
+    Amount      money.Money `json:"amount" gorm:"type:decimal(12,2);not null;comment:凭证金额(借方合计)"`
+    Description string    `json:"description" gorm:"size:500;comment:摘要"`
+    CreatedAt   time.Time `json:"created_at"`
+
+    Postings []LedgerPosting `json:"postings,omitempty" gorm:"foreignKey:EntryID"`
+}
+
+// TableName 设置表名
+func (LedgerEntry) TableName() string {
+    return "ledger_entries"
+}
+
+// LedgerPosting 记账分录表
+type LedgerPosting struct {
+    ID        uint64    `json:"id" gorm:"primaryKey;column:posting_id"`
+    EntryID   uint64    `json:"entry_id" gorm:"index;not null;comment:凭证ID"`
+    AccountID uint64    `json:"account_id" gorm:"index;not null;comment:账户ID"`
+    Direction string    `json:"direction" gorm:"type:enum('debit','credit');not null;comment:借贷方向"`
+    Amount    money.Money `json:"amount" gorm:"type:decimal(12,2);not null;comment:金额"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (LedgerPosting) TableName() string {
+    return "ledger_postings"
+}
+
+// UserAvailableAccountCode 用户可用余额账户编码
+func UserAvailableAccountCode(userID uint64) string {
+    return fmt.Sprintf("user:%d:available", userID)
+}
+
+// UserFrozenAccountCode 用户冻结余额账户编码
+func UserFrozenAccountCode(userID uint64) string {
+    return fmt.Sprintf("user:%d:frozen", userID)
+}
+
+// IsDebitNormal 账户是否借方为正常余额方向
+func (a *LedgerAccount) IsDebitNormal() bool {
+    return a.AccountType == LedgerAccountAsset || a.AccountType == LedgerAccountExpense
+}
+
+// SignedAmount 按账户正常方向计算分录对余额的影响
+func (a *LedgerAccount) SignedAmount(direction string, amount money.Money) money.Money {
+    if (direction == LedgerDebit) == a.IsDebitNormal() {
+        return amount
+    }
+    return -amount
+}
@@ -3,6 +3,30 @@ package models
 import (
     "time"
     "gorm.io/gorm"
+
+    "task-platform-api/pkg/money"
+)
+
+// 交易类型
+const (
+    TradeTypePrepay        = "prepay"         // 预付款
+    TradeTypeSettle        = "settle"         // 结算
+    TradeTypeRefund        = "refund"         // 退款
+    TradeTypePenalty       = "penalty"        // 违约金，没收接取者保证金
+    TradeTypeDeposit       = "deposit"        // 接取保证金冻结
+    TradeTypeDepositReturn = "deposit_return" // 接取保证金退还
+)
+
+// PaymentMethodWallet 钱包余额内部划转，保证金、违约金等交易不经过支付渠道
+const PaymentMethodWallet = "wallet"
+
+// 交易状态
+const (
+    TradeStatusPending  int8 = 0 // 待支付
+    TradeStatusPaid     int8 = 1 // 已支付
+    TradeStatusFailed   int8 = 2 // 已失败
+    TradeStatusRefunded int8 = 3 // 已退款
+    TradeStatusClosed   int8 = 4 // 已关闭，超过支付期限未支付
 )
 
 // Trade 交易表
@@ -10,15 +34,18 @@ type Trade struct {
     ID            uint64    `json:"id" gorm:"primaryKey;column:trade_id"`
     UserID        uint64    `json:"user_id" gorm:"index;not null;comment:用户ID"`
     TaskID        *uint64   `json:"task_id" gorm:"index;comment:关联任务ID"`
-    TradeType     string    `json:"trade_type" gorm:"type:enum('prepay','settle','refund','penalty');not null;comment:交易类型"`
-    Amount        float64   `json:"amount" gorm:"type:decimal(10,2);not null;comment:交易金额"`
+    TradeType     string    `json:"trade_type" gorm:"type:enum('prepay','settle','refund','penalty','deposit','deposit_return');not null;comment:交易类型"`
+    Amount        money.Money `json:"amount" gorm:"type:decimal(10,2);not null;comment:交易金额"`
+    Discount      money.Money `json:"discount" gorm:"type:decimal(10,2);default:0;comment:优惠券抵扣金额,由平台营销费用承担"`
     ThirdPartyNo  string    `json:"third_party_no" gorm:"size:64;index;comment:第三方交易号"`
     InternalNo    string    `json:"internal_no" gorm:"size:64;uniqueIndex;comment:内部交易号"`
-    Status        int8      `json:"status" gorm:"default:0;comment:状态:0-待支付,1-已支付,2-已失败,3-已退款"`
+    Status        int8      `json:"status" gorm:"default:0;comment:状态:0-待支付,1-已支付,2-已失败,3-已退款,4-已关闭"`
     PaymentMethod string    `json:"payment_method" gorm:"size:20;comment:支付方式"`
     Description   string    `json:"description" gorm:"size:500;comment:交易描述"`
     PayTime       *time.Time `json:"pay_time" gorm:"comment:支付时间"`
     ExpireTime    *time.Time `json:"expire_time" gorm:"comment:过期时间"`
+    QueryCount    int       `json:"query_count" gorm:"default:0;comment:主动查询渠道次数"`
+    NextQueryTime *time.Time `json:"next_query_time" gorm:"comment:下次允许主动查询渠道的时间"`
     CreatedAt     time.Time `json:"created_at" gorm:"column:create_time"`
     UpdatedAt     time.Time `json:"updated_at" gorm:"column:update_time"`
     
@@ -31,14 +58,22 @@ func (Trade) TableName() string {
     return "trades"
 }
 
+// 结算状态
+const (
+    SettlementStatusPending int8 = 0 // 待结算
+    SettlementStatusSettled int8 = 1 // 已结算
+    SettlementStatusFailed  int8 = 2 // 结算失败
+)
+
 // Settlement 结算表
 type Settlement struct {
     ID             uint64    `json:"id" gorm:"primaryKey;column:settle_id"`
     TaskID         uint64    `json:"task_id" gorm:"index;not null;comment:任务ID"`
-    PublisherAmount float64  `json:"publisher_amount" gorm:"type:decimal(10,2);not null;comment:发布方收入"`
-    TakerAmount    float64  `json:"taker_amount" gorm:"type:decimal(10,2);not null;comment:接取方收入"`
-    PlatformFee    float64  `json:"platform_fee" gorm:"type:decimal(10,2);not null;comment:平台费用"`
-    Penalty        float64  `json:"penalty" gorm:"type:decimal(10,2);default:0;comment:违约金"`
+    StageID        *uint64   `json:"stage_id" gorm:"index;comment:阶段ID,为空表示整单结算"`
+    PublisherAmount money.Money `json:"publisher_amount" gorm:"type:decimal(10,2);not null;comment:发布方收入"`
+    TakerAmount    money.Money `json:"taker_amount" gorm:"type:decimal(10,2);not null;comment:接取方收入"`
+    PlatformFee    money.Money `json:"platform_fee" gorm:"type:decimal(10,2);not null;comment:平台费用"`
+    Penalty        money.Money `json:"penalty" gorm:"type:decimal(10,2);default:0;comment:违约金,由接取者保证金支付"`
     SettleTime     time.Time `json:"settle_time" gorm:"column:settle_time"`
     Status         int8      `json:"status" gorm:"default:0;comment:状态:0-待结算,1-已结算,2-结算失败"`
     Remark         string    `json:"remark" gorm:"type:text;comment:结算备注"`
@@ -53,11 +88,18 @@ func (Settlement) TableName() string {
     return "settlements"
 }
 
+// 退款状态
+const (
+    RefundStatusProcessing int8 = 0 // 处理中
+    RefundStatusSuccess    int8 = 1 // 已成功
+    RefundStatusFailed     int8 = 2 // 已失败
+)
+
 // Refund 退款表
 type Refund struct {
     ID          uint64    `json:"id" gorm:"primaryKey;column:refund_id"`
     TradeID     uint64    `json:"trade_id" gorm:"index;not null;comment:原交易ID"`
-    RefundAmount float64   `json:"refund_amount" gorm:"type:decimal(10,2);not null;comment:退款金额"`
+    RefundAmount money.Money `json:"refund_amount" gorm:"type:decimal(10,2);not null;comment:退款金额"`
     Reason      string    `json:"reason" gorm:"size:500;comment:退款原因"`
     Status      int8      `json:"status" gorm:"default:0;comment:状态:0-处理中,1-已成功,2-已失败"`
     RefundNo    string    `json:"refund_no" gorm:"size:64;uniqueIndex;comment:退款单号"`
@@ -77,10 +119,10 @@ func (Refund) TableName() string {
 type Wallet struct {
     ID              uint64    `json:"id" gorm:"primaryKey"`
     UserID          uint64    `json:"user_id" gorm:"uniqueIndex;not null;comment:用户ID"`
-    Balance         float64   `json:"balance" gorm:"type:decimal(10,2);default:0.00;comment:可用余额"`
-    FrozenBalance   float64   `json:"frozen_balance" gorm:"type:decimal(10,2);default:0.00;comment:冻结余额"`
-    TotalIncome     float64   `json:"total_income" gorm:"type:decimal(10,2);default:0.00;comment:总收入"`
-    TotalWithdraw   float64   `json:"total_withdraw" gorm:"type:decimal(10,2);default:0.00;comment:总提现"`
+    Balance         money.Money `json:"balance" gorm:"type:decimal(10,2);default:0;comment:可用余额"`
+    FrozenBalance   money.Money `json:"frozen_balance" gorm:"type:decimal(10,2);default:0;comment:冻结余额"`
+    TotalIncome     money.Money `json:"total_income" gorm:"type:decimal(10,2);default:0;comment:总收入"`
+    TotalWithdraw   money.Money `json:"total_withdraw" gorm:"type:decimal(10,2);default:0;comment:总提现"`
     Version         int       `json:"version" gorm:"default:0;comment:乐观锁版本号"`
     CreatedAt       time.Time `json:"created_at"`
     UpdatedAt       time.Time `json:"updated_at"`
@@ -93,15 +135,23 @@ func (Wallet) TableName() string {
     return "wallets"
 }
 
+// 钱包流水类型
+const (
+    WalletTxIncome   = "income"   // 收入
+    WalletTxExpense  = "expense"  // 支出
+    WalletTxFreeze   = "freeze"   // 冻结
+    WalletTxUnfreeze = "unfreeze" // 解冻
+)
+
 // WalletTransaction 钱包交易记录表
 type WalletTransaction struct {
     ID             uint64    `json:"id" gorm:"primaryKey"`
     UserID         uint64    `json:"user_id" gorm:"index;not null;comment:用户ID"`
     TradeID        *uint64   `json:"trade_id" gorm:"index;comment:关联交易ID"`
     Type           string    `json:"type" gorm:"type:enum('income','expense','freeze','unfreeze');not null;comment:交易类型"`
-    Amount         float64   `json:"amount" gorm:"type:decimal(10,2);not null;comment:交易金额"`
-    BalanceBefore  float64   `json:"balance_before" gorm:"type:decimal(10,2);not null;comment:交易前余额"`
-    BalanceAfter   float64   `json:"balance_after" gorm:"type:decimal(10,2);not null;comment:交易后余额"`
+    Amount         money.Money `json:"amount" gorm:"type:decimal(10,2);not null;comment:交易金额"`
+    BalanceBefore  money.Money `json:"balance_before" gorm:"type:decimal(10,2);not null;comment:交易前余额"`
+    BalanceAfter   money.Money `json:"balance_after" gorm:"type:decimal(10,2);not null;comment:交易后余额"`
     Description    string    `json:"description" gorm:"size:500;comment:交易描述"`
     RelatedID      uint64    `json:"related_id" gorm:"index;comment:关联业务ID"`
     RelatedType    string    `json:"related_type" gorm:"size:20;comment:关联业务类型"`
@@ -116,11 +166,26 @@ func (WalletTransaction) TableName() string {
     return "wallet_transactions"
 }
 
+// 提现方式
+const (
+    WithdrawMethodAlipay = "alipay" // 支付宝
+    WithdrawMethodWechat = "wechat" // 微信零钱
+    WithdrawMethodBank   = "bank"   // 银行卡
+)
+
+// 提现状态
+const (
+    WithdrawStatusPending    int8 = 0 // 待处理，等待人工审核
+    WithdrawStatusProcessing int8 = 1 // 处理中，已提交渠道打款
+    WithdrawStatusCompleted  int8 = 2 // 已完成
+    WithdrawStatusRejected   int8 = 3 // 已拒绝，审核拒绝或打款失败
+)
+
 // WithdrawRequest 提现申请表
 type WithdrawRequest struct {
     ID             uint64    `json:"id" gorm:"primaryKey"`
     UserID         uint64    `json:"user_id" gorm:"index;not null;comment:用户ID"`
-    Amount         float64   `json:"amount" gorm:"type:decimal(10,2);not null;comment:提现金额"`
+    Amount         money.Money `json:"amount" gorm:"type:decimal(10,2);not null;comment:提现金额"`
     WithdrawMethod string    `json:"withdraw_method" gorm:"type:enum('alipay','wechat','bank');not null;comment:提现方式"`
     AccountInfo    string    `json:"account_info" gorm:"type:json;comment:账户信息"`
     Status         int8      `json:"status" gorm:"default:0;comment:状态:0-待处理,1-处理中,2-已完成,3-已拒绝"`
@@ -138,6 +203,16 @@ func (WithdrawRequest) TableName() string {
     return "withdraw_requests"
 }
 
+// IsPending 提现是否待审核
+func (w *WithdrawRequest) IsPending() bool {
+    return w.Status == WithdrawStatusPending
+}
+
+// IsProcessing 提现是否打款中
+func (w *WithdrawRequest) IsProcessing() bool {
+    return w.Status == WithdrawStatusProcessing
+}
+
 // BeforeCreate GORM钩子：创建前
 func (t *Trade) BeforeCreate(tx *gorm.DB) error {
     if t.CreatedAt.IsZero() {
@@ -157,22 +232,32 @@ func (t *Trade) BeforeUpdate(tx *gorm.DB) error {
 
 // IsPending 交易是否待支付
 func (t *Trade) IsPending() bool {
-    return t.Status == 0
+    return t.Status == TradeStatusPending
 }
 
 // IsPaid 交易是否已支付
 func (t *Trade) IsPaid() bool {
-    return t.Status == 1
+    return t.Status == TradeStatusPaid
 }
 
 // IsFailed 交易是否失败
 func (t *Trade) IsFailed() bool {
-    return t.Status == 2
+    return t.Status == TradeStatusFailed
 }
 
 // IsRefunded 交易是否已退款
 func (t *Trade) IsRefunded() bool {
-    return t.Status == 3
+    return t.Status == TradeStatusRefunded
+}
+
+// IsProcessing 退款是否处理中
+func (r *Refund) IsProcessing() bool {
+    return r.Status == RefundStatusProcessing
+}
+
+// IsClosed 交易是否已关闭
+func (t *Trade) IsClosed() bool {
+    return t.Status == TradeStatusClosed
 }
 
 // IsExpired 交易是否已过期
@@ -183,17 +268,61 @@ func (t *Trade) IsExpired() bool {
     return time.Now().After(*t.ExpireTime)
 }
 
+// GetEscrowAmount 获取预付款托管金额（实付金额加优惠券抵扣金额）
+func (t *Trade) GetEscrowAmount() money.Money {
+    return t.Amount + t.Discount
+}
+
+// IsCollected 渠道是否已收款，已退款的交易也曾收款
+func (t *Trade) IsCollected() bool {
+    return t.Status == TradeStatusPaid || t.Status == TradeStatusRefunded
+}
+
+// TradeStatusName 交易状态名称
+func TradeStatusName(status int8) string {
+    switch status {
+    case TradeStatusPending:
+        return "待支付"
+    case TradeStatusPaid:
+        return "已支付"
+    case TradeStatusFailed:
+        return "已失败"
+    case TradeStatusRefunded:
+        return "已退款"
+    case TradeStatusClosed:
+        return "已关闭"
+    default:
+        return "未知"
+    }
+}
+
+// WalletTxTypeName 钱包流水类型名称
+func WalletTxTypeName(txType string) string {
+    switch txType {
+    case WalletTxIncome:
+        return "收入"
+    case WalletTxExpense:
+        return "支出"
+    case WalletTxFreeze:
+        return "冻结"
+    case WalletTxUnfreeze:
+        return "解冻"
+    default:
+        return "未知"
+    }
+}
+
 // GetAvailableBalance 获取可用余额
-func (w *Wallet) GetAvailableBalance() float64 {
+func (w *Wallet) GetAvailableBalance() money.Money {
     return w.Balance
 }
 
 // GetFrozenBalance 获取冻结余额
-func (w *Wallet) GetFrozenBalance() float64 {
+func (w *Wallet) GetFrozenBalance() money.Money {
     return w.FrozenBalance
 }
 
 // GetTotalBalance 获取总余额
-func (w *Wallet) GetTotalBalance() float64 {
+func (w *Wallet) GetTotalBalance() money.Money {
     return w.Balance + w.FrozenBalance
 }
\ No newline at end of file
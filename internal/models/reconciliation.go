@@ -0,0 +1,70 @@
+package models
+
+import (
+    "time"
+
+    "task-platform-api/pkg/money"
+)
+
+// 对账结果状态
+const (
+    ReconciliationStatusBalanced   int8 = 0 // 账平
+    ReconciliationStatusUnbalanced int8 = 1 // 存在差异
+)
+
+// 对账差异类型
+const (
+    DiscrepancyMissingLocal   = "missing_local"   // 渠道有记录，本地无交易
+    DiscrepancyMissingRemote  = "missing_remote"  // 本地已支付，渠道对账单无记录
+    DiscrepancyAmountMismatch = "amount_mismatch" // 金额不一致
+    DiscrepancyStatusMismatch = "status_mismatch" // 支付状态不一致
+)
+
+// ReconciliationReport 对账报告表，每个渠道每个账单日一份，重新对账时覆盖
+type ReconciliationReport struct {
+    ID               uint64    `json:"id" gorm:"primaryKey;column:report_id"`
+    Provider         string    `json:"provider" gorm:"size:20;not null;uniqueIndex:uk_provider_bill_date;comment:支付渠道"`
+    BillDate         time.Time `json:"bill_date" gorm:"type:date;not null;uniqueIndex:uk_provider_bill_date;comment:账单日"`
+    Source           string    `json:"source" gorm:"size:20;not null;comment:对账单来源:api-渠道接口,csv-文件导入"`
+    RemoteCount      int       `json:"remote_count" gorm:"default:0;comment:渠道成功笔数"`
+    RemoteAmount     money.Money `json:"remote_amount" gorm:"type:decimal(12,2);default:0;comment:渠道成功金额"`
+    LocalCount       int       `json:"local_count" gorm:"default:0;comment:本地已支付笔数"`
+    LocalAmount      money.Money `json:"local_amount" gorm:"type:decimal(12,2);default:0;comment:本地已支付金额"`
+    MatchedCount     int       `json:"matched_count" gorm:"default:0;comment:核对一致笔数"`
+    DiscrepancyCount int       `json:"discrepancy_count" gorm:"default:0;comment:差异笔数"`
+    Status           int8      `json:"status" gorm:"default:0;comment:状态:0-账平,1-存在差异"`
+    CreatedAt        time.Time `json:"created_at"`
+    UpdatedAt        time.Time `json:"updated_at"`
+
+    Discrepancies []ReconciliationDiscrepancy `json:"discrepancies,omitempty" gorm:"foreignKey:ReportID"`
+}
+
+// TableName 设置表名
+func (ReconciliationReport) TableName() string {
+    return "reconciliation_reports"
+}
+
+// IsBalanced 对账是否账平
+func (r *ReconciliationReport) IsBalanced() bool {
+    return r.Status == ReconciliationStatusBalanced
+}
+
+// ReconciliationDiscrepancy 对账差异明细表
+type ReconciliationDiscrepancy struct {
+    ID           uint64    `json:"id" gorm:"primaryKey"`
+    ReportID     uint64    `json:"report_id" gorm:"index;not null;comment:对账报告ID"`
+    Type         string    `json:"type" gorm:"type:enum('missing_local','missing_remote','amount_mismatch','status_mismatch');not null;comment:差异类型"`
+    TradeID      *uint64   `json:"trade_id" gorm:"index;comment:本地交易ID"`
+    InternalNo   string    `json:"internal_no" gorm:"size:64;comment:内部交易号"`
+    ThirdPartyNo string    `json:"third_party_no" gorm:"size:64;comment:第三方交易号"`
+    LocalAmount  money.Money `json:"local_amount" gorm:"type:decimal(10,2);default:0;comment:本地金额"`
+    RemoteAmount money.Money `json:"remote_amount" gorm:"type:decimal(10,2);default:0;comment:渠道金额"`
+    LocalStatus  string    `json:"local_status" gorm:"size:20;comment:本地状态"`
+    RemoteStatus string    `json:"remote_status" gorm:"size:20;comment:渠道状态"`
+    CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (ReconciliationDiscrepancy) TableName() string {
+    return "reconciliation_discrepancies"
+}
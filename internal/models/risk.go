@@ -3,6 +3,24 @@ package models
 import (
     "time"
     "gorm.io/gorm"
+
+    "task-platform-api/pkg/money"
+)
+
+// 违规类型
+const (
+    ViolationTypeFraud   = "fraud"   // 欺诈
+    ViolationTypeDelay   = "delay"   // 逾期
+    ViolationTypeQuality = "quality" // 质量问题
+    ViolationTypeOther   = "other"   // 其他
+)
+
+// 违规处理状态
+const (
+    ViolationStatusPending   int8 = 0 // 待处理
+    ViolationStatusHandled   int8 = 1 // 已处理，违约金已从保证金扣除
+    ViolationStatusAppealed  int8 = 2 // 已申诉
+    ViolationStatusDismissed int8 = 3 // 已撤销
 )
 
 // Violation 违规表
@@ -11,10 +29,10 @@ type Violation struct {
     UserID    uint64    `json:"user_id" gorm:"index;not null;comment:用户ID"`
     TaskID    *uint64   `json:"task_id" gorm:"index;comment:关联任务ID"`
     ViolateType string   `json:"violate_type" gorm:"type:enum('fraud','delay','quality','other');not null;comment:违规类型"`
-    Penalty   float64   `json:"penalty" gorm:"type:decimal(10,2);default:0;comment:处罚金额"`
+    Penalty   money.Money `json:"penalty" gorm:"type:decimal(10,2);default:0;comment:处罚金额"`
     Description string   `json:"description" gorm:"type:text;comment:违规描述"`
     Evidence   string    `json:"evidence" gorm:"type:json;comment:违规证据"`
-    Status     int8      `json:"status" gorm:"default:0;comment:状态:0-待处理,1-已处理,2-已申诉"`
+    Status     int8      `json:"status" gorm:"default:0;comment:状态:0-待处理,1-已处理,2-已申诉,3-已撤销"`
     HandleTime *time.Time `json:"handle_time" gorm:"comment:处理时间"`
     CreatedAt  time.Time `json:"created_at"`
     UpdatedAt  time.Time `json:"updated_at"`
@@ -28,6 +46,11 @@ func (Violation) TableName() string {
     return "violations"
 }
 
+// IsOpen 是否待处理，待处理和已申诉的违规可由管理员确认或撤销
+func (v *Violation) IsOpen() bool {
+    return v.Status == ViolationStatusPending || v.Status == ViolationStatusAppealed
+}
+
 // Complaint 申诉表
 type Complaint struct {
     ID         uint64    `json:"id" gorm:"primaryKey;column:complaint_id"`
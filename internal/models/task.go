@@ -3,6 +3,19 @@ package models
 import (
     "time"
     "gorm.io/gorm"
+
+    "task-platform-api/pkg/money"
+)
+
+// 任务状态
+const (
+    TaskStatusDraft         int8 = 0 // 草稿
+    TaskStatusAvailable     int8 = 1 // 待接取
+    TaskStatusInProgress    int8 = 2 // 进行中
+    TaskStatusPendingAccept int8 = 3 // 待验收
+    TaskStatusCompleted     int8 = 4 // 已完成
+    TaskStatusCancelled     int8 = 5 // 已取消
+    TaskStatusOverdue       int8 = 6 // 已逾期
 )
 
 // Task 任务表
@@ -12,11 +25,15 @@ type Task struct {
     TakerID         uint64    `json:"taker_id" gorm:"index;comment:接取者ID"`
     Title           string    `json:"title" gorm:"size:200;not null;comment:任务标题"`
     Content         string    `json:"content" gorm:"type:text;not null;comment:任务内容"`
-    Amount          float64   `json:"amount" gorm:"type:decimal(10,2);not null;comment:任务金额"`
+    Amount          money.Money `json:"amount" gorm:"type:decimal(10,2);not null;comment:任务金额"`
     ServiceFeeRatio float64   `json:"service_fee_ratio" gorm:"type:decimal(3,2);default:0.06;comment:服务费比例"`
     DepositRatio    float64   `json:"deposit_ratio" gorm:"type:decimal(3,2);default:0.10;comment:保证金比例"`
+    PublisherFee    money.Money `json:"publisher_fee" gorm:"type:decimal(10,2);default:0;comment:发布者服务费"`
+    TakerFee        money.Money `json:"taker_fee" gorm:"type:decimal(10,2);default:0;comment:接取者服务费"`
+    FeeBreakdown    string    `json:"fee_breakdown" gorm:"type:json;comment:服务费计算明细,支付预付款时按规则计算"`
+    MaxRevisions    int       `json:"max_revisions" gorm:"default:3;comment:最大整改轮次"`
     Deadline        time.Time `json:"deadline" gorm:"not null;comment:截止时间"`
-    Status          int8      `json:"status" gorm:"default:0;comment:状态:0-草稿,1-待接取,2-进行中,3-待验收,4-已完成,5-已取消"`
+    Status          int8      `json:"status" gorm:"default:0;comment:状态:0-草稿,1-待接取,2-进行中,3-待验收,4-已完成,5-已取消,6-已逾期"`
     ViewCount       int       `json:"view_count" gorm:"default:0;comment:浏览次数"`
     ApplyCount      int       `json:"apply_count" gorm:"default:0;comment:申请次数"`
     CategoryID      uint64    `json:"category_id" gorm:"index;comment:分类ID"`
@@ -31,6 +48,7 @@ type Task struct {
     Stages     []TaskStage  `json:"stages" gorm:"foreignKey:TaskID"`
     Deliveries []TaskDelivery `json:"deliveries" gorm:"foreignKey:TaskID"`
     Trades     []Trade       `json:"trades" gorm:"foreignKey:TaskID"`
+    StatusLogs []TaskStatusLog `json:"status_logs,omitempty" gorm:"foreignKey:TaskID"`
 }
 
 // TableName 设置表名
@@ -38,13 +56,20 @@ func (Task) TableName() string {
     return "tasks"
 }
 
+// 任务阶段状态
+const (
+    StageStatusPending    int8 = 0 // 待开始
+    StageStatusInProgress int8 = 1 // 进行中
+    StageStatusCompleted  int8 = 2 // 已完成
+)
+
 // TaskStage 任务阶段表
 type TaskStage struct {
     ID          uint64    `json:"id" gorm:"primaryKey;column:stage_id"`
     TaskID      uint64    `json:"task_id" gorm:"index;not null;comment:任务ID"`
     StageName   string    `json:"stage_name" gorm:"size:100;not null;comment:阶段名称"`
     AmountRatio float64   `json:"amount_ratio" gorm:"type:decimal(3,2);not null;comment:阶段金额比例"`
-    Amount      float64   `json:"amount" gorm:"type:decimal(10,2);comment:阶段金额"`
+    Amount      money.Money `json:"amount" gorm:"type:decimal(10,2);comment:阶段金额"`
     Deadline    *time.Time `json:"deadline" gorm:"comment:阶段截止时间"`
     Status      int8      `json:"status" gorm:"default:0;comment:状态:0-待开始,1-进行中,2-已完成"`
     Description string    `json:"description" gorm:"type:text;comment:阶段描述"`
@@ -59,6 +84,13 @@ func (TaskStage) TableName() string {
     return "task_stages"
 }
 
+// 交付状态
+const (
+    DeliveryStatusPending  int8 = 0 // 待验收
+    DeliveryStatusAccepted int8 = 1 // 已验收
+    DeliveryStatusRevision int8 = 2 // 需整改
+)
+
 // TaskDelivery 交付凭证表
 type TaskDelivery struct {
     ID         uint64    `json:"id" gorm:"primaryKey;column:delivery_id"`
@@ -101,15 +133,23 @@ func (TaskCategory) TableName() string {
     return "task_categories"
 }
 
+// 任务申请状态
+const (
+    ApplicationStatusPending     int8 = 0 // 待审核
+    ApplicationStatusAccepted    int8 = 1 // 已接受
+    ApplicationStatusRejected    int8 = 2 // 已拒绝
+    ApplicationStatusShortlisted int8 = 3 // 已入围
+)
+
 // TaskApplication 任务申请表
 type TaskApplication struct {
     ID           uint64    `json:"id" gorm:"primaryKey;column:application_id"`
     TaskID       uint64    `json:"task_id" gorm:"index;not null;comment:任务ID"`
     ApplicantID  uint64    `json:"applicant_id" gorm:"index;not null;comment:申请者ID"`
     Message      string    `json:"message" gorm:"type:text;comment:申请留言"`
-    QuotedPrice  float64   `json:"quoted_price" gorm:"type:decimal(10,2);comment:报价"`
+    QuotedPrice  money.Money `json:"quoted_price" gorm:"type:decimal(10,2);comment:报价"`
     Attachments  string    `json:"attachments" gorm:"type:json;comment:附件"`
-    Status       int8      `json:"status" gorm:"default:0;comment:状态:0-待审核,1-已接受,2-已拒绝"`
+    Status       int8      `json:"status" gorm:"default:0;comment:状态:0-待审核,1-已接受,2-已拒绝,3-已入围"`
     ReviewNote   string    `json:"review_note" gorm:"type:text;comment:审核备注"`
     CreatedAt    time.Time `json:"created_at"`
     UpdatedAt    time.Time `json:"updated_at"`
@@ -123,6 +163,23 @@ func (TaskApplication) TableName() string {
     return "task_applications"
 }
 
+// TaskStatusLog 任务状态变更记录表
+type TaskStatusLog struct {
+    ID         uint64    `json:"id" gorm:"primaryKey;column:log_id"`
+    TaskID     uint64    `json:"task_id" gorm:"index;not null;comment:任务ID"`
+    FromStatus int8      `json:"from_status" gorm:"not null;comment:变更前状态"`
+    ToStatus   int8      `json:"to_status" gorm:"not null;comment:变更后状态"`
+    Event      string    `json:"event" gorm:"size:32;not null;comment:触发事件"`
+    OperatorID uint64    `json:"operator_id" gorm:"index;comment:操作人ID,0表示系统"`
+    Reason     string    `json:"reason" gorm:"size:500;comment:变更原因"`
+    CreatedAt  time.Time `json:"created_at" gorm:"column:create_time"`
+}
+
+// TableName 设置表名
+func (TaskStatusLog) TableName() string {
+    return "task_status_logs"
+}
+
 // BeforeCreate GORM钩子：创建前
 func (t *Task) BeforeCreate(tx *gorm.DB) error {
     if t.CreatedAt.IsZero() {
@@ -142,32 +199,37 @@ func (t *Task) BeforeUpdate(tx *gorm.DB) error {
 
 // IsDraft 任务是否为草稿状态
 func (t *Task) IsDraft() bool {
-    return t.Status == 0
+    return t.Status == TaskStatusDraft
 }
 
 // IsAvailable 任务是否可接取
 func (t *Task) IsAvailable() bool {
-    return t.Status == 1 && t.TakerID == 0
+    return t.Status == TaskStatusAvailable && t.TakerID == 0
 }
 
 // IsInProgress 任务是否进行中
 func (t *Task) IsInProgress() bool {
-    return t.Status == 2
+    return t.Status == TaskStatusInProgress
 }
 
 // IsPendingAccept 任务是否待验收
 func (t *Task) IsPendingAccept() bool {
-    return t.Status == 3
+    return t.Status == TaskStatusPendingAccept
 }
 
 // IsCompleted 任务是否已完成
 func (t *Task) IsCompleted() bool {
-    return t.Status == 4
+    return t.Status == TaskStatusCompleted
 }
 
 // IsCancelled 任务是否已取消
 func (t *Task) IsCancelled() bool {
-    return t.Status == 5
+    return t.Status == TaskStatusCancelled
+}
+
+// IsOverdue 任务是否已逾期
+func (t *Task) IsOverdue() bool {
+    return t.Status == TaskStatusOverdue
 }
 
 // HasTaker 任务是否已有人接取
@@ -175,27 +237,107 @@ func (t *Task) HasTaker() bool {
     return t.TakerID > 0
 }
 
+// GetStatusName 获取任务状态名称
+func (t *Task) GetStatusName() string {
+    return TaskStatusName(t.Status)
+}
+
 // IsExpired 任务是否已过期
 func (t *Task) IsExpired() bool {
     return time.Now().After(t.Deadline)
 }
 
-// GetPublisherAmount 获取发布者应收金额（扣除服务费后）
-func (t *Task) GetPublisherAmount() float64 {
-    return t.Amount * (1 - t.ServiceFeeRatio)
+// HasFeeQuote 是否已按服务费规则计算过费用
+func (t *Task) HasFeeQuote() bool {
+    return t.FeeBreakdown != ""
+}
+
+// GetPublisherFee 获取发布者服务费
+func (t *Task) GetPublisherFee() money.Money {
+    return t.PublisherFee
 }
 
-// GetTakerAmount 获取接取者应收金额（扣除服务费后）
-func (t *Task) GetTakerAmount() float64 {
-    return t.Amount * (1 - t.ServiceFeeRatio)
+// GetTakerFee 获取接取者服务费，未按规则计算的历史任务按服务费比例从赏金中扣除
+func (t *Task) GetTakerFee() money.Money {
+    if !t.HasFeeQuote() {
+        return t.Amount.MulRate(t.ServiceFeeRatio, money.RoundHalfUp)
+    }
+    return t.TakerFee
+}
+
+// GetPublisherAmount 获取发布者应付金额（赏金加发布者服务费，不含保证金）
+func (t *Task) GetPublisherAmount() money.Money {
+    return t.Amount + t.GetPublisherFee()
+}
+
+// GetTakerAmount 获取接取者实得金额（赏金扣除接取者服务费）
+func (t *Task) GetTakerAmount() money.Money {
+    return t.Amount - t.GetTakerFee()
+}
+
+// GetPlatformFee 获取平台服务费，为发布者和接取者服务费之和
+func (t *Task) GetPlatformFee() money.Money {
+    return t.GetPublisherFee() + t.GetTakerFee()
+}
+
+// GetDepositAmount 获取保证金金额，四舍五入到分；发布者随预付款托管，接取者接取任务时从钱包冻结
+func (t *Task) GetDepositAmount() money.Money {
+    return t.Amount.MulRate(t.DepositRatio, money.RoundHalfUp)
+}
+
+// IsPending 申请是否待审核
+func (a *TaskApplication) IsPending() bool {
+    return a.Status == ApplicationStatusPending
 }
 
-// GetPlatformFee 获取平台服务费
-func (t *Task) GetPlatformFee() float64 {
-    return t.Amount * t.ServiceFeeRatio
+// IsShortlisted 申请是否已入围
+func (a *TaskApplication) IsShortlisted() bool {
+    return a.Status == ApplicationStatusShortlisted
 }
 
-// GetDepositAmount 获取保证金金额
-func (t *Task) GetDepositAmount() float64 {
-    return t.Amount * t.DepositRatio
+// IsOpen 申请是否仍在审核中（待审核或已入围）
+func (a *TaskApplication) IsOpen() bool {
+    return a.IsPending() || a.IsShortlisted()
+}
+
+// HasStages 任务是否分阶段交付
+func (t *Task) HasStages() bool {
+    return len(t.Stages) > 0
+}
+
+// IsInProgress 阶段是否进行中
+func (s *TaskStage) IsInProgress() bool {
+    return s.Status == StageStatusInProgress
+}
+
+// IsCompleted 阶段是否已完成
+func (s *TaskStage) IsCompleted() bool {
+    return s.Status == StageStatusCompleted
+}
+
+// IsPending 交付是否待验收
+func (d *TaskDelivery) IsPending() bool {
+    return d.Status == DeliveryStatusPending
+}
+
+// TaskStatusName 获取任务状态对应的名称
+func TaskStatusName(status int8) string {
+    switch status {
+    case TaskStatusDraft:
+        return "草稿"
+    case TaskStatusAvailable:
+        return "待接取"
+    case TaskStatusInProgress:
+        return "进行中"
+    case TaskStatusPendingAccept:
+        return "待验收"
+    case TaskStatusCompleted:
+        return "已完成"
+    case TaskStatusCancelled:
+        return "已取消"
+    case TaskStatusOverdue:
+        return "已逾期"
+    default:
+        return "未知"
+    }
 }
\ No newline at end of file
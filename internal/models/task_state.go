@@ -0,0 +1,71 @@
+package models
+
+import (
+    "fmt"
+)
+
+// TaskEvent 任务状态事件
+type TaskEvent string
+
+// 任务状态事件
+const (
+    TaskEventPublish     TaskEvent = "publish"      // 发布
+    TaskEventTake        TaskEvent = "take"         // 接取
+    TaskEventAssign      TaskEvent = "assign"       // 发布者接受申请并指派
+    TaskEventSubmit      TaskEvent = "submit"       // 提交验收
+    TaskEventAccept      TaskEvent = "accept"       // 验收通过
+    TaskEventStageAccept TaskEvent = "stage_accept" // 阶段验收通过，继续下一阶段
+    TaskEventRevise      TaskEvent = "revise"       // 要求整改
+    TaskEventCancel      TaskEvent = "cancel"       // 取消
+    TaskEventExpire      TaskEvent = "expire"       // 超过截止时间，仅由系统触发
+)
+
+// TaskTransition 任务状态流转定义
+type TaskTransition struct {
+    Event TaskEvent
+    From  []int8
+    To    int8
+}
+
+// taskStateMachine 任务状态机，声明所有合法的状态流转
+//
+//  草稿 → 待接取 → 进行中 ⇄ 待验收 → 已完成
+//    ↘      ↘        ↓       ↑
+//     已取消  已取消  已逾期 ──┘
+//                     ↘
+//                      已取消
+//
+// 待接取的任务超过截止时间自动取消，进行中的任务超过截止时间转为已逾期，
+// 逾期后接取者仍可提交验收，发布者也可直接取消
+var taskStateMachine = []TaskTransition{
+    {Event: TaskEventPublish, From: []int8{TaskStatusDraft}, To: TaskStatusAvailable},
+    {Event: TaskEventTake, From: []int8{TaskStatusAvailable}, To: TaskStatusInProgress},
+    {Event: TaskEventAssign, From: []int8{TaskStatusAvailable}, To: TaskStatusInProgress},
+    {Event: TaskEventSubmit, From: []int8{TaskStatusInProgress, TaskStatusOverdue}, To: TaskStatusPendingAccept},
+    {Event: TaskEventAccept, From: []int8{TaskStatusPendingAccept}, To: TaskStatusCompleted},
+    {Event: TaskEventStageAccept, From: []int8{TaskStatusPendingAccept}, To: TaskStatusInProgress},
+    {Event: TaskEventRevise, From: []int8{TaskStatusPendingAccept}, To: TaskStatusInProgress},
+    {Event: TaskEventCancel, From: []int8{TaskStatusDraft, TaskStatusAvailable, TaskStatusOverdue}, To: TaskStatusCancelled},
+    {Event: TaskEventExpire, From: []int8{TaskStatusAvailable}, To: TaskStatusCancelled},
+    {Event: TaskEventExpire, From: []int8{TaskStatusInProgress}, To: TaskStatusOverdue},
+}
+
+// FireTaskEvent 根据当前状态和事件计算目标状态，不合法时返回错误
+func FireTaskEvent(from int8, event TaskEvent) (int8, error) {
+    found := false
+    for _, t := range taskStateMachine {
+        if t.Event != event {
+            continue
+        }
+        found = true
+        for _, status := range t.From {
+            if status == from {
+                return t.To, nil
+            }
+        }
+    }
+    if !found {
+        return from, fmt.Errorf("未知的任务事件: %s", event)
+    }
+    return from, fmt.Errorf("任务当前状态为%s，不能执行%s操作", TaskStatusName(from), event)
+}
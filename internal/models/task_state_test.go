@@ -0,0 +1,60 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFireTaskEvent(t *testing.T) {
+	// 全部合法流转，未列出的状态与事件组合均不合法
+	legal := []struct {
+		from  int8
+		event TaskEvent
+		to    int8
+	}{
+		{TaskStatusDraft, TaskEventPublish, TaskStatusAvailable},
+		{TaskStatusAvailable, TaskEventTake, TaskStatusInProgress},
+		{TaskStatusAvailable, TaskEventAssign, TaskStatusInProgress},
+		{TaskStatusInProgress, TaskEventSubmit, TaskStatusPendingAccept},
+		{TaskStatusOverdue, TaskEventSubmit, TaskStatusPendingAccept}, // 逾期后接取者仍可提交验收
+		{TaskStatusPendingAccept, TaskEventAccept, TaskStatusCompleted},
+		{TaskStatusPendingAccept, TaskEventStageAccept, TaskStatusInProgress},
+		{TaskStatusPendingAccept, TaskEventRevise, TaskStatusInProgress},
+		{TaskStatusDraft, TaskEventCancel, TaskStatusCancelled},
+		{TaskStatusAvailable, TaskEventCancel, TaskStatusCancelled},
+		{TaskStatusOverdue, TaskEventCancel, TaskStatusCancelled},
+		{TaskStatusAvailable, TaskEventExpire, TaskStatusCancelled}, // 无人接取的任务过期自动取消
+		{TaskStatusInProgress, TaskEventExpire, TaskStatusOverdue},  // 进行中的任务过期转为已逾期
+	}
+	statuses := []int8{TaskStatusDraft, TaskStatusAvailable, TaskStatusInProgress, TaskStatusPendingAccept, TaskStatusCompleted, TaskStatusCancelled, TaskStatusOverdue}
+	taskEvents := []TaskEvent{TaskEventPublish, TaskEventTake, TaskEventAssign, TaskEventSubmit, TaskEventAccept, TaskEventStageAccept, TaskEventRevise, TaskEventCancel, TaskEventExpire}
+
+	for _, from := range statuses {
+		for _, event := range taskEvents {
+			wantTo, wantOK := from, false
+			for _, l := range legal {
+				if l.from == from && l.event == event {
+					wantTo, wantOK = l.to, true
+				}
+			}
+
+			to, err := FireTaskEvent(from, event)
+			if wantOK {
+				if err != nil || to != wantTo {
+					t.Errorf("%s执行%s: 应流转到%s, 实际%s, %v", TaskStatusName(from), event, TaskStatusName(wantTo), TaskStatusName(to), err)
+				}
+				continue
+			}
+			if err == nil || to != from {
+				t.Errorf("%s执行%s: 应拒绝且保持原状态, 实际%s, %v", TaskStatusName(from), event, TaskStatusName(to), err)
+			}
+		}
+	}
+}
+
+func TestFireTaskEventUnknownEvent(t *testing.T) {
+	to, err := FireTaskEvent(TaskStatusInProgress, TaskEvent("reopen"))
+	if err == nil || !strings.Contains(err.Error(), "未知的任务事件") || to != TaskStatusInProgress {
+		t.Fatalf("未知事件应返回错误并保持原状态: %s, %v", TaskStatusName(to), err)
+	}
+}
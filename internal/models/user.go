@@ -8,8 +8,8 @@ import (
 // User 用户表
 type User struct {
     ID         uint64    `json:"id" gorm:"primaryKey;column:user_id"`
-    OpenID     string    `json:"openid" gorm:"uniqueIndex;size:128;comment:微信/支付宝用户标识"`
-    UnionID    string    `json:"unionid" gorm:"index;size:128;comment:跨平台用户标识"`
+    OpenID     string    `json:"openid" gorm:"column:openid;uniqueIndex;size:128;comment:微信/支付宝用户标识"`
+    UnionID    string    `json:"unionid" gorm:"column:unionid;index;size:128;comment:跨平台用户标识"`
     AuthType   string    `json:"auth_type" gorm:"type:enum('wechat','alipay');not null;comment:授权类型"`
     Nickname   string    `json:"nickname" gorm:"size:100;comment:用户昵称"`
     Avatar     string    `json:"avatar" gorm:"size:500;comment:用户头像"`
@@ -47,6 +47,30 @@ func (UserSession) TableName() string {
     return "user_sessions"
 }
 
+// 登录渠道
+const (
+    AuthChannelMiniProgram = "miniprogram" // 微信小程序
+    AuthChannelOfficial    = "official"    // 微信公众号
+    AuthChannelAlipay      = "alipay"      // 支付宝
+)
+
+// UserAuth 用户登录身份表，同一用户可绑定多个渠道的openid，微信各渠道通过unionid关联到同一用户
+type UserAuth struct {
+    ID        uint64    `json:"id" gorm:"primaryKey;column:auth_id"`
+    UserID    uint64    `json:"user_id" gorm:"index;not null;comment:用户ID"`
+    AuthType  string    `json:"auth_type" gorm:"type:enum('wechat','alipay');not null;comment:授权类型"`
+    Channel   string    `json:"channel" gorm:"size:20;not null;uniqueIndex:uk_channel_openid;comment:登录渠道"`
+    OpenID    string    `json:"openid" gorm:"column:openid;size:128;not null;uniqueIndex:uk_channel_openid;comment:渠道用户标识"`
+    UnionID   string    `json:"unionid" gorm:"column:unionid;size:128;index;comment:跨渠道用户标识"`
+    CreatedAt time.Time `json:"created_at" gorm:"column:create_time"`
+    UpdatedAt time.Time `json:"updated_at" gorm:"column:update_time"`
+}
+
+// TableName 设置表名
+func (UserAuth) TableName() string {
+    return "user_auths"
+}
+
 // UserCredit 用户信誉表
 type UserCredit struct {
     ID           uint64    `json:"id" gorm:"primaryKey;column:credit_id"`
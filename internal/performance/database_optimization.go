@@ -9,6 +9,7 @@ import (
 	"gorm.io/gorm/clause"
 	
 	"task-platform-api/internal/models"
+	"task-platform-api/pkg/money"
 )
 
 // DatabaseOptimizer 数据库性能优化器
@@ -73,8 +74,8 @@ type TaskListQuery struct {
 	CategoryID *uint64
 	PublisherID *uint64
 	TakerID   *uint64
-	MinAmount *float64
-	MaxAmount *float64
+	MinAmount *money.Money
+	MaxAmount *money.Money
 	Keyword   string
 	Offset    int
 	Limit     int
@@ -141,32 +142,6 @@ func (d *DatabaseOptimizer) GetTaskListWithOptimization(ctx context.Context, que
 	return tasks, total, nil
 }
 
-// BatchUpdateTaskStatus 批量更新任务状态（优化版）
-func (d *DatabaseOptimizer) BatchUpdateTaskStatus(ctx context.Context, taskIDs []uint64, status int8) error {
-	if len(taskIDs) == 0 {
-		return nil
-	}
-
-	// 使用单个批量更新语句，避免多次数据库往返
-	result := d.db.WithContext(ctx).
-		Model(&models.Task{}).
-		Where("task_id IN ?", taskIDs).
-		Updates(map[string]interface{}{
-			"status":     status,
-			"update_time": time.Now(),
-		})
-
-	if result.Error != nil {
-		return fmt.Errorf("批量更新任务状态失败: %w", result.Error)
-	}
-
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("没有找到需要更新的任务")
-	}
-
-	return nil
-}
-
 // GetUserTaskStatistics 获取用户任务统计（优化版）
 func (d *DatabaseOptimizer) GetUserTaskStatistics(ctx context.Context, userID uint64) (map[string]int64, error) {
 	var stats []struct {
@@ -191,17 +166,17 @@ func (d *DatabaseOptimizer) GetUserTaskStatistics(ctx context.Context, userID ui
 	for _, stat := range stats {
 		var statusName string
 		switch stat.Status {
-		case 0:
+		case models.TaskStatusDraft:
 			statusName = "draft"
-		case 1:
+		case models.TaskStatusAvailable:
 			statusName = "available"
-		case 2:
+		case models.TaskStatusInProgress:
 			statusName = "in_progress"
-		case 3:
+		case models.TaskStatusPendingAccept:
 			statusName = "pending_accept"
-		case 4:
+		case models.TaskStatusCompleted:
 			statusName = "completed"
-		case 5:
+		case models.TaskStatusCancelled:
 			statusName = "cancelled"
 		default:
 			statusName = "unknown"
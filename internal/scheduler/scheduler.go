@@ -0,0 +1,91 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Job 定时任务执行函数
+type Job func(ctx context.Context) error
+
+type entry struct {
+	name     string
+	interval time.Duration
+	job      Job
+}
+
+// Scheduler 进程内定时任务调度器，每个任务独立按固定间隔执行
+type Scheduler struct {
+	logger  *zap.Logger
+	entries []entry
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// New 创建调度器
+func New(logger *zap.Logger) *Scheduler {
+	return &Scheduler{
+		logger: logger,
+	}
+}
+
+// Register 注册定时任务，须在Start之前调用
+func (s *Scheduler) Register(name string, interval time.Duration, job Job) {
+	s.entries = append(s.entries, entry{name: name, interval: interval, job: job})
+}
+
+// Start 启动全部定时任务
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, e := range s.entries {
+		s.wg.Add(1)
+		go s.run(ctx, e)
+	}
+}
+
+// Stop 停止调度并等待正在执行的任务结束
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+}
+
+// run 按间隔循环执行单个任务
+func (s *Scheduler) run(ctx context.Context, e entry) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.execute(ctx, e)
+		}
+	}
+}
+
+// execute 执行一次任务，捕获panic避免影响其他任务
+func (s *Scheduler) execute(ctx context.Context, e entry) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("定时任务异常", zap.String("job", e.name), zap.Any("panic", r))
+		}
+	}()
+
+	start := time.Now()
+	if err := e.job(ctx); err != nil {
+		s.logger.Error("定时任务执行失败", zap.String("job", e.name), zap.Error(err))
+		return
+	}
+	s.logger.Debug("定时任务执行完成", zap.String("job", e.name), zap.Duration("cost", time.Since(start)))
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"task-platform-api/internal/models"
+	"task-platform-api/pkg/money"
+)
+
+// minPayAmount 使用优惠券后预付款的最低实付金额，支付渠道不支持0元订单
+var minPayAmount = money.FromCents(1)
+
+// couponTypeOrder 叠加使用时的计算顺序：先减免服务费，再立减
+var couponTypeOrder = map[string]int{
+	models.CouponTypeFirstTaskFee: 0,
+	models.CouponTypeFeePercent:   1,
+	models.CouponTypeFixed:        2,
+}
+
+// activeRedemptionStatuses 占用优惠券可用次数的使用状态
+var activeRedemptionStatuses = []int8{models.CouponRedemptionReserved, models.CouponRedemptionUsed}
+
+// CouponDiscount 单张优惠券的优惠金额
+type CouponDiscount struct {
+	CouponID   uint64      `json:"coupon_id"`
+	Code       string      `json:"code"`
+	Name       string      `json:"name"`
+	CouponType string      `json:"coupon_type"`
+	Discount   money.Money `json:"discount"`
+}
+
+// CouponQuote 预付款优惠计算结果
+type CouponQuote struct {
+	OriginalAmount money.Money      `json:"original_amount"` // 优惠前应付金额，含赏金、发布者服务费和保证金
+	Discount       money.Money      `json:"discount"`        // 优惠合计，由平台营销费用承担
+	PayableAmount  money.Money      `json:"payable_amount"`  // 实付金额
+	Coupons        []CouponDiscount `json:"coupons"`
+}
+
+// normalizeCouponCode 优惠码不区分大小写，统一转换为大写
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// quoteCoupons 校验优惠码并计算预付款优惠，须在任务服务费计算后调用。
+// 服务费类优惠券只减免发布者服务费，保证金不参与优惠且实付金额至少0.01元；
+// lock为true时锁定优惠券，使并发下单时的可用次数校验串行执行
+func quoteCoupons(tx *gorm.DB, userID uint64, task *models.Task, codes []string, lock bool) (*CouponQuote, error) {
+	original := escrowAmount(task)
+	quote := &CouponQuote{
+		OriginalAmount: original,
+		PayableAmount:  original,
+		Coupons:        []CouponDiscount{},
+	}
+
+	normalized := make([]string, 0, len(codes))
+	seen := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		code = normalizeCouponCode(code)
+		if code == "" {
+			continue
+		}
+		if seen[code] {
+			return nil, ErrInvalidParam.WithMessage(fmt.Sprintf("优惠码%s重复", code))
+		}
+		seen[code] = true
+		normalized = append(normalized, code)
+	}
+	if len(normalized) == 0 {
+		return quote, nil
+	}
+
+	query := tx
+	if lock {
+		query = tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var coupons []models.Coupon
+	if err := query.Where("code IN ?", normalized).Order("id ASC").Find(&coupons).Error; err != nil {
+		return nil, fmt.Errorf("查询优惠券失败: %w", err)
+	}
+	if len(coupons) != len(normalized) {
+		found := make(map[string]bool, len(coupons))
+		for _, c := range coupons {
+			found[c.Code] = true
+		}
+		for _, code := range normalized {
+			if !found[code] {
+				return nil, ErrCouponNotFound.WithMessage(fmt.Sprintf("优惠码%s不存在", code))
+			}
+		}
+	}
+
+	if err := checkCouponStacking(coupons); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range coupons {
+		if err := checkCouponUsable(tx, &coupons[i], userID, task, now); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(coupons, func(i, j int) bool {
+		return couponTypeOrder[coupons[i].CouponType] < couponTypeOrder[coupons[j].CouponType]
+	})
+	remainingFee := task.GetPublisherFee()
+	maxDiscount := min(task.GetPublisherAmount(), original-minPayAmount)
+	for _, c := range coupons {
+		var discount money.Money
+		switch c.CouponType {
+		case models.CouponTypeFirstTaskFee:
+			discount = remainingFee
+		case models.CouponTypeFeePercent:
+			discount = remainingFee.MulRate(c.FeeDiscountRate, money.RoundHalfUp)
+		case models.CouponTypeFixed:
+			discount = c.Amount
+		}
+		if c.MaxDiscount.IsPositive() {
+			discount = min(discount, c.MaxDiscount)
+		}
+		discount = min(discount, maxDiscount-quote.Discount)
+		if !discount.IsPositive() {
+			return nil, ErrCouponNotApplicable.WithMessage(fmt.Sprintf("优惠券「%s」对该任务不产生优惠", c.Name))
+		}
+
+		if c.IsFeeCoupon() {
+			remainingFee -= discount
+		}
+		quote.Discount += discount
+		quote.Coupons = append(quote.Coupons, CouponDiscount{
+			CouponID:   c.ID,
+			Code:       c.Code,
+			Name:       c.Name,
+			CouponType: c.CouponType,
+			Discount:   discount,
+		})
+	}
+	quote.PayableAmount = original - quote.Discount
+	return quote, nil
+}
+
+// checkCouponStacking 叠加规则：多张优惠券同时使用时每张都须允许叠加，且同一类型只能使用一张
+func checkCouponStacking(coupons []models.Coupon) error {
+	if len(coupons) <= 1 {
+		return nil
+	}
+	types := make(map[string]bool, len(coupons))
+	for _, c := range coupons {
+		if !c.Stackable {
+			return ErrCouponNotStackable.WithMessage(fmt.Sprintf("优惠券「%s」不能与其他优惠券叠加使用", c.Name))
+		}
+		if types[c.CouponType] {
+			return ErrCouponNotStackable.WithMessage("同一类型的优惠券只能使用一张")
+		}
+		types[c.CouponType] = true
+	}
+	return nil
+}
+
+// checkCouponUsable 校验优惠券状态、有效期、全局和每人可用次数、赏金门槛以及首单资格
+func checkCouponUsable(tx *gorm.DB, coupon *models.Coupon, userID uint64, task *models.Task, now time.Time) error {
+	if !coupon.IsEnabled() || !coupon.IsEffective(now) {
+		return ErrCouponUnavailable.WithMessage(fmt.Sprintf("优惠券「%s」已停用或不在有效期内", coupon.Name))
+	}
+	if coupon.IsExhausted() {
+		return ErrCouponUnavailable.WithMessage(fmt.Sprintf("优惠券「%s」已被用完", coupon.Name))
+	}
+	if coupon.MinTaskAmount.IsPositive() && task.Amount < coupon.MinTaskAmount {
+		return ErrCouponNotApplicable.WithMessage(fmt.Sprintf("优惠券「%s」要求任务赏金不低于%s元", coupon.Name, coupon.MinTaskAmount))
+	}
+
+	if coupon.PerUserLimit > 0 {
+		var used int64
+		if err := tx.Model(&models.CouponRedemption{}).
+			Where("coupon_id = ? AND user_id = ? AND status IN ?", coupon.ID, userID, activeRedemptionStatuses).
+			Count(&used).Error; err != nil {
+			return fmt.Errorf("查询优惠券使用记录失败: %w", err)
+		}
+		if used >= int64(coupon.PerUserLimit) {
+			return ErrCouponUnavailable.WithMessage(fmt.Sprintf("您已达到优惠券「%s」的使用次数上限", coupon.Name))
+		}
+	}
+
+	if coupon.CouponType == models.CouponTypeFirstTaskFee {
+		first, err := isFirstTask(tx, userID)
+		if err != nil {
+			return err
+		}
+		if !first {
+			return ErrCouponNotApplicable.WithMessage(fmt.Sprintf("优惠券「%s」仅限发布的首个任务使用", coupon.Name))
+		}
+	}
+	return nil
+}
+
+// isFirstTask 用户是否从未支付过任务预付款，也没有待支付订单占用首单优惠
+func isFirstTask(tx *gorm.DB, userID uint64) (bool, error) {
+	var paid int64
+	if err := tx.Model(&models.Trade{}).
+		Where("user_id = ? AND trade_type = ? AND status IN ?", userID, models.TradeTypePrepay,
+			[]int8{models.TradeStatusPaid, models.TradeStatusRefunded}).
+		Count(&paid).Error; err != nil {
+		return false, fmt.Errorf("查询预付款交易失败: %w", err)
+	}
+	if paid > 0 {
+		return false, nil
+	}
+
+	var reserved int64
+	if err := tx.Model(&models.CouponRedemption{}).
+		Joins("JOIN coupons ON coupons.id = coupon_redemptions.coupon_id").
+		Where("coupon_redemptions.user_id = ? AND coupon_redemptions.status IN ? AND coupons.coupon_type = ?",
+			userID, activeRedemptionStatuses, models.CouponTypeFirstTaskFee).
+		Count(&reserved).Error; err != nil {
+		return false, fmt.Errorf("查询优惠券使用记录失败: %w", err)
+	}
+	return reserved == 0, nil
+}
+
+// reserveCoupons 创建预付款订单时占用优惠券并记录使用，须在quoteCoupons锁定优惠券后于同一事务内调用
+func reserveCoupons(tx *gorm.DB, quote *CouponQuote, trade *models.Trade) error {
+	for _, c := range quote.Coupons {
+		redemption := &models.CouponRedemption{
+			CouponID: c.CouponID,
+			UserID:   trade.UserID,
+			TaskID:   *trade.TaskID,
+			TradeID:  trade.ID,
+			Discount: c.Discount,
+			Status:   models.CouponRedemptionReserved,
+		}
+		if err := tx.Create(redemption).Error; err != nil {
+			return fmt.Errorf("创建优惠券使用记录失败: %w", err)
+		}
+		if err := tx.Model(&models.Coupon{}).Where("id = ?", c.CouponID).
+			Update("used_count", gorm.Expr("used_count + 1")).Error; err != nil {
+			return fmt.Errorf("更新优惠券使用次数失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// confirmTradeCoupons 预付款支付成功后将占用的优惠券标记为已使用，须在锁定交易后于事务内调用
+func confirmTradeCoupons(tx *gorm.DB, trade *models.Trade) error {
+	if !trade.Discount.IsPositive() {
+		return nil
+	}
+	if err := tx.Model(&models.CouponRedemption{}).
+		Where("trade_id = ? AND status = ?", trade.ID, models.CouponRedemptionReserved).
+		Update("status", models.CouponRedemptionUsed).Error; err != nil {
+		return fmt.Errorf("更新优惠券使用记录失败: %w", err)
+	}
+	return nil
+}
+
+// releaseTradeCoupons 订单未支付或任务取消时释放交易占用的优惠券，退回可用次数；
+// 交易上的操作均先锁任务或交易，此处按主键逐条更新，不对使用记录加范围锁
+func releaseTradeCoupons(tx *gorm.DB, trade *models.Trade) error {
+	if !trade.Discount.IsPositive() {
+		return nil
+	}
+	var redemptions []models.CouponRedemption
+	if err := tx.Where("trade_id = ? AND status IN ?", trade.ID, activeRedemptionStatuses).
+		Order("coupon_id ASC").
+		Find(&redemptions).Error; err != nil {
+		return fmt.Errorf("查询优惠券使用记录失败: %w", err)
+	}
+
+	for _, r := range redemptions {
+		result := tx.Model(&models.CouponRedemption{}).
+			Where("id = ? AND status = ?", r.ID, r.Status).
+			Update("status", models.CouponRedemptionReleased)
+		if result.Error != nil {
+			return fmt.Errorf("更新优惠券使用记录失败: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		if err := tx.Model(&models.Coupon{}).Where("id = ? AND used_count > 0", r.CouponID).
+			Update("used_count", gorm.Expr("used_count - 1")).Error; err != nil {
+			return fmt.Errorf("更新优惠券使用次数失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// reclaimCouponDiscount 任务取消时按未结算比例将优惠券抵扣从发布者冻结余额退回平台营销费用，
+// 发布者不能通过取消任务把优惠金额转为可用余额；未发生结算时全额退回并释放优惠券。
+// settled为任务已结算划出的托管金额，返回退回的金额，须在事务内调用
+func reclaimCouponDiscount(tx *gorm.DB, task *models.Task, trade *models.Trade, settled money.Money) (money.Money, error) {
+	if !trade.Discount.IsPositive() {
+		return money.Zero, nil
+	}
+	// 优惠只抵扣赏金和服务费，保证金不参与按比例分摊
+	spendable := trade.GetEscrowAmount() - task.GetDepositAmount()
+	unsettled := spendable - settled
+	if !unsettled.IsPositive() {
+		return money.Zero, nil
+	}
+	reclaim := trade.Discount
+	if settled.IsPositive() {
+		reclaim = trade.Discount.Allocate(unsettled.Cents(), settled.Cents())[0]
+	}
+
+	description := fmt.Sprintf("任务「%s」取消，未使用的优惠券抵扣退回", task.Title)
+	if _, err := postJournal(tx, ledgerBizCoupon, trade.ID, description,
+		debit(userFrozenAccount(task.PublisherID), reclaim),
+		credit(marketingAccount, reclaim),
+	); err != nil {
+		return 0, err
+	}
+	if _, err := debitFrozenWallet(tx, task.PublisherID, reclaim, walletEntry{
+		TradeID:     &trade.ID,
+		RelatedID:   task.ID,
+		RelatedType: "task",
+		Description: description,
+	}); err != nil {
+		return 0, err
+	}
+
+	if reclaim == trade.Discount {
+		if err := releaseTradeCoupons(tx, trade); err != nil {
+			return 0, err
+		}
+	}
+	return reclaim, nil
+}
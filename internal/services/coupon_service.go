@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"task-platform-api/internal/models"
+	"task-platform-api/pkg/money"
+	"task-platform-api/pkg/utils"
+)
+
+// CreateCouponRequest 创建优惠券请求
+type CreateCouponRequest struct {
+	Code            string
+	Name            string
+	CouponType      string
+	Amount          money.Money
+	FeeDiscountRate float64
+	MaxDiscount     money.Money
+	MinTaskAmount   money.Money
+	Stackable       bool
+	TotalLimit      int
+	PerUserLimit    int
+	StartTime       *time.Time
+	EndTime         *time.Time
+}
+
+// CouponListRequest 优惠券列表查询条件
+type CouponListRequest struct {
+	CouponType string
+	Status     *int8
+	Page       int
+	PageSize   int
+}
+
+// CouponService 优惠券服务
+type CouponService struct {
+	db *gorm.DB
+}
+
+// NewCouponService 创建优惠券服务
+func NewCouponService(db *gorm.DB) *CouponService {
+	return &CouponService{
+		db: db,
+	}
+}
+
+// CreateCoupon 创建优惠券，按类型校验优惠参数，优惠码统一转换为大写且不能重复
+func (s *CouponService) CreateCoupon(ctx context.Context, req *CreateCouponRequest) (*models.Coupon, error) {
+	code := normalizeCouponCode(req.Code)
+	if code == "" {
+		return nil, ErrInvalidParam.WithMessage("优惠码不能为空")
+	}
+	switch req.CouponType {
+	case models.CouponTypeFirstTaskFee:
+	case models.CouponTypeFeePercent:
+		if req.FeeDiscountRate <= 0 || req.FeeDiscountRate > 1 {
+			return nil, ErrInvalidParam.WithMessage("服务费减免比例须大于0且不超过1")
+		}
+	case models.CouponTypeFixed:
+		if !req.Amount.IsPositive() {
+			return nil, ErrInvalidParam.WithMessage("立减金额须大于0")
+		}
+	default:
+		return nil, ErrInvalidParam.WithMessage("不支持的优惠券类型")
+	}
+	if req.MaxDiscount.IsNegative() || req.MinTaskAmount.IsNegative() || req.TotalLimit < 0 || req.PerUserLimit < 0 {
+		return nil, ErrInvalidParam.WithMessage("优惠上限、赏金门槛和可用次数不能为负数")
+	}
+	if req.StartTime != nil && req.EndTime != nil && !req.EndTime.After(*req.StartTime) {
+		return nil, ErrInvalidParam.WithMessage("失效时间须晚于生效时间")
+	}
+
+	coupon := &models.Coupon{
+		Code:          code,
+		Name:          req.Name,
+		CouponType:    req.CouponType,
+		MaxDiscount:   req.MaxDiscount,
+		MinTaskAmount: req.MinTaskAmount,
+		Stackable:     req.Stackable,
+		TotalLimit:    req.TotalLimit,
+		PerUserLimit:  req.PerUserLimit,
+		StartTime:     req.StartTime,
+		EndTime:       req.EndTime,
+		Status:        models.CouponStatusEnabled,
+	}
+	switch req.CouponType {
+	case models.CouponTypeFeePercent:
+		coupon.FeeDiscountRate = req.FeeDiscountRate
+	case models.CouponTypeFixed:
+		coupon.Amount = req.Amount
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Coupon{}).Where("code = ?", code).Count(&count).Error; err != nil {
+			return fmt.Errorf("查询优惠券失败: %w", err)
+		}
+		if count > 0 {
+			return ErrCouponDuplicate
+		}
+		if err := tx.Create(coupon).Error; err != nil {
+			return fmt.Errorf("创建优惠券失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return coupon, nil
+}
+
+// ListCoupons 分页查询优惠券
+func (s *CouponService) ListCoupons(ctx context.Context, req *CouponListRequest) ([]models.Coupon, int64, error) {
+	var coupons []models.Coupon
+	var total int64
+
+	db := s.db.WithContext(ctx).Model(&models.Coupon{})
+	if req.CouponType != "" {
+		db = db.Where("coupon_type = ?", req.CouponType)
+	}
+	if req.Status != nil {
+		db = db.Where("status = ?", *req.Status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取优惠券总数失败: %w", err)
+	}
+
+	offset, limit := utils.Pagination(req.Page, req.PageSize)
+	if err := db.Order("id DESC").Offset(offset).Limit(limit).Find(&coupons).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询优惠券失败: %w", err)
+	}
+
+	return coupons, total, nil
+}
+
+// UpdateCouponStatus 启用或停用优惠券，停用后不能再使用，已占用的待支付订单不受影响
+func (s *CouponService) UpdateCouponStatus(ctx context.Context, couponID uint64, status int8) (*models.Coupon, error) {
+	var coupon models.Coupon
+	db := s.db.WithContext(ctx)
+	if err := db.First(&coupon, couponID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCouponNotFound
+		}
+		return nil, fmt.Errorf("查询优惠券失败: %w", err)
+	}
+	if coupon.Status == status {
+		return &coupon, nil
+	}
+	if err := db.Model(&coupon).Update("status", status).Error; err != nil {
+		return nil, fmt.Errorf("更新优惠券状态失败: %w", err)
+	}
+	return &coupon, nil
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"task-platform-api/internal/models"
+	"task-platform-api/internal/testutil"
+	"task-platform-api/pkg/money"
+)
+
+// couponRows 按优惠码查询时返回的优惠券
+func couponRows(coupons ...models.Coupon) testutil.StubRows {
+	rows := testutil.StubRows{
+		Match:   "FROM `coupons`",
+		Columns: []string{"id", "code", "name", "coupon_type", "amount", "fee_discount_rate", "max_discount", "min_task_amount", "stackable", "total_limit", "per_user_limit", "used_count", "status"},
+	}
+	for _, c := range coupons {
+		rows.Values = append(rows.Values, []driver.Value{
+			int64(c.ID), c.Code, c.Name, c.CouponType, []byte(c.Amount.String()), c.FeeDiscountRate, []byte(c.MaxDiscount.String()),
+			[]byte(c.MinTaskAmount.String()), c.Stackable, int64(c.TotalLimit), int64(c.PerUserLimit), int64(c.UsedCount), int64(models.CouponStatusEnabled),
+		})
+	}
+	return rows
+}
+
+// countRows 计数查询的结果
+func countRows(match string, count int64) testutil.StubRows {
+	return testutil.StubRows{Match: match, Columns: []string{"count(*)"}, Values: [][]driver.Value{{count}}}
+}
+
+// couponTestTask 发布者1的任务，赏金100元、发布者服务费6元、保证金比例10%，优惠前应付116元
+func couponTestTask() *models.Task {
+	return &models.Task{ID: 1, PublisherID: 1, Amount: money.FromCents(10000), PublisherFee: money.FromCents(600), FeeBreakdown: "{}", DepositRatio: 0.1}
+}
+
+var (
+	firstTaskCoupon  = models.Coupon{ID: 1, Code: "FIRST", Name: "首单免服务费", CouponType: models.CouponTypeFirstTaskFee, Stackable: true}
+	feePercentCoupon = models.Coupon{ID: 2, Code: "HALF", Name: "服务费五折", CouponType: models.CouponTypeFeePercent, FeeDiscountRate: 0.5, Stackable: true}
+	fixedCoupon      = models.Coupon{ID: 3, Code: "MINUS20", Name: "立减20元", CouponType: models.CouponTypeFixed, Amount: money.FromCents(2000), Stackable: true}
+)
+
+func TestQuoteCouponsStacking(t *testing.T) {
+	exclusive := fixedCoupon
+	exclusive.Stackable = false
+	anotherFixed := fixedCoupon
+	anotherFixed.ID, anotherFixed.Code = 4, "MINUS10"
+	capped := feePercentCoupon
+	capped.MaxDiscount = money.FromCents(200)
+
+	tests := []struct {
+		name         string
+		codes        []string
+		coupons      []models.Coupon
+		wantDiscount []money.Money
+		wantErr      error
+	}{
+		{name: "单张立减", codes: []string{"minus20"}, coupons: []models.Coupon{fixedCoupon}, wantDiscount: []money.Money{money.FromCents(2000)}},
+		{name: "先免服务费再立减", codes: []string{"MINUS20", "FIRST"}, coupons: []models.Coupon{firstTaskCoupon, fixedCoupon}, wantDiscount: []money.Money{money.FromCents(600), money.FromCents(2000)}},
+		{name: "服务费折扣受单次最高优惠限制", codes: []string{"HALF", "MINUS20"}, coupons: []models.Coupon{capped, fixedCoupon}, wantDiscount: []money.Money{money.FromCents(200), money.FromCents(2000)}},
+		{name: "免服务费后服务费折扣不产生优惠", codes: []string{"FIRST", "HALF"}, coupons: []models.Coupon{firstTaskCoupon, feePercentCoupon}, wantErr: ErrCouponNotApplicable},
+		{name: "不可叠加的优惠券", codes: []string{"FIRST", "MINUS20"}, coupons: []models.Coupon{firstTaskCoupon, exclusive}, wantErr: ErrCouponNotStackable},
+		{name: "同一类型只能使用一张", codes: []string{"MINUS20", "MINUS10"}, coupons: []models.Coupon{fixedCoupon, anotherFixed}, wantErr: ErrCouponNotStackable},
+		{name: "重复的优惠码", codes: []string{"MINUS20", " minus20 "}, coupons: []models.Coupon{fixedCoupon}, wantErr: ErrInvalidParam},
+		{name: "不存在的优惠码", codes: []string{"MINUS20", "NONE"}, coupons: []models.Coupon{fixedCoupon}, wantErr: ErrCouponNotFound},
+	}
+	for _, tt := range tests {
+		db, _ := testutil.NewGorm(t, couponRows(tt.coupons...))
+
+		quote, err := quoteCoupons(db, 1, couponTestTask(), tt.codes, false)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: quoteCoupons() = %v, 期望 %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr != nil {
+			continue
+		}
+		if len(quote.Coupons) != len(tt.wantDiscount) {
+			t.Errorf("%s: 优惠明细数量错误: %+v", tt.name, quote.Coupons)
+			continue
+		}
+		var total money.Money
+		for i, want := range tt.wantDiscount {
+			if quote.Coupons[i].Discount != want {
+				t.Errorf("%s: 第%d张优惠券「%s」优惠%s, 期望%s", tt.name, i+1, quote.Coupons[i].Name, quote.Coupons[i].Discount, want)
+			}
+			total += want
+		}
+		if quote.OriginalAmount != money.FromCents(11600) || quote.Discount != total || quote.PayableAmount != quote.OriginalAmount-total {
+			t.Errorf("%s: 优惠合计或实付金额错误: %+v", tt.name, quote)
+		}
+	}
+}
+
+func TestQuoteCouponsUsageLimits(t *testing.T) {
+	exhausted := fixedCoupon
+	exhausted.TotalLimit, exhausted.UsedCount = 100, 100
+	perUser := fixedCoupon
+	perUser.PerUserLimit = 2
+
+	tests := []struct {
+		name    string
+		coupon  models.Coupon
+		rows    []testutil.StubRows
+		wantErr error
+	}{
+		{name: "全局次数已用完", coupon: exhausted, wantErr: ErrCouponUnavailable},
+		{name: "未达每人次数上限", coupon: perUser, rows: []testutil.StubRows{countRows("FROM `coupon_redemptions`", 1)}},
+		{name: "已达每人次数上限", coupon: perUser, rows: []testutil.StubRows{countRows("FROM `coupon_redemptions`", 2)}, wantErr: ErrCouponUnavailable},
+		{name: "首个任务", coupon: firstTaskCoupon},
+		{name: "已支付过预付款", coupon: firstTaskCoupon, rows: []testutil.StubRows{countRows("FROM `trades`", 1)}, wantErr: ErrCouponNotApplicable},
+		{name: "首单优惠已被待支付订单占用", coupon: firstTaskCoupon, rows: []testutil.StubRows{countRows("JOIN coupons", 1)}, wantErr: ErrCouponNotApplicable},
+	}
+	for _, tt := range tests {
+		db, stub := testutil.NewGorm(t, append(tt.rows, couponRows(tt.coupon))...)
+
+		_, err := quoteCoupons(db, 1, couponTestTask(), []string{tt.coupon.Code}, true)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: quoteCoupons() = %v, 期望 %v", tt.name, err, tt.wantErr)
+		}
+		if len(stub.Executed("FOR UPDATE")) != 1 {
+			t.Errorf("%s: 下单时应锁定优惠券使可用次数校验串行执行", tt.name)
+		}
+		if tt.coupon.PerUserLimit > 0 {
+			counts := stub.Executed("FROM `coupon_redemptions`")
+			if len(counts) != 1 || !counts[0].HasArgs(int64(tt.coupon.ID), int64(1), int64(models.CouponRedemptionReserved), int64(models.CouponRedemptionUsed)) {
+				t.Errorf("%s: 每人次数应统计该用户占用和已使用的记录: %+v", tt.name, counts)
+			}
+		}
+	}
+}
+
+func TestQuoteCouponsKeepsMinPayAmount(t *testing.T) {
+	bigCoupon := fixedCoupon
+	bigCoupon.Amount = money.FromCents(50000)
+
+	tests := []struct {
+		name        string
+		depositRate float64
+		wantPayable money.Money
+	}{
+		// 保证金不参与优惠，实付金额不低于保证金
+		{name: "保证金不参与优惠", depositRate: 0.1, wantPayable: money.FromCents(1000)},
+		{name: "无保证金时实付至少0.01元", depositRate: 0, wantPayable: minPayAmount},
+	}
+	for _, tt := range tests {
+		db, _ := testutil.NewGorm(t, couponRows(bigCoupon))
+		task := couponTestTask()
+		task.DepositRatio = tt.depositRate
+
+		quote, err := quoteCoupons(db, 1, task, []string{bigCoupon.Code}, false)
+		if err != nil {
+			t.Errorf("%s: 计算优惠失败: %v", tt.name, err)
+			continue
+		}
+		if quote.PayableAmount != tt.wantPayable || quote.Discount != quote.OriginalAmount-tt.wantPayable {
+			t.Errorf("%s: 实付金额 = %s, 期望 %s", tt.name, quote.PayableAmount, tt.wantPayable)
+		}
+	}
+}
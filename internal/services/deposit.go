@@ -0,0 +1,206 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"task-platform-api/internal/models"
+	"task-platform-api/pkg/money"
+	"task-platform-api/pkg/utils"
+)
+
+// depositTradeTypes 影响接取保证金余额的交易类型
+var depositTradeTypes = []string{models.TradeTypeDeposit, models.TradeTypeDepositReturn, models.TradeTypePenalty}
+
+// createWalletTrade 记录一笔钱包内部划转交易，不经过支付渠道，创建即为已支付
+func createWalletTrade(tx *gorm.DB, userID, taskID uint64, tradeType string, amount money.Money, description string) (*models.Trade, error) {
+	now := time.Now()
+	trade := &models.Trade{
+		UserID:        userID,
+		TaskID:        &taskID,
+		TradeType:     tradeType,
+		Amount:        amount,
+		InternalNo:    utils.GenerateOrderNo(),
+		Status:        models.TradeStatusPaid,
+		PaymentMethod: models.PaymentMethodWallet,
+		Description:   description,
+		PayTime:       &now,
+	}
+	if err := tx.Create(trade).Error; err != nil {
+		return nil, fmt.Errorf("创建交易记录失败: %w", err)
+	}
+	return trade, nil
+}
+
+// takerDepositHeld 接取者在任务上仍冻结的保证金 = 已冻结 - 已退还 - 已没收
+func takerDepositHeld(tx *gorm.DB, taskID, takerID uint64) (money.Money, error) {
+	var trades []models.Trade
+	if err := tx.Select("trade_type", "amount").
+		Where("task_id = ? AND user_id = ? AND trade_type IN ? AND status = ?", taskID, takerID, depositTradeTypes, models.TradeStatusPaid).
+		Find(&trades).Error; err != nil {
+		return 0, fmt.Errorf("查询保证金交易失败: %w", err)
+	}
+	var held money.Money
+	for _, trade := range trades {
+		if trade.TradeType == models.TradeTypeDeposit {
+			held += trade.Amount
+		} else {
+			held -= trade.Amount
+		}
+	}
+	return held, nil
+}
+
+// hasOpenViolation 接取者在任务上是否有待处理的违规，有时保证金暂不退还
+func hasOpenViolation(tx *gorm.DB, taskID, takerID uint64) (bool, error) {
+	var count int64
+	if err := tx.Model(&models.Violation{}).
+		Where("task_id = ? AND user_id = ? AND status IN ?", taskID, takerID,
+			[]int8{models.ViolationStatusPending, models.ViolationStatusAppealed}).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("查询违规记录失败: %w", err)
+	}
+	return count > 0, nil
+}
+
+// lockTakerDeposit 接取任务时从接取者可用余额冻结保证金，须在设置task.TakerID后于事务内调用
+func lockTakerDeposit(tx *gorm.DB, task *models.Task) error {
+	amount := task.GetDepositAmount()
+	if !amount.IsPositive() {
+		return nil
+	}
+
+	description := fmt.Sprintf("接取任务「%s」冻结保证金", task.Title)
+	trade, err := createWalletTrade(tx, task.TakerID, task.ID, models.TradeTypeDeposit, amount, description)
+	if err != nil {
+		return err
+	}
+	if _, err := postJournal(tx, ledgerBizDeposit, trade.ID, description,
+		debit(userAvailableAccount(task.TakerID), amount),
+		credit(userFrozenAccount(task.TakerID), amount),
+	); err != nil {
+		return err
+	}
+
+	_, err = freezeWallet(tx, task.TakerID, amount, walletEntry{
+		TradeID:     &trade.ID,
+		RelatedID:   task.ID,
+		RelatedType: "task",
+		Description: description,
+	})
+	if errors.Is(err, ErrInsufficientBalance) {
+		return ErrInsufficientBalance.WithMessage(fmt.Sprintf("接取者钱包可用余额不足以冻结保证金%s元", amount))
+	}
+	return err
+}
+
+// releaseTakerDeposit 任务完成或取消时将接取者剩余的保证金解冻退还；
+// 接取者在该任务上还有待处理的违规时暂不退还，待违规处理后再退，须在事务内调用
+func releaseTakerDeposit(tx *gorm.DB, task *models.Task, reason string) error {
+	if !task.HasTaker() {
+		return nil
+	}
+	open, err := hasOpenViolation(tx, task.ID, task.TakerID)
+	if err != nil || open {
+		return err
+	}
+	amount, err := takerDepositHeld(tx, task.ID, task.TakerID)
+	if err != nil || !amount.IsPositive() {
+		return err
+	}
+
+	description := fmt.Sprintf("%s，保证金退还", reason)
+	trade, err := createWalletTrade(tx, task.TakerID, task.ID, models.TradeTypeDepositReturn, amount, description)
+	if err != nil {
+		return err
+	}
+	if _, err := postJournal(tx, ledgerBizDeposit, trade.ID, description,
+		debit(userFrozenAccount(task.TakerID), amount),
+		credit(userAvailableAccount(task.TakerID), amount),
+	); err != nil {
+		return err
+	}
+
+	_, err = unfreezeWallet(tx, task.TakerID, amount, walletEntry{
+		TradeID:     &trade.ID,
+		RelatedID:   task.ID,
+		RelatedType: "task",
+		Description: description,
+	})
+	return err
+}
+
+// forfeitTakerDeposit 违规确认后从接取者冻结的保证金中没收违约金，不超过剩余保证金；
+// 按publisherRatio补偿发布者，其余计入平台违约金收入，生成违约金结算记录，返回实际没收金额，须在事务内调用
+func forfeitTakerDeposit(tx *gorm.DB, task *models.Task, violation *models.Violation, penalty money.Money, publisherRatio float64) (money.Money, error) {
+	held, err := takerDepositHeld(tx, task.ID, task.TakerID)
+	if err != nil {
+		return 0, err
+	}
+	amount := min(penalty, held)
+	if !amount.IsPositive() {
+		return money.Zero, nil
+	}
+	publisherShare := amount.MulRate(publisherRatio, money.RoundHalfUp)
+	platformShare := amount - publisherShare
+
+	description := fmt.Sprintf("任务「%s」违规#%d违约金", task.Title, violation.ID)
+	trade, err := createWalletTrade(tx, task.TakerID, task.ID, models.TradeTypePenalty, amount, description)
+	if err != nil {
+		return 0, err
+	}
+
+	// 违约金由接取者保证金支付，不占用发布者托管的预付款
+	settlement := &models.Settlement{
+		TaskID:          task.ID,
+		PublisherAmount: publisherShare,
+		PlatformFee:     platformShare,
+		Penalty:         amount,
+		SettleTime:      time.Now(),
+		Status:          models.SettlementStatusSettled,
+		Remark:          description,
+	}
+	if err := tx.Create(settlement).Error; err != nil {
+		return 0, fmt.Errorf("创建违约金结算记录失败: %w", err)
+	}
+
+	if _, err := postJournal(tx, ledgerBizPenalty, trade.ID, description,
+		debit(userFrozenAccount(task.TakerID), amount),
+		credit(userAvailableAccount(task.PublisherID), publisherShare),
+		credit(penaltyRevenueAccount, platformShare),
+	); err != nil {
+		return 0, err
+	}
+
+	if err := transferWallet(tx, task.TakerID, task.PublisherID, publisherShare,
+		walletEntry{
+			TradeID:     &trade.ID,
+			RelatedID:   settlement.ID,
+			RelatedType: "settlement",
+			Description: fmt.Sprintf("%s，从保证金扣除并补偿发布者", description),
+		},
+		walletEntry{
+			TradeID:     &trade.ID,
+			RelatedID:   settlement.ID,
+			RelatedType: "settlement",
+			Description: fmt.Sprintf("%s补偿", description),
+		},
+	); err != nil {
+		return 0, err
+	}
+
+	if platformShare.IsPositive() {
+		if _, err := debitFrozenWallet(tx, task.TakerID, platformShare, walletEntry{
+			TradeID:     &trade.ID,
+			RelatedID:   settlement.ID,
+			RelatedType: "settlement",
+			Description: fmt.Sprintf("%s，从保证金扣除归平台", description),
+		}); err != nil {
+			return 0, err
+		}
+	}
+	return amount, nil
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"net/http"
+)
+
+// ServiceError 业务错误，携带对外暴露的错误码和HTTP状态码
+type ServiceError struct {
+	HTTPStatus int    // HTTP状态码
+	Code       string // 业务错误码
+	Message    string // 错误信息
+}
+
+// Error 实现error接口
+func (e *ServiceError) Error() string {
+	return e.Message
+}
+
+// Is 按错误码判断是否为同一类业务错误
+func (e *ServiceError) Is(target error) bool {
+	t, ok := target.(*ServiceError)
+	if !ok {
+		return false
+	}
+	return e.Code == t.Code
+}
+
+// WithMessage 复制错误并替换错误信息
+func (e *ServiceError) WithMessage(message string) *ServiceError {
+	return &ServiceError{
+		HTTPStatus: e.HTTPStatus,
+		Code:       e.Code,
+		Message:    message,
+	}
+}
+
+// 任务相关错误
+var (
+	ErrInvalidParam          = &ServiceError{HTTPStatus: http.StatusBadRequest, Code: "INVALID_PARAM", Message: "参数错误"}
+	ErrTaskNotFound          = &ServiceError{HTTPStatus: http.StatusNotFound, Code: "TASK_NOT_FOUND", Message: "任务不存在"}
+	ErrTaskForbidden         = &ServiceError{HTTPStatus: http.StatusForbidden, Code: "TASK_FORBIDDEN", Message: "无权操作该任务"}
+	ErrTaskInvalidTransition = &ServiceError{HTTPStatus: http.StatusConflict, Code: "TASK_INVALID_TRANSITION", Message: "任务当前状态不允许该操作"}
+	ErrTaskSelfTake          = &ServiceError{HTTPStatus: http.StatusBadRequest, Code: "TASK_SELF_TAKE", Message: "不能接取自己发布的任务"}
+	ErrTaskExpired           = &ServiceError{HTTPStatus: http.StatusBadRequest, Code: "TASK_EXPIRED", Message: "任务已过截止时间"}
+)
+
+// 任务申请相关错误
+var (
+	ErrApplicationNotFound      = &ServiceError{HTTPStatus: http.StatusNotFound, Code: "APPLICATION_NOT_FOUND", Message: "申请不存在"}
+	ErrApplicationDuplicate     = &ServiceError{HTTPStatus: http.StatusConflict, Code: "APPLICATION_DUPLICATE", Message: "已申请过该任务"}
+	ErrApplicationInvalidStatus = &ServiceError{HTTPStatus: http.StatusConflict, Code: "APPLICATION_INVALID_STATUS", Message: "申请当前状态不允许该操作"}
+)
+
+// 交付相关错误
+var (
+	ErrDeliveryNotFound      = &ServiceError{HTTPStatus: http.StatusNotFound, Code: "DELIVERY_NOT_FOUND", Message: "交付记录不存在"}
+	ErrDeliveryInvalidStatus = &ServiceError{HTTPStatus: http.StatusConflict, Code: "DELIVERY_INVALID_STATUS", Message: "交付当前状态不允许该操作"}
+	ErrRevisionLimitExceeded = &ServiceError{HTTPStatus: http.StatusConflict, Code: "REVISION_LIMIT_EXCEEDED", Message: "已达到最大整改轮次"}
+	ErrStageNotFound         = &ServiceError{HTTPStatus: http.StatusNotFound, Code: "STAGE_NOT_FOUND", Message: "任务阶段不存在"}
+	ErrStageInvalidStatus    = &ServiceError{HTTPStatus: http.StatusConflict, Code: "STAGE_INVALID_STATUS", Message: "任务阶段当前状态不允许交付"}
+)
+
+// 支付及钱包相关错误
+var (
+	ErrTradeNotFound            = &ServiceError{HTTPStatus: http.StatusNotFound, Code: "TRADE_NOT_FOUND", Message: "交易记录不存在"}
+	ErrPaymentSignature         = &ServiceError{HTTPStatus: http.StatusBadRequest, Code: "PAYMENT_SIGNATURE_INVALID", Message: "支付回调签名验证失败"}
+	ErrPaymentAmountMismatch    = &ServiceError{HTTPStatus: http.StatusBadRequest, Code: "PAYMENT_AMOUNT_MISMATCH", Message: "支付金额与订单不一致"}
+	ErrTaskNotPaid              = &ServiceError{HTTPStatus: http.StatusPaymentRequired, Code: "TASK_NOT_PAID", Message: "任务尚未完成预付款托管"}
+	ErrTaskAlreadyPaid          = &ServiceError{HTTPStatus: http.StatusConflict, Code: "TASK_ALREADY_PAID", Message: "任务已完成预付款托管"}
+	ErrPrepayPending            = &ServiceError{HTTPStatus: http.StatusConflict, Code: "PREPAY_PENDING", Message: "任务已有待支付的预付款订单，请完成支付或等待订单过期"}
+	ErrPaymentMethodUnsupported = &ServiceError{HTTPStatus: http.StatusBadRequest, Code: "PAYMENT_METHOD_UNSUPPORTED", Message: "不支持的支付方式"}
+	ErrPaymentGateway           = &ServiceError{HTTPStatus: http.StatusBadGateway, Code: "PAYMENT_GATEWAY_ERROR", Message: "支付渠道请求失败"}
+	ErrInsufficientBalance      = &ServiceError{HTTPStatus: http.StatusConflict, Code: "INSUFFICIENT_BALANCE", Message: "钱包可用余额不足"}
+	ErrInsufficientFrozen       = &ServiceError{HTTPStatus: http.StatusConflict, Code: "INSUFFICIENT_FROZEN", Message: "钱包冻结余额不足"}
+	ErrWalletConflict           = &ServiceError{HTTPStatus: http.StatusConflict, Code: "WALLET_CONFLICT", Message: "钱包余额变动频繁，请稍后重试"}
+	ErrRefundNotFound           = &ServiceError{HTTPStatus: http.StatusNotFound, Code: "REFUND_NOT_FOUND", Message: "退款记录不存在"}
+	ErrTradeNotRefundable       = &ServiceError{HTTPStatus: http.StatusConflict, Code: "TRADE_NOT_REFUNDABLE", Message: "交易当前状态不允许退款"}
+	ErrRefundAmountExceeded     = &ServiceError{HTTPStatus: http.StatusBadRequest, Code: "REFUND_AMOUNT_EXCEEDED", Message: "退款金额超过可退金额"}
+	ErrWithdrawNotFound         = &ServiceError{HTTPStatus: http.StatusNotFound, Code: "WITHDRAW_NOT_FOUND", Message: "提现申请不存在"}
+	ErrWithdrawInvalidStatus    = &ServiceError{HTTPStatus: http.StatusConflict, Code: "WITHDRAW_INVALID_STATUS", Message: "提现申请当前状态不允许该操作"}
+	ErrWithdrawAmountTooSmall   = &ServiceError{HTTPStatus: http.StatusBadRequest, Code: "WITHDRAW_AMOUNT_TOO_SMALL", Message: "提现金额低于最低限额"}
+	ErrWithdrawLimitExceeded    = &ServiceError{HTTPStatus: http.StatusConflict, Code: "WITHDRAW_LIMIT_EXCEEDED", Message: "超过单日提现限额"}
+)
+
+// 违规相关错误
+var (
+	ErrViolationNotFound      = &ServiceError{HTTPStatus: http.StatusNotFound, Code: "VIOLATION_NOT_FOUND", Message: "违规记录不存在"}
+	ErrViolationInvalidStatus = &ServiceError{HTTPStatus: http.StatusConflict, Code: "VIOLATION_INVALID_STATUS", Message: "违规记录当前状态不允许该操作"}
+)
+
+// 发票相关错误
+var (
+	ErrInvoiceNotFound        = &ServiceError{HTTPStatus: http.StatusNotFound, Code: "INVOICE_NOT_FOUND", Message: "发票不存在"}
+	ErrInvoiceProfileNotFound = &ServiceError{HTTPStatus: http.StatusNotFound, Code: "INVOICE_PROFILE_NOT_FOUND", Message: "尚未填写开票信息"}
+	ErrInvoiceDuplicate       = &ServiceError{HTTPStatus: http.StatusConflict, Code: "INVOICE_DUPLICATE", Message: "该笔款项已开具发票"}
+	ErrInvoiceNotAllowed      = &ServiceError{HTTPStatus: http.StatusConflict, Code: "INVOICE_NOT_ALLOWED", Message: "该笔款项当前不允许开票"}
+	ErrInvoiceInvalidStatus   = &ServiceError{HTTPStatus: http.StatusConflict, Code: "INVOICE_INVALID_STATUS", Message: "发票当前状态不允许该操作"}
+	ErrSettlementNotFound     = &ServiceError{HTTPStatus: http.StatusNotFound, Code: "SETTLEMENT_NOT_FOUND", Message: "结算记录不存在"}
+)
+
+// 优惠券相关错误
+var (
+	ErrCouponNotFound      = &ServiceError{HTTPStatus: http.StatusNotFound, Code: "COUPON_NOT_FOUND", Message: "优惠券不存在"}
+	ErrCouponDuplicate     = &ServiceError{HTTPStatus: http.StatusConflict, Code: "COUPON_DUPLICATE", Message: "优惠码已存在"}
+	ErrCouponUnavailable   = &ServiceError{HTTPStatus: http.StatusConflict, Code: "COUPON_UNAVAILABLE", Message: "优惠券不可用"}
+	ErrCouponNotApplicable = &ServiceError{HTTPStatus: http.StatusConflict, Code: "COUPON_NOT_APPLICABLE", Message: "优惠券不适用于该任务"}
+	ErrCouponNotStackable  = &ServiceError{HTTPStatus: http.StatusConflict, Code: "COUPON_NOT_STACKABLE", Message: "优惠券不能叠加使用"}
+)
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"task-platform-api/internal/models"
+	"task-platform-api/pkg/money"
+)
+
+// escrowAmount 发布任务需托管的金额，包含任务赏金、发布者服务费和保证金
+func escrowAmount(task *models.Task) money.Money {
+	return task.GetPublisherAmount() + task.GetDepositAmount()
+}
+
+// findPaidPrepay 查询任务已支付的预付款交易，不存在时返回nil
+func findPaidPrepay(tx *gorm.DB, taskID uint64) (*models.Trade, error) {
+	var trade models.Trade
+	err := tx.Where("task_id = ? AND trade_type = ? AND status = ?", taskID, models.TradeTypePrepay, models.TradeStatusPaid).
+		Order("trade_id DESC").
+		First(&trade).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询预付款交易失败: %w", err)
+	}
+	return &trade, nil
+}
+
+// freezeEscrow 预付款支付成功后冻结到发布者钱包，须在事务内调用；
+// 使用优惠券的订单由平台营销费用补足抵扣金额，发布者冻结的托管金额与未使用优惠券时一致
+func freezeEscrow(tx *gorm.DB, trade *models.Trade) error {
+	// 渠道资金入账到平台托管，再划入发布者冻结余额
+	if _, err := postJournal(tx, ledgerBizPayment, trade.ID, fmt.Sprintf("预付款%s入账", trade.InternalNo),
+		debit(gatewayAccount, trade.Amount),
+		credit(escrowAccount, trade.Amount),
+	); err != nil {
+		return err
+	}
+	if trade.Discount.IsPositive() {
+		if _, err := postJournal(tx, ledgerBizCoupon, trade.ID, fmt.Sprintf("预付款%s优惠券抵扣", trade.InternalNo),
+			debit(marketingAccount, trade.Discount),
+			credit(escrowAccount, trade.Discount),
+		); err != nil {
+			return err
+		}
+	}
+	amount := trade.GetEscrowAmount()
+	if _, err := postJournal(tx, ledgerBizFreeze, trade.ID, "任务预付款冻结",
+		debit(escrowAccount, amount),
+		credit(userFrozenAccount(trade.UserID), amount),
+	); err != nil {
+		return err
+	}
+
+	description := "任务预付款冻结"
+	if trade.Discount.IsPositive() {
+		description = fmt.Sprintf("任务预付款冻结，含优惠券抵扣%s", trade.Discount)
+	}
+	_, err := creditFrozenWallet(tx, trade.UserID, amount, walletEntry{
+		TradeID:     &trade.ID,
+		RelatedID:   *trade.TaskID,
+		RelatedType: "task",
+		Description: description,
+	})
+	return err
+}
+
+// escrowSettled 任务已结算划出的托管金额，分阶段任务部分阶段验收后取消时需扣除；
+// 违约金由接取者保证金支付，不计入托管划出金额
+func escrowSettled(tx *gorm.DB, taskID uint64) (money.Money, error) {
+	var settlements []models.Settlement
+	if err := tx.Select("publisher_amount", "taker_amount", "platform_fee", "penalty").
+		Where("task_id = ? AND status = ?", taskID, models.SettlementStatusSettled).
+		Find(&settlements).Error; err != nil {
+		return 0, fmt.Errorf("查询结算记录失败: %w", err)
+	}
+	var settled money.Money
+	for _, st := range settlements {
+		settled += st.TakerAmount + st.PlatformFee + st.PublisherAmount - st.Penalty
+	}
+	return settled, nil
+}
+
+// releaseEscrow 任务取消时将尚未结算的预付款解冻回发布者可用余额，未使用的优惠券抵扣先退回平台，须在事务内调用
+func releaseEscrow(tx *gorm.DB, task *models.Task, reason string) error {
+	trade, err := findPaidPrepay(tx, task.ID)
+	if err != nil || trade == nil {
+		return err
+	}
+	settled, err := escrowSettled(tx, task.ID)
+	if err != nil {
+		return err
+	}
+	reclaimed, err := reclaimCouponDiscount(tx, task, trade, settled)
+	if err != nil {
+		return err
+	}
+	amount := trade.GetEscrowAmount() - settled - reclaimed
+	if !amount.IsPositive() {
+		return nil
+	}
+
+	if _, err := postJournal(tx, ledgerBizUnfreeze, task.ID, fmt.Sprintf("任务取消解冻: %s", reason),
+		debit(userFrozenAccount(task.PublisherID), amount),
+		credit(userAvailableAccount(task.PublisherID), amount),
+	); err != nil {
+		return err
+	}
+
+	_, err = unfreezeWallet(tx, task.PublisherID, amount, walletEntry{
+		TradeID:     &trade.ID,
+		RelatedID:   task.ID,
+		RelatedType: "task",
+		Description: fmt.Sprintf("任务取消解冻: %s", reason),
+	})
+	return err
+}
+
+// releasedEscrow 预付款交易已解冻退回发布者可用余额的托管金额，由任务取消或支付时任务已不是草稿产生，
+// 仅这部分金额可原路退款；已结算给接取者和平台的部分不能退款
+func releasedEscrow(tx *gorm.DB, trade *models.Trade) (money.Money, error) {
+	var records []models.WalletTransaction
+	if err := tx.Select("amount").
+		Where("user_id = ? AND trade_id = ? AND type = ? AND related_type = ?", trade.UserID, trade.ID, models.WalletTxUnfreeze, "task").
+		Find(&records).Error; err != nil {
+		return 0, fmt.Errorf("查询托管解冻记录失败: %w", err)
+	}
+	var total money.Money
+	for _, r := range records {
+		total += r.Amount
+	}
+	return total, nil
+}
+
+// settleEscrow 按结算单从发布者冻结资金中划转：接取者入账实得金额，平台收取双方服务费，
+// 任务最终结算时将保证金解冻退回发布者，amount为本次结算的赏金，须在事务内调用
+func settleEscrow(tx *gorm.DB, task *models.Task, settlement *models.Settlement, amount money.Money) error {
+	// 发布者支出 = 赏金 + 发布者服务费 = 接取者收入 + 平台服务费，保证金退回发布者可用余额
+	expense := settlement.TakerAmount + settlement.PlatformFee
+	publisherFee := expense - amount
+	takerFee := amount - settlement.TakerAmount
+	if _, err := postJournal(tx, ledgerBizSettlement, settlement.ID, settlement.Remark,
+		debit(userFrozenAccount(task.PublisherID), expense+settlement.PublisherAmount),
+		credit(userAvailableAccount(task.TakerID), settlement.TakerAmount),
+		credit(feeRevenueAccount, settlement.PlatformFee),
+		credit(userAvailableAccount(task.PublisherID), settlement.PublisherAmount),
+	); err != nil {
+		return err
+	}
+
+	if err := transferWallet(tx, task.PublisherID, task.TakerID, settlement.TakerAmount,
+		walletEntry{
+			RelatedID:   settlement.ID,
+			RelatedType: "settlement",
+			Description: fmt.Sprintf("%s支出", settlement.Remark),
+		},
+		walletEntry{
+			RelatedID:   settlement.ID,
+			RelatedType: "settlement",
+			Description: fmt.Sprintf("%s收入，已扣服务费%s", settlement.Remark, takerFee),
+		},
+	); err != nil {
+		return err
+	}
+
+	if settlement.PlatformFee.IsPositive() {
+		if _, err := debitFrozenWallet(tx, task.PublisherID, settlement.PlatformFee, walletEntry{
+			RelatedID:   settlement.ID,
+			RelatedType: "settlement",
+			Description: fmt.Sprintf("%s平台服务费，含发布者服务费%s、接取者服务费%s", settlement.Remark, publisherFee, takerFee),
+		}); err != nil {
+			return err
+		}
+	}
+
+	if settlement.PublisherAmount.IsPositive() {
+		_, err := unfreezeWallet(tx, task.PublisherID, settlement.PublisherAmount, walletEntry{
+			RelatedID:   settlement.ID,
+			RelatedType: "settlement",
+			Description: "任务完成，保证金解冻",
+		})
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/internal/models"
+	"task-platform-api/pkg/money"
+)
+
+// FeeQuote 任务服务费计算结果，支付预付款时写入任务的服务费明细
+type FeeQuote struct {
+	PublisherRate   float64     `json:"publisher_rate"`              // 发布者服务费率
+	TakerRate       float64     `json:"taker_rate"`                  // 接取者服务费率
+	MinPublisherFee money.Money `json:"min_publisher_fee"`           // 发布者最低服务费
+	MinTakerFee     money.Money `json:"min_taker_fee"`               // 接取者最低服务费
+	Discount        float64     `json:"discount"`                    // 促销折扣，1表示无折扣
+	PublisherFee    money.Money `json:"publisher_fee"`               // 发布者服务费
+	TakerFee        money.Money `json:"taker_fee"`                   // 接取者服务费
+	BaseRuleID      *uint64     `json:"base_rule_id,omitempty"`      // 命中的基础费率规则，为空表示使用默认费率
+	PromotionRuleID *uint64     `json:"promotion_rule_id,omitempty"` // 命中的促销规则
+	UserLevel       int         `json:"user_level"`                  // 计算时发布者的用户等级
+	CreditScore     float64     `json:"credit_score"`                // 计算时发布者的信用评分
+	QuotedAt        time.Time   `json:"quoted_at"`                   // 计算时间
+}
+
+// FeeEngine 服务费引擎，按任务分类、发布者等级和信用评分匹配服务费规则及促销，没有匹配规则时使用默认费率
+type FeeEngine struct {
+	db  *gorm.DB
+	cfg config.FeeConfig
+}
+
+// NewFeeEngine 创建服务费引擎
+func NewFeeEngine(db *gorm.DB, cfg config.FeeConfig) *FeeEngine {
+	return &FeeEngine{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// Quote 计算任务的发布者服务费和接取者服务费。
+// 费用 = max(赏金×费率, 最低服务费) × 促销折扣，接取者服务费不超过赏金
+func (e *FeeEngine) Quote(tx *gorm.DB, task *models.Task) (*FeeQuote, error) {
+	level, score, err := publisherStanding(tx, task.PublisherID)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &FeeQuote{
+		PublisherRate:   e.cfg.PublisherRate,
+		TakerRate:       e.cfg.TakerRate,
+		MinPublisherFee: money.FromYuan(e.cfg.MinPublisherFee),
+		MinTakerFee:     money.FromYuan(e.cfg.MinTakerFee),
+		Discount:        1,
+		UserLevel:       level,
+		CreditScore:     score,
+		QuotedAt:        time.Now(),
+	}
+
+	base, err := matchFeeRule(tx, models.FeeRuleTypeBase, task, level, score)
+	if err != nil {
+		return nil, err
+	}
+	if base != nil {
+		quote.PublisherRate = base.PublisherRate
+		quote.TakerRate = base.TakerRate
+		quote.MinPublisherFee = base.MinPublisherFee
+		quote.MinTakerFee = base.MinTakerFee
+		quote.BaseRuleID = &base.ID
+	}
+
+	promotion, err := matchFeeRule(tx, models.FeeRuleTypePromotion, task, level, score)
+	if err != nil {
+		return nil, err
+	}
+	if promotion != nil {
+		quote.Discount = promotion.Discount
+		quote.PromotionRuleID = &promotion.ID
+	}
+
+	quote.PublisherFee = computeFee(task.Amount, quote.PublisherRate, quote.MinPublisherFee, quote.Discount)
+	quote.TakerFee = min(computeFee(task.Amount, quote.TakerRate, quote.MinTakerFee, quote.Discount), task.Amount)
+	return quote, nil
+}
+
+// ApplyQuote 计算服务费并写入任务，须在事务内调用
+func (e *FeeEngine) ApplyQuote(tx *gorm.DB, task *models.Task) (*FeeQuote, error) {
+	quote, err := e.Quote(tx, task)
+	if err != nil {
+		return nil, err
+	}
+	breakdown, err := json.Marshal(quote)
+	if err != nil {
+		return nil, fmt.Errorf("序列化服务费明细失败: %w", err)
+	}
+
+	if err := tx.Model(&models.Task{}).Where("task_id = ?", task.ID).Updates(map[string]interface{}{
+		"publisher_fee": quote.PublisherFee,
+		"taker_fee":     quote.TakerFee,
+		"fee_breakdown": string(breakdown),
+	}).Error; err != nil {
+		return nil, fmt.Errorf("更新任务服务费失败: %w", err)
+	}
+	task.PublisherFee = quote.PublisherFee
+	task.TakerFee = quote.TakerFee
+	task.FeeBreakdown = string(breakdown)
+	return quote, nil
+}
+
+// publisherStanding 读取发布者的用户等级和信用评分，有信誉记录时以信誉评分为准
+func publisherStanding(tx *gorm.DB, userID uint64) (int, float64, error) {
+	var user models.User
+	if err := tx.Select("user_id", "level", "credit_score").First(&user, userID).Error; err != nil {
+		return 0, 0, fmt.Errorf("查询发布者失败: %w", err)
+	}
+	score := float64(user.CreditScore)
+
+	var credit models.UserCredit
+	err := tx.Select("score").Where("user_id = ?", userID).First(&credit).Error
+	if err == nil {
+		score = float64(credit.Score)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, 0, fmt.Errorf("查询用户信誉失败: %w", err)
+	}
+	return user.Level, score, nil
+}
+
+// matchFeeRule 查找当前生效且匹配任务分类、发布者等级和信用评分的优先级最高的规则，没有时返回nil
+func matchFeeRule(tx *gorm.DB, ruleType string, task *models.Task, level int, score float64) (*models.FeeRule, error) {
+	now := time.Now()
+	var rule models.FeeRule
+	err := tx.Where("rule_type = ? AND status = ?", ruleType, models.FeeRuleStatusEnabled).
+		Where("(category_id IS NULL OR category_id = ?)", task.CategoryID).
+		Where("min_level <= ? AND min_credit_score <= ?", level, score).
+		Where("(start_time IS NULL OR start_time <= ?) AND (end_time IS NULL OR end_time > ?)", now, now).
+		Order("priority DESC, rule_id DESC").
+		First(&rule).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询服务费规则失败: %w", err)
+	}
+	return &rule, nil
+}
+
+// computeFee 按费率计算服务费并应用最低服务费，再按促销折扣减免，四舍五入到分
+func computeFee(amount money.Money, rate float64, minFee money.Money, discount float64) money.Money {
+	fee := max(amount.MulRate(rate, money.RoundHalfUp), minFee)
+	return fee.MulRate(discount, money.RoundHalfUp)
+}
+
+// settlementFees 结算时分摊任务服务费，分阶段任务按各阶段金额拆分，各阶段之和等于任务服务费
+func settlementFees(tx *gorm.DB, task *models.Task, stage *models.TaskStage) (publisherFee, takerFee money.Money, err error) {
+	if stage == nil {
+		return task.GetPublisherFee(), task.GetTakerFee(), nil
+	}
+	if len(task.Stages) == 0 {
+		if err := loadTaskStages(tx, task); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	weights := make([]int64, len(task.Stages))
+	index := -1
+	for i, s := range task.Stages {
+		weights[i] = s.Amount.Cents()
+		if s.ID == stage.ID {
+			index = i
+		}
+	}
+	if index < 0 {
+		return 0, 0, ErrStageNotFound
+	}
+	return task.GetPublisherFee().Allocate(weights...)[index], task.GetTakerFee().Allocate(weights...)[index], nil
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/internal/models"
+	"task-platform-api/internal/testutil"
+	"task-platform-api/pkg/money"
+)
+
+var feeRuleColumns = []string{"rule_id", "rule_type", "publisher_rate", "taker_rate", "min_publisher_fee", "min_taker_fee", "discount", "priority"}
+
+// publisherRows 发布者1，等级3、信用评分8.5
+func publisherRows() testutil.StubRows {
+	return testutil.StubRows{
+		Match:   "FROM `users`",
+		Columns: []string{"user_id", "level", "credit_score"},
+		Values:  [][]driver.Value{{int64(1), int64(3), []byte("8.5")}},
+	}
+}
+
+func TestComputeFee(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   money.Money
+		rate     float64
+		minFee   money.Money
+		discount float64
+		want     money.Money
+	}{
+		{name: "按费率计算", amount: money.FromCents(10000), rate: 0.05, minFee: money.FromCents(100), discount: 1, want: money.FromCents(500)},
+		{name: "费率计算四舍五入到分", amount: money.FromCents(3333), rate: 0.05, discount: 1, want: money.FromCents(167)},
+		{name: "低于最低服务费", amount: money.FromCents(1000), rate: 0.05, minFee: money.FromCents(200), discount: 1, want: money.FromCents(200)},
+		{name: "折扣四舍五入到分", amount: money.FromCents(3333), rate: 0.05, discount: 0.85, want: money.FromCents(142)},
+		{name: "最低服务费同样享受折扣", amount: money.FromCents(1000), rate: 0.05, minFee: money.FromCents(200), discount: 0.85, want: money.FromCents(170)},
+		{name: "免服务费促销", amount: money.FromCents(10000), rate: 0.05, minFee: money.FromCents(200), discount: 0, want: 0},
+	}
+	for _, tt := range tests {
+		if got := computeFee(tt.amount, tt.rate, tt.minFee, tt.discount); got != tt.want {
+			t.Errorf("%s: computeFee() = %s, 期望 %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchFeeRuleOrdersByPriority(t *testing.T) {
+	db, stub := testutil.NewGorm(t, testutil.StubRows{
+		Match:   "FROM `fee_rules`",
+		Columns: feeRuleColumns,
+		Values:  [][]driver.Value{{int64(9), models.FeeRuleTypeBase, []byte("0.0300"), []byte("0.0500"), []byte("1.00"), []byte("0.00"), []byte("1.00"), int64(10)}},
+	})
+	categoryID := uint64(4)
+
+	rule, err := matchFeeRule(db, models.FeeRuleTypeBase, &models.Task{CategoryID: categoryID}, 3, 8.5)
+	if err != nil {
+		t.Fatalf("匹配规则失败: %v", err)
+	}
+	if rule == nil || rule.ID != 9 || rule.PublisherRate != 0.03 || rule.MinPublisherFee != money.FromCents(100) {
+		t.Fatalf("应返回查询到的规则: %+v", rule)
+	}
+
+	queries := stub.Executed("FROM `fee_rules`")
+	if len(queries) != 1 || !queries[0].HasArgs(models.FeeRuleTypeBase, int64(models.FeeRuleStatusEnabled), int64(categoryID), int64(3), 8.5) {
+		t.Fatalf("应按规则类型、启用状态、分类、等级和信用评分过滤: %+v", queries)
+	}
+	if !strings.Contains(queries[0].Query, "ORDER BY priority DESC, rule_id DESC") {
+		t.Fatalf("同时命中多条规则时应取优先级最高、最新创建的一条: %s", queries[0].Query)
+	}
+}
+
+func TestMatchFeeRuleNoMatch(t *testing.T) {
+	db, _ := testutil.NewGorm(t)
+	rule, err := matchFeeRule(db, models.FeeRuleTypePromotion, &models.Task{}, 1, 5)
+	if err != nil || rule != nil {
+		t.Fatalf("没有匹配规则时应返回nil: %+v, %v", rule, err)
+	}
+}
+
+func TestFeeEngineQuote(t *testing.T) {
+	defaults := config.FeeConfig{PublisherRate: 0.06, TakerRate: 0.1, MinPublisherFee: 2, MinTakerFee: 1}
+	baseRule := testutil.StubRows{
+		Match:   "FROM `fee_rules`",
+		Args:    []driver.Value{models.FeeRuleTypeBase},
+		Columns: feeRuleColumns,
+		Values:  [][]driver.Value{{int64(9), models.FeeRuleTypeBase, []byte("0.0300"), []byte("0.0500"), []byte("5.00"), []byte("0.50"), []byte("1.00"), int64(10)}},
+	}
+	promotionRule := testutil.StubRows{
+		Match:   "FROM `fee_rules`",
+		Args:    []driver.Value{models.FeeRuleTypePromotion},
+		Columns: feeRuleColumns,
+		Values:  [][]driver.Value{{int64(12), models.FeeRuleTypePromotion, []byte("0.0000"), []byte("0.0000"), []byte("0.00"), []byte("0.00"), []byte("0.85"), int64(0)}},
+	}
+	tests := []struct {
+		name          string
+		amount        money.Money
+		rules         []testutil.StubRows
+		wantPublisher money.Money
+		wantTaker     money.Money
+		wantBaseRule  bool
+		wantDiscount  float64
+	}{
+		{name: "没有规则时使用默认费率", amount: money.FromCents(10000), wantPublisher: money.FromCents(600), wantTaker: money.FromCents(1000), wantDiscount: 1},
+		{name: "默认最低服务费", amount: money.FromCents(1000), wantPublisher: money.FromCents(200), wantTaker: money.FromCents(100), wantDiscount: 1},
+		{name: "基础规则覆盖默认费率和最低服务费", amount: money.FromCents(10000), rules: []testutil.StubRows{baseRule}, wantPublisher: money.FromCents(500), wantTaker: money.FromCents(500), wantBaseRule: true, wantDiscount: 1},
+		{name: "促销折扣叠加基础规则", amount: money.FromCents(10000), rules: []testutil.StubRows{baseRule, promotionRule}, wantPublisher: money.FromCents(425), wantTaker: money.FromCents(425), wantBaseRule: true, wantDiscount: 0.85},
+		{name: "促销折扣叠加默认费率", amount: money.FromCents(3333), rules: []testutil.StubRows{promotionRule}, wantPublisher: money.FromCents(170), wantTaker: money.FromCents(283), wantDiscount: 0.85},
+		{name: "接取者服务费不超过赏金", amount: money.FromCents(50), wantPublisher: money.FromCents(200), wantTaker: money.FromCents(50), wantDiscount: 1},
+	}
+	for _, tt := range tests {
+		db, _ := testutil.NewGorm(t, append(tt.rules, publisherRows())...)
+		quote, err := NewFeeEngine(db, defaults).Quote(db, &models.Task{PublisherID: 1, Amount: tt.amount})
+		if err != nil {
+			t.Errorf("%s: 计算服务费失败: %v", tt.name, err)
+			continue
+		}
+		if quote.PublisherFee != tt.wantPublisher || quote.TakerFee != tt.wantTaker {
+			t.Errorf("%s: 服务费 = %s/%s, 期望 %s/%s", tt.name, quote.PublisherFee, quote.TakerFee, tt.wantPublisher, tt.wantTaker)
+		}
+		if (quote.BaseRuleID != nil) != tt.wantBaseRule || quote.Discount != tt.wantDiscount {
+			t.Errorf("%s: 命中规则错误: %+v", tt.name, quote)
+		}
+		if quote.UserLevel != 3 || quote.CreditScore != 8.5 {
+			t.Errorf("%s: 应记录计算时发布者的等级和信用评分: %+v", tt.name, quote)
+		}
+	}
+}
+
+func TestSettlementFeesSumToTaskFee(t *testing.T) {
+	task := &models.Task{
+		Amount:       money.FromCents(10000),
+		PublisherFee: money.FromCents(1001),
+		TakerFee:     money.FromCents(599),
+		FeeBreakdown: "{}",
+		Stages: []models.TaskStage{
+			{ID: 1, Amount: money.FromCents(3333)},
+			{ID: 2, Amount: money.FromCents(3333)},
+			{ID: 3, Amount: money.FromCents(3334)},
+		},
+	}
+
+	var publisherTotal, takerTotal money.Money
+	for _, stage := range task.Stages {
+		publisherFee, takerFee, err := settlementFees(nil, task, &stage)
+		if err != nil {
+			t.Fatalf("分摊阶段%d服务费失败: %v", stage.ID, err)
+		}
+		publisherTotal += publisherFee
+		takerTotal += takerFee
+	}
+	if publisherTotal != task.PublisherFee || takerTotal != task.TakerFee {
+		t.Fatalf("各阶段服务费之和应等于任务服务费: %s/%s", publisherTotal, takerTotal)
+	}
+
+	if publisherFee, takerFee, err := settlementFees(nil, task, nil); err != nil || publisherFee != task.PublisherFee || takerFee != task.TakerFee {
+		t.Fatalf("整单结算应收取全部服务费: %s/%s, %v", publisherFee, takerFee, err)
+	}
+	if _, _, err := settlementFees(nil, task, &models.TaskStage{ID: 4}); !errors.Is(err, ErrStageNotFound) {
+		t.Fatalf("不属于任务的阶段应返回ErrStageNotFound, 实际: %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"task-platform-api/internal/models"
+	"task-platform-api/pkg/money"
+)
+
+// receiptTemplate 可打印的收据页面，仅使用发票保存的快照渲染，与当前开票信息和配置无关
+var receiptTemplate = template.Must(template.New("receipt").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>收据 {{.Invoice.InvoiceNo}}</title>
+<style>
+  body { font-family: "PingFang SC", "Microsoft YaHei", sans-serif; color: #222; margin: 0; padding: 24px; }
+  .receipt { position: relative; max-width: 760px; margin: 0 auto; border: 1px solid #999; padding: 32px; }
+  h1 { text-align: center; font-size: 24px; letter-spacing: 8px; margin: 0 0 8px; }
+  .meta { display: flex; justify-content: space-between; font-size: 13px; margin-bottom: 16px; }
+  table { width: 100%; border-collapse: collapse; font-size: 14px; }
+  th, td { border: 1px solid #999; padding: 8px; text-align: left; }
+  th { background: #f3f3f3; width: 120px; }
+  .amount { text-align: right; white-space: nowrap; }
+  .parties td { vertical-align: top; width: 50%; }
+  .voided { position: absolute; top: 40%; left: 0; right: 0; text-align: center; font-size: 72px; color: rgba(200, 0, 0, 0.25); transform: rotate(-20deg); }
+  .footer { margin-top: 16px; font-size: 12px; color: #666; }
+  @media print { body { padding: 0; } .receipt { border: none; } }
+</style>
+</head>
+<body>
+<div class="receipt">
+  {{if .Invoice.IsVoided}}<div class="voided">已作废</div>{{end}}
+  <h1>收据</h1>
+  <div class="meta">
+    <span>号码：{{.Invoice.InvoiceNo}}</span>
+    <span>类别：{{.SourceName}}</span>
+    <span>开具日期：{{.Invoice.IssueTime.Format "2006-01-02"}}</span>
+  </div>
+  <table class="parties">
+    <tr>
+      <td>
+        <strong>购买方</strong><br>
+        名称：{{.Buyer.Title}}<br>
+        {{if .Buyer.TaxNo}}纳税人识别号：{{.Buyer.TaxNo}}<br>{{end}}
+        {{if or .Buyer.Address .Buyer.Phone}}地址、电话：{{.Buyer.Address}} {{.Buyer.Phone}}<br>{{end}}
+        {{if or .Buyer.BankName .Buyer.BankAccount}}开户行及账号：{{.Buyer.BankName}} {{.Buyer.BankAccount}}{{end}}
+      </td>
+      <td>
+        <strong>销售方</strong><br>
+        名称：{{.Seller.Title}}<br>
+        {{if .Seller.TaxNo}}纳税人识别号：{{.Seller.TaxNo}}<br>{{end}}
+        {{if or .Seller.Address .Seller.Phone}}地址、电话：{{.Seller.Address}} {{.Seller.Phone}}<br>{{end}}
+        {{if or .Seller.BankName .Seller.BankAccount}}开户行及账号：{{.Seller.BankName}} {{.Seller.BankAccount}}{{end}}
+      </td>
+    </tr>
+  </table>
+  <table>
+    <tr><th style="width:auto">项目</th><th class="amount">金额（元）</th></tr>
+    {{range .Items}}<tr><td>{{.Name}}</td><td class="amount">{{.Amount}}</td></tr>
+    {{end}}<tr><th style="width:auto">合计（大写）：{{.AmountInWords}}</th><th class="amount">¥{{.Invoice.Amount}}</th></tr>
+  </table>
+  {{if .Invoice.IsVoided}}<p class="footer">作废时间：{{.Invoice.VoidTime.Format "2006-01-02 15:04:05"}}，作废原因：{{.Invoice.VoidReason}}</p>{{end}}
+  <p class="footer">本收据由系统根据开具时的交易记录生成，号码连续编号，可凭号码在平台查询真伪。</p>
+</div>
+</body>
+</html>
+`))
+
+// receiptData 收据模板数据
+type receiptData struct {
+	Invoice       *models.Invoice
+	SourceName    string
+	Buyer         models.InvoiceParty
+	Seller        models.InvoiceParty
+	Items         []models.InvoiceItem
+	AmountInWords string
+}
+
+// RenderReceipt 按发票快照输出可打印的HTML收据，已作废的发票带作废水印
+func (s *InvoiceService) RenderReceipt(w io.Writer, invoice *models.Invoice) error {
+	buyer, err := invoice.GetBuyer()
+	if err != nil {
+		return fmt.Errorf("解析购买方信息失败: %w", err)
+	}
+	seller, err := invoice.GetSeller()
+	if err != nil {
+		return fmt.Errorf("解析销售方信息失败: %w", err)
+	}
+	items, err := invoice.GetItems()
+	if err != nil {
+		return fmt.Errorf("解析开票明细失败: %w", err)
+	}
+
+	if err := receiptTemplate.Execute(w, receiptData{
+		Invoice:       invoice,
+		SourceName:    models.InvoiceSourceName(invoice.SourceType),
+		Buyer:         buyer,
+		Seller:        seller,
+		Items:         items,
+		AmountInWords: chineseAmount(invoice.Amount),
+	}); err != nil {
+		return fmt.Errorf("渲染收据失败: %w", err)
+	}
+	return nil
+}
+
+var (
+	chineseDigits     = []string{"零", "壹", "贰", "叁", "肆", "伍", "陆", "柒", "捌", "玖"}
+	chineseUnits      = []string{"", "拾", "佰", "仟"}
+	chineseGroupUnits = []string{"", "万", "亿", "万亿"}
+)
+
+// chineseAmount 金额转换为中文大写，如1005.20元为壹仟零伍元贰角
+func chineseAmount(m money.Money) string {
+	cents := m.Cents()
+	var b strings.Builder
+	if cents < 0 {
+		b.WriteString("负")
+		cents = -cents
+	}
+	yuan, jiao, fen := cents/100, cents/10%10, cents%10
+
+	if yuan > 0 {
+		b.WriteString(chineseInteger(yuan))
+		b.WriteString("元")
+	}
+	if jiao == 0 && fen == 0 {
+		if yuan == 0 {
+			b.WriteString("零元")
+		}
+		b.WriteString("整")
+		return b.String()
+	}
+	if jiao > 0 {
+		b.WriteString(chineseDigits[jiao] + "角")
+	} else if yuan > 0 {
+		b.WriteString("零")
+	}
+	if fen > 0 {
+		b.WriteString(chineseDigits[fen] + "分")
+	}
+	return b.String()
+}
+
+// chineseInteger 正整数转换为中文大写，按万、亿分节，节间缺位补零
+func chineseInteger(n int64) string {
+	var groups []int64
+	for n > 0 {
+		groups = append(groups, n%10000)
+		n /= 10000
+	}
+
+	var b strings.Builder
+	zero := false
+	for i := len(groups) - 1; i >= 0; i-- {
+		group := groups[i]
+		if group == 0 {
+			zero = b.Len() > 0
+			continue
+		}
+		if b.Len() > 0 && (zero || group < 1000) {
+			b.WriteString("零")
+		}
+		b.WriteString(chineseGroup(group))
+		b.WriteString(chineseGroupUnits[i])
+		zero = false
+	}
+	return b.String()
+}
+
+// chineseGroup 四位以内的数字转换为中文大写，连续的零只读一个
+func chineseGroup(n int64) string {
+	var b strings.Builder
+	zero := false
+	for pos, base := 3, int64(1000); pos >= 0; pos, base = pos-1, base/10 {
+		d := n / base % 10
+		if d == 0 {
+			zero = b.Len() > 0
+			continue
+		}
+		if zero {
+			b.WriteString("零")
+			zero = false
+		}
+		b.WriteString(chineseDigits[d] + chineseUnits[pos])
+	}
+	return b.String()
+}
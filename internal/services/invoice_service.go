@@ -0,0 +1,449 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/internal/models"
+	"task-platform-api/pkg/utils"
+)
+
+// InvoiceProfileRequest 保存开票信息请求
+type InvoiceProfileRequest struct {
+	UserID      uint64
+	TitleType   string
+	Title       string
+	TaxNo       string
+	Address     string
+	Phone       string
+	BankName    string
+	BankAccount string
+	Email       string
+}
+
+// IssueInvoiceRequest 申请开票请求，SourceID为预付款交易ID或结算ID
+type IssueInvoiceRequest struct {
+	UserID     uint64
+	SourceType string
+	SourceID   uint64
+}
+
+// InvoiceListRequest 发票列表查询条件
+type InvoiceListRequest struct {
+	UserID     uint64
+	SourceType string
+	Status     *int8
+	Page       int
+	PageSize   int
+}
+
+// InvoiceService 发票服务，为已支付的预付款和结算中的平台服务费开具连续编号的收据
+type InvoiceService struct {
+	db  *gorm.DB
+	cfg config.InvoiceConfig
+}
+
+// NewInvoiceService 创建发票服务
+func NewInvoiceService(db *gorm.DB, cfg config.InvoiceConfig) *InvoiceService {
+	return &InvoiceService{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// GetProfile 查询用户的开票信息
+func (s *InvoiceService) GetProfile(ctx context.Context, userID uint64) (*models.InvoiceProfile, error) {
+	var profile models.InvoiceProfile
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).First(&profile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvoiceProfileNotFound
+		}
+		return nil, fmt.Errorf("查询开票信息失败: %w", err)
+	}
+	return &profile, nil
+}
+
+// SaveProfile 保存用户的开票信息，企业抬头须填写纳税人识别号；修改只影响之后开具的发票
+func (s *InvoiceService) SaveProfile(ctx context.Context, req *InvoiceProfileRequest) (*models.InvoiceProfile, error) {
+	switch req.TitleType {
+	case models.InvoiceTitlePersonal:
+	case models.InvoiceTitleCompany:
+		if req.TaxNo == "" {
+			return nil, ErrInvalidParam.WithMessage("企业抬头须填写纳税人识别号")
+		}
+	default:
+		return nil, ErrInvalidParam.WithMessage("抬头类型错误")
+	}
+
+	profile := &models.InvoiceProfile{
+		UserID:      req.UserID,
+		TitleType:   req.TitleType,
+		Title:       req.Title,
+		TaxNo:       req.TaxNo,
+		Address:     req.Address,
+		Phone:       req.Phone,
+		BankName:    req.BankName,
+		BankAccount: req.BankAccount,
+		Email:       req.Email,
+	}
+	if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"title_type", "title", "tax_no", "address", "phone", "bank_name", "bank_account", "email", "updated_at"}),
+	}).Create(profile).Error; err != nil {
+		return nil, fmt.Errorf("保存开票信息失败: %w", err)
+	}
+	return s.GetProfile(ctx, req.UserID)
+}
+
+// IssueInvoice 按用户当前开票信息开具发票：预付款按实付金额扣除已退款金额开具，
+// 平台服务费按结算中由该用户承担的服务费开具；同一笔款项只能有一张有效发票，作废后可重新开具
+func (s *InvoiceService) IssueInvoice(ctx context.Context, req *IssueInvoiceRequest) (*models.Invoice, error) {
+	var invoice *models.Invoice
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var profile models.InvoiceProfile
+		if err := tx.Where("user_id = ?", req.UserID).First(&profile).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvoiceProfileNotFound.WithMessage("请先填写开票信息")
+			}
+			return fmt.Errorf("查询开票信息失败: %w", err)
+		}
+
+		var err error
+		switch req.SourceType {
+		case models.InvoiceSourcePrepay:
+			invoice, err = prepayInvoice(tx, req.UserID, req.SourceID)
+		case models.InvoiceSourcePlatformFee:
+			invoice, err = platformFeeInvoice(tx, req.UserID, req.SourceID)
+		default:
+			err = ErrInvalidParam.WithMessage("开票来源错误")
+		}
+		if err != nil {
+			return err
+		}
+
+		buyer, err := json.Marshal(models.InvoiceParty{
+			Title:       profile.Title,
+			TaxNo:       profile.TaxNo,
+			Address:     profile.Address,
+			Phone:       profile.Phone,
+			BankName:    profile.BankName,
+			BankAccount: profile.BankAccount,
+		})
+		if err != nil {
+			return fmt.Errorf("序列化购买方信息失败: %w", err)
+		}
+		seller, err := json.Marshal(models.InvoiceParty{
+			Title:       s.cfg.SellerName,
+			TaxNo:       s.cfg.SellerTaxNo,
+			Address:     s.cfg.SellerAddress,
+			Phone:       s.cfg.SellerPhone,
+			BankName:    s.cfg.SellerBankName,
+			BankAccount: s.cfg.SellerBankAccount,
+		})
+		if err != nil {
+			return fmt.Errorf("序列化销售方信息失败: %w", err)
+		}
+
+		now := time.Now()
+		if invoice.InvoiceNo, err = s.nextInvoiceNo(tx, now); err != nil {
+			return err
+		}
+		invoice.UserID = req.UserID
+		invoice.SourceType = req.SourceType
+		invoice.BuyerTitle = profile.Title
+		invoice.BuyerInfo = string(buyer)
+		invoice.SellerInfo = string(seller)
+		invoice.Status = models.InvoiceStatusIssued
+		invoice.IssueTime = now
+		if err := tx.Create(invoice).Error; err != nil {
+			return fmt.Errorf("创建发票失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// ListInvoices 分页查询用户的发票
+func (s *InvoiceService) ListInvoices(ctx context.Context, req *InvoiceListRequest) ([]models.Invoice, int64, error) {
+	var invoices []models.Invoice
+	var total int64
+
+	db := s.db.WithContext(ctx).Model(&models.Invoice{}).Where("user_id = ?", req.UserID)
+	if req.SourceType != "" {
+		db = db.Where("source_type = ?", req.SourceType)
+	}
+	if req.Status != nil {
+		db = db.Where("status = ?", *req.Status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取发票总数失败: %w", err)
+	}
+
+	offset, limit := utils.Pagination(req.Page, req.PageSize)
+	if err := db.Order("invoice_id DESC").Offset(offset).Limit(limit).Find(&invoices).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询发票失败: %w", err)
+	}
+
+	return invoices, total, nil
+}
+
+// GetInvoice 按发票号码查询用户本人的发票
+func (s *InvoiceService) GetInvoice(ctx context.Context, userID uint64, invoiceNo string) (*models.Invoice, error) {
+	var invoice models.Invoice
+	if err := s.db.WithContext(ctx).Where("invoice_no = ? AND user_id = ?", invoiceNo, userID).First(&invoice).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvoiceNotFound
+		}
+		return nil, fmt.Errorf("查询发票失败: %w", err)
+	}
+	return &invoice, nil
+}
+
+// VoidInvoice 管理员作废发票
+func (s *InvoiceService) VoidInvoice(ctx context.Context, invoiceID uint64, reason string) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&invoice, invoiceID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvoiceNotFound
+			}
+			return fmt.Errorf("查询发票失败: %w", err)
+		}
+		if !invoice.IsIssued() {
+			return ErrInvoiceInvalidStatus
+		}
+		return voidInvoice(tx, &invoice, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// nextInvoiceNo 生成发票号码：前缀+年月+6位序号，序号按月连续递增；
+// 序列行在事务提交前保持锁定，开票失败回滚时序号一并回滚，不产生断号
+func (s *InvoiceService) nextInvoiceNo(tx *gorm.DB, now time.Time) (string, error) {
+	period := now.Format("200601")
+	if err := tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"last_no":    gorm.Expr("last_no + 1"),
+			"updated_at": now,
+		}),
+	}).Create(&models.InvoiceSequence{Period: period, LastNo: 1, UpdatedAt: now}).Error; err != nil {
+		return "", fmt.Errorf("生成发票号码失败: %w", err)
+	}
+
+	var seq models.InvoiceSequence
+	if err := tx.Where("period = ?", period).First(&seq).Error; err != nil {
+		return "", fmt.Errorf("查询发票号码序列失败: %w", err)
+	}
+	return fmt.Sprintf("%s%s%06d", s.cfg.Prefix, period, seq.LastNo), nil
+}
+
+// prepayInvoice 按预付款交易生成待开具的发票，开票金额为实付金额扣除已成功退款的金额；
+// 加锁读取交易，与退款成功后作废发票互斥
+func prepayInvoice(tx *gorm.DB, userID, tradeID uint64) (*models.Invoice, error) {
+	var trade models.Trade
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&trade, tradeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTradeNotFound
+		}
+		return nil, fmt.Errorf("查询交易记录失败: %w", err)
+	}
+	if trade.UserID != userID || trade.TradeType != models.TradeTypePrepay {
+		return nil, ErrTradeNotFound
+	}
+	if !trade.IsPaid() {
+		return nil, ErrInvoiceNotAllowed.WithMessage("交易未支付或已全额退款，不能开票")
+	}
+	processing, err := sumRefunds(tx, trade.ID, models.RefundStatusProcessing)
+	if err != nil {
+		return nil, err
+	}
+	if processing.IsPositive() {
+		return nil, ErrInvoiceNotAllowed.WithMessage("交易有处理中的退款，请在退款完成后开票")
+	}
+	if err := checkInvoiceDuplicate(tx, tx.Where("trade_id = ?", trade.ID), models.InvoiceSourcePrepay); err != nil {
+		return nil, err
+	}
+
+	refunded, err := sumRefunds(tx, trade.ID, models.RefundStatusSuccess)
+	if err != nil {
+		return nil, err
+	}
+	var task *models.Task
+	if trade.TaskID != nil {
+		task = &models.Task{}
+		if err := tx.First(task, *trade.TaskID).Error; err != nil {
+			return nil, fmt.Errorf("查询任务失败: %w", err)
+		}
+	}
+
+	items := prepayInvoiceItems(&trade, task)
+	if refunded.IsPositive() {
+		items = append(items, models.InvoiceItem{Name: "已退款", Amount: -refunded})
+	}
+	return newInvoice(models.Invoice{
+		TradeID: &trade.ID,
+		TaskID:  trade.TaskID,
+		Amount:  trade.Amount - refunded,
+	}, items)
+}
+
+// prepayInvoiceItems 预付款开票明细，按赏金、发布者服务费和保证金拆分，使用优惠券时列出抵扣金额；
+// 拆分合计与托管金额不一致时（如任务金额在支付后被调整）按实付金额开具一行
+func prepayInvoiceItems(trade *models.Trade, task *models.Task) []models.InvoiceItem {
+	if task == nil {
+		return []models.InvoiceItem{{Name: "任务预付款", Amount: trade.Amount}}
+	}
+	if escrowAmount(task) != trade.GetEscrowAmount() {
+		return []models.InvoiceItem{{Name: fmt.Sprintf("任务「%s」预付款", task.Title), Amount: trade.Amount}}
+	}
+
+	items := []models.InvoiceItem{{Name: fmt.Sprintf("任务「%s」赏金", task.Title), Amount: task.Amount}}
+	if fee := task.GetPublisherFee(); fee.IsPositive() {
+		items = append(items, models.InvoiceItem{Name: "发布者服务费", Amount: fee})
+	}
+	if deposit := task.GetDepositAmount(); deposit.IsPositive() {
+		items = append(items, models.InvoiceItem{Name: "任务保证金", Amount: deposit})
+	}
+	if trade.Discount.IsPositive() {
+		items = append(items, models.InvoiceItem{Name: "优惠券抵扣", Amount: -trade.Discount})
+	}
+	return items
+}
+
+// platformFeeInvoice 按结算生成平台服务费发票：发布者开具发布者服务费，接取者开具接取者服务费；
+// 违约金结算不含服务费，不能开票
+func platformFeeInvoice(tx *gorm.DB, userID, settlementID uint64) (*models.Invoice, error) {
+	var settlement models.Settlement
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&settlement, settlementID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSettlementNotFound
+		}
+		return nil, fmt.Errorf("查询结算记录失败: %w", err)
+	}
+	var task models.Task
+	if err := tx.First(&task, settlement.TaskID).Error; err != nil {
+		return nil, fmt.Errorf("查询任务失败: %w", err)
+	}
+	if task.PublisherID != userID && task.TakerID != userID {
+		return nil, ErrSettlementNotFound
+	}
+	if settlement.Status != models.SettlementStatusSettled || settlement.Penalty.IsPositive() {
+		return nil, ErrInvoiceNotAllowed.WithMessage("该结算未完成或不含平台服务费，不能开票")
+	}
+	if err := checkInvoiceDuplicate(tx, tx.Where("settlement_id = ? AND user_id = ?", settlement.ID, userID), models.InvoiceSourcePlatformFee); err != nil {
+		return nil, err
+	}
+
+	var stage *models.TaskStage
+	name := fmt.Sprintf("任务「%s」平台服务费", task.Title)
+	if settlement.StageID != nil {
+		stage = &models.TaskStage{}
+		if err := tx.First(stage, *settlement.StageID).Error; err != nil {
+			return nil, fmt.Errorf("查询任务阶段失败: %w", err)
+		}
+		name = fmt.Sprintf("任务「%s」阶段「%s」平台服务费", task.Title, stage.StageName)
+	}
+	publisherFee, takerFee, err := settlementFees(tx, &task, stage)
+	if err != nil {
+		return nil, err
+	}
+	fee := takerFee
+	if userID == task.PublisherID {
+		fee = publisherFee
+	}
+	if !fee.IsPositive() {
+		return nil, ErrInvoiceNotAllowed.WithMessage("该结算没有由您承担的平台服务费")
+	}
+
+	return newInvoice(models.Invoice{
+		SettlementID: &settlement.ID,
+		TaskID:       &task.ID,
+		Amount:       fee,
+	}, []models.InvoiceItem{{Name: name, Amount: fee}})
+}
+
+// checkInvoiceDuplicate 同一来源已有有效发票时返回错误，scope为限定来源的查询条件
+func checkInvoiceDuplicate(tx *gorm.DB, scope *gorm.DB, sourceType string) error {
+	var count int64
+	if err := tx.Model(&models.Invoice{}).
+		Where(scope).
+		Where("source_type = ? AND status = ?", sourceType, models.InvoiceStatusIssued).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("查询发票失败: %w", err)
+	}
+	if count > 0 {
+		return ErrInvoiceDuplicate
+	}
+	return nil
+}
+
+// newInvoice 填充开票明细，金额为0的款项不能开票
+func newInvoice(invoice models.Invoice, items []models.InvoiceItem) (*models.Invoice, error) {
+	if !invoice.Amount.IsPositive() {
+		return nil, ErrInvoiceNotAllowed.WithMessage("开票金额为0，不能开票")
+	}
+	raw, err := json.Marshal(items)
+	if err != nil {
+		return nil, fmt.Errorf("序列化开票明细失败: %w", err)
+	}
+	invoice.Items = string(raw)
+	return &invoice, nil
+}
+
+// voidInvoice 作废发票并通知购买方，须在事务内对已锁定的有效发票调用
+func voidInvoice(tx *gorm.DB, invoice *models.Invoice, reason string) error {
+	now := time.Now()
+	if err := tx.Model(invoice).Updates(map[string]interface{}{
+		"status":      models.InvoiceStatusVoided,
+		"void_time":   now,
+		"void_reason": reason,
+	}).Error; err != nil {
+		return fmt.Errorf("作废发票失败: %w", err)
+	}
+	invoice.Status = models.InvoiceStatusVoided
+	invoice.VoidTime = &now
+	invoice.VoidReason = reason
+
+	return createNotification(tx, &models.Notification{
+		UserID:      invoice.UserID,
+		Title:       "发票已作废",
+		Content:     fmt.Sprintf("您的发票%s（%s元）已作废: %s", invoice.InvoiceNo, invoice.Amount, reason),
+		Type:        "payment",
+		RelatedID:   &invoice.ID,
+		RelatedType: "invoice",
+	}, map[string]interface{}{
+		"invoice_no": invoice.InvoiceNo,
+		"status":     invoice.Status,
+	})
+}
+
+// voidTradeInvoices 退款成功后作废交易的有效发票，用户可按扣除退款后的金额重新开票；须在锁定交易后于事务内调用
+func voidTradeInvoices(tx *gorm.DB, trade *models.Trade, refund *models.Refund) error {
+	var invoices []models.Invoice
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("trade_id = ? AND source_type = ? AND status = ?", trade.ID, models.InvoiceSourcePrepay, models.InvoiceStatusIssued).
+		Find(&invoices).Error; err != nil {
+		return fmt.Errorf("查询发票失败: %w", err)
+	}
+	reason := fmt.Sprintf("交易退款%s元（退款单号%s）", refund.RefundAmount, refund.RefundNo)
+	for i := range invoices {
+		if err := voidInvoice(tx, &invoices[i], reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
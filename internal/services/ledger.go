@@ -0,0 +1,375 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"task-platform-api/internal/models"
+	"task-platform-api/pkg/money"
+)
+
+// 记账业务类型
+const (
+	ledgerBizPayment    = "payment"    // 支付入账
+	ledgerBizFreeze     = "freeze"     // 托管冻结
+	ledgerBizUnfreeze   = "unfreeze"   // 解冻
+	ledgerBizSettlement = "settlement" // 任务结算
+	ledgerBizRefund     = "refund"     // 退款
+	ledgerBizWithdraw   = "withdraw"   // 提现
+	ledgerBizDeposit    = "deposit"    // 接取保证金冻结与退还
+	ledgerBizPenalty    = "penalty"    // 没收保证金支付违约金
+	ledgerBizCoupon     = "coupon"     // 优惠券抵扣补贴与回收
+	ledgerBizOpening    = "opening"    // 账本上线前的钱包期初余额
+)
+
+// ledgerAccountRef 账户引用，记账时按编码获取或创建账户
+type ledgerAccountRef struct {
+	Code        string
+	Name        string
+	AccountType string
+	UserID      *uint64
+}
+
+// ledgerLine 记账分录行
+type ledgerLine struct {
+	Account   ledgerAccountRef
+	Direction string
+	Amount    money.Money
+}
+
+// userAvailableAccount 用户可用余额账户
+func userAvailableAccount(userID uint64) ledgerAccountRef {
+	return ledgerAccountRef{
+		Code:        models.UserAvailableAccountCode(userID),
+		Name:        fmt.Sprintf("用户%d可用余额", userID),
+		AccountType: models.LedgerAccountLiability,
+		UserID:      &userID,
+	}
+}
+
+// userFrozenAccount 用户冻结余额账户
+func userFrozenAccount(userID uint64) ledgerAccountRef {
+	return ledgerAccountRef{
+		Code:        models.UserFrozenAccountCode(userID),
+		Name:        fmt.Sprintf("用户%d冻结余额", userID),
+		AccountType: models.LedgerAccountLiability,
+		UserID:      &userID,
+	}
+}
+
+// 平台账户
+var (
+	gatewayAccount        = ledgerAccountRef{Code: models.LedgerAccountGateway, Name: "支付渠道资金", AccountType: models.LedgerAccountAsset}
+	escrowAccount         = ledgerAccountRef{Code: models.LedgerAccountEscrow, Name: "平台托管资金", AccountType: models.LedgerAccountLiability}
+	feeRevenueAccount     = ledgerAccountRef{Code: models.LedgerAccountFeeRevenue, Name: "平台服务费收入", AccountType: models.LedgerAccountRevenue}
+	penaltyRevenueAccount = ledgerAccountRef{Code: models.LedgerAccountPenaltyRevenue, Name: "平台违约金收入", AccountType: models.LedgerAccountRevenue}
+	marketingAccount      = ledgerAccountRef{Code: models.LedgerAccountMarketing, Name: "平台营销费用", AccountType: models.LedgerAccountExpense}
+)
+
+// debit 借方分录
+func debit(account ledgerAccountRef, amount money.Money) ledgerLine {
+	return ledgerLine{Account: account, Direction: models.LedgerDebit, Amount: amount}
+}
+
+// credit 贷方分录
+func credit(account ledgerAccountRef, amount money.Money) ledgerLine {
+	return ledgerLine{Account: account, Direction: models.LedgerCredit, Amount: amount}
+}
+
+// postJournal 记一张借贷平衡的凭证并更新账户余额，金额为0的分录忽略，须在事务内调用
+func postJournal(tx *gorm.DB, bizType string, bizID uint64, description string, lines ...ledgerLine) (*models.LedgerEntry, error) {
+	var debitTotal, creditTotal money.Money
+	postingLines := make([]ledgerLine, 0, len(lines))
+	for _, line := range lines {
+		if line.Amount.IsNegative() {
+			return nil, fmt.Errorf("记账金额不能为负: %s %s", line.Account.Code, line.Amount)
+		}
+		if line.Amount.IsZero() {
+			continue
+		}
+		if line.Direction == models.LedgerDebit {
+			debitTotal += line.Amount
+		} else {
+			creditTotal += line.Amount
+		}
+		postingLines = append(postingLines, line)
+	}
+	if debitTotal != creditTotal {
+		return nil, fmt.Errorf("凭证借贷不平衡: 借方%s, 贷方%s", debitTotal, creditTotal)
+	}
+	if debitTotal.IsZero() {
+		return nil, nil
+	}
+
+	// 按编码顺序加锁，避免并发记账死锁
+	refs := make(map[string]ledgerAccountRef)
+	for _, line := range postingLines {
+		refs[line.Account.Code] = line.Account
+	}
+	codes := make([]string, 0, len(refs))
+	for code := range refs {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	accounts := make(map[string]*models.LedgerAccount, len(codes))
+	for _, code := range codes {
+		account, err := lockLedgerAccount(tx, refs[code])
+		if err != nil {
+			return nil, err
+		}
+		accounts[code] = account
+	}
+
+	entry := &models.LedgerEntry{
+		BizType:     bizType,
+		BizID:       bizID,
+		Amount:      debitTotal,
+		Description: description,
+	}
+	if err := tx.Create(entry).Error; err != nil {
+		return nil, fmt.Errorf("创建记账凭证失败: %w", err)
+	}
+
+	postings := make([]models.LedgerPosting, 0, len(postingLines))
+	for _, line := range postingLines {
+		account := accounts[line.Account.Code]
+		postings = append(postings, models.LedgerPosting{
+			EntryID:   entry.ID,
+			AccountID: account.ID,
+			Direction: line.Direction,
+			Amount:    line.Amount,
+		})
+		account.Balance += account.SignedAmount(line.Direction, line.Amount)
+	}
+	if err := tx.Create(&postings).Error; err != nil {
+		return nil, fmt.Errorf("创建记账分录失败: %w", err)
+	}
+
+	for _, code := range codes {
+		account := accounts[code]
+		if err := tx.Model(account).Update("balance", account.Balance).Error; err != nil {
+			return nil, fmt.Errorf("更新账户余额失败: %w", err)
+		}
+	}
+
+	entry.Postings = postings
+	return entry, nil
+}
+
+// lockLedgerAccount 加行锁读取账本账户，不存在时自动创建
+func lockLedgerAccount(tx *gorm.DB, ref ledgerAccountRef) (*models.LedgerAccount, error) {
+	var account models.LedgerAccount
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", ref.Code).First(&account).Error
+	if err == nil {
+		return &account, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("查询账本账户失败: %w", err)
+	}
+
+	// 并发创建时以唯一索引去重，再以当前读取回已存在的账户
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.LedgerAccount{
+		Code:        ref.Code,
+		Name:        ref.Name,
+		AccountType: ref.AccountType,
+		UserID:      ref.UserID,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("创建账本账户失败: %w", err)
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", ref.Code).First(&account).Error; err != nil {
+		return nil, fmt.Errorf("查询账本账户失败: %w", err)
+	}
+	return &account, nil
+}
+
+// WalletCheckResult 钱包与账本核对结果
+type WalletCheckResult struct {
+	UserID              uint64      `json:"user_id"`
+	WalletBalance       money.Money `json:"wallet_balance"`
+	LedgerBalance       money.Money `json:"ledger_balance"`
+	WalletFrozenBalance money.Money `json:"wallet_frozen_balance"`
+	LedgerFrozenBalance money.Money `json:"ledger_frozen_balance"`
+}
+
+// Matched 钱包缓存余额与账本是否一致
+func (r *WalletCheckResult) Matched() bool {
+	return r.WalletBalance == r.LedgerBalance && r.WalletFrozenBalance == r.LedgerFrozenBalance
+}
+
+// LedgerService 账本服务，钱包余额是账本用户账户的缓存视图
+type LedgerService struct {
+	db *gorm.DB
+}
+
+// NewLedgerService 创建账本服务
+func NewLedgerService(db *gorm.DB) *LedgerService {
+	return &LedgerService{
+		db: db,
+	}
+}
+
+// VerifyWallet 核对单个用户钱包与账本余额
+func (s *LedgerService) VerifyWallet(ctx context.Context, userID uint64) (*WalletCheckResult, error) {
+	var wallet models.Wallet
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).First(&wallet).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("查询钱包失败: %w", err)
+	}
+
+	results, err := s.checkWallets(ctx, []models.Wallet{wallet}, []uint64{userID})
+	if err != nil {
+		return nil, err
+	}
+	return &results[0], nil
+}
+
+// VerifyWallets 批量核对全部钱包，返回与账本不一致的记录
+func (s *LedgerService) VerifyWallets(ctx context.Context) ([]WalletCheckResult, error) {
+	var mismatched []WalletCheckResult
+	var wallets []models.Wallet
+	err := s.db.WithContext(ctx).FindInBatches(&wallets, 500, func(tx *gorm.DB, batch int) error {
+		userIDs := make([]uint64, len(wallets))
+		for i, w := range wallets {
+			userIDs[i] = w.UserID
+		}
+		results, err := s.checkWallets(ctx, wallets, userIDs)
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			if !r.Matched() {
+				mismatched = append(mismatched, r)
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("核对钱包失败: %w", err)
+	}
+	return mismatched, nil
+}
+
+// CheckTrialBalance 试算平衡：全部分录借方合计必须等于贷方合计
+func (s *LedgerService) CheckTrialBalance(ctx context.Context) error {
+	var totals []struct {
+		Direction string
+		Total     money.Money
+	}
+	if err := s.db.WithContext(ctx).Model(&models.LedgerPosting{}).
+		Select("direction, SUM(amount) AS total").
+		Group("direction").
+		Scan(&totals).Error; err != nil {
+		return fmt.Errorf("查询分录合计失败: %w", err)
+	}
+
+	var debitTotal, creditTotal money.Money
+	for _, t := range totals {
+		if t.Direction == models.LedgerDebit {
+			debitTotal = t.Total
+		} else {
+			creditTotal = t.Total
+		}
+	}
+	if debitTotal != creditTotal {
+		return fmt.Errorf("账本试算不平衡: 借方%s, 贷方%s", debitTotal, creditTotal)
+	}
+	return nil
+}
+
+// PostOpeningBalances 为账本上线前已有余额的钱包登记期初余额，须在启用ledger_verify前执行。
+// 按钱包余额与账本用户账户余额的差额记账，对方为支付渠道资金账户，已登记的钱包差额为零，重复执行不会重复记账；
+// 返回登记的钱包数量，单个钱包登记失败时跳过并继续，失败原因汇总后返回
+func (s *LedgerService) PostOpeningBalances(ctx context.Context) (int, error) {
+	posted := 0
+	var errs []error
+	var wallets []models.Wallet
+	err := s.db.WithContext(ctx).Select("id", "user_id").FindInBatches(&wallets, 500, func(tx *gorm.DB, batch int) error {
+		for _, w := range wallets {
+			ok, err := s.postOpeningBalance(ctx, w.UserID)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("登记用户%d期初余额失败: %w", w.UserID, err))
+				continue
+			}
+			if ok {
+				posted++
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return posted, fmt.Errorf("查询钱包失败: %w", err)
+	}
+	return posted, errors.Join(errs...)
+}
+
+// postOpeningBalance 在事务内将单个钱包与账本的余额差额登记为期初余额，返回是否记账
+func (s *LedgerService) postOpeningBalance(ctx context.Context, userID uint64) (bool, error) {
+	posted := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 与业务记账一致，先按编码顺序锁定账本账户再锁定钱包
+		if _, err := lockLedgerAccount(tx, gatewayAccount); err != nil {
+			return err
+		}
+		available, err := lockLedgerAccount(tx, userAvailableAccount(userID))
+		if err != nil {
+			return err
+		}
+		frozen, err := lockLedgerAccount(tx, userFrozenAccount(userID))
+		if err != nil {
+			return err
+		}
+		wallet, err := lockWallet(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		lines := append(openingLines(userAvailableAccount(userID), wallet.Balance-available.Balance),
+			openingLines(userFrozenAccount(userID), wallet.FrozenBalance-frozen.Balance)...)
+		entry, err := postJournal(tx, ledgerBizOpening, wallet.ID, fmt.Sprintf("用户%d期初余额", userID), lines...)
+		posted = entry != nil
+		return err
+	})
+	return posted, err
+}
+
+// openingLines 期初余额分录，差额为正时由支付渠道资金转入用户账户，为负时冲回
+func openingLines(account ledgerAccountRef, diff money.Money) []ledgerLine {
+	if diff.IsNegative() {
+		return []ledgerLine{debit(account, -diff), credit(gatewayAccount, -diff)}
+	}
+	return []ledgerLine{debit(gatewayAccount, diff), credit(account, diff)}
+}
+
+// checkWallets 读取用户账本账户余额并与钱包逐一比对
+func (s *LedgerService) checkWallets(ctx context.Context, wallets []models.Wallet, userIDs []uint64) ([]WalletCheckResult, error) {
+	codes := make([]string, 0, len(userIDs)*2)
+	for _, id := range userIDs {
+		codes = append(codes, models.UserAvailableAccountCode(id), models.UserFrozenAccountCode(id))
+	}
+
+	var accounts []models.LedgerAccount
+	if err := s.db.WithContext(ctx).Where("code IN ?", codes).Find(&accounts).Error; err != nil {
+		return nil, fmt.Errorf("查询账本账户失败: %w", err)
+	}
+	balances := make(map[string]money.Money, len(accounts))
+	for _, a := range accounts {
+		balances[a.Code] = a.Balance
+	}
+
+	results := make([]WalletCheckResult, len(userIDs))
+	for i, id := range userIDs {
+		results[i] = WalletCheckResult{
+			UserID:              id,
+			WalletBalance:       wallets[i].Balance,
+			LedgerBalance:       balances[models.UserAvailableAccountCode(id)],
+			WalletFrozenBalance: wallets[i].FrozenBalance,
+			LedgerFrozenBalance: balances[models.UserFrozenAccountCode(id)],
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"task-platform-api/internal/models"
+	"task-platform-api/internal/testutil"
+	"task-platform-api/pkg/money"
+)
+
+var ledgerAccountColumns = []string{"account_id", "code", "account_type", "balance"}
+
+// anyLedgerAccountRows 对任意编码的账本账户查询返回同一账户，用于不关心账本余额的用例
+func anyLedgerAccountRows() testutil.StubRows {
+	return testutil.StubRows{
+		Match:   "FROM `ledger_accounts`",
+		Columns: ledgerAccountColumns,
+		Values:  [][]driver.Value{{int64(1), "platform:gateway", models.LedgerAccountAsset, []byte("0.00")}},
+	}
+}
+
+// ledgerAccountRows 按编码返回指定账户
+func ledgerAccountRows(id int64, code, accountType, balance string) testutil.StubRows {
+	return testutil.StubRows{
+		Match:   "FROM `ledger_accounts`",
+		Args:    []driver.Value{code},
+		Columns: ledgerAccountColumns,
+		Values:  [][]driver.Value{{id, code, accountType, []byte(balance)}},
+	}
+}
+
+func TestPostJournalUpdatesBalances(t *testing.T) {
+	db, stub := testutil.NewGorm(t,
+		ledgerAccountRows(1, models.LedgerAccountGateway, models.LedgerAccountAsset, "500.00"),
+		ledgerAccountRows(2, models.UserAvailableAccountCode(3), models.LedgerAccountLiability, "20.00"),
+	)
+
+	entry, err := postJournal(db, ledgerBizPayment, 9, "支付入账",
+		debit(gatewayAccount, money.FromCents(10000)),
+		credit(userAvailableAccount(3), money.FromCents(10000)),
+		credit(escrowAccount, 0),
+	)
+	if err != nil {
+		t.Fatalf("记账失败: %v", err)
+	}
+	if entry.Amount != money.FromCents(10000) || len(entry.Postings) != 2 {
+		t.Fatalf("凭证金额为借方合计，金额为0的分录应忽略: %+v", entry)
+	}
+
+	// 资产账户借方增加，负债账户贷方增加
+	updates := stub.Executed("UPDATE `ledger_accounts`")
+	if len(updates) != 2 || !updates[0].HasArgs("600.00", int64(1)) || !updates[1].HasArgs("120.00", int64(2)) {
+		t.Fatalf("账户余额应按正常方向更新: %+v", updates)
+	}
+	if len(stub.Executed(models.LedgerAccountEscrow)) > 0 {
+		t.Fatal("金额为0的分录不应锁定账户")
+	}
+}
+
+func TestPostJournalRejectsUnbalancedEntry(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []ledgerLine
+	}{
+		{name: "借贷不平衡", lines: []ledgerLine{debit(gatewayAccount, money.FromCents(10000)), credit(userAvailableAccount(3), money.FromCents(9999))}},
+		{name: "只有借方", lines: []ledgerLine{debit(gatewayAccount, money.FromCents(100))}},
+		{name: "金额为负", lines: []ledgerLine{debit(gatewayAccount, money.FromCents(-100)), credit(userAvailableAccount(3), money.FromCents(-100))}},
+	}
+	for _, tt := range tests {
+		db, stub := testutil.NewGorm(t, anyLedgerAccountRows())
+		if _, err := postJournal(db, ledgerBizPayment, 9, tt.name, tt.lines...); err == nil {
+			t.Errorf("%s: 应返回错误", tt.name)
+		}
+		if len(stub.Executed("INSERT")) > 0 || len(stub.Executed("UPDATE `")) > 0 {
+			t.Errorf("%s: 不应写入凭证或更新余额", tt.name)
+		}
+	}
+}
+
+func TestPostJournalSkipsZeroEntry(t *testing.T) {
+	db, stub := testutil.NewGorm(t)
+	entry, err := postJournal(db, ledgerBizPayment, 9, "零金额", debit(gatewayAccount, 0), credit(userAvailableAccount(3), 0))
+	if err != nil || entry != nil {
+		t.Fatalf("全部分录金额为0时应不记账: %+v, %v", entry, err)
+	}
+	if len(stub.Executed("INSERT")) > 0 {
+		t.Fatal("全部分录金额为0时不应写入凭证")
+	}
+}
+
+func TestLockLedgerAccountCreatesMissingAccount(t *testing.T) {
+	code := models.UserFrozenAccountCode(3)
+	db, stub := testutil.NewGorm(t,
+		// 首次查询账户不存在，创建后当前读取回并发事务已创建的账户
+		testutil.StubRows{Match: "FROM `ledger_accounts`", Once: true},
+		ledgerAccountRows(7, code, models.LedgerAccountLiability, "15.00"),
+	)
+
+	account, err := lockLedgerAccount(db, userFrozenAccount(3))
+	if err != nil {
+		t.Fatalf("获取账户失败: %v", err)
+	}
+	if account.ID != 7 || account.Balance != money.FromCents(1500) {
+		t.Fatalf("应返回唯一索引上已存在的账户: %+v", account)
+	}
+
+	inserts := stub.Executed("INSERT INTO `ledger_accounts`")
+	if len(inserts) != 1 || !inserts[0].HasArgs(code) {
+		t.Fatalf("应创建缺失的账户: %+v", inserts)
+	}
+	if len(stub.Executed("ON DUPLICATE KEY")) != 1 {
+		t.Fatalf("并发创建时应忽略唯一索引冲突: %s", inserts[0].Query)
+	}
+	if selects := stub.Executed("FOR UPDATE"); len(selects) != 2 {
+		t.Fatalf("创建后应以当前读重新加锁读取: %+v", selects)
+	}
+}
+
+func TestCheckTrialBalance(t *testing.T) {
+	tests := []struct {
+		name          string
+		debit, credit string
+		wantErr       bool
+	}{
+		{name: "平衡", debit: "1234.56", credit: "1234.56"},
+		{name: "不平衡", debit: "1234.56", credit: "1234.55", wantErr: true},
+	}
+	for _, tt := range tests {
+		db, _ := testutil.NewGorm(t, testutil.StubRows{
+			Match:   "SUM(amount)",
+			Columns: []string{"direction", "total"},
+			Values:  [][]driver.Value{{models.LedgerDebit, []byte(tt.debit)}, {models.LedgerCredit, []byte(tt.credit)}},
+		})
+		err := NewLedgerService(db).CheckTrialBalance(context.Background())
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: CheckTrialBalance() = %v", tt.name, err)
+		}
+	}
+}
+
+func TestPostOpeningBalances(t *testing.T) {
+	// 钱包可用100元、冻结20元，账本只记录了上线后的可用30元和冻结20元
+	db, stub := testutil.NewGorm(t,
+		testutil.StubRows{
+			Match:   "FROM `wallets`",
+			Columns: []string{"id", "user_id", "balance", "frozen_balance", "version"},
+			Values:  [][]driver.Value{{int64(4), int64(3), []byte("100.00"), []byte("20.00"), int64(9)}},
+		},
+		ledgerAccountRows(1, models.LedgerAccountGateway, models.LedgerAccountAsset, "500.00"),
+		ledgerAccountRows(2, models.UserAvailableAccountCode(3), models.LedgerAccountLiability, "30.00"),
+		ledgerAccountRows(3, models.UserFrozenAccountCode(3), models.LedgerAccountLiability, "20.00"),
+	)
+
+	posted, err := NewLedgerService(db).PostOpeningBalances(context.Background())
+	if err != nil {
+		t.Fatalf("登记期初余额失败: %v", err)
+	}
+	if posted != 1 {
+		t.Fatalf("应为余额不一致的钱包登记期初余额, 实际: %d", posted)
+	}
+
+	entries := stub.Executed("INSERT INTO `ledger_entries`")
+	if len(entries) != 1 || !entries[0].HasArgs(ledgerBizOpening, int64(4), "70.00") {
+		t.Fatalf("应按差额登记一张期初凭证: %+v", entries)
+	}
+	updates := stub.Executed("UPDATE `ledger_accounts`")
+	if len(updates) != 2 || !updates[0].HasArgs("570.00", int64(1)) || !updates[1].HasArgs("100.00", int64(2)) {
+		t.Fatalf("差额应由渠道资金转入用户可用余额: %+v", updates)
+	}
+	if len(stub.Executed("UPDATE `wallets`")) > 0 {
+		t.Fatal("登记期初余额不应改动钱包")
+	}
+}
+
+func TestPostOpeningBalancesSkipsMatchedWallets(t *testing.T) {
+	db, stub := testutil.NewGorm(t,
+		testutil.StubRows{
+			Match:   "FROM `wallets`",
+			Columns: []string{"id", "user_id", "balance", "frozen_balance", "version"},
+			Values:  [][]driver.Value{{int64(4), int64(3), []byte("30.00"), []byte("20.00"), int64(9)}},
+		},
+		ledgerAccountRows(1, models.LedgerAccountGateway, models.LedgerAccountAsset, "500.00"),
+		ledgerAccountRows(2, models.UserAvailableAccountCode(3), models.LedgerAccountLiability, "30.00"),
+		ledgerAccountRows(3, models.UserFrozenAccountCode(3), models.LedgerAccountLiability, "20.00"),
+	)
+
+	posted, err := NewLedgerService(db).PostOpeningBalances(context.Background())
+	if err != nil || posted != 0 {
+		t.Fatalf("已与账本一致的钱包不应重复登记: %d, %v", posted, err)
+	}
+	if len(stub.Executed("INSERT")) > 0 {
+		t.Fatal("已与账本一致的钱包不应写入凭证")
+	}
+}
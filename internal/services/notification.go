@@ -0,0 +1,38 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"task-platform-api/internal/models"
+)
+
+// createNotification 写入站内通知，data序列化为通知扩展数据
+func createNotification(db *gorm.DB, notification *models.Notification, data map[string]interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("序列化通知数据失败: %w", err)
+	}
+	notification.Data = string(raw)
+	if err := db.Create(notification).Error; err != nil {
+		return fmt.Errorf("创建通知失败: %w", err)
+	}
+	return nil
+}
+
+// notifyTask 向用户发送任务相关通知
+func notifyTask(db *gorm.DB, userID uint64, task *models.Task, title, content string) error {
+	return createNotification(db, &models.Notification{
+		UserID:      userID,
+		Title:       title,
+		Content:     content,
+		Type:        "task",
+		RelatedID:   &task.ID,
+		RelatedType: "task",
+	}, map[string]interface{}{
+		"task_id": task.ID,
+		"status":  task.Status,
+	})
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"task-platform-api/internal/events"
+	"task-platform-api/internal/models"
+)
+
+// SubscribePaymentEvents 注册支付事件处理器：
+// 同步冻结预付款并发布任务，与交易状态更新在同一事务内完成；事务提交后异步发送支付通知
+func SubscribePaymentEvents(bus *events.Bus, db *gorm.DB) {
+	bus.Subscribe(events.PaymentSucceededEvent, handlePrepayEscrow)
+	bus.SubscribeAsync(events.PaymentSucceededEvent, func(ctx context.Context, event events.Event) error {
+		return notifyPaymentSucceeded(ctx, db, event.(*events.PaymentSucceeded))
+	})
+}
+
+// handlePrepayEscrow 预付款支付成功：冻结托管资金、确认使用优惠券并自动发布草稿任务；
+// 任务已不是草稿（如支付期间被取消）时立即解冻退回发布者余额
+func handlePrepayEscrow(ctx context.Context, event events.Event) error {
+	e := event.(*events.PaymentSucceeded)
+	if e.TradeType != models.TradeTypePrepay || e.TaskID == nil {
+		return nil
+	}
+
+	tx := txFromContext(ctx)
+	if tx == nil {
+		return fmt.Errorf("预付款托管须在支付事务内执行")
+	}
+
+	task, err := lockTask(tx, *e.TaskID)
+	if err != nil {
+		return err
+	}
+	var trade models.Trade
+	if err := tx.First(&trade, e.TradeID).Error; err != nil {
+		return fmt.Errorf("查询交易记录失败: %w", err)
+	}
+
+	if err := freezeEscrow(tx, &trade); err != nil {
+		return err
+	}
+	if err := confirmTradeCoupons(tx, &trade); err != nil {
+		return err
+	}
+	if !task.IsDraft() {
+		return releaseEscrow(tx, task, "任务已不是草稿状态")
+	}
+	return fireTaskEvent(tx, task, models.TaskEventPublish, trade.UserID, "预付款托管成功，自动发布")
+}
+
+// notifyPaymentSucceeded 向付款用户发送支付成功通知
+func notifyPaymentSucceeded(ctx context.Context, db *gorm.DB, e *events.PaymentSucceeded) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"internal_no": e.InternalNo,
+		"amount":      e.Amount,
+		"task_id":     e.TaskID,
+	})
+	if err != nil {
+		return fmt.Errorf("序列化通知数据失败: %w", err)
+	}
+
+	notification := &models.Notification{
+		UserID:      e.UserID,
+		Title:       "支付成功",
+		Content:     fmt.Sprintf("订单%s已支付成功，金额%s元", e.InternalNo, e.Amount),
+		Type:        "payment",
+		RelatedID:   &e.TradeID,
+		RelatedType: "trade",
+		Data:        string(data),
+	}
+	if err := db.WithContext(ctx).Create(notification).Error; err != nil {
+		return fmt.Errorf("创建支付通知失败: %w", err)
+	}
+	return nil
+}
@@ -2,143 +2,558 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/internal/events"
 	"task-platform-api/internal/models"
+	"task-platform-api/pkg/money"
 	"task-platform-api/pkg/payment"
 	"task-platform-api/pkg/utils"
 )
 
 // CreatePrePayOrderRequest 预支付订单请求
 type CreatePrePayOrderRequest struct {
-	UserID      uint64 `json:"user_id"`
-	TaskID      uint64 `json:"task_id"`
-	OrderType   string `json:"order_type"`
-	Amount      float64 `json:"amount"`
-	Remark      string `json:"remark"`
-	ClientIP    string `json:"client_ip"`
+	UserID        uint64   `json:"user_id"`
+	TaskID        uint64   `json:"task_id"`
+	Remark        string   `json:"remark"`
+	ClientIP      string   `json:"client_ip"`
+	PaymentMethod string   `json:"payment_method"` // 支付渠道，为空时使用默认渠道
+	OpenID        string   `json:"openid"`         // 微信JSAPI支付的用户标识
+	Scene         string   `json:"scene"`          // 支付场景：page、app
+	CouponCodes   []string `json:"coupon_codes"`   // 使用的优惠码，可叠加时传多个
+}
+
+// tradeCloseGrace 交易过期后延迟关闭的时长，留出渠道支付结果回传的时间
+const tradeCloseGrace = 5 * time.Minute
+
+// paymentResult 渠道返回的支付结果，来自异步通知或主动查询
+type paymentResult struct {
+	Status       string
+	Amount       money.Money
+	ThirdPartyNo string
 }
 
 // PaymentService 支付服务
 type PaymentService struct {
-	db         interface{} // 临时使用interface{}避免GORM导入问题
-	sqbClient *payment.ShouqianbaClient
+	db         *gorm.DB
+	config     config.PaymentConfig
+	gateways   *payment.Registry
+	bus        *events.Bus
+	fees       *FeeEngine
+	financeIDs []uint64
 }
 
-// NewPaymentService 创建支付服务
-func NewPaymentService(db interface{}, sqbClient *payment.ShouqianbaClient) *PaymentService {
+// NewPaymentService 创建支付服务，交易关闭后收到的支付成功通知财务人员人工处理
+func NewPaymentService(db *gorm.DB, cfg config.PaymentConfig, gateways *payment.Registry, bus *events.Bus, fees *FeeEngine, admin config.AdminConfig) *PaymentService {
 	return &PaymentService{
 		db:         db,
-		sqbClient: sqbClient,
+		config:     cfg,
+		gateways:   gateways,
+		bus:        bus,
+		fees:       fees,
+		financeIDs: admin.FinanceUserIDs,
+	}
+}
+
+// gatewayFor 按交易记录的支付方式选择渠道，历史交易未记录支付方式时使用默认渠道
+func (s *PaymentService) gatewayFor(trade *models.Trade) (payment.PaymentGateway, error) {
+	gateway, ok := s.gateways.Get(trade.PaymentMethod)
+	if !ok {
+		return nil, ErrPaymentMethodUnsupported.WithMessage(fmt.Sprintf("支付渠道未启用: %s", trade.PaymentMethod))
 	}
+	return gateway, nil
 }
 
-// CreatePrePayOrder 创建预支付订单
+// CreatePrePayOrder 为草稿任务创建预付款订单，按服务费规则计算任务服务费并写入任务，
+// 金额为任务赏金、发布者服务费与保证金之和扣除优惠券抵扣，支付成功后冻结托管并发布任务。
+// 同一任务只能有一笔待支付的预付款，已过支付期限的订单向渠道核实后关闭，未过期的须先完成支付或等待过期
 func (s *PaymentService) CreatePrePayOrder(ctx context.Context, req *CreatePrePayOrderRequest) (*models.Trade, *payment.PrePayResponseData, error) {
-	// 生成订单号
-	orderNo := utils.GenerateOrderNo()
-
-	// 创建交易记录
-	taskIDPtr := &req.TaskID
-	expireTime := time.Now().Add(15 * time.Minute)
-	trade := &models.Trade{
-		InternalNo:   orderNo,
-		UserID:       req.UserID,
-		TaskID:       taskIDPtr,
-		TradeType:    string(req.OrderType),
-		Amount:       req.Amount,
-		Status:       0, // 待支付
-		PayTime:      nil,
-		Description:  req.Remark,
-		ExpireTime:   &expireTime,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
-	}
-
-	// 保存交易记录 - 暂时跳过实际数据库操作
-	// if err := s.db.WithContext(ctx).Create(trade).Error; err != nil {
-	//     return nil, nil, fmt.Errorf("创建交易记录失败: %w", err)
-	// }
-
-// 调用收钱吧预支付接口 - 暂时返回模拟数据
-	prePayResp := &payment.PrePayResponseData{
-		TradeNo: "mock_trade_no_" + orderNo,
-		PayURL:  "https://mock.pay.url/" + orderNo,
-		QRCode:  "mock_qr_code_" + orderNo,
+	gateway, ok := s.gateways.Get(req.PaymentMethod)
+	if !ok {
+		return nil, nil, ErrPaymentMethodUnsupported
+	}
+	if err := s.closeExpiredPrepays(ctx, req.TaskID); err != nil {
+		return nil, nil, err
+	}
+
+	var trade *models.Trade
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		task, err := lockTask(tx, req.TaskID)
+		if err != nil {
+			return err
+		}
+		if task.PublisherID != req.UserID {
+			return ErrTaskForbidden
+		}
+		if !task.IsDraft() {
+			return ErrTaskInvalidTransition.WithMessage("只有草稿状态的任务可以支付预付款")
+		}
+
+		prepay, err := findPaidPrepay(tx, task.ID)
+		if err != nil {
+			return err
+		}
+		if prepay != nil {
+			return ErrTaskAlreadyPaid
+		}
+		var pending int64
+		if err := tx.Model(&models.Trade{}).
+			Where("task_id = ? AND trade_type = ? AND status = ?", task.ID, models.TradeTypePrepay, models.TradeStatusPending).
+			Count(&pending).Error; err != nil {
+			return fmt.Errorf("查询待支付预付款失败: %w", err)
+		}
+		if pending > 0 {
+			return ErrPrepayPending
+		}
+
+		if _, err := s.fees.ApplyQuote(tx, task); err != nil {
+			return err
+		}
+		quote, err := quoteCoupons(tx, req.UserID, task, req.CouponCodes, true)
+		if err != nil {
+			return err
+		}
+
+		expireTime := time.Now().Add(15 * time.Minute)
+		trade = &models.Trade{
+			InternalNo:    utils.GenerateOrderNo(),
+			UserID:        req.UserID,
+			TaskID:        &task.ID,
+			TradeType:     models.TradeTypePrepay,
+			Amount:        quote.PayableAmount,
+			Discount:      quote.Discount,
+			Status:        models.TradeStatusPending,
+			PaymentMethod: gateway.Name(),
+			Description:   req.Remark,
+			ExpireTime:    &expireTime,
+		}
+		if trade.Description == "" {
+			trade.Description = fmt.Sprintf("任务「%s」预付款(含服务费%s、保证金%s)", task.Title, task.GetPublisherFee(), task.GetDepositAmount())
+			if quote.Discount.IsPositive() {
+				trade.Description = fmt.Sprintf("任务「%s」预付款(含服务费%s、保证金%s，优惠券抵扣%s)", task.Title, task.GetPublisherFee(), task.GetDepositAmount(), quote.Discount)
+			}
+		}
+		if err := tx.Create(trade).Error; err != nil {
+			return fmt.Errorf("创建交易记录失败: %w", err)
+		}
+		return reserveCoupons(tx, quote, trade)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 交易记录先落库再调用渠道，渠道失败时标记交易失败，便于对账追溯
+	prePayResp, err := gateway.PrePay(&payment.PrePayRequest{
+		OrderNo:     trade.InternalNo,
+		Amount:      trade.Amount,
+		Subject:     "任务预付款",
+		Description: trade.Description,
+		ExpireTime:  int(time.Until(*trade.ExpireTime).Seconds()),
+		ClientIP:    req.ClientIP,
+		OpenID:      req.OpenID,
+		Scene:       req.Scene,
+	})
+	if err != nil {
+		if updateErr := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(trade).Update("status", models.TradeStatusFailed).Error; err != nil {
+				return fmt.Errorf("更新交易状态失败: %w", err)
+			}
+			return releaseTradeCoupons(tx, trade)
+		}); updateErr != nil {
+			return nil, nil, updateErr
+		}
+		trade.Status = models.TradeStatusFailed
+		return nil, nil, ErrPaymentGateway.WithMessage(fmt.Sprintf("创建支付订单失败: %v", err))
 	}
 
 	// 更新交易记录
 	trade.ThirdPartyNo = prePayResp.TradeNo
-	// s.db.WithContext(ctx).Save(trade)
+	if err := s.db.WithContext(ctx).Model(trade).Update("third_party_no", trade.ThirdPartyNo).Error; err != nil {
+		return nil, nil, fmt.Errorf("更新交易记录失败: %w", err)
+	}
 
 	return trade, prePayResp, nil
 }
 
-// ProcessPaymentCallback 处理支付回调
-func (s *PaymentService) ProcessPaymentCallback(ctx context.Context, data map[string]string) error {
-	// 验证签名
-	if !s.sqbClient.VerifyNotification(data) {
-		return fmt.Errorf("签名验证失败")
+// closeExpiredPrepays 关闭任务已过支付期限的待支付预付款，渠道已收款的按支付成功处理
+func (s *PaymentService) closeExpiredPrepays(ctx context.Context, taskID uint64) error {
+	var trades []models.Trade
+	if err := s.db.WithContext(ctx).
+		Where("task_id = ? AND trade_type = ? AND status = ? AND expire_time < ?",
+			taskID, models.TradeTypePrepay, models.TradeStatusPending, time.Now()).
+		Find(&trades).Error; err != nil {
+		return fmt.Errorf("查询过期预付款失败: %w", err)
+	}
+	for i := range trades {
+		if _, err := s.closeExpiredTrade(ctx, &trades[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PreviewPrePay 试算草稿任务的预付款金额和优惠券抵扣，不写入任务服务费也不占用优惠券
+func (s *PaymentService) PreviewPrePay(ctx context.Context, userID, taskID uint64, couponCodes []string) (*CouponQuote, error) {
+	db := s.db.WithContext(ctx)
+	var task models.Task
+	if err := db.First(&task, taskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("查询任务失败: %w", err)
+	}
+	if task.PublisherID != userID {
+		return nil, ErrTaskForbidden
+	}
+	if !task.IsDraft() {
+		return nil, ErrTaskInvalidTransition.WithMessage("只有草稿状态的任务可以支付预付款")
+	}
+
+	fees, err := s.fees.Quote(db, &task)
+	if err != nil {
+		return nil, err
 	}
+	task.PublisherFee = fees.PublisherFee
+	task.TakerFee = fees.TakerFee
+	return quoteCoupons(db, userID, &task, couponCodes, false)
+}
 
-	tradeNo := data["trade_no"]
-	amountStr := data["amount"]
+// ProcessPaymentCallback 处理支付回调，由对应渠道校验签名并解析通知后更新交易，provider为空时使用默认渠道
+func (s *PaymentService) ProcessPaymentCallback(ctx context.Context, provider string, header http.Header, body []byte) error {
+	gateway, ok := s.gateways.Get(provider)
+	if !ok {
+		return ErrPaymentMethodUnsupported
+	}
 
-	// 查询交易记录 - 暂时跳过
-	/*
-		var trade models.Trade
-		if err := s.db.WithContext(ctx).Where("internal_no = ?", orderNo).First(&trade).Error; err != nil {
-			return fmt.Errorf("交易记录不存在: %w", err)
+	notification, err := gateway.ParseNotification(header, body)
+	if err != nil {
+		if errors.Is(err, payment.ErrInvalidSignature) {
+			return ErrPaymentSignature
 		}
+		return ErrInvalidParam.WithMessage(fmt.Sprintf("回调数据错误: %v", err))
+	}
 
-		// 检查交易状态
-		if trade.Status == 1 { // 1=已支付
-			return nil // 已处理，直接返回
+	var trade models.Trade
+	if err := s.db.WithContext(ctx).Where("internal_no = ?", notification.OrderNo).First(&trade).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTradeNotFound
 		}
+		return fmt.Errorf("查询交易记录失败: %w", err)
+	}
+	if trade.PaymentMethod != "" && trade.PaymentMethod != gateway.Name() {
+		return ErrPaymentMethodUnsupported.WithMessage("通知渠道与交易支付方式不一致")
+	}
 
-		// 解析金额
-		amount, err := strconv.ParseFloat(amountStr, 64)
-		if err != nil {
-			return fmt.Errorf("金额格式错误: %w", err)
+	return s.applyPaymentResult(ctx, &trade, &paymentResult{
+		Status:       notification.Status,
+		Amount:       notification.Amount,
+		ThirdPartyNo: notification.TradeNo,
+	})
+}
+
+// applyPaymentResult 校验金额后在事务内更新交易并发布支付成功事件。
+// 以交易待支付状态为条件更新，重复通知直接返回成功，保证事件每笔交易只发布一次；
+// 只有渠道明确失败或关闭时才将交易置为失败，支付中的结果保持待支付；
+// 交易已关闭或失败后才收到的支付成功记录第三方交易号并通知财务人工退款
+func (s *PaymentService) applyPaymentResult(ctx context.Context, trade *models.Trade, result *paymentResult) error {
+	if trade.IsPaid() {
+		return nil
+	}
+
+	var event *events.PaymentSucceeded
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 与任务操作保持先锁任务再锁交易的顺序
+		if trade.TaskID != nil {
+			if _, err := lockTask(tx, *trade.TaskID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(trade, trade.ID).Error; err != nil {
+			return fmt.Errorf("查询交易记录失败: %w", err)
+		}
+		if !trade.IsPending() {
+			if result.Status == payment.PayStatusSuccess &&
+				(trade.Status == models.TradeStatusClosed || trade.Status == models.TradeStatusFailed) {
+				return s.recordLatePayment(tx, trade, result)
+			}
+			return nil
 		}
 
-		// 验证金额
-		if amount != trade.Amount {
-			return fmt.Errorf("金额不匹配: 预期%.2f, 实际%.2f", trade.Amount, amount)
+		switch result.Status {
+		case payment.PayStatusSuccess:
+		case payment.PayStatusFailed, payment.PayStatusClosed:
+			if err := tx.Model(trade).Update("status", models.TradeStatusFailed).Error; err != nil {
+				return fmt.Errorf("更新交易状态失败: %w", err)
+			}
+			trade.Status = models.TradeStatusFailed
+			return releaseTradeCoupons(tx, trade)
+		default:
+			return nil
+		}
+		if result.Amount != trade.Amount {
+			return ErrPaymentAmountMismatch.WithMessage(fmt.Sprintf("支付金额不匹配: 预期%s, 实际%s", trade.Amount, result.Amount))
 		}
-	*/
 
-	_ = tradeNo
-	_ = amountStr
+		now := time.Now()
+		updates := map[string]interface{}{
+			"status":   models.TradeStatusPaid,
+			"pay_time": now,
+		}
+		if result.ThirdPartyNo != "" {
+			updates["third_party_no"] = result.ThirdPartyNo
+			trade.ThirdPartyNo = result.ThirdPartyNo
+		}
+		res := tx.Model(&models.Trade{}).
+			Where("trade_id = ? AND status = ?", trade.ID, models.TradeStatusPending).
+			Updates(updates)
+		if res.Error != nil {
+			return fmt.Errorf("更新交易状态失败: %w", res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		trade.Status = models.TradeStatusPaid
+		trade.PayTime = &now
 
-	return fmt.Errorf("支付回调处理暂未实现")
+		event = &events.PaymentSucceeded{
+			TradeID:      trade.ID,
+			InternalNo:   trade.InternalNo,
+			ThirdPartyNo: trade.ThirdPartyNo,
+			TradeType:    trade.TradeType,
+			UserID:       trade.UserID,
+			TaskID:       trade.TaskID,
+			Amount:       trade.Amount,
+			PaidAt:       now,
+		}
+		return s.bus.Publish(contextWithTx(ctx, tx), event)
+	})
+	if err != nil {
+		return err
+	}
+
+	if event != nil {
+		s.bus.Dispatch(ctx, event)
+	}
+	return nil
 }
 
-// QueryPaymentStatus 查询支付状态
-func (s *PaymentService) QueryPaymentStatus(ctx context.Context, orderNo string) (*models.Trade, error) {
-	// 暂时返回模拟数据
-	trade := &models.Trade{
-		ID:          1,
-		InternalNo:  orderNo,
-		Status:      0, // 待支付
-		Amount:      100.00,
-		TradeType:   "prepay",
-		Description: "模拟交易",
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+// recordLatePayment 记录交易关闭后才到达的支付成功，不改变交易状态，
+// 仅首次记录时通知财务核实退款并告知用户，重复通知不再提醒
+func (s *PaymentService) recordLatePayment(tx *gorm.DB, trade *models.Trade, result *paymentResult) error {
+	now := time.Now()
+	updates := map[string]interface{}{"pay_time": now}
+	if result.ThirdPartyNo != "" {
+		updates["third_party_no"] = result.ThirdPartyNo
+	}
+	res := tx.Model(&models.Trade{}).
+		Where("trade_id = ? AND pay_time IS NULL", trade.ID).
+		Updates(updates)
+	if res.Error != nil {
+		return fmt.Errorf("记录关闭后支付失败: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil
+	}
+	trade.PayTime = &now
+	if result.ThirdPartyNo != "" {
+		trade.ThirdPartyNo = result.ThirdPartyNo
+	}
+
+	data := map[string]interface{}{
+		"trade_id":       trade.ID,
+		"internal_no":    trade.InternalNo,
+		"third_party_no": trade.ThirdPartyNo,
+		"payment_method": trade.PaymentMethod,
+		"amount":         result.Amount,
+	}
+	for _, userID := range s.financeIDs {
+		if err := createNotification(tx, &models.Notification{
+			UserID:      userID,
+			Title:       "交易关闭后收到支付，需人工退款",
+			Content:     fmt.Sprintf("交易%s已关闭或失败，渠道回传支付成功%s元(第三方交易号%s)，请核实后原路退款", trade.InternalNo, result.Amount, trade.ThirdPartyNo),
+			Type:        "system",
+			RelatedID:   &trade.ID,
+			RelatedType: "trade",
+		}, data); err != nil {
+			return err
+		}
 	}
-	return trade, nil
+	return createNotification(tx, &models.Notification{
+		UserID:      trade.UserID,
+		Title:       "订单已关闭，款项将退回",
+		Content:     fmt.Sprintf("订单%s已关闭，您支付的%s元平台核实后将原路退回", trade.InternalNo, result.Amount),
+		Type:        "system",
+		RelatedID:   &trade.ID,
+		RelatedType: "trade",
+	}, data)
 }
 
-// CreateRefund 创建退款
-func (s *PaymentService) CreateRefund(ctx context.Context, tradeID uint64, reason string) error {
-	// 暂时不实现
-	return fmt.Errorf("退款功能暂未实现")
+// QueryPaymentStatus 查询支付状态。待支付交易超过查询延迟仍未收到回调时主动向渠道查询，
+// 结果与回调走相同的幂等更新；同一交易的主动查询按退避间隔限流，未到查询时间直接返回本地状态
+func (s *PaymentService) QueryPaymentStatus(ctx context.Context, userID uint64, orderNo string) (*models.Trade, error) {
+	var trade models.Trade
+	if err := s.db.WithContext(ctx).Where("internal_no = ? AND user_id = ?", orderNo, userID).First(&trade).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTradeNotFound
+		}
+		return nil, fmt.Errorf("查询交易记录失败: %w", err)
+	}
+	if !trade.IsPending() {
+		return &trade, nil
+	}
+
+	claimed, err := s.claimStatusQuery(ctx, &trade)
+	if err != nil || !claimed {
+		return &trade, err
+	}
+
+	// 待支付交易向渠道查询最新状态，未出结果时原样返回
+	gateway, err := s.gatewayFor(&trade)
+	if err != nil {
+		return nil, err
+	}
+	status, err := gateway.QueryPayStatus(&payment.PayStatusRequest{
+		OrderNo: trade.InternalNo,
+		TradeNo: trade.ThirdPartyNo,
+	})
+	if err != nil {
+		return nil, ErrPaymentGateway.WithMessage(fmt.Sprintf("查询支付状态失败: %v", err))
+	}
+	if status.Status != payment.PayStatusSuccess && status.Status != payment.PayStatusFailed && status.Status != payment.PayStatusClosed {
+		return &trade, nil
+	}
+
+	if err := s.applyPaymentResult(ctx, &trade, &paymentResult{
+		Status:       status.Status,
+		Amount:       status.Amount,
+		ThirdPartyNo: status.TradeNo,
+	}); err != nil {
+		return nil, err
+	}
+	return &trade, nil
 }
 
-// ProcessRefundCallback 处理退款回调
-func (s *PaymentService) ProcessRefundCallback(ctx context.Context, data map[string]string) error {
-	// 暂时不实现
-	return fmt.Errorf("退款回调处理暂未实现")
-}
\ No newline at end of file
+// claimStatusQuery 判断本次请求是否应向渠道查询：交易创建未满查询延迟时等待回调，
+// 否则以下次查询时间为条件占用本次查询并按查询次数推迟下次查询，并发请求只有一个能占用成功
+func (s *PaymentService) claimStatusQuery(ctx context.Context, trade *models.Trade) (bool, error) {
+	cfg := s.config.StatusQuery
+	now := time.Now()
+	if now.Sub(trade.CreatedAt) < time.Duration(cfg.Delay)*time.Second {
+		return false, nil
+	}
+	if trade.NextQueryTime != nil && now.Before(*trade.NextQueryTime) {
+		return false, nil
+	}
+
+	next := now.Add(statusQueryBackoff(cfg, trade.QueryCount))
+	result := s.db.WithContext(ctx).Model(&models.Trade{}).
+		Where("trade_id = ? AND status = ? AND (next_query_time IS NULL OR next_query_time <= ?)", trade.ID, models.TradeStatusPending, now).
+		Updates(map[string]interface{}{
+			"query_count":     gorm.Expr("query_count + 1"),
+			"next_query_time": next,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("更新交易查询时间失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	trade.QueryCount++
+	trade.NextQueryTime = &next
+	return true, nil
+}
+
+// statusQueryBackoff 第queryCount+1次查询后到下次查询的间隔，从Interval开始逐次翻倍，不超过MaxInterval
+func statusQueryBackoff(cfg config.StatusQueryConfig, queryCount int) time.Duration {
+	interval := time.Duration(cfg.Interval) * time.Second
+	maxInterval := time.Duration(cfg.MaxInterval) * time.Second
+	if maxInterval < interval {
+		maxInterval = interval
+	}
+	for i := 0; i < queryCount && interval < maxInterval; i++ {
+		interval *= 2
+	}
+	return min(interval, maxInterval)
+}
+
+// CloseExpiredTrades 关闭超过支付期限仍未支付的交易，返回关闭数量。
+// 关闭前先向渠道查询，用户在过期前已支付但通知未送达的交易按支付成功处理；
+// 单笔交易处理失败时跳过并继续，失败原因汇总后返回由调度器记录
+func (s *PaymentService) CloseExpiredTrades(ctx context.Context) (int, error) {
+	var trades []models.Trade
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND expire_time < ?", models.TradeStatusPending, time.Now().Add(-tradeCloseGrace)).
+		Order("expire_time ASC").
+		Limit(100).
+		Find(&trades).Error; err != nil {
+		return 0, fmt.Errorf("查询过期交易失败: %w", err)
+	}
+
+	closed := 0
+	var errs []error
+	for i := range trades {
+		ok, err := s.closeExpiredTrade(ctx, &trades[i])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ok {
+			closed++
+		}
+	}
+
+	return closed, errors.Join(errs...)
+}
+
+// closeExpiredTrade 查询渠道后关闭单笔过期交易，渠道已收款时按支付成功处理，返回是否关闭
+func (s *PaymentService) closeExpiredTrade(ctx context.Context, trade *models.Trade) (bool, error) {
+	gateway, err := s.gatewayFor(trade)
+	if err != nil {
+		return false, fmt.Errorf("关闭交易%s失败: %w", trade.InternalNo, err)
+	}
+	status, err := gateway.QueryPayStatus(&payment.PayStatusRequest{
+		OrderNo: trade.InternalNo,
+		TradeNo: trade.ThirdPartyNo,
+	})
+	if err != nil {
+		return false, fmt.Errorf("查询交易%s支付状态失败: %w", trade.InternalNo, err)
+	}
+
+	if status.Status == payment.PayStatusSuccess {
+		if err := s.applyPaymentResult(ctx, trade, &paymentResult{
+			Status:       status.Status,
+			Amount:       status.Amount,
+			ThirdPartyNo: status.TradeNo,
+		}); err != nil {
+			return false, fmt.Errorf("同步交易%s支付结果失败: %w", trade.InternalNo, err)
+		}
+		return false, nil
+	}
+
+	// 以待支付状态为条件关闭，避免覆盖查询期间到达的支付通知；关闭成功时释放占用的优惠券
+	var affected int64
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Trade{}).
+			Where("trade_id = ? AND status = ?", trade.ID, models.TradeStatusPending).
+			Update("status", models.TradeStatusClosed)
+		if result.Error != nil {
+			return result.Error
+		}
+		affected = result.RowsAffected
+		if affected == 0 {
+			return nil
+		}
+		trade.Status = models.TradeStatusClosed
+		return releaseTradeCoupons(tx, trade)
+	}); err != nil {
+		return false, fmt.Errorf("关闭交易%s失败: %w", trade.InternalNo, err)
+	}
+	return affected > 0, nil
+}
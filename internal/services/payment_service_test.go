@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/internal/events"
+	"task-platform-api/internal/models"
+	"task-platform-api/internal/testutil"
+	"task-platform-api/pkg/payment"
+)
+
+// draftTaskRows 发布者1的草稿任务1
+func draftTaskRows() testutil.StubRows {
+	return testutil.StubRows{
+		Match:   "FROM `tasks`",
+		Columns: []string{"task_id", "publisher_id", "title", "amount", "status", "deadline"},
+		Values:  [][]driver.Value{{int64(1), int64(1), "测试任务", []byte("100.00"), int64(models.TaskStatusDraft), time.Now().Add(24 * time.Hour)}},
+	}
+}
+
+func newTestPaymentService(t *testing.T, db *gorm.DB) (*PaymentService, *payment.Registry) {
+	t.Helper()
+	gateways, err := payment.NewRegistry(&config.Config{
+		Payment: config.PaymentConfig{Provider: payment.ProviderFake},
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("初始化支付渠道失败: %v", err)
+	}
+	bus := events.NewBus(zap.NewNop())
+	return NewPaymentService(db, config.PaymentConfig{}, gateways, bus, NewFeeEngine(db, config.FeeConfig{}), config.AdminConfig{}), gateways
+}
+
+// newShouqianbaGateways 创建仅启用收钱吧的渠道注册表，收钱吧接口对任意请求返回body
+func newShouqianbaGateways(t *testing.T, body string) *payment.Registry {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	gateways, err := payment.NewRegistry(&config.Config{
+		Payment:    config.PaymentConfig{Provider: payment.ProviderShouqianba},
+		Shouqianba: config.ShouqianbaConfig{APIURL: server.URL},
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("初始化支付渠道失败: %v", err)
+	}
+	return gateways
+}
+
+func TestCreatePrePayOrderRejectsPendingPrepay(t *testing.T) {
+	db, stub := testutil.NewGorm(t,
+		draftTaskRows(),
+		testutil.StubRows{Match: "count(*)", Columns: []string{"count(*)"}, Values: [][]driver.Value{{int64(1)}}},
+	)
+	svc, _ := newTestPaymentService(t, db)
+
+	trade, _, err := svc.CreatePrePayOrder(context.Background(), &CreatePrePayOrderRequest{UserID: 1, TaskID: 1})
+	if !errors.Is(err, ErrPrepayPending) {
+		t.Fatalf("重复预付款应返回ErrPrepayPending, 实际: %v", err)
+	}
+	if trade != nil {
+		t.Fatalf("重复预付款不应返回交易: %+v", trade)
+	}
+	if inserts := stub.Executed("INSERT"); len(inserts) > 0 {
+		t.Fatalf("重复预付款不应创建记录: %s", inserts[0].Query)
+	}
+	if len(stub.Executed("ROLLBACK")) != 1 {
+		t.Fatal("重复预付款应回滚事务")
+	}
+}
+
+func TestCreatePrePayOrderClosesExpiredPrepay(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	db, stub := testutil.NewGorm(t,
+		testutil.StubRows{
+			Match:   "expire_time <",
+			Columns: []string{"trade_id", "user_id", "task_id", "trade_type", "amount", "internal_no", "status", "payment_method", "expire_time"},
+			Values:  [][]driver.Value{{int64(7), int64(1), int64(1), models.TradeTypePrepay, []byte("100.00"), "OLD001", int64(models.TradeStatusPending), payment.ProviderFake, expired}},
+		},
+		draftTaskRows(),
+		testutil.StubRows{Match: "FROM `users`", Columns: []string{"user_id", "level", "credit_score"}, Values: [][]driver.Value{{int64(1), int64(1), []byte("5.0")}}},
+		testutil.StubRows{Match: "count(*)", Columns: []string{"count(*)"}, Values: [][]driver.Value{{int64(0)}}},
+	)
+	svc, gateways := newTestPaymentService(t, db)
+	gateway, _ := gateways.Get(payment.ProviderFake)
+	if _, err := gateway.PrePay(&payment.PrePayRequest{OrderNo: "OLD001", Amount: 10000}); err != nil {
+		t.Fatalf("创建渠道旧订单失败: %v", err)
+	}
+
+	trade, _, err := svc.CreatePrePayOrder(context.Background(), &CreatePrePayOrderRequest{UserID: 1, TaskID: 1})
+	if err != nil {
+		t.Fatalf("旧订单过期后应能重新下单: %v", err)
+	}
+	if !trade.IsPending() || trade.Amount != 10000 {
+		t.Fatalf("新订单状态或金额错误: status=%d amount=%s", trade.Status, trade.Amount)
+	}
+
+	// 参数依次为status、update_time、trade_id、原status
+	closed := false
+	for _, stmt := range stub.Executed("UPDATE `trades` SET `status`") {
+		if len(stmt.Args) == 4 && stmt.Args[0] == int64(models.TradeStatusClosed) && stmt.Args[2] == int64(7) {
+			closed = true
+		}
+	}
+	if !closed {
+		t.Fatal("过期的待支付预付款应先关闭")
+	}
+	if len(stub.Executed("INSERT INTO `trades`")) != 1 {
+		t.Fatal("应创建一笔新的预付款交易")
+	}
+}
+
+// paidTradeRows 用户1的预付款交易5，status为数据库中的交易状态
+func paidTradeRows(status int8) testutil.StubRows {
+	return testutil.StubRows{
+		Match:   "FROM `trades`",
+		Columns: []string{"trade_id", "user_id", "trade_type", "amount", "internal_no", "status", "payment_method"},
+		Values:  [][]driver.Value{{int64(5), int64(1), models.TradeTypePrepay, []byte("100.00"), "T001", int64(status), payment.ProviderFake}},
+	}
+}
+
+// countPaymentSucceeded 订阅支付成功事件并返回发布次数
+func countPaymentSucceeded(svc *PaymentService) *int {
+	published := 0
+	svc.bus.Subscribe(events.PaymentSucceededEvent, func(context.Context, events.Event) error {
+		published++
+		return nil
+	})
+	return &published
+}
+
+func TestApplyPaymentResultIsIdempotent(t *testing.T) {
+	tests := []struct {
+		name          string
+		tradeStatus   int8
+		lockedStatus  int8
+		affected      int64
+		wantPublished int
+		wantUpdate    bool
+	}{
+		{name: "首次通知", tradeStatus: models.TradeStatusPending, lockedStatus: models.TradeStatusPending, affected: 1, wantPublished: 1, wantUpdate: true},
+		{name: "重复通知已支付交易", tradeStatus: models.TradeStatusPaid, lockedStatus: models.TradeStatusPaid},
+		{name: "加锁读取时已被并发通知处理", tradeStatus: models.TradeStatusPending, lockedStatus: models.TradeStatusPaid},
+		{name: "条件更新未命中待支付状态", tradeStatus: models.TradeStatusPending, lockedStatus: models.TradeStatusPending, affected: 0, wantUpdate: true},
+	}
+	for _, tt := range tests {
+		db, stub := testutil.NewGorm(t, paidTradeRows(tt.lockedStatus))
+		stub.AffectRows("UPDATE `trades`", tt.affected)
+		svc, _ := newTestPaymentService(t, db)
+		published := countPaymentSucceeded(svc)
+
+		trade := &models.Trade{ID: 5, UserID: 1, TradeType: models.TradeTypePrepay, Amount: 10000, InternalNo: "T001", Status: tt.tradeStatus}
+		result := &paymentResult{Status: payment.PayStatusSuccess, Amount: 10000, ThirdPartyNo: "TP001"}
+		if err := svc.applyPaymentResult(context.Background(), trade, result); err != nil {
+			t.Errorf("%s: 处理支付结果失败: %v", tt.name, err)
+			continue
+		}
+		if *published != tt.wantPublished {
+			t.Errorf("%s: 支付成功事件应发布%d次, 实际%d次", tt.name, tt.wantPublished, *published)
+		}
+		if updated := len(stub.Executed("UPDATE `trades`")) > 0; updated != tt.wantUpdate {
+			t.Errorf("%s: 是否更新交易状态应为%v", tt.name, tt.wantUpdate)
+		}
+	}
+}
+
+func TestApplyPaymentResultRejectsAmountMismatch(t *testing.T) {
+	db, stub := testutil.NewGorm(t, paidTradeRows(models.TradeStatusPending))
+	svc, _ := newTestPaymentService(t, db)
+	published := countPaymentSucceeded(svc)
+
+	trade := &models.Trade{ID: 5, UserID: 1, TradeType: models.TradeTypePrepay, Amount: 10000, InternalNo: "T001", Status: models.TradeStatusPending}
+	err := svc.applyPaymentResult(context.Background(), trade, &paymentResult{Status: payment.PayStatusSuccess, Amount: 9999})
+	if !errors.Is(err, ErrPaymentAmountMismatch) {
+		t.Fatalf("支付金额不一致应返回ErrPaymentAmountMismatch, 实际: %v", err)
+	}
+	if *published != 0 {
+		t.Fatal("金额不一致时不应发布支付成功事件")
+	}
+	if len(stub.Executed("UPDATE `")) > 0 || len(stub.Executed("ROLLBACK")) != 1 {
+		t.Fatal("金额不一致时不应更新交易并应回滚事务")
+	}
+}
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/internal/models"
+	"task-platform-api/pkg/payment"
+)
+
+// 对账单来源
+const (
+	StatementSourceAPI = "api" // 渠道接口下载
+	StatementSourceCSV = "csv" // 文件导入
+)
+
+// ReconcileRequest 对账请求
+type ReconcileRequest struct {
+	Provider string                   // 支付渠道，为空时使用默认渠道
+	BillDate time.Time                // 账单日
+	Source   string                   // 对账单来源
+	Entries  []payment.StatementEntry // 渠道对账单明细
+}
+
+// ReconciliationService 渠道对账服务，核对渠道对账单与本地交易
+type ReconciliationService struct {
+	db         *gorm.DB
+	gateways   *payment.Registry
+	financeIDs []uint64
+}
+
+// NewReconciliationService 创建对账服务
+func NewReconciliationService(db *gorm.DB, gateways *payment.Registry, cfg config.AdminConfig) *ReconciliationService {
+	return &ReconciliationService{
+		db:         db,
+		gateways:   gateways,
+		financeIDs: cfg.FinanceUserIDs,
+	}
+}
+
+// FetchStatement 通过渠道接口下载指定日期的对账单
+func (s *ReconciliationService) FetchStatement(ctx context.Context, provider string, billDate time.Time) ([]payment.StatementEntry, error) {
+	gateway, ok := s.gateways.Get(provider)
+	if !ok {
+		return nil, ErrPaymentMethodUnsupported.WithMessage(fmt.Sprintf("支付渠道未启用: %s", provider))
+	}
+	downloader, ok := gateway.(payment.StatementDownloader)
+	if !ok {
+		return nil, ErrPaymentMethodUnsupported.WithMessage(fmt.Sprintf("渠道%s不支持下载对账单，请导入CSV对账单", gateway.Name()))
+	}
+	entries, err := downloader.DownloadStatement(billDate)
+	if err != nil {
+		return nil, ErrPaymentGateway.WithMessage(fmt.Sprintf("下载对账单失败: %v", err))
+	}
+	return entries, nil
+}
+
+// ReconcileDaily 对所有支持下载对账单的渠道核对前一日账单，已生成报告的渠道跳过，返回本次对账的渠道数
+func (s *ReconciliationService) ReconcileDaily(ctx context.Context) (int, error) {
+	billDate := startOfDay(time.Now()).AddDate(0, 0, -1)
+
+	reconciled := 0
+	for _, name := range s.gateways.Names() {
+		gateway, _ := s.gateways.Get(name)
+		if _, ok := gateway.(payment.StatementDownloader); !ok {
+			continue
+		}
+
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&models.ReconciliationReport{}).
+			Where("provider = ? AND bill_date = ?", name, billDate).
+			Count(&count).Error; err != nil {
+			return reconciled, fmt.Errorf("查询对账报告失败: %w", err)
+		}
+		if count > 0 {
+			continue
+		}
+
+		entries, err := s.FetchStatement(ctx, name, billDate)
+		if err != nil {
+			return reconciled, fmt.Errorf("渠道%s: %w", name, err)
+		}
+		if _, err := s.Reconcile(ctx, &ReconcileRequest{
+			Provider: name,
+			BillDate: billDate,
+			Source:   StatementSourceAPI,
+			Entries:  entries,
+		}); err != nil {
+			return reconciled, fmt.Errorf("渠道%s: %w", name, err)
+		}
+		reconciled++
+	}
+	return reconciled, nil
+}
+
+// Reconcile 按商户订单号或第三方交易号匹配对账单与本地交易，归类差异后写入对账报告并通知财务。
+// 同一渠道同一账单日重新对账时覆盖原报告
+func (s *ReconciliationService) Reconcile(ctx context.Context, req *ReconcileRequest) (*models.ReconciliationReport, error) {
+	gateway, ok := s.gateways.Get(req.Provider)
+	if !ok {
+		return nil, ErrPaymentMethodUnsupported.WithMessage(fmt.Sprintf("支付渠道未启用: %s", req.Provider))
+	}
+	provider := gateway.Name()
+	billDate := startOfDay(req.BillDate)
+
+	// 账单日内本地已收款的交易，未记录支付方式的历史交易属于默认渠道
+	db := s.db.WithContext(ctx)
+	query := db.Where("status IN ? AND pay_time >= ? AND pay_time < ?",
+		[]int8{models.TradeStatusPaid, models.TradeStatusRefunded}, billDate, billDate.AddDate(0, 0, 1))
+	if provider == s.gateways.DefaultName() {
+		query = query.Where("payment_method IN ?", []string{provider, ""})
+	} else {
+		query = query.Where("payment_method = ?", provider)
+	}
+	var localTrades []models.Trade
+	if err := query.Find(&localTrades).Error; err != nil {
+		return nil, fmt.Errorf("查询本地交易失败: %w", err)
+	}
+
+	index := newTradeIndex(localTrades)
+	if err := s.loadUnmatchedTrades(db, index, req.Entries); err != nil {
+		return nil, err
+	}
+
+	report := &models.ReconciliationReport{
+		Provider: provider,
+		BillDate: billDate,
+		Source:   req.Source,
+		Status:   models.ReconciliationStatusBalanced,
+	}
+	for _, trade := range localTrades {
+		report.LocalCount++
+		report.LocalAmount += trade.Amount
+	}
+
+	var discrepancies []models.ReconciliationDiscrepancy
+	seen := make(map[uint64]bool)
+	for _, entry := range req.Entries {
+		if entry.Status == payment.PayStatusSuccess {
+			report.RemoteCount++
+			report.RemoteAmount += entry.Amount
+		}
+
+		trade := index.find(entry)
+		if trade != nil {
+			seen[trade.ID] = true
+		}
+		discrepancy := classifyDiscrepancy(trade, entry)
+		if discrepancy == nil {
+			if trade != nil {
+				report.MatchedCount++
+			}
+			continue
+		}
+		discrepancies = append(discrepancies, *discrepancy)
+	}
+	for i := range localTrades {
+		trade := &localTrades[i]
+		if seen[trade.ID] {
+			continue
+		}
+		discrepancies = append(discrepancies, models.ReconciliationDiscrepancy{
+			Type:         models.DiscrepancyMissingRemote,
+			TradeID:      &trade.ID,
+			InternalNo:   trade.InternalNo,
+			ThirdPartyNo: trade.ThirdPartyNo,
+			LocalAmount:  trade.Amount,
+			LocalStatus:  models.TradeStatusName(trade.Status),
+		})
+	}
+	report.DiscrepancyCount = len(discrepancies)
+	if report.DiscrepancyCount > 0 {
+		report.Status = models.ReconciliationStatusUnbalanced
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var previous models.ReconciliationReport
+		err := tx.Where("provider = ? AND bill_date = ?", provider, billDate).First(&previous).Error
+		if err == nil {
+			if err := tx.Where("report_id = ?", previous.ID).Delete(&models.ReconciliationDiscrepancy{}).Error; err != nil {
+				return fmt.Errorf("清理原对账差异失败: %w", err)
+			}
+			if err := tx.Delete(&previous).Error; err != nil {
+				return fmt.Errorf("清理原对账报告失败: %w", err)
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("查询对账报告失败: %w", err)
+		}
+
+		if err := tx.Create(report).Error; err != nil {
+			return fmt.Errorf("创建对账报告失败: %w", err)
+		}
+		if len(discrepancies) == 0 {
+			return nil
+		}
+		for i := range discrepancies {
+			discrepancies[i].ReportID = report.ID
+		}
+		if err := tx.CreateInBatches(discrepancies, 200).Error; err != nil {
+			return fmt.Errorf("写入对账差异失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Discrepancies = discrepancies
+
+	if err := s.notifyFinance(db, report); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// loadUnmatchedTrades 对账单中不在账单日本地交易内的记录，按订单号或第三方交易号补充查询本地交易，
+// 用于区分跨日支付、本地状态不一致和本地缺失
+func (s *ReconciliationService) loadUnmatchedTrades(db *gorm.DB, index *tradeIndex, entries []payment.StatementEntry) error {
+	var orderNos, tradeNos []string
+	for _, entry := range entries {
+		if index.find(entry) != nil {
+			continue
+		}
+		if entry.OrderNo != "" {
+			orderNos = append(orderNos, entry.OrderNo)
+		}
+		if entry.TradeNo != "" {
+			tradeNos = append(tradeNos, entry.TradeNo)
+		}
+	}
+
+	for start := 0; start < len(orderNos); start += 500 {
+		end := min(start+500, len(orderNos))
+		var trades []models.Trade
+		if err := db.Where("internal_no IN ?", orderNos[start:end]).Find(&trades).Error; err != nil {
+			return fmt.Errorf("查询本地交易失败: %w", err)
+		}
+		index.add(trades)
+	}
+	for start := 0; start < len(tradeNos); start += 500 {
+		end := min(start+500, len(tradeNos))
+		var trades []models.Trade
+		if err := db.Where("third_party_no IN ?", tradeNos[start:end]).Find(&trades).Error; err != nil {
+			return fmt.Errorf("查询本地交易失败: %w", err)
+		}
+		index.add(trades)
+	}
+	return nil
+}
+
+// notifyFinance 向财务人员发送对账结果通知
+func (s *ReconciliationService) notifyFinance(db *gorm.DB, report *models.ReconciliationReport) error {
+	billDate := report.BillDate.Format("2006-01-02")
+	title := fmt.Sprintf("%s %s对账完成，账平", report.Provider, billDate)
+	if !report.IsBalanced() {
+		title = fmt.Sprintf("%s %s对账存在%d笔差异", report.Provider, billDate, report.DiscrepancyCount)
+	}
+
+	counts := make(map[string]int)
+	for _, d := range report.Discrepancies {
+		counts[d.Type]++
+	}
+	content := fmt.Sprintf("渠道成功%d笔共%s元，本地已收款%d笔共%s元，核对一致%d笔；本地缺失%d笔，渠道缺失%d笔，金额不符%d笔，状态不符%d笔",
+		report.RemoteCount, report.RemoteAmount, report.LocalCount, report.LocalAmount, report.MatchedCount,
+		counts[models.DiscrepancyMissingLocal], counts[models.DiscrepancyMissingRemote],
+		counts[models.DiscrepancyAmountMismatch], counts[models.DiscrepancyStatusMismatch])
+
+	for _, userID := range s.financeIDs {
+		if err := createNotification(db, &models.Notification{
+			UserID:      userID,
+			Title:       title,
+			Content:     content,
+			Type:        "system",
+			RelatedID:   &report.ID,
+			RelatedType: "reconciliation",
+		}, map[string]interface{}{
+			"report_id":         report.ID,
+			"provider":          report.Provider,
+			"bill_date":         billDate,
+			"discrepancy_count": report.DiscrepancyCount,
+			"discrepancies":     counts,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// classifyDiscrepancy 比对单笔对账单记录与本地交易，一致或无需关注时返回nil
+func classifyDiscrepancy(trade *models.Trade, entry payment.StatementEntry) *models.ReconciliationDiscrepancy {
+	discrepancy := &models.ReconciliationDiscrepancy{
+		InternalNo:   entry.OrderNo,
+		ThirdPartyNo: entry.TradeNo,
+		RemoteAmount: entry.Amount,
+		RemoteStatus: entry.Status,
+	}
+	remoteCollected := entry.Status == payment.PayStatusSuccess
+
+	if trade == nil {
+		if !remoteCollected {
+			return nil
+		}
+		discrepancy.Type = models.DiscrepancyMissingLocal
+		return discrepancy
+	}
+
+	discrepancy.TradeID = &trade.ID
+	discrepancy.InternalNo = trade.InternalNo
+	if trade.ThirdPartyNo != "" {
+		discrepancy.ThirdPartyNo = trade.ThirdPartyNo
+	}
+	discrepancy.LocalAmount = trade.Amount
+	discrepancy.LocalStatus = models.TradeStatusName(trade.Status)
+
+	switch {
+	case remoteCollected != trade.IsCollected():
+		discrepancy.Type = models.DiscrepancyStatusMismatch
+	case !remoteCollected:
+		return nil
+	case entry.Amount != trade.Amount:
+		discrepancy.Type = models.DiscrepancyAmountMismatch
+	default:
+		return nil
+	}
+	return discrepancy
+}
+
+// tradeIndex 按内部交易号和第三方交易号索引本地交易
+type tradeIndex struct {
+	byInternalNo   map[string]*models.Trade
+	byThirdPartyNo map[string]*models.Trade
+}
+
+// newTradeIndex 创建交易索引
+func newTradeIndex(trades []models.Trade) *tradeIndex {
+	index := &tradeIndex{
+		byInternalNo:   make(map[string]*models.Trade),
+		byThirdPartyNo: make(map[string]*models.Trade),
+	}
+	index.add(trades)
+	return index
+}
+
+// add 加入交易，已存在的交易不覆盖
+func (i *tradeIndex) add(trades []models.Trade) {
+	for k := range trades {
+		trade := &trades[k]
+		if _, ok := i.byInternalNo[trade.InternalNo]; !ok {
+			i.byInternalNo[trade.InternalNo] = trade
+		}
+		if trade.ThirdPartyNo == "" {
+			continue
+		}
+		if _, ok := i.byThirdPartyNo[trade.ThirdPartyNo]; !ok {
+			i.byThirdPartyNo[trade.ThirdPartyNo] = trade
+		}
+	}
+}
+
+// find 先按商户订单号再按第三方交易号查找本地交易
+func (i *tradeIndex) find(entry payment.StatementEntry) *models.Trade {
+	if trade, ok := i.byInternalNo[entry.OrderNo]; ok && entry.OrderNo != "" {
+		return trade
+	}
+	if trade, ok := i.byThirdPartyNo[entry.TradeNo]; ok && entry.TradeNo != "" {
+		return trade
+	}
+	return nil
+}
+
+// startOfDay 当日零点
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,367 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"task-platform-api/internal/models"
+	"task-platform-api/pkg/money"
+	"task-platform-api/pkg/payment"
+	"task-platform-api/pkg/utils"
+)
+
+// refundPollDelay 退款发起后超过该时长仍处理中的，由定时任务向渠道查询结果
+const refundPollDelay = time.Minute
+
+// CreateRefundRequest 退款请求，金额为零时退还剩余可退金额
+type CreateRefundRequest struct {
+	UserID  uint64      `json:"user_id"`
+	TradeID uint64      `json:"trade_id"`
+	Amount  money.Money `json:"amount"`
+	Reason  string      `json:"reason"`
+}
+
+// CreateRefund 对已支付的预付款交易发起全额或部分退款，仅限任务取消等原因已解冻退回可用余额的托管资金，
+// 累计退款不超过解冻金额和交易金额。退款金额先从可用余额转入冻结余额，渠道返回结果后扣减或解冻
+func (s *PaymentService) CreateRefund(ctx context.Context, req *CreateRefundRequest) (*models.Refund, error) {
+	trade, err := s.findUserTrade(ctx, req.UserID, req.TradeID)
+	if err != nil {
+		return nil, err
+	}
+	gateway, err := s.gatewayFor(trade)
+	if err != nil {
+		return nil, err
+	}
+
+	var refund *models.Refund
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(trade, trade.ID).Error; err != nil {
+			return fmt.Errorf("查询交易记录失败: %w", err)
+		}
+		if trade.TradeType != models.TradeTypePrepay || !trade.IsPaid() {
+			return ErrTradeNotRefundable
+		}
+
+		// 只有已解冻退回可用余额的托管资金可以退款，处理中的退款同样占用可退金额，避免并发退款超额
+		refunded, err := sumRefunds(tx, trade.ID, models.RefundStatusProcessing, models.RefundStatusSuccess)
+		if err != nil {
+			return err
+		}
+		released, err := releasedEscrow(tx, trade)
+		if err != nil {
+			return err
+		}
+		remaining := min(released, trade.Amount) - refunded
+		if !remaining.IsPositive() {
+			return ErrTradeNotRefundable.WithMessage("任务未取消或预付款未退回钱包余额，不能退款")
+		}
+		amount := req.Amount
+		if amount.IsZero() {
+			amount = remaining
+		}
+		if !amount.IsPositive() || amount > remaining {
+			return ErrRefundAmountExceeded.WithMessage(fmt.Sprintf("退款金额超过可退金额%s", remaining))
+		}
+
+		refund = &models.Refund{
+			TradeID:      trade.ID,
+			RefundAmount: amount,
+			Reason:       req.Reason,
+			Status:       models.RefundStatusProcessing,
+			RefundNo:     utils.GenerateOrderNo(),
+		}
+		if err := tx.Create(refund).Error; err != nil {
+			return fmt.Errorf("创建退款记录失败: %w", err)
+		}
+		return holdRefund(tx, trade, refund)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 退款记录和资金冻结先落库再调用渠道，渠道异常时退款保持处理中，由定时任务查询确认
+	result, err := gateway.Refund(&payment.RefundRequest{
+		OrderNo:     trade.InternalNo,
+		RefundNo:    refund.RefundNo,
+		Amount:      refund.RefundAmount,
+		TotalAmount: trade.Amount,
+		Reason:      refund.Reason,
+		NotifyURL:   s.refundNotifyURL(gateway.Name()),
+	})
+	if err != nil {
+		return nil, ErrPaymentGateway.WithMessage(fmt.Sprintf("退款请求失败，稍后将自动确认结果: %v", err))
+	}
+	if err := s.applyRefundResult(ctx, refund, result); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// QueryRefund 查询当前用户的退款，处理中的退款会主动向渠道查询并同步结果
+func (s *PaymentService) QueryRefund(ctx context.Context, userID uint64, refundNo string) (*models.Refund, error) {
+	var refund models.Refund
+	if err := s.db.WithContext(ctx).Where("refund_no = ?", refundNo).First(&refund).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefundNotFound
+		}
+		return nil, fmt.Errorf("查询退款记录失败: %w", err)
+	}
+	trade, err := s.findUserTrade(ctx, userID, refund.TradeID)
+	if err != nil {
+		if errors.Is(err, ErrTradeNotFound) {
+			return nil, ErrRefundNotFound
+		}
+		return nil, err
+	}
+	if !refund.IsProcessing() {
+		return &refund, nil
+	}
+
+	if err := s.syncRefund(ctx, &refund, trade); err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// ProcessRefundCallback 处理退款结果通知，由对应渠道校验签名并解析，provider为空时使用默认渠道
+func (s *PaymentService) ProcessRefundCallback(ctx context.Context, provider string, header http.Header, body []byte) error {
+	gateway, ok := s.gateways.Get(provider)
+	if !ok {
+		return ErrPaymentMethodUnsupported
+	}
+
+	notification, err := gateway.ParseRefundNotification(header, body)
+	if err != nil {
+		if errors.Is(err, payment.ErrInvalidSignature) {
+			return ErrPaymentSignature
+		}
+		return ErrInvalidParam.WithMessage(fmt.Sprintf("回调数据错误: %v", err))
+	}
+
+	var refund models.Refund
+	if err := s.db.WithContext(ctx).Where("refund_no = ?", notification.RefundNo).First(&refund).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRefundNotFound
+		}
+		return fmt.Errorf("查询退款记录失败: %w", err)
+	}
+	var trade models.Trade
+	if err := s.db.WithContext(ctx).First(&trade, refund.TradeID).Error; err != nil {
+		return fmt.Errorf("查询交易记录失败: %w", err)
+	}
+	if trade.PaymentMethod != "" && trade.PaymentMethod != gateway.Name() {
+		return ErrPaymentMethodUnsupported.WithMessage("通知渠道与交易支付方式不一致")
+	}
+
+	return s.applyRefundResult(ctx, &refund, notification)
+}
+
+// SyncPendingRefunds 向渠道查询超时仍处理中的退款并同步结果，返回已出结果的数量；
+// 单笔退款同步失败时跳过并继续，失败原因汇总后返回由调度器记录
+func (s *PaymentService) SyncPendingRefunds(ctx context.Context) (int, error) {
+	var refunds []models.Refund
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND created_at < ?", models.RefundStatusProcessing, time.Now().Add(-refundPollDelay)).
+		Order("created_at ASC").
+		Limit(100).
+		Find(&refunds).Error; err != nil {
+		return 0, fmt.Errorf("查询处理中退款失败: %w", err)
+	}
+
+	resolved := 0
+	var errs []error
+	for i := range refunds {
+		refund := &refunds[i]
+		var trade models.Trade
+		if err := s.db.WithContext(ctx).First(&trade, refund.TradeID).Error; err != nil {
+			errs = append(errs, fmt.Errorf("查询退款%s的交易记录失败: %w", refund.RefundNo, err))
+			continue
+		}
+		if err := s.syncRefund(ctx, refund, &trade); err != nil {
+			errs = append(errs, fmt.Errorf("同步退款%s失败: %w", refund.RefundNo, err))
+			continue
+		}
+		if !refund.IsProcessing() {
+			resolved++
+		}
+	}
+	return resolved, errors.Join(errs...)
+}
+
+// syncRefund 向渠道查询退款结果并更新，渠道仍在处理时原样返回
+func (s *PaymentService) syncRefund(ctx context.Context, refund *models.Refund, trade *models.Trade) error {
+	gateway, err := s.gatewayFor(trade)
+	if err != nil {
+		return err
+	}
+	result, err := gateway.QueryRefund(&payment.RefundQueryRequest{
+		OrderNo:  trade.InternalNo,
+		RefundNo: refund.RefundNo,
+	})
+	if err != nil {
+		return ErrPaymentGateway.WithMessage(fmt.Sprintf("查询退款状态失败: %v", err))
+	}
+	return s.applyRefundResult(ctx, refund, result)
+}
+
+// applyRefundResult 在事务内更新退款状态并同步钱包和账本：成功时扣减冻结金额，失败时解冻回可用余额。
+// 只处理处理中的退款，重复通知直接返回成功；退款成功时作废交易已开具的发票，全部金额退款成功后交易标记为已退款
+func (s *PaymentService) applyRefundResult(ctx context.Context, refund *models.Refund, result *payment.RefundData) error {
+	if result.Status != payment.PayStatusSuccess && result.Status != payment.PayStatusFailed {
+		return nil
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 与发起退款保持先锁交易再锁退款的顺序
+		var trade models.Trade
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&trade, refund.TradeID).Error; err != nil {
+			return fmt.Errorf("查询交易记录失败: %w", err)
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(refund, refund.ID).Error; err != nil {
+			return fmt.Errorf("查询退款记录失败: %w", err)
+		}
+		if !refund.IsProcessing() {
+			return nil
+		}
+
+		if result.Status != payment.PayStatusSuccess {
+			if err := tx.Model(refund).Update("status", models.RefundStatusFailed).Error; err != nil {
+				return fmt.Errorf("更新退款状态失败: %w", err)
+			}
+			refund.Status = models.RefundStatusFailed
+			return releaseRefund(tx, &trade, refund)
+		}
+
+		if !result.Amount.IsZero() && result.Amount != refund.RefundAmount {
+			return ErrPaymentAmountMismatch.WithMessage(fmt.Sprintf("退款金额不匹配: 预期%s, 实际%s", refund.RefundAmount, result.Amount))
+		}
+		refundTime := result.RefundTime
+		if refundTime.IsZero() {
+			refundTime = time.Now()
+		}
+		if err := tx.Model(refund).Updates(map[string]interface{}{
+			"status":      models.RefundStatusSuccess,
+			"refund_time": refundTime,
+		}).Error; err != nil {
+			return fmt.Errorf("更新退款状态失败: %w", err)
+		}
+		refund.Status = models.RefundStatusSuccess
+		refund.RefundTime = &refundTime
+		if err := settleRefund(tx, &trade, refund); err != nil {
+			return err
+		}
+		if err := voidTradeInvoices(tx, &trade, refund); err != nil {
+			return err
+		}
+
+		refunded, err := sumRefunds(tx, trade.ID, models.RefundStatusSuccess)
+		if err != nil {
+			return err
+		}
+		if refunded == trade.Amount {
+			if err := tx.Model(&trade).Update("status", models.TradeStatusRefunded).Error; err != nil {
+				return fmt.Errorf("更新交易状态失败: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// findUserTrade 查询用户的交易记录，不属于该用户时视为不存在
+func (s *PaymentService) findUserTrade(ctx context.Context, userID, tradeID uint64) (*models.Trade, error) {
+	var trade models.Trade
+	if err := s.db.WithContext(ctx).Where("trade_id = ? AND user_id = ?", tradeID, userID).First(&trade).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTradeNotFound
+		}
+		return nil, fmt.Errorf("查询交易记录失败: %w", err)
+	}
+	return &trade, nil
+}
+
+// refundNotifyURL 退款通知地址，按渠道区分以便回调时选择验签方式
+func (s *PaymentService) refundNotifyURL(provider string) string {
+	if s.config.RefundNotifyURL == "" {
+		return ""
+	}
+	return strings.TrimRight(s.config.RefundNotifyURL, "/") + "/" + provider
+}
+
+// sumRefunds 汇总交易指定状态的退款金额
+func sumRefunds(tx *gorm.DB, tradeID uint64, statuses ...int8) (money.Money, error) {
+	var refunds []models.Refund
+	if err := tx.Select("refund_amount").
+		Where("trade_id = ? AND status IN ?", tradeID, statuses).
+		Find(&refunds).Error; err != nil {
+		return 0, fmt.Errorf("查询退款记录失败: %w", err)
+	}
+	var total money.Money
+	for _, r := range refunds {
+		total += r.RefundAmount
+	}
+	return total, nil
+}
+
+// holdRefund 发起退款时将退款金额从可用余额转入冻结余额，须在事务内调用
+func holdRefund(tx *gorm.DB, trade *models.Trade, refund *models.Refund) error {
+	description := fmt.Sprintf("退款%s冻结", refund.RefundNo)
+	if _, err := postJournal(tx, ledgerBizRefund, refund.ID, description,
+		debit(userAvailableAccount(trade.UserID), refund.RefundAmount),
+		credit(userFrozenAccount(trade.UserID), refund.RefundAmount),
+	); err != nil {
+		return err
+	}
+	_, err := freezeWallet(tx, trade.UserID, refund.RefundAmount, walletEntry{
+		TradeID:     &trade.ID,
+		RelatedID:   refund.ID,
+		RelatedType: "refund",
+		Description: description,
+	})
+	if errors.Is(err, ErrInsufficientBalance) {
+		return ErrInsufficientBalance.WithMessage(fmt.Sprintf("可用余额不足，退款需%s", refund.RefundAmount))
+	}
+	return err
+}
+
+// settleRefund 退款成功后扣减冻结金额，资金从渠道账户退回用户，须在事务内调用
+func settleRefund(tx *gorm.DB, trade *models.Trade, refund *models.Refund) error {
+	description := fmt.Sprintf("退款%s成功", refund.RefundNo)
+	if _, err := postJournal(tx, ledgerBizRefund, refund.ID, description,
+		debit(userFrozenAccount(trade.UserID), refund.RefundAmount),
+		credit(gatewayAccount, refund.RefundAmount),
+	); err != nil {
+		return err
+	}
+	_, err := debitFrozenWallet(tx, trade.UserID, refund.RefundAmount, walletEntry{
+		TradeID:     &trade.ID,
+		RelatedID:   refund.ID,
+		RelatedType: "refund",
+		Description: description,
+	})
+	return err
+}
+
+// releaseRefund 退款失败后将冻结金额解冻回可用余额，须在事务内调用
+func releaseRefund(tx *gorm.DB, trade *models.Trade, refund *models.Refund) error {
+	description := fmt.Sprintf("退款%s失败解冻", refund.RefundNo)
+	if _, err := postJournal(tx, ledgerBizRefund, refund.ID, description,
+		debit(userFrozenAccount(trade.UserID), refund.RefundAmount),
+		credit(userAvailableAccount(trade.UserID), refund.RefundAmount),
+	); err != nil {
+		return err
+	}
+	_, err := unfreezeWallet(tx, trade.UserID, refund.RefundAmount, walletEntry{
+		TradeID:     &trade.ID,
+		RelatedID:   refund.ID,
+		RelatedType: "refund",
+		Description: description,
+	})
+	return err
+}
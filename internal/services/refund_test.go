@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/internal/events"
+	"task-platform-api/internal/models"
+	"task-platform-api/internal/testutil"
+	"task-platform-api/pkg/money"
+	"task-platform-api/pkg/payment"
+)
+
+var refundColumns = []string{"refund_id", "trade_id", "refund_amount", "status", "refund_no", "created_at"}
+
+// refundTestRows 用户1经收钱吧支付100元的预付款交易5，托管已全部解冻退回，钱包可用100元、冻结70元
+func refundTestRows(rows ...testutil.StubRows) []testutil.StubRows {
+	return append(rows,
+		testutil.StubRows{
+			Match:   "FROM `trades`",
+			Columns: []string{"trade_id", "user_id", "trade_type", "amount", "internal_no", "status", "payment_method"},
+			Values:  [][]driver.Value{{int64(5), int64(1), models.TradeTypePrepay, []byte("100.00"), "T001", int64(models.TradeStatusPaid), payment.ProviderShouqianba}},
+		},
+		testutil.StubRows{
+			Match:   "FROM `wallet_transactions`",
+			Columns: []string{"amount"},
+			Values:  [][]driver.Value{{[]byte("100.00")}},
+		},
+		testutil.StubRows{
+			Match:   "FROM `wallets`",
+			Columns: []string{"id", "user_id", "balance", "frozen_balance", "version"},
+			Values:  [][]driver.Value{{int64(1), int64(1), []byte("100.00"), []byte("70.00"), int64(1)}},
+		},
+		anyLedgerAccountRows(),
+	)
+}
+
+func newTestRefundService(t *testing.T, db *gorm.DB, body string) *PaymentService {
+	t.Helper()
+	return NewPaymentService(db, config.PaymentConfig{}, newShouqianbaGateways(t, body), events.NewBus(zap.NewNop()), NewFeeEngine(db, config.FeeConfig{}), config.AdminConfig{})
+}
+
+func TestCreateRefundCapsPartialRefunds(t *testing.T) {
+	// 已成功退款30元，剩余可退70元
+	db, stub := testutil.NewGorm(t, refundTestRows(testutil.StubRows{
+		Match:   "FROM `refunds` WHERE trade_id",
+		Columns: []string{"refund_amount"},
+		Values:  [][]driver.Value{{[]byte("30.00")}},
+	})...)
+	svc := newTestRefundService(t, db, `{"code":"500","message":"不应调用渠道"}`)
+
+	_, err := svc.CreateRefund(context.Background(), &CreateRefundRequest{UserID: 1, TradeID: 5, Amount: money.FromCents(7001)})
+	if !errors.Is(err, ErrRefundAmountExceeded) {
+		t.Fatalf("超过剩余可退金额应返回ErrRefundAmountExceeded, 实际: %v", err)
+	}
+	if len(stub.Executed("INSERT")) > 0 || len(stub.Executed("UPDATE `")) > 0 {
+		t.Fatal("超额退款不应写入记录")
+	}
+}
+
+func TestCreateRefundHoldsAndSettles(t *testing.T) {
+	db, stub := testutil.NewGorm(t, refundTestRows(
+		testutil.StubRows{
+			Match:   "FROM `refunds` WHERE trade_id",
+			Columns: []string{"refund_amount"},
+			Values:  [][]driver.Value{{[]byte("30.00")}},
+		},
+		testutil.StubRows{
+			Match:   "FROM `refunds`",
+			Columns: refundColumns,
+			Values:  [][]driver.Value{{int64(8), int64(5), []byte("70.00"), int64(models.RefundStatusProcessing), "R001", time.Now()}},
+		},
+	)...)
+	svc := newTestRefundService(t, db, `{"code":"200","data":{"refund_no":"R001","order_no":"T001","amount":"70.00","status":"SUCCESS"}}`)
+
+	refund, err := svc.CreateRefund(context.Background(), &CreateRefundRequest{UserID: 1, TradeID: 5})
+	if err != nil {
+		t.Fatalf("退款失败: %v", err)
+	}
+	if refund.Status != models.RefundStatusSuccess || refund.RefundAmount != money.FromCents(7000) {
+		t.Fatalf("未指定金额时应全额退还剩余可退金额并成功: %+v", refund)
+	}
+
+	inserts := stub.Executed("INSERT INTO `refunds`")
+	if len(inserts) != 1 || !inserts[0].HasArgs("70.00", int64(models.RefundStatusProcessing)) {
+		t.Fatalf("应先创建处理中的退款记录: %+v", inserts)
+	}
+	// 先从可用余额冻结退款金额，渠道退款成功后再从冻结余额扣减
+	wallets := stub.Executed("UPDATE `wallets`")
+	if len(wallets) != 2 || !wallets[0].HasArgs("30.00", "140.00") || !wallets[1].HasArgs("100.00", "0.00") {
+		t.Fatalf("退款应先冻结再扣减冻结余额: %+v", wallets)
+	}
+	updates := stub.Executed("UPDATE `refunds`")
+	if len(updates) != 1 || !updates[0].HasArgs(int64(models.RefundStatusSuccess)) {
+		t.Fatalf("退款应标记为成功: %+v", updates)
+	}
+	if len(stub.Executed("INSERT INTO `ledger_entries`")) != 2 {
+		t.Fatal("冻结和退款出账应各记一张账本凭证")
+	}
+}
+
+func TestSyncPendingRefundsReleasesUnknownRefund(t *testing.T) {
+	db, stub := testutil.NewGorm(t, refundTestRows(testutil.StubRows{
+		Match:   "FROM `refunds`",
+		Columns: refundColumns,
+		Values:  [][]driver.Value{{int64(8), int64(5), []byte("70.00"), int64(models.RefundStatusProcessing), "R001", time.Now().Add(-time.Hour)}},
+	})...)
+	svc := newTestRefundService(t, db, `{"code":"404","message":"退款单不存在"}`)
+
+	resolved, err := svc.SyncPendingRefunds(context.Background())
+	if err != nil {
+		t.Fatalf("同步退款失败: %v", err)
+	}
+	if resolved != 1 {
+		t.Fatalf("渠道查无退款单的退款应出结果, 实际: %d", resolved)
+	}
+
+	updates := stub.Executed("UPDATE `refunds`")
+	if len(updates) != 1 || !updates[0].HasArgs(int64(models.RefundStatusFailed)) {
+		t.Fatalf("退款应标记为失败: %+v", updates)
+	}
+	wallets := stub.Executed("UPDATE `wallets`")
+	if len(wallets) != 1 || !wallets[0].HasArgs("170.00", "0.00") {
+		t.Fatalf("退款金额应从冻结余额解冻回可用余额: %+v", wallets)
+	}
+}
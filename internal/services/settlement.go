@@ -0,0 +1,70 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"task-platform-api/internal/models"
+	"task-platform-api/pkg/money"
+)
+
+// createSettlement 为验收通过的任务或阶段生成结算记录，stage为nil表示整单结算；
+// 服务费取支付预付款时计算的任务服务费，分阶段任务按阶段金额分摊；
+// final表示任务最终结算，此时发布者保证金作为发布方收入解冻退回，接取者保证金同时退还。
+// 任务已托管预付款时直接从冻结资金划转并标记为已结算，否则保留为待结算
+func createSettlement(tx *gorm.DB, task *models.Task, stage *models.TaskStage, final bool) (*models.Settlement, error) {
+	amount := task.Amount
+	remark := "任务验收结算"
+	var stageID *uint64
+	if stage != nil {
+		amount = stage.Amount
+		stageID = &stage.ID
+		remark = fmt.Sprintf("阶段「%s」验收结算", stage.StageName)
+	}
+
+	publisherFee, takerFee, err := settlementFees(tx, task, stage)
+	if err != nil {
+		return nil, err
+	}
+	publisherAmount := money.Zero
+	if final {
+		publisherAmount = task.GetDepositAmount()
+	}
+	settlement := &models.Settlement{
+		TaskID:          task.ID,
+		StageID:         stageID,
+		PublisherAmount: publisherAmount,
+		TakerAmount:     amount - takerFee,
+		PlatformFee:     publisherFee + takerFee,
+		SettleTime:      time.Now(),
+		Status:          models.SettlementStatusPending,
+		Remark:          remark,
+	}
+
+	if err := tx.Create(settlement).Error; err != nil {
+		return nil, fmt.Errorf("创建结算记录失败: %w", err)
+	}
+
+	if final {
+		if err := releaseTakerDeposit(tx, task, "任务完成"); err != nil {
+			return nil, err
+		}
+	}
+
+	prepay, err := findPaidPrepay(tx, task.ID)
+	if err != nil || prepay == nil {
+		return settlement, err
+	}
+
+	if err := settleEscrow(tx, task, settlement, amount); err != nil {
+		return nil, err
+	}
+	if err := tx.Model(settlement).Update("status", models.SettlementStatusSettled).Error; err != nil {
+		return nil, fmt.Errorf("更新结算状态失败: %w", err)
+	}
+	settlement.Status = models.SettlementStatusSettled
+
+	return settlement, nil
+}
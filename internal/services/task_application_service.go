@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"task-platform-api/internal/models"
+	"task-platform-api/pkg/money"
+)
+
+// ApplyTaskRequest 申请任务请求
+type ApplyTaskRequest struct {
+	TaskID      uint64      `json:"task_id"`
+	ApplicantID uint64      `json:"applicant_id"`
+	QuotedPrice money.Money `json:"quoted_price"`
+	Message     string      `json:"message"`
+	Attachments string      `json:"attachments"`
+}
+
+// TaskApplicationService 任务申请服务
+type TaskApplicationService struct {
+	db *gorm.DB
+}
+
+// NewTaskApplicationService 创建任务申请服务
+func NewTaskApplicationService(db *gorm.DB) *TaskApplicationService {
+	return &TaskApplicationService{
+		db: db,
+	}
+}
+
+// Apply 申请任务
+func (s *TaskApplicationService) Apply(ctx context.Context, req *ApplyTaskRequest) (*models.TaskApplication, error) {
+	if req.QuotedPrice < 0 {
+		return nil, ErrInvalidParam.WithMessage("报价不能为负数")
+	}
+
+	application := &models.TaskApplication{
+		TaskID:      req.TaskID,
+		ApplicantID: req.ApplicantID,
+		Message:     req.Message,
+		QuotedPrice: req.QuotedPrice,
+		Attachments: req.Attachments,
+		Status:      models.ApplicationStatusPending,
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		task, err := lockTask(tx, req.TaskID)
+		if err != nil {
+			return err
+		}
+		if task.PublisherID == req.ApplicantID {
+			return ErrTaskSelfTake
+		}
+		if !task.IsAvailable() {
+			return ErrTaskInvalidTransition.WithMessage(fmt.Sprintf("任务当前状态为%s，不能申请", task.GetStatusName()))
+		}
+		if task.IsExpired() {
+			return ErrTaskExpired
+		}
+
+		var count int64
+		if err := tx.Model(&models.TaskApplication{}).
+			Where("task_id = ? AND applicant_id = ? AND status IN ?", req.TaskID, req.ApplicantID,
+				[]int8{models.ApplicationStatusPending, models.ApplicationStatusShortlisted}).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("查询申请记录失败: %w", err)
+		}
+		if count > 0 {
+			return ErrApplicationDuplicate
+		}
+
+		if err := tx.Create(application).Error; err != nil {
+			return fmt.Errorf("创建申请失败: %w", err)
+		}
+
+		if err := tx.Model(&models.Task{}).
+			Where("task_id = ?", req.TaskID).
+			UpdateColumn("apply_count", gorm.Expr("apply_count + 1")).Error; err != nil {
+			return fmt.Errorf("更新申请次数失败: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return application, nil
+}
+
+// ListApplications 发布者查询任务的申请列表
+func (s *TaskApplicationService) ListApplications(ctx context.Context, taskID, userID uint64, status *int8) ([]models.TaskApplication, error) {
+	var task models.Task
+	if err := s.db.WithContext(ctx).Select("task_id", "publisher_id").First(&task, taskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("查询任务失败: %w", err)
+	}
+	if task.PublisherID != userID {
+		return nil, ErrTaskForbidden
+	}
+
+	var applications []models.TaskApplication
+	db := s.db.WithContext(ctx).Where("task_id = ?", taskID)
+	if status != nil {
+		db = db.Where("status = ?", *status)
+	}
+	if err := db.Preload("Applicant").
+		Order("created_at ASC").
+		Find(&applications).Error; err != nil {
+		return nil, fmt.Errorf("查询申请列表失败: %w", err)
+	}
+
+	return applications, nil
+}
+
+// Shortlist 发布者将申请加入候选名单
+func (s *TaskApplicationService) Shortlist(ctx context.Context, taskID, applicationID, userID uint64, note string) (*models.TaskApplication, error) {
+	return s.review(ctx, taskID, applicationID, userID, func(tx *gorm.DB, task *models.Task, application *models.TaskApplication) error {
+		if !application.IsPending() {
+			return ErrApplicationInvalidStatus.WithMessage("只有待审核的申请才能加入候选")
+		}
+		return updateApplicationStatus(tx, application, models.ApplicationStatusShortlisted, note)
+	})
+}
+
+// Reject 发布者拒绝申请
+func (s *TaskApplicationService) Reject(ctx context.Context, taskID, applicationID, userID uint64, note string) (*models.TaskApplication, error) {
+	return s.review(ctx, taskID, applicationID, userID, func(tx *gorm.DB, task *models.Task, application *models.TaskApplication) error {
+		if !application.IsOpen() {
+			return ErrApplicationInvalidStatus
+		}
+		return updateApplicationStatus(tx, application, models.ApplicationStatusRejected, note)
+	})
+}
+
+// Accept 发布者接受申请：指定接取者并冻结其保证金、任务进入进行中，并自动拒绝其余申请
+func (s *TaskApplicationService) Accept(ctx context.Context, taskID, applicationID, userID uint64, note string) (*models.TaskApplication, error) {
+	return s.review(ctx, taskID, applicationID, userID, func(tx *gorm.DB, task *models.Task, application *models.TaskApplication) error {
+		if !application.IsOpen() {
+			return ErrApplicationInvalidStatus
+		}
+		if task.HasTaker() {
+			return ErrTaskInvalidTransition.WithMessage("任务已被接取")
+		}
+		if task.IsExpired() {
+			return ErrTaskExpired
+		}
+
+		toStatus, err := models.FireTaskEvent(task.Status, models.TaskEventAssign)
+		if err != nil {
+			return ErrTaskInvalidTransition.WithMessage(err.Error())
+		}
+
+		if err := updateApplicationStatus(tx, application, models.ApplicationStatusAccepted, note); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.TaskApplication{}).
+			Where("task_id = ? AND application_id <> ? AND status IN ?", task.ID, application.ID,
+				[]int8{models.ApplicationStatusPending, models.ApplicationStatusShortlisted}).
+			Updates(map[string]interface{}{
+				"status":      models.ApplicationStatusRejected,
+				"review_note": "任务已指派给其他申请者",
+				"updated_at":  time.Now(),
+			}).Error; err != nil {
+			return fmt.Errorf("拒绝其余申请失败: %w", err)
+		}
+
+		if _, err := startNextStage(tx, task.ID); err != nil {
+			return err
+		}
+
+		task.TakerID = application.ApplicantID
+		if err := lockTakerDeposit(tx, task); err != nil {
+			return err
+		}
+		reason := fmt.Sprintf("接受申请#%d", application.ID)
+		return updateTaskStatus(tx, task, toStatus, models.TaskEventAssign, userID, reason)
+	})
+}
+
+// review 在事务内依次锁定任务和申请，校验发布者身份后执行审核操作
+func (s *TaskApplicationService) review(ctx context.Context, taskID, applicationID, userID uint64, fn func(tx *gorm.DB, task *models.Task, application *models.TaskApplication) error) (*models.TaskApplication, error) {
+	var application models.TaskApplication
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("application_id", "task_id").First(&application, applicationID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrApplicationNotFound
+			}
+			return fmt.Errorf("查询申请失败: %w", err)
+		}
+		if application.TaskID != taskID {
+			return ErrApplicationNotFound
+		}
+
+		// 与申请、接取流程保持先锁任务再锁申请的顺序，避免死锁
+		task, err := lockTask(tx, application.TaskID)
+		if err != nil {
+			return err
+		}
+		if task.PublisherID != userID {
+			return ErrTaskForbidden
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&application, applicationID).Error; err != nil {
+			return fmt.Errorf("查询申请失败: %w", err)
+		}
+
+		return fn(tx, task, &application)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &application, nil
+}
+
+// updateApplicationStatus 更新申请状态及审核备注
+func updateApplicationStatus(tx *gorm.DB, application *models.TaskApplication, status int8, note string) error {
+	if err := tx.Model(application).Updates(map[string]interface{}{
+		"status":      status,
+		"review_note": note,
+		"updated_at":  time.Now(),
+	}).Error; err != nil {
+		return fmt.Errorf("更新申请状态失败: %w", err)
+	}
+	application.Status = status
+	application.ReviewNote = note
+	return nil
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/internal/models"
+)
+
+// SubmitDeliveryRequest 提交交付请求
+type SubmitDeliveryRequest struct {
+	TaskID  uint64  `json:"task_id"`
+	TakerID uint64  `json:"taker_id"`
+	StageID *uint64 `json:"stage_id"`
+	FileURL string  `json:"file_url"`
+	Content string  `json:"content"`
+}
+
+// TaskDeliveryService 任务交付服务
+type TaskDeliveryService struct {
+	db  *gorm.DB
+	cfg config.TaskConfig
+}
+
+// NewTaskDeliveryService 创建任务交付服务
+func NewTaskDeliveryService(db *gorm.DB, cfg config.TaskConfig) *TaskDeliveryService {
+	return &TaskDeliveryService{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// Submit 接取者提交交付，分阶段任务需指定当前进行中的阶段
+func (s *TaskDeliveryService) Submit(ctx context.Context, req *SubmitDeliveryRequest) (*models.TaskDelivery, error) {
+	if strings.TrimSpace(req.FileURL) == "" && strings.TrimSpace(req.Content) == "" {
+		return nil, ErrInvalidParam.WithMessage("交付文件和交付说明不能同时为空")
+	}
+
+	delivery := &models.TaskDelivery{
+		TaskID:  req.TaskID,
+		TakerID: req.TakerID,
+		StageID: req.StageID,
+		FileURL: req.FileURL,
+		Content: req.Content,
+		Status:  models.DeliveryStatusPending,
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		task, err := lockTask(tx, req.TaskID)
+		if err != nil {
+			return err
+		}
+		if task.TakerID != req.TakerID {
+			return ErrTaskForbidden
+		}
+
+		toStatus, err := models.FireTaskEvent(task.Status, models.TaskEventSubmit)
+		if err != nil {
+			return ErrTaskInvalidTransition.WithMessage(err.Error())
+		}
+
+		if err := loadTaskStages(tx, task); err != nil {
+			return err
+		}
+		reason := "提交交付"
+		if task.HasStages() {
+			if req.StageID == nil {
+				return ErrInvalidParam.WithMessage("分阶段任务需指定交付阶段")
+			}
+			stage := findStage(task.Stages, *req.StageID)
+			if stage == nil {
+				return ErrStageNotFound
+			}
+			if !stage.IsInProgress() {
+				return ErrStageInvalidStatus
+			}
+			reason = fmt.Sprintf("提交阶段「%s」交付", stage.StageName)
+		} else if req.StageID != nil {
+			return ErrInvalidParam.WithMessage("该任务未分阶段，不能指定交付阶段")
+		}
+
+		if err := tx.Create(delivery).Error; err != nil {
+			return fmt.Errorf("创建交付记录失败: %w", err)
+		}
+
+		return updateTaskStatus(tx, task, toStatus, models.TaskEventSubmit, req.TakerID, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// ListDeliveries 查询任务的交付记录，仅发布者和接取者可见
+func (s *TaskDeliveryService) ListDeliveries(ctx context.Context, taskID, userID uint64) ([]models.TaskDelivery, error) {
+	var task models.Task
+	if err := s.db.WithContext(ctx).Select("task_id", "publisher_id", "taker_id").First(&task, taskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("查询任务失败: %w", err)
+	}
+	if task.PublisherID != userID && task.TakerID != userID {
+		return nil, ErrTaskForbidden
+	}
+
+	var deliveries []models.TaskDelivery
+	if err := s.db.WithContext(ctx).
+		Where("task_id = ?", taskID).
+		Preload("Stage").
+		Order("create_time DESC").
+		Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("查询交付记录失败: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// Accept 发布者验收交付；分阶段任务按阶段生成结算并开启下一阶段，最后一个阶段验收后任务完成
+func (s *TaskDeliveryService) Accept(ctx context.Context, taskID, deliveryID, userID uint64, feedback string) (*models.TaskDelivery, error) {
+	return s.review(ctx, taskID, deliveryID, userID, func(tx *gorm.DB, task *models.Task, delivery *models.TaskDelivery) error {
+		return acceptDelivery(tx, task, delivery, userID, feedback)
+	})
+}
+
+// RequestRevision 发布者要求整改，任务回到进行中；整改轮次不能超过任务设置的上限
+func (s *TaskDeliveryService) RequestRevision(ctx context.Context, taskID, deliveryID, userID uint64, feedback string) (*models.TaskDelivery, error) {
+	if strings.TrimSpace(feedback) == "" {
+		return nil, ErrInvalidParam.WithMessage("请填写整改意见")
+	}
+
+	return s.review(ctx, taskID, deliveryID, userID, func(tx *gorm.DB, task *models.Task, delivery *models.TaskDelivery) error {
+		var revisions int64
+		if err := tx.Model(&models.TaskDelivery{}).
+			Where("task_id = ? AND status = ?", task.ID, models.DeliveryStatusRevision).
+			Count(&revisions).Error; err != nil {
+			return fmt.Errorf("查询整改次数失败: %w", err)
+		}
+		if revisions >= int64(task.MaxRevisions) {
+			return ErrRevisionLimitExceeded.WithMessage(fmt.Sprintf("已达到最大整改轮次(%d)，请验收或发起申诉", task.MaxRevisions))
+		}
+
+		if err := updateDeliveryStatus(tx, delivery, models.DeliveryStatusRevision, feedback); err != nil {
+			return err
+		}
+
+		reason := fmt.Sprintf("第%d轮整改: %s", revisions+1, feedback)
+		return fireTaskEvent(tx, task, models.TaskEventRevise, userID, reason)
+	})
+}
+
+// AutoAcceptOverdue 对超过自动验收期限仍未处理的交付执行系统验收，返回实际验收的数量；
+// 单个交付处理失败时跳过并继续，失败原因汇总后返回由调度器记录
+func (s *TaskDeliveryService) AutoAcceptOverdue(ctx context.Context) (int, error) {
+	if s.cfg.AutoAcceptDays <= 0 {
+		return 0, nil
+	}
+
+	deadline := time.Now().AddDate(0, 0, -s.cfg.AutoAcceptDays)
+	var deliveries []models.TaskDelivery
+	if err := s.db.WithContext(ctx).
+		Select("delivery_id", "task_id").
+		Where("status = ? AND create_time < ?", models.DeliveryStatusPending, deadline).
+		Order("create_time ASC").
+		Limit(100).
+		Find(&deliveries).Error; err != nil {
+		return 0, fmt.Errorf("查询待自动验收交付失败: %w", err)
+	}
+
+	accepted := 0
+	var errs []error
+	for _, d := range deliveries {
+		done := false
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			task, err := lockTask(tx, d.TaskID)
+			if err != nil {
+				return err
+			}
+			var delivery models.TaskDelivery
+			if err := lockDelivery(tx, d.TaskID, d.ID, &delivery); err != nil {
+				return err
+			}
+			if !delivery.IsPending() || !task.IsPendingAccept() {
+				return nil
+			}
+			feedback := fmt.Sprintf("发布者%d天内未处理，系统自动验收", s.cfg.AutoAcceptDays)
+			if err := acceptDelivery(tx, task, &delivery, 0, feedback); err != nil {
+				return err
+			}
+			done = true
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("自动验收交付%d失败: %w", d.ID, err))
+			continue
+		}
+		if done {
+			accepted++
+		}
+	}
+
+	return accepted, errors.Join(errs...)
+}
+
+// review 在事务内锁定任务和交付，校验发布者身份及交付状态后执行验收操作
+func (s *TaskDeliveryService) review(ctx context.Context, taskID, deliveryID, userID uint64, fn func(tx *gorm.DB, task *models.Task, delivery *models.TaskDelivery) error) (*models.TaskDelivery, error) {
+	var delivery models.TaskDelivery
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		task, err := lockTask(tx, taskID)
+		if err != nil {
+			return err
+		}
+		if task.PublisherID != userID {
+			return ErrTaskForbidden
+		}
+
+		if err := lockDelivery(tx, taskID, deliveryID, &delivery); err != nil {
+			return err
+		}
+		if !delivery.IsPending() {
+			return ErrDeliveryInvalidStatus
+		}
+
+		return fn(tx, task, &delivery)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// acceptDelivery 验收交付并推进任务，operatorID为0表示系统操作，须在事务内调用
+func acceptDelivery(tx *gorm.DB, task *models.Task, delivery *models.TaskDelivery, operatorID uint64, feedback string) error {
+	if err := updateDeliveryStatus(tx, delivery, models.DeliveryStatusAccepted, feedback); err != nil {
+		return err
+	}
+
+	if delivery.StageID == nil {
+		if _, err := createSettlement(tx, task, nil, true); err != nil {
+			return err
+		}
+		return fireTaskEvent(tx, task, models.TaskEventAccept, operatorID, feedbackOr(feedback, "交付验收通过"))
+	}
+
+	if err := loadTaskStages(tx, task); err != nil {
+		return err
+	}
+	stage := findStage(task.Stages, *delivery.StageID)
+	if stage == nil {
+		return ErrStageNotFound
+	}
+	if err := tx.Model(stage).Update("status", models.StageStatusCompleted).Error; err != nil {
+		return fmt.Errorf("更新任务阶段失败: %w", err)
+	}
+	stage.Status = models.StageStatusCompleted
+
+	next, err := startNextStage(tx, task.ID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := createSettlement(tx, task, stage, next == nil); err != nil {
+		return err
+	}
+	if next == nil {
+		return fireTaskEvent(tx, task, models.TaskEventAccept, operatorID, fmt.Sprintf("最后阶段「%s」验收通过", stage.StageName))
+	}
+	return fireTaskEvent(tx, task, models.TaskEventStageAccept, operatorID,
+		fmt.Sprintf("阶段「%s」验收通过，进入阶段「%s」", stage.StageName, next.StageName))
+}
+
+// updateDeliveryStatus 更新交付状态及反馈
+func updateDeliveryStatus(tx *gorm.DB, delivery *models.TaskDelivery, status int8, feedback string) error {
+	if err := tx.Model(delivery).Updates(map[string]interface{}{
+		"status":      status,
+		"feedback":    feedback,
+		"update_time": time.Now(),
+	}).Error; err != nil {
+		return fmt.Errorf("更新交付状态失败: %w", err)
+	}
+	delivery.Status = status
+	delivery.Feedback = feedback
+	return nil
+}
+
+// feedbackOr 反馈为空时使用默认说明
+func feedbackOr(feedback, fallback string) string {
+	if strings.TrimSpace(feedback) == "" {
+		return fallback
+	}
+	return feedback
+}
+
+// lockDelivery 在事务内加行锁读取交付记录并校验所属任务
+func lockDelivery(tx *gorm.DB, taskID, deliveryID uint64, delivery *models.TaskDelivery) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(delivery, deliveryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrDeliveryNotFound
+		}
+		return fmt.Errorf("查询交付记录失败: %w", err)
+	}
+	if delivery.TaskID != taskID {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
+
+// fireTaskEvent 按状态机触发任务事件并更新状态，须在事务内调用
+func fireTaskEvent(tx *gorm.DB, task *models.Task, event models.TaskEvent, operatorID uint64, reason string) error {
+	toStatus, err := models.FireTaskEvent(task.Status, event)
+	if err != nil {
+		return ErrTaskInvalidTransition.WithMessage(err.Error())
+	}
+	return updateTaskStatus(tx, task, toStatus, event, operatorID, reason)
+}
+
+// findStage 按ID查找阶段
+func findStage(stages []models.TaskStage, stageID uint64) *models.TaskStage {
+	for i := range stages {
+		if stages[i].ID == stageID {
+			return &stages[i]
+		}
+	}
+	return nil
+}
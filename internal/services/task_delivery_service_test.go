@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/internal/models"
+	"task-platform-api/internal/testutil"
+)
+
+// deliveryTestRows 发布者1、接取者2的待验收任务1，最多整改2轮，交付5待验收
+func deliveryTestRows(rows ...testutil.StubRows) []testutil.StubRows {
+	return append(rows,
+		testutil.StubRows{
+			Match:   "FROM `tasks`",
+			Columns: []string{"task_id", "publisher_id", "taker_id", "amount", "max_revisions", "status"},
+			Values:  [][]driver.Value{{int64(1), int64(1), int64(2), []byte("100.00"), int64(2), int64(models.TaskStatusPendingAccept)}},
+		},
+		testutil.StubRows{
+			Match:   "FROM `task_deliveries`",
+			Columns: []string{"delivery_id", "task_id", "taker_id", "status", "create_time"},
+			Values:  [][]driver.Value{{int64(5), int64(1), int64(2), int64(models.DeliveryStatusPending), time.Now().AddDate(0, 0, -8)}},
+		},
+	)
+}
+
+func TestRequestRevisionLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		revisions int64
+		wantErr   error
+	}{
+		{name: "未达上限", revisions: 1},
+		{name: "已达上限", revisions: 2, wantErr: ErrRevisionLimitExceeded},
+	}
+	for _, tt := range tests {
+		db, stub := testutil.NewGorm(t, deliveryTestRows(testutil.StubRows{
+			Match:   "count(*)",
+			Columns: []string{"count(*)"},
+			Values:  [][]driver.Value{{tt.revisions}},
+		})...)
+		svc := NewTaskDeliveryService(db, config.TaskConfig{})
+
+		_, err := svc.RequestRevision(context.Background(), 1, 5, 1, "图片分辨率不足")
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: RequestRevision() = %v, 期望 %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		deliveries := stub.Executed("UPDATE `task_deliveries`")
+		tasks := stub.Executed("UPDATE `tasks`")
+		if tt.wantErr != nil {
+			if len(deliveries) > 0 || len(tasks) > 0 {
+				t.Errorf("%s: 超过整改上限时不应更新交付和任务", tt.name)
+			}
+			continue
+		}
+		if len(deliveries) != 1 || !deliveries[0].HasArgs(int64(models.DeliveryStatusRevision), "图片分辨率不足") {
+			t.Errorf("%s: 交付应标记为需整改: %+v", tt.name, deliveries)
+		}
+		if len(tasks) != 1 || !tasks[0].HasArgs(int64(models.TaskStatusInProgress)) {
+			t.Errorf("%s: 任务应回到进行中: %+v", tt.name, tasks)
+		}
+		logs := stub.Executed("INSERT INTO `task_status_logs`")
+		if len(logs) != 1 || !logs[0].HasArgs("第2轮整改: 图片分辨率不足") {
+			t.Errorf("%s: 应记录整改轮次: %+v", tt.name, logs)
+		}
+	}
+}
+
+func TestRequestRevisionRejectsNonPublisher(t *testing.T) {
+	db, stub := testutil.NewGorm(t, deliveryTestRows()...)
+	svc := NewTaskDeliveryService(db, config.TaskConfig{})
+
+	if _, err := svc.RequestRevision(context.Background(), 1, 5, 2, "整改"); !errors.Is(err, ErrTaskForbidden) {
+		t.Fatalf("非发布者要求整改应返回ErrTaskForbidden, 实际: %v", err)
+	}
+	if len(stub.Executed("UPDATE `")) > 0 {
+		t.Fatal("非发布者不能更新交付")
+	}
+}
+
+func TestAutoAcceptOverdue(t *testing.T) {
+	db, stub := testutil.NewGorm(t, deliveryTestRows()...)
+	svc := NewTaskDeliveryService(db, config.TaskConfig{AutoAcceptDays: 7})
+
+	accepted, err := svc.AutoAcceptOverdue(context.Background())
+	if err != nil {
+		t.Fatalf("自动验收失败: %v", err)
+	}
+	if accepted != 1 {
+		t.Fatalf("超过期限的交付应自动验收, 实际: %d", accepted)
+	}
+
+	queries := stub.Executed("FROM `task_deliveries` WHERE status")
+	if len(queries) != 1 || !queries[0].HasArgs(int64(models.DeliveryStatusPending)) {
+		t.Fatalf("应只查询待验收的交付: %+v", queries)
+	}
+	deliveries := stub.Executed("UPDATE `task_deliveries`")
+	if len(deliveries) != 1 || !deliveries[0].HasArgs(int64(models.DeliveryStatusAccepted), "发布者7天内未处理，系统自动验收") {
+		t.Fatalf("交付应由系统验收: %+v", deliveries)
+	}
+	tasks := stub.Executed("UPDATE `tasks`")
+	if len(tasks) != 1 || !tasks[0].HasArgs(int64(models.TaskStatusCompleted)) {
+		t.Fatalf("整单交付验收后任务应完成: %+v", tasks)
+	}
+	if len(stub.Executed("INSERT INTO `settlements`")) != 1 {
+		t.Fatal("验收后应生成结算")
+	}
+	logs := stub.Executed("INSERT INTO `task_status_logs`")
+	if len(logs) != 1 || !logs[0].HasArgs(int64(0), "发布者7天内未处理，系统自动验收") {
+		t.Fatalf("自动验收应记录为系统操作: %+v", logs)
+	}
+}
+
+func TestAutoAcceptOverdueDisabled(t *testing.T) {
+	db, stub := testutil.NewGorm(t, deliveryTestRows()...)
+	svc := NewTaskDeliveryService(db, config.TaskConfig{})
+
+	if accepted, err := svc.AutoAcceptOverdue(context.Background()); accepted != 0 || err != nil {
+		t.Fatalf("未配置自动验收期限时不应处理: %d, %v", accepted, err)
+	}
+	if len(stub.Executed("")) > 0 {
+		t.Fatal("未配置自动验收期限时不应查询")
+	}
+}
@@ -0,0 +1,480 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/internal/models"
+	"task-platform-api/pkg/money"
+	"task-platform-api/pkg/utils"
+)
+
+// taskRole 允许触发事件的角色
+type taskRole int
+
+const (
+	taskRolePublisher taskRole = iota // 发布者
+	taskRoleTaker                     // 接取者
+	taskRoleOther                     // 非发布者的其他用户
+)
+
+// taskEventRoles 各任务事件允许的触发角色，状态流转规则见 models.FireTaskEvent
+var taskEventRoles = map[models.TaskEvent]taskRole{
+	models.TaskEventPublish: taskRolePublisher,
+	models.TaskEventTake:    taskRoleOther,
+	models.TaskEventAssign:  taskRolePublisher,
+	models.TaskEventSubmit:  taskRoleTaker,
+	models.TaskEventAccept:  taskRolePublisher,
+	models.TaskEventCancel:  taskRolePublisher,
+}
+
+// CreateTaskRequest 创建任务请求
+type CreateTaskRequest struct {
+	PublisherID uint64      `json:"publisher_id"`
+	Title       string      `json:"title"`
+	Content     string      `json:"content"`
+	Amount      money.Money `json:"amount"`
+	Deadline    time.Time   `json:"deadline"`
+	CategoryID  uint64      `json:"category_id"`
+	Tags        string      `json:"tags"`
+	Attachments string      `json:"attachments"`
+
+	MaxRevisions *int                     `json:"max_revisions"` // 最大整改轮次，为空使用平台默认值
+	Stages       []CreateTaskStageRequest `json:"stages"`        // 分阶段交付定义，为空表示整单交付
+}
+
+// TaskListRequest 任务列表查询请求
+type TaskListRequest struct {
+	Status      *int8
+	CategoryID  *uint64
+	PublisherID *uint64
+	TakerID     *uint64
+	Keyword     string
+	Page        int
+	PageSize    int
+}
+
+// TaskService 任务服务
+type TaskService struct {
+	db  *gorm.DB
+	cfg config.TaskConfig
+}
+
+// NewTaskService 创建任务服务
+func NewTaskService(db *gorm.DB, cfg config.TaskConfig) *TaskService {
+	return &TaskService{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// CreateTask 创建任务（草稿）
+func (s *TaskService) CreateTask(ctx context.Context, req *CreateTaskRequest) (*models.Task, error) {
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return nil, ErrInvalidParam.WithMessage("任务标题不能为空")
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		return nil, ErrInvalidParam.WithMessage("任务内容不能为空")
+	}
+	if req.Amount <= 0 {
+		return nil, ErrInvalidParam.WithMessage("任务金额必须大于0")
+	}
+	if !req.Deadline.After(time.Now()) {
+		return nil, ErrInvalidParam.WithMessage("截止时间必须晚于当前时间")
+	}
+
+	stages, err := buildTaskStages(req.Amount, req.Deadline, req.Stages)
+	if err != nil {
+		return nil, err
+	}
+
+	maxRevisions := s.cfg.MaxRevisions
+	if req.MaxRevisions != nil {
+		if *req.MaxRevisions < 0 {
+			return nil, ErrInvalidParam.WithMessage("最大整改轮次不能为负数")
+		}
+		maxRevisions = *req.MaxRevisions
+	}
+
+	task := &models.Task{
+		PublisherID:     req.PublisherID,
+		Title:           title,
+		Content:         req.Content,
+		Amount:          req.Amount,
+		ServiceFeeRatio: s.cfg.ServiceFeeRatio,
+		DepositRatio:    s.cfg.DepositRatio,
+		MaxRevisions:    maxRevisions,
+		Deadline:        req.Deadline,
+		Status:          models.TaskStatusDraft,
+		CategoryID:      req.CategoryID,
+		Tags:            req.Tags,
+		Attachments:     req.Attachments,
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 接取者为空时写入NULL，避免违反外键约束；服务费明细在支付预付款时计算，JSON字段不能写入空串
+		if err := tx.Omit("TakerID", "Stages", "FeeBreakdown").Create(task).Error; err != nil {
+			return fmt.Errorf("创建任务失败: %w", err)
+		}
+
+		if len(stages) > 0 {
+			for i := range stages {
+				stages[i].TaskID = task.ID
+			}
+			if err := tx.Create(&stages).Error; err != nil {
+				return fmt.Errorf("创建任务阶段失败: %w", err)
+			}
+			task.Stages = stages
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// GetTask 获取任务详情
+func (s *TaskService) GetTask(ctx context.Context, taskID uint64) (*models.Task, error) {
+	var task models.Task
+	err := s.db.WithContext(ctx).
+		Preload("Publisher").
+		Preload("Taker").
+		Preload("Stages", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sort_order ASC, stage_id ASC")
+		}).
+		Preload("StatusLogs", func(db *gorm.DB) *gorm.DB {
+			return db.Order("create_time ASC, log_id ASC")
+		}).
+		First(&task, taskID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("查询任务失败: %w", err)
+	}
+
+	return &task, nil
+}
+
+// ListTasks 查询任务列表
+func (s *TaskService) ListTasks(ctx context.Context, req *TaskListRequest) ([]models.Task, int64, error) {
+	var tasks []models.Task
+	var total int64
+
+	db := s.db.WithContext(ctx).Model(&models.Task{})
+	if req.Status != nil {
+		db = db.Where("status = ?", *req.Status)
+	}
+	if req.CategoryID != nil {
+		db = db.Where("category_id = ?", *req.CategoryID)
+	}
+	if req.PublisherID != nil {
+		db = db.Where("publisher_id = ?", *req.PublisherID)
+	}
+	if req.TakerID != nil {
+		db = db.Where("taker_id = ?", *req.TakerID)
+	}
+	if req.Keyword != "" {
+		db = db.Where("title LIKE ? OR content LIKE ?", "%"+req.Keyword+"%", "%"+req.Keyword+"%")
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取任务总数失败: %w", err)
+	}
+
+	offset, limit := utils.Pagination(req.Page, req.PageSize)
+	if err := db.Preload("Publisher").
+		Order("create_time DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&tasks).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询任务列表失败: %w", err)
+	}
+
+	return tasks, total, nil
+}
+
+// PublishTask 发布任务，须先完成预付款托管
+func (s *TaskService) PublishTask(ctx context.Context, taskID, userID uint64, reason string) (*models.Task, error) {
+	return s.transit(ctx, taskID, userID, models.TaskEventPublish, reason, func(tx *gorm.DB, task *models.Task) error {
+		if task.IsExpired() {
+			return ErrTaskExpired
+		}
+		prepay, err := findPaidPrepay(tx, task.ID)
+		if err != nil {
+			return err
+		}
+		if prepay == nil {
+			return ErrTaskNotPaid
+		}
+		return nil
+	})
+}
+
+// TakeTask 接取任务，同时从接取者钱包冻结保证金
+func (s *TaskService) TakeTask(ctx context.Context, taskID, userID uint64, reason string) (*models.Task, error) {
+	return s.transit(ctx, taskID, userID, models.TaskEventTake, reason, func(tx *gorm.DB, task *models.Task) error {
+		if task.IsExpired() {
+			return ErrTaskExpired
+		}
+		if task.HasTaker() {
+			return ErrTaskInvalidTransition.WithMessage("任务已被接取")
+		}
+		task.TakerID = userID
+		if err := lockTakerDeposit(tx, task); err != nil {
+			return err
+		}
+		_, err := startNextStage(tx, task.ID)
+		return err
+	})
+}
+
+// CancelTask 取消任务，已托管的预付款解冻退回发布者余额，逾期任务接取者的保证金在违规处理后退还
+func (s *TaskService) CancelTask(ctx context.Context, taskID, userID uint64, reason string) (*models.Task, error) {
+	return s.transit(ctx, taskID, userID, models.TaskEventCancel, reason, func(tx *gorm.DB, task *models.Task) error {
+		if err := releaseEscrow(tx, task, reason); err != nil {
+			return err
+		}
+		return releaseTakerDeposit(tx, task, "任务取消")
+	})
+}
+
+// ExpireOverdueTasks 处理超过截止时间的任务，返回状态实际变更的数量：
+// 无人接取的任务自动取消并解冻预付款，进行中的任务转为已逾期并按配置对接取者记违约，同时通知双方；
+// 单个任务处理失败时跳过并继续，失败原因汇总后返回由调度器记录
+func (s *TaskService) ExpireOverdueTasks(ctx context.Context) (int, error) {
+	var tasks []models.Task
+	if err := s.db.WithContext(ctx).
+		Select("task_id").
+		Where("status IN ? AND deadline < ?", []int8{models.TaskStatusAvailable, models.TaskStatusInProgress}, time.Now()).
+		Order("deadline ASC").
+		Limit(100).
+		Find(&tasks).Error; err != nil {
+		return 0, fmt.Errorf("查询逾期任务失败: %w", err)
+	}
+
+	expired := 0
+	var errs []error
+	for _, t := range tasks {
+		changed := false
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			task, err := lockTask(tx, t.ID)
+			if err != nil {
+				return err
+			}
+			if !task.IsExpired() {
+				return nil
+			}
+			switch task.Status {
+			case models.TaskStatusAvailable:
+				err = s.expireAvailableTask(tx, task)
+			case models.TaskStatusInProgress:
+				err = s.expireInProgressTask(tx, task)
+			default:
+				return nil
+			}
+			changed = err == nil
+			return err
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("处理逾期任务%d失败: %w", t.ID, err))
+			continue
+		}
+		if changed {
+			expired++
+		}
+	}
+
+	return expired, errors.Join(errs...)
+}
+
+// expireAvailableTask 截止时仍无人接取的任务自动取消，预付款解冻退回发布者，须在事务内调用
+func (s *TaskService) expireAvailableTask(tx *gorm.DB, task *models.Task) error {
+	reason := "超过截止时间无人接取，系统自动取消"
+	if err := releaseEscrow(tx, task, reason); err != nil {
+		return err
+	}
+	if err := fireTaskEvent(tx, task, models.TaskEventExpire, 0, reason); err != nil {
+		return err
+	}
+	return notifyTask(tx, task.PublisherID, task, "任务已自动取消",
+		fmt.Sprintf("任务「%s」截止时仍无人接取，已自动取消，预付款已退回钱包余额", task.Title))
+}
+
+// expireInProgressTask 截止时仍未提交验收的任务转为已逾期，对接取者记逾期违约并通知双方，须在事务内调用
+func (s *TaskService) expireInProgressTask(tx *gorm.DB, task *models.Task) error {
+	if err := fireTaskEvent(tx, task, models.TaskEventExpire, 0, "超过截止时间未提交验收"); err != nil {
+		return err
+	}
+
+	penalty, err := s.recordOverdueViolation(tx, task)
+	if err != nil {
+		return err
+	}
+
+	deadline := task.Deadline.Format("2006-01-02 15:04")
+	takerContent := fmt.Sprintf("任务「%s」已超过截止时间%s，请尽快提交交付", task.Title, deadline)
+	if penalty.IsPositive() {
+		takerContent = fmt.Sprintf("%s，本次逾期记违约金%s元", takerContent, penalty)
+	}
+	if err := notifyTask(tx, task.TakerID, task, "任务已逾期", takerContent); err != nil {
+		return err
+	}
+	return notifyTask(tx, task.PublisherID, task, "任务已逾期",
+		fmt.Sprintf("任务「%s」接取者未在截止时间%s前提交验收，您可继续等待交付或取消任务", task.Title, deadline))
+}
+
+// recordOverdueViolation 为逾期任务的接取者记录逾期违规，违约金按任务金额和配置比例计算；
+// 同一任务已有逾期违规时不重复记录，返回本次违约金
+func (s *TaskService) recordOverdueViolation(tx *gorm.DB, task *models.Task) (money.Money, error) {
+	var count int64
+	if err := tx.Model(&models.Violation{}).
+		Where("user_id = ? AND task_id = ? AND violate_type = ?", task.TakerID, task.ID, models.ViolationTypeDelay).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("查询逾期违规记录失败: %w", err)
+	}
+	if count > 0 {
+		return money.Zero, nil
+	}
+
+	evidence, err := json.Marshal(map[string]interface{}{
+		"deadline":   task.Deadline,
+		"overdue_at": time.Now(),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("序列化违规证据失败: %w", err)
+	}
+
+	penalty := task.Amount.MulRate(s.cfg.OverduePenaltyRatio, money.RoundHalfUp)
+	violation := &models.Violation{
+		UserID:      task.TakerID,
+		TaskID:      &task.ID,
+		ViolateType: models.ViolationTypeDelay,
+		Penalty:     penalty,
+		Description: fmt.Sprintf("任务「%s」超过截止时间未提交验收", task.Title),
+		Evidence:    string(evidence),
+		Status:      models.ViolationStatusPending,
+	}
+	if err := tx.Create(violation).Error; err != nil {
+		return 0, fmt.Errorf("记录逾期违规失败: %w", err)
+	}
+	return penalty, nil
+}
+
+// transit 触发任务状态事件，在事务内加锁校验角色和状态机后更新任务并记录变更历史
+func (s *TaskService) transit(ctx context.Context, taskID, userID uint64, event models.TaskEvent, reason string, mutate func(tx *gorm.DB, task *models.Task) error) (*models.Task, error) {
+	role, ok := taskEventRoles[event]
+	if !ok {
+		return nil, ErrTaskInvalidTransition.WithMessage(fmt.Sprintf("未知的任务事件: %s", event))
+	}
+
+	var task *models.Task
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		task, err = lockTask(tx, taskID)
+		if err != nil {
+			return err
+		}
+
+		if err := checkTaskRole(task, userID, role); err != nil {
+			return err
+		}
+
+		toStatus, err := models.FireTaskEvent(task.Status, event)
+		if err != nil {
+			return ErrTaskInvalidTransition.WithMessage(err.Error())
+		}
+
+		if mutate != nil {
+			if err := mutate(tx, task); err != nil {
+				return err
+			}
+		}
+
+		return updateTaskStatus(tx, task, toStatus, event, userID, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// lockTask 在事务内加行锁读取任务
+func lockTask(tx *gorm.DB, taskID uint64) (*models.Task, error) {
+	var task models.Task
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&task, taskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("查询任务失败: %w", err)
+	}
+	return &task, nil
+}
+
+// updateTaskStatus 以当前状态为条件更新任务状态并写入状态变更记录，须在事务内调用
+func updateTaskStatus(tx *gorm.DB, task *models.Task, toStatus int8, event models.TaskEvent, operatorID uint64, reason string) error {
+	fromStatus := task.Status
+	updates := map[string]interface{}{
+		"status":      toStatus,
+		"update_time": time.Now(),
+	}
+	if task.HasTaker() {
+		updates["taker_id"] = task.TakerID
+	}
+	result := tx.Model(&models.Task{}).
+		Where("task_id = ? AND status = ?", task.ID, fromStatus).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("更新任务状态失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrTaskInvalidTransition.WithMessage("任务状态已变更，请刷新后重试")
+	}
+
+	log := &models.TaskStatusLog{
+		TaskID:     task.ID,
+		FromStatus: fromStatus,
+		ToStatus:   toStatus,
+		Event:      string(event),
+		OperatorID: operatorID,
+		Reason:     reason,
+	}
+	if err := tx.Create(log).Error; err != nil {
+		return fmt.Errorf("记录任务状态变更失败: %w", err)
+	}
+
+	task.Status = toStatus
+	return nil
+}
+
+// checkTaskRole 校验用户是否有权执行操作
+func checkTaskRole(task *models.Task, userID uint64, role taskRole) error {
+	switch role {
+	case taskRolePublisher:
+		if task.PublisherID != userID {
+			return ErrTaskForbidden
+		}
+	case taskRoleTaker:
+		if task.TakerID != userID {
+			return ErrTaskForbidden
+		}
+	case taskRoleOther:
+		if task.PublisherID == userID {
+			return ErrTaskSelfTake
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"task-platform-api/internal/models"
+	"task-platform-api/pkg/money"
+)
+
+// CreateTaskStageRequest 创建任务阶段请求
+type CreateTaskStageRequest struct {
+	StageName   string     `json:"stage_name"`
+	AmountRatio float64    `json:"amount_ratio"`
+	Deadline    *time.Time `json:"deadline"`
+	Description string     `json:"description"`
+}
+
+// buildTaskStages 校验阶段定义并按比例拆分任务金额，比例之和必须为1
+func buildTaskStages(amount money.Money, deadline time.Time, reqs []CreateTaskStageRequest) ([]models.TaskStage, error) {
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+
+	// 比例字段为decimal(3,2)，按百分点整数校验避免浮点误差
+	percents := make([]int64, len(reqs))
+	var totalPercent int64
+	for i, req := range reqs {
+		if strings.TrimSpace(req.StageName) == "" {
+			return nil, ErrInvalidParam.WithMessage(fmt.Sprintf("第%d个阶段名称不能为空", i+1))
+		}
+		percent := int64(math.Round(req.AmountRatio * 100))
+		if percent <= 0 || math.Abs(req.AmountRatio*100-float64(percent)) > 1e-6 {
+			return nil, ErrInvalidParam.WithMessage(fmt.Sprintf("第%d个阶段金额比例无效，需为0.01~1之间的两位小数", i+1))
+		}
+		if req.Deadline != nil && req.Deadline.After(deadline) {
+			return nil, ErrInvalidParam.WithMessage(fmt.Sprintf("第%d个阶段截止时间不能晚于任务截止时间", i+1))
+		}
+		percents[i] = percent
+		totalPercent += percent
+	}
+	if totalPercent != 100 {
+		return nil, ErrInvalidParam.WithMessage(fmt.Sprintf("阶段金额比例之和必须为1，当前为%.2f", float64(totalPercent)/100))
+	}
+
+	// 按比例拆分金额，各阶段之和严格等于任务金额
+	amounts := amount.Allocate(percents...)
+	stages := make([]models.TaskStage, len(reqs))
+	for i, req := range reqs {
+		stages[i] = models.TaskStage{
+			StageName:   strings.TrimSpace(req.StageName),
+			AmountRatio: float64(percents[i]) / 100,
+			Amount:      amounts[i],
+			Deadline:    req.Deadline,
+			Status:      models.StageStatusPending,
+			Description: req.Description,
+			SortOrder:   i + 1,
+		}
+	}
+
+	return stages, nil
+}
+
+// loadTaskStages 读取任务的全部阶段并按顺序排列
+func loadTaskStages(tx *gorm.DB, task *models.Task) error {
+	if err := tx.Where("task_id = ?", task.ID).
+		Order("sort_order ASC, stage_id ASC").
+		Find(&task.Stages).Error; err != nil {
+		return fmt.Errorf("查询任务阶段失败: %w", err)
+	}
+	return nil
+}
+
+// startNextStage 将下一个待开始的阶段置为进行中，没有剩余阶段时返回nil
+func startNextStage(tx *gorm.DB, taskID uint64) (*models.TaskStage, error) {
+	var stage models.TaskStage
+	err := tx.Where("task_id = ? AND status = ?", taskID, models.StageStatusPending).
+		Order("sort_order ASC, stage_id ASC").
+		First(&stage).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询任务阶段失败: %w", err)
+	}
+
+	if err := tx.Model(&stage).Update("status", models.StageStatusInProgress).Error; err != nil {
+		return nil, fmt.Errorf("更新任务阶段失败: %w", err)
+	}
+	stage.Status = models.StageStatusInProgress
+
+	return &stage, nil
+}
@@ -0,0 +1,20 @@
+package services
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txContextKey struct{}
+
+// contextWithTx 将事务放入上下文，供同步事件处理器在发布方事务内执行
+func contextWithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+// txFromContext 读取上下文中的事务，不存在时返回nil
+func txFromContext(ctx context.Context) *gorm.DB {
+	tx, _ := ctx.Value(txContextKey{}).(*gorm.DB)
+	return tx
+}
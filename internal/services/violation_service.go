@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/internal/models"
+	"task-platform-api/pkg/money"
+	"task-platform-api/pkg/utils"
+)
+
+// ViolationListRequest 违规列表查询条件
+type ViolationListRequest struct {
+	UserID   *uint64
+	TaskID   *uint64
+	Status   *int8
+	Page     int
+	PageSize int
+}
+
+// ViolationService 违规处理服务，管理员确认违规后从接取者保证金没收违约金，撤销后保证金照常退还
+type ViolationService struct {
+	db  *gorm.DB
+	cfg config.TaskConfig
+}
+
+// NewViolationService 创建违规处理服务
+func NewViolationService(db *gorm.DB, cfg config.TaskConfig) *ViolationService {
+	return &ViolationService{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// ListViolations 分页查询违规记录
+func (s *ViolationService) ListViolations(ctx context.Context, req *ViolationListRequest) ([]models.Violation, int64, error) {
+	var violations []models.Violation
+	var total int64
+
+	db := s.db.WithContext(ctx).Model(&models.Violation{})
+	if req.UserID != nil {
+		db = db.Where("user_id = ?", *req.UserID)
+	}
+	if req.TaskID != nil {
+		db = db.Where("task_id = ?", *req.TaskID)
+	}
+	if req.Status != nil {
+		db = db.Where("status = ?", *req.Status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取违规记录总数失败: %w", err)
+	}
+
+	offset, limit := utils.Pagination(req.Page, req.PageSize)
+	if err := db.Order("violate_id DESC").Offset(offset).Limit(limit).Find(&violations).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询违规记录失败: %w", err)
+	}
+
+	return violations, total, nil
+}
+
+// ConfirmViolation 管理员确认违规：penalty不为空时覆盖记录的违约金，须大于0；forfeitAll为true时没收全部剩余保证金，
+// 此时不能同时指定违约金。违约金从接取者在该任务冻结的保证金中扣除，按配置比例补偿发布者，其余归平台，任务已结束时退还剩余保证金
+func (s *ViolationService) ConfirmViolation(ctx context.Context, violationID uint64, penalty *money.Money, forfeitAll bool) (*models.Violation, error) {
+	if forfeitAll && penalty != nil {
+		return nil, ErrInvalidParam.WithMessage("没收全部保证金时不能指定违约金")
+	}
+	if penalty != nil && !penalty.IsPositive() {
+		return nil, ErrInvalidParam.WithMessage("违约金须大于0，没收全部保证金请使用forfeit_all")
+	}
+
+	return s.handle(ctx, violationID, models.ViolationStatusHandled, func(tx *gorm.DB, violation *models.Violation, task *models.Task) error {
+		if penalty != nil {
+			violation.Penalty = *penalty
+		}
+		if task == nil || task.TakerID != violation.UserID {
+			return nil
+		}
+
+		amount := violation.Penalty
+		if forfeitAll {
+			held, err := takerDepositHeld(tx, task.ID, task.TakerID)
+			if err != nil {
+				return err
+			}
+			amount = held
+		} else if !amount.IsPositive() {
+			return ErrInvalidParam.WithMessage("违规记录未设置违约金，请指定违约金或没收全部保证金")
+		}
+		forfeited, err := forfeitTakerDeposit(tx, task, violation, amount, s.cfg.PenaltyPublisherRatio)
+		if err != nil || !forfeited.IsPositive() {
+			return err
+		}
+		if forfeitAll {
+			violation.Penalty = forfeited
+		}
+		if err := notifyTask(tx, violation.UserID, task, "违规已确认",
+			fmt.Sprintf("您在任务「%s」中的违规已确认，已从保证金扣除违约金%s元", task.Title, forfeited)); err != nil {
+			return err
+		}
+		return notifyTask(tx, task.PublisherID, task, "违约金补偿到账",
+			fmt.Sprintf("任务「%s」接取者违规已确认，违约金补偿已转入您的钱包余额", task.Title))
+	})
+}
+
+// DismissViolation 管理员撤销违规，任务已结束时退还接取者的保证金
+func (s *ViolationService) DismissViolation(ctx context.Context, violationID uint64, reason string) (*models.Violation, error) {
+	return s.handle(ctx, violationID, models.ViolationStatusDismissed, func(tx *gorm.DB, violation *models.Violation, task *models.Task) error {
+		if task == nil {
+			return nil
+		}
+		return notifyTask(tx, violation.UserID, task, "违规已撤销",
+			fmt.Sprintf("您在任务「%s」中的违规记录已撤销: %s", task.Title, reason))
+	})
+}
+
+// handle 加锁处理待处理的违规并更新状态，关联任务已完成或取消时随后退还接取者的剩余保证金；
+// 按 任务 → 违规 的顺序加锁，与任务流转保持一致
+func (s *ViolationService) handle(ctx context.Context, violationID uint64, status int8, fn func(tx *gorm.DB, violation *models.Violation, task *models.Task) error) (*models.Violation, error) {
+	var violation models.Violation
+	var task *models.Task
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("violate_id", "task_id").First(&violation, violationID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrViolationNotFound
+			}
+			return fmt.Errorf("查询违规记录失败: %w", err)
+		}
+		if violation.TaskID != nil {
+			var err error
+			if task, err = lockTask(tx, *violation.TaskID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&violation, violationID).Error; err != nil {
+			return fmt.Errorf("查询违规记录失败: %w", err)
+		}
+		if !violation.IsOpen() {
+			return ErrViolationInvalidStatus
+		}
+
+		if err := fn(tx, &violation, task); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&violation).Updates(map[string]interface{}{
+			"status":      status,
+			"penalty":     violation.Penalty,
+			"handle_time": now,
+		}).Error; err != nil {
+			return fmt.Errorf("更新违规记录失败: %w", err)
+		}
+		violation.Status = status
+		violation.HandleTime = &now
+
+		if task != nil && (task.IsCompleted() || task.IsCancelled()) {
+			return releaseTakerDeposit(tx, task, "违规处理完毕")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &violation, nil
+}
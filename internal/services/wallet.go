@@ -0,0 +1,257 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"task-platform-api/internal/models"
+	"task-platform-api/pkg/money"
+)
+
+// walletMaxRetries 钱包版本冲突时的最大重试次数
+const walletMaxRetries = 3
+
+// walletEntry 钱包流水的关联信息
+type walletEntry struct {
+	TradeID     *uint64
+	RelatedID   uint64
+	RelatedType string
+	Description string
+}
+
+// walletChange 钱包变动，余额与冻结余额的增量可正可负
+type walletChange struct {
+	walletEntry
+	Type          string
+	Amount        money.Money
+	BalanceDelta  money.Money
+	FrozenDelta   money.Money
+	IncomeDelta   money.Money
+	WithdrawDelta money.Money
+}
+
+// creditWallet 入账到可用余额并计入累计收入，须在事务内调用
+func creditWallet(tx *gorm.DB, userID uint64, amount money.Money, entry walletEntry) (*models.Wallet, error) {
+	return mutateWallet(tx, userID, walletChange{
+		walletEntry:  entry,
+		Type:         models.WalletTxIncome,
+		Amount:       amount,
+		BalanceDelta: amount,
+		IncomeDelta:  amount,
+	})
+}
+
+// debitWallet 从可用余额扣款，可用余额不足时返回错误，须在事务内调用
+func debitWallet(tx *gorm.DB, userID uint64, amount money.Money, entry walletEntry) (*models.Wallet, error) {
+	return mutateWallet(tx, userID, walletChange{
+		walletEntry:  entry,
+		Type:         models.WalletTxExpense,
+		Amount:       amount,
+		BalanceDelta: -amount,
+	})
+}
+
+// freezeWallet 将可用余额转入冻结余额，可用余额不足时返回错误，须在事务内调用
+func freezeWallet(tx *gorm.DB, userID uint64, amount money.Money, entry walletEntry) (*models.Wallet, error) {
+	return mutateWallet(tx, userID, walletChange{
+		walletEntry:  entry,
+		Type:         models.WalletTxFreeze,
+		Amount:       amount,
+		BalanceDelta: -amount,
+		FrozenDelta:  amount,
+	})
+}
+
+// unfreezeWallet 将冻结余额解冻回可用余额，冻结余额不足时返回错误，须在事务内调用
+func unfreezeWallet(tx *gorm.DB, userID uint64, amount money.Money, entry walletEntry) (*models.Wallet, error) {
+	return mutateWallet(tx, userID, walletChange{
+		walletEntry:  entry,
+		Type:         models.WalletTxUnfreeze,
+		Amount:       amount,
+		BalanceDelta: amount,
+		FrozenDelta:  -amount,
+	})
+}
+
+// creditFrozenWallet 外部资金直接入账到冻结余额，如预付款托管，须在事务内调用
+func creditFrozenWallet(tx *gorm.DB, userID uint64, amount money.Money, entry walletEntry) (*models.Wallet, error) {
+	return mutateWallet(tx, userID, walletChange{
+		walletEntry: entry,
+		Type:        models.WalletTxFreeze,
+		Amount:      amount,
+		FrozenDelta: amount,
+	})
+}
+
+// debitFrozenWallet 从冻结余额扣款，如退款出账、平台服务费，冻结余额不足时返回错误，须在事务内调用
+func debitFrozenWallet(tx *gorm.DB, userID uint64, amount money.Money, entry walletEntry) (*models.Wallet, error) {
+	return mutateWallet(tx, userID, walletChange{
+		walletEntry: entry,
+		Type:        models.WalletTxExpense,
+		Amount:      amount,
+		FrozenDelta: -amount,
+	})
+}
+
+// payoutWallet 提现打款成功，从冻结余额扣除并计入累计提现，须在事务内调用
+func payoutWallet(tx *gorm.DB, userID uint64, amount money.Money, entry walletEntry) (*models.Wallet, error) {
+	return mutateWallet(tx, userID, walletChange{
+		walletEntry:   entry,
+		Type:          models.WalletTxExpense,
+		Amount:        amount,
+		FrozenDelta:   -amount,
+		WithdrawDelta: amount,
+	})
+}
+
+// transferWallet 从付款方冻结余额划转到收款方可用余额，双方各写一条流水；
+// 按用户ID从小到大更新，避免双方互相转账的并发事务死锁，须在事务内调用
+func transferWallet(tx *gorm.DB, fromUserID, toUserID uint64, amount money.Money, out, in walletEntry) error {
+	if !amount.IsPositive() {
+		return nil
+	}
+	debitFrom := func() error {
+		_, err := debitFrozenWallet(tx, fromUserID, amount, out)
+		return err
+	}
+	creditTo := func() error {
+		_, err := creditWallet(tx, toUserID, amount, in)
+		return err
+	}
+
+	steps := []func() error{debitFrom, creditTo}
+	if toUserID < fromUserID {
+		steps = []func() error{creditTo, debitFrom}
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mutateWallet 以Version做比较交换更新钱包余额，并在同一事务内写入前后余额准确的钱包流水。
+// 可重复读隔离级别下普通读取返回事务快照，版本冲突或快照余额不足时改用当前读重新读取最新版本后重试；
+// 可用余额或冻结余额将变为负数时返回错误，须在事务内调用
+func mutateWallet(tx *gorm.DB, userID uint64, change walletChange) (*models.Wallet, error) {
+	current := false
+	wallet, err := loadWallet(tx, userID, current)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		balanceAfter := wallet.Balance + change.BalanceDelta
+		frozenAfter := wallet.FrozenBalance + change.FrozenDelta
+		var insufficient error
+		if balanceAfter.IsNegative() {
+			insufficient = ErrInsufficientBalance
+		} else if frozenAfter.IsNegative() {
+			insufficient = ErrInsufficientFrozen
+		}
+
+		if insufficient == nil {
+			updated, err := compareAndSwapWallet(tx, wallet, balanceAfter, frozenAfter, change)
+			if err != nil {
+				return nil, err
+			}
+			if updated {
+				break
+			}
+		} else if current {
+			return nil, insufficient
+		}
+
+		if attempt >= walletMaxRetries {
+			return nil, ErrWalletConflict
+		}
+		current = true
+		if wallet, err = loadWallet(tx, userID, current); err != nil {
+			return nil, err
+		}
+	}
+
+	record := &models.WalletTransaction{
+		UserID:        wallet.UserID,
+		TradeID:       change.TradeID,
+		Type:          change.Type,
+		Amount:        change.Amount,
+		BalanceBefore: wallet.Balance - change.BalanceDelta,
+		BalanceAfter:  wallet.Balance,
+		Description:   change.Description,
+		RelatedID:     change.RelatedID,
+		RelatedType:   change.RelatedType,
+	}
+	if err := tx.Create(record).Error; err != nil {
+		return nil, fmt.Errorf("写入钱包流水失败: %w", err)
+	}
+	return wallet, nil
+}
+
+// compareAndSwapWallet 仅当版本号未变化时写入新余额并递增版本号，成功后同步wallet，返回是否更新成功
+func compareAndSwapWallet(tx *gorm.DB, wallet *models.Wallet, balance, frozen money.Money, change walletChange) (bool, error) {
+	updates := map[string]interface{}{
+		"balance":        balance,
+		"frozen_balance": frozen,
+		"version":        wallet.Version + 1,
+		"updated_at":     time.Now(),
+	}
+	if !change.IncomeDelta.IsZero() {
+		updates["total_income"] = wallet.TotalIncome + change.IncomeDelta
+	}
+	if !change.WithdrawDelta.IsZero() {
+		updates["total_withdraw"] = wallet.TotalWithdraw + change.WithdrawDelta
+	}
+
+	result := tx.Model(&models.Wallet{}).
+		Where("id = ? AND version = ?", wallet.ID, wallet.Version).
+		Updates(updates)
+	if result.Error != nil {
+		return false, fmt.Errorf("更新钱包失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	wallet.Balance = balance
+	wallet.FrozenBalance = frozen
+	wallet.TotalIncome += change.IncomeDelta
+	wallet.TotalWithdraw += change.WithdrawDelta
+	wallet.Version++
+	return true, nil
+}
+
+// loadWallet 读取用户钱包，不存在时自动创建；current为true时使用当前读获取已提交的最新版本
+func loadWallet(tx *gorm.DB, userID uint64, current bool) (*models.Wallet, error) {
+	query := tx
+	if current {
+		query = tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var wallet models.Wallet
+	err := query.Where("user_id = ?", userID).First(&wallet).Error
+	if err == nil {
+		return &wallet, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("查询钱包失败: %w", err)
+	}
+
+	// 并发创建时以唯一索引去重，再以当前读取回已存在的钱包
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Wallet{UserID: userID}).Error; err != nil {
+		return nil, fmt.Errorf("创建钱包失败: %w", err)
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		return nil, fmt.Errorf("查询钱包失败: %w", err)
+	}
+	return &wallet, nil
+}
+
+// lockWallet 加行锁读取用户钱包，不存在时自动创建；仅用于需要串行化同一用户操作的场景，余额变动统一通过mutateWallet
+func lockWallet(tx *gorm.DB, userID uint64) (*models.Wallet, error) {
+	return loadWallet(tx, userID, true)
+}
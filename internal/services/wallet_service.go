@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"task-platform-api/internal/models"
+	"task-platform-api/pkg/money"
+	"task-platform-api/pkg/utils"
+)
+
+// WalletTransactionListRequest 钱包流水查询条件，按流水ID倒序游标分页
+type WalletTransactionListRequest struct {
+	UserID    uint64
+	Type      string     // 流水类型，为空表示全部
+	StartTime *time.Time // 起始时间（含）
+	EndTime   *time.Time // 结束时间（不含）
+	TaskID    *uint64    // 关联任务
+	Cursor    uint64     // 上一页返回的游标，0表示第一页
+	Limit     int
+}
+
+// WalletStatementLine 对账单明细行
+type WalletStatementLine struct {
+	ID            uint64      `json:"id"`
+	CreatedAt     time.Time   `json:"created_at"`
+	Type          string      `json:"type"`
+	Amount        money.Money `json:"amount"`
+	BalanceBefore money.Money `json:"balance_before"`
+	BalanceAfter  money.Money `json:"balance_after"`
+	Description   string      `json:"description"`
+	RelatedType   string      `json:"related_type"`
+	RelatedID     uint64      `json:"related_id"`
+	TradeID       *uint64     `json:"trade_id"`
+}
+
+// WalletStatement 钱包月度对账单，余额均为可用余额
+type WalletStatement struct {
+	UserID         uint64                `json:"user_id"`
+	Month          string                `json:"month"`
+	StartTime      time.Time             `json:"start_time"`
+	EndTime        time.Time             `json:"end_time"`
+	OpeningBalance money.Money           `json:"opening_balance"` // 期初可用余额
+	ClosingBalance money.Money           `json:"closing_balance"` // 期末可用余额
+	TotalIncome    money.Money           `json:"total_income"`
+	TotalExpense   money.Money           `json:"total_expense"`
+	TotalFreeze    money.Money           `json:"total_freeze"`
+	TotalUnfreeze  money.Money           `json:"total_unfreeze"`
+	Lines          []WalletStatementLine `json:"lines"`
+	GeneratedAt    time.Time             `json:"generated_at"`
+}
+
+// WalletService 钱包查询服务，提供余额、流水查询和月度对账单导出
+type WalletService struct {
+	db *gorm.DB
+}
+
+// NewWalletService 创建钱包查询服务
+func NewWalletService(db *gorm.DB) *WalletService {
+	return &WalletService{
+		db: db,
+	}
+}
+
+// GetWallet 查询用户钱包，尚未开通时返回余额为0的钱包
+func (s *WalletService) GetWallet(ctx context.Context, userID uint64) (*models.Wallet, error) {
+	var wallet models.Wallet
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).First(&wallet).Error
+	if err == nil {
+		return &wallet, nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.Wallet{UserID: userID}, nil
+	}
+	return nil, fmt.Errorf("查询钱包失败: %w", err)
+}
+
+// ListTransactions 游标分页查询钱包流水，返回本页流水和下一页游标，没有更多时游标为0
+func (s *WalletService) ListTransactions(ctx context.Context, req *WalletTransactionListRequest) ([]models.WalletTransaction, uint64, error) {
+	if req.Type != "" && !isWalletTxType(req.Type) {
+		return nil, 0, ErrInvalidParam.WithMessage("流水类型错误")
+	}
+	_, limit := utils.Pagination(1, req.Limit)
+
+	db := s.db.WithContext(ctx).Where("user_id = ?", req.UserID)
+	if req.Type != "" {
+		db = db.Where("type = ?", req.Type)
+	}
+	if req.StartTime != nil {
+		db = db.Where("created_at >= ?", *req.StartTime)
+	}
+	if req.EndTime != nil {
+		db = db.Where("created_at < ?", *req.EndTime)
+	}
+	if req.TaskID != nil {
+		db = db.Where(s.taskTransactions(*req.TaskID))
+	}
+	if req.Cursor > 0 {
+		db = db.Where("id < ?", req.Cursor)
+	}
+
+	var transactions []models.WalletTransaction
+	if err := db.Order("id DESC").Limit(limit + 1).Find(&transactions).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询钱包流水失败: %w", err)
+	}
+
+	var next uint64
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+		next = transactions[limit-1].ID
+	}
+	return transactions, next, nil
+}
+
+// MonthlyStatement 生成用户指定月份的钱包对账单，month为该月任意时间
+func (s *WalletService) MonthlyStatement(ctx context.Context, userID uint64, month time.Time) (*WalletStatement, error) {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+	end := start.AddDate(0, 1, 0)
+	db := s.db.WithContext(ctx)
+
+	// 期初余额取月初之前最后一条流水的交易后余额
+	var opening money.Money
+	var last models.WalletTransaction
+	err := db.Select("balance_after").
+		Where("user_id = ? AND created_at < ?", userID, start).
+		Order("created_at DESC, id DESC").
+		First(&last).Error
+	if err == nil {
+		opening = last.BalanceAfter
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("查询期初余额失败: %w", err)
+	}
+
+	var transactions []models.WalletTransaction
+	if err := db.Where("user_id = ? AND created_at >= ? AND created_at < ?", userID, start, end).
+		Order("created_at ASC, id ASC").
+		Find(&transactions).Error; err != nil {
+		return nil, fmt.Errorf("查询钱包流水失败: %w", err)
+	}
+
+	statement := &WalletStatement{
+		UserID:         userID,
+		Month:          start.Format("2006-01"),
+		StartTime:      start,
+		EndTime:        end,
+		OpeningBalance: opening,
+		ClosingBalance: opening,
+		Lines:          make([]WalletStatementLine, 0, len(transactions)),
+		GeneratedAt:    time.Now(),
+	}
+	for _, t := range transactions {
+		switch t.Type {
+		case models.WalletTxIncome:
+			statement.TotalIncome += t.Amount
+		case models.WalletTxExpense:
+			statement.TotalExpense += t.Amount
+		case models.WalletTxFreeze:
+			statement.TotalFreeze += t.Amount
+		case models.WalletTxUnfreeze:
+			statement.TotalUnfreeze += t.Amount
+		}
+		statement.ClosingBalance = t.BalanceAfter
+		statement.Lines = append(statement.Lines, WalletStatementLine{
+			ID:            t.ID,
+			CreatedAt:     t.CreatedAt,
+			Type:          t.Type,
+			Amount:        t.Amount,
+			BalanceBefore: t.BalanceBefore,
+			BalanceAfter:  t.BalanceAfter,
+			Description:   t.Description,
+			RelatedType:   t.RelatedType,
+			RelatedID:     t.RelatedID,
+			TradeID:       t.TradeID,
+		})
+	}
+	return statement, nil
+}
+
+// WriteCSV 以CSV格式输出对账单：先输出汇总，空一行后输出流水明细；带UTF-8 BOM以便表格软件正确识别中文
+func (st *WalletStatement) WriteCSV(w io.Writer) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	rows := [][]string{
+		{"用户ID", strconv.FormatUint(st.UserID, 10)},
+		{"账单月份", st.Month},
+		{"期初余额", st.OpeningBalance.String()},
+		{"期末余额", st.ClosingBalance.String()},
+		{"收入合计", st.TotalIncome.String()},
+		{"支出合计", st.TotalExpense.String()},
+		{"冻结合计", st.TotalFreeze.String()},
+		{"解冻合计", st.TotalUnfreeze.String()},
+		{"生成时间", st.GeneratedAt.Format("2006-01-02 15:04:05")},
+		{},
+		{"流水ID", "时间", "类型", "金额", "交易前余额", "交易后余额", "说明", "关联业务", "关联ID", "交易ID"},
+	}
+	for _, line := range st.Lines {
+		tradeID := ""
+		if line.TradeID != nil {
+			tradeID = strconv.FormatUint(*line.TradeID, 10)
+		}
+		rows = append(rows, []string{
+			strconv.FormatUint(line.ID, 10),
+			line.CreatedAt.Format("2006-01-02 15:04:05"),
+			models.WalletTxTypeName(line.Type),
+			line.Amount.String(),
+			line.BalanceBefore.String(),
+			line.BalanceAfter.String(),
+			line.Description,
+			line.RelatedType,
+			strconv.FormatUint(line.RelatedID, 10),
+			tradeID,
+		})
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("写入对账单失败: %w", err)
+	}
+	return nil
+}
+
+// taskTransactions 与任务相关的流水：直接关联任务、关联任务的交易（预付款、保证金、退款等）或任务的结算单
+func (s *WalletService) taskTransactions(taskID uint64) *gorm.DB {
+	return s.db.Where("related_type = ? AND related_id = ?", "task", taskID).
+		Or("trade_id IN (?)", s.db.Model(&models.Trade{}).Select("trade_id").Where("task_id = ?", taskID)).
+		Or("related_type = ? AND related_id IN (?)", "settlement",
+			s.db.Model(&models.Settlement{}).Select("settle_id").Where("task_id = ?", taskID))
+}
+
+// isWalletTxType 是否为合法的钱包流水类型
+func isWalletTxType(txType string) bool {
+	switch txType {
+	case models.WalletTxIncome, models.WalletTxExpense, models.WalletTxFreeze, models.WalletTxUnfreeze:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"task-platform-api/internal/testutil"
+	"task-platform-api/pkg/money"
+)
+
+var walletColumns = []string{"id", "user_id", "balance", "frozen_balance", "version"}
+
+// walletRows 用户3的钱包4
+func walletRows(balance, frozen string, version int64, once bool) testutil.StubRows {
+	return testutil.StubRows{
+		Match:   "FROM `wallets`",
+		Once:    once,
+		Columns: walletColumns,
+		Values:  [][]driver.Value{{int64(4), int64(3), []byte(balance), []byte(frozen), version}},
+	}
+}
+
+func TestMutateWalletRetriesOnVersionConflict(t *testing.T) {
+	// 快照读到版本7，期间并发事务已将余额改为120元、版本9
+	db, stub := testutil.NewGorm(t, walletRows("100.00", "0.00", 7, true), walletRows("120.00", "0.00", 9, false))
+	stub.AffectRows("UPDATE `wallets`", 0)
+
+	wallet, err := creditWallet(db, 3, money.FromCents(5000), walletEntry{Description: "任务收入"})
+	if err != nil {
+		t.Fatalf("入账失败: %v", err)
+	}
+	if wallet.Balance != money.FromCents(17000) || wallet.Version != 10 {
+		t.Fatalf("应基于最新版本入账: %+v", wallet)
+	}
+
+	updates := stub.Executed("UPDATE `wallets`")
+	if len(updates) != 2 {
+		t.Fatalf("版本冲突后应重试一次: %+v", updates)
+	}
+	if !updates[0].HasArgs("150.00", int64(8), int64(4), int64(7)) {
+		t.Errorf("首次更新应以快照版本做比较交换: %+v", updates[0])
+	}
+	if !updates[1].HasArgs("170.00", int64(10), int64(4), int64(9)) {
+		t.Errorf("重试应以当前读的版本做比较交换: %+v", updates[1])
+	}
+	if selects := stub.Executed("FOR UPDATE"); len(selects) != 1 {
+		t.Errorf("版本冲突后应以当前读重新读取: %+v", selects)
+	}
+
+	records := stub.Executed("INSERT INTO `wallet_transactions`")
+	if len(records) != 1 || !records[0].HasArgs("50.00", "120.00", "170.00") {
+		t.Fatalf("流水应记录实际更新时的前后余额: %+v", records)
+	}
+}
+
+func TestMutateWalletRejectsOverdraft(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(db *gorm.DB) error
+		wantErr error
+	}{
+		{
+			name: "可用余额不足",
+			mutate: func(db *gorm.DB) error {
+				_, err := debitWallet(db, 3, money.FromCents(10001), walletEntry{})
+				return err
+			},
+			wantErr: ErrInsufficientBalance,
+		},
+		{
+			name: "冻结余额不足",
+			mutate: func(db *gorm.DB) error {
+				_, err := debitFrozenWallet(db, 3, money.FromCents(2001), walletEntry{})
+				return err
+			},
+			wantErr: ErrInsufficientFrozen,
+		},
+	}
+	for _, tt := range tests {
+		db, stub := testutil.NewGorm(t, walletRows("100.00", "20.00", 7, false))
+
+		if err := tt.mutate(db); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: 应返回%v, 实际: %v", tt.name, tt.wantErr, err)
+			continue
+		}
+		// 快照余额不足时须以当前读确认，避免快照过期误判
+		if selects := stub.Executed("FOR UPDATE"); len(selects) != 1 {
+			t.Errorf("%s: 应以当前读确认余额不足: %+v", tt.name, selects)
+		}
+		if len(stub.Executed("UPDATE `")) > 0 || len(stub.Executed("INSERT")) > 0 {
+			t.Errorf("%s: 余额不足时不应更新钱包或写入流水", tt.name)
+		}
+	}
+}
+
+func TestMutateWalletRereadsStaleSnapshot(t *testing.T) {
+	// 快照余额100元不足，当前读到并发入账后的200元
+	db, stub := testutil.NewGorm(t, walletRows("100.00", "0.00", 7, true), walletRows("200.00", "0.00", 8, false))
+
+	wallet, err := debitWallet(db, 3, money.FromCents(15000), walletEntry{})
+	if err != nil {
+		t.Fatalf("当前读余额充足时应扣款成功: %v", err)
+	}
+	if wallet.Balance != money.FromCents(5000) || wallet.Version != 9 {
+		t.Fatalf("扣款后余额错误: %+v", wallet)
+	}
+
+	updates := stub.Executed("UPDATE `wallets`")
+	if len(updates) != 1 || !updates[0].HasArgs("50.00", int64(9), int64(4), int64(8)) {
+		t.Fatalf("应只以最新版本更新一次: %+v", updates)
+	}
+	records := stub.Executed("INSERT INTO `wallet_transactions`")
+	if len(records) != 1 || !records[0].HasArgs("150.00", "200.00", "50.00") {
+		t.Fatalf("流水应记录实际扣款时的前后余额: %+v", records)
+	}
+}
+
+func TestMutateWalletGivesUpAfterMaxRetries(t *testing.T) {
+	db, stub := testutil.NewGorm(t, walletRows("100.00", "0.00", 7, false))
+	stub.AffectRows("UPDATE `wallets`", 0, 0, 0, 0)
+
+	if _, err := creditWallet(db, 3, money.FromCents(100), walletEntry{}); !errors.Is(err, ErrWalletConflict) {
+		t.Fatalf("持续版本冲突应返回ErrWalletConflict, 实际: %v", err)
+	}
+	if updates := stub.Executed("UPDATE `wallets`"); len(updates) != walletMaxRetries+1 {
+		t.Fatalf("应重试%d次: %d", walletMaxRetries, len(updates))
+	}
+	if len(stub.Executed("INSERT")) > 0 {
+		t.Fatal("更新失败时不应写入流水")
+	}
+}
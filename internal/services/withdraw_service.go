@@ -0,0 +1,487 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/internal/models"
+	"task-platform-api/pkg/money"
+	"task-platform-api/pkg/payment"
+	"task-platform-api/pkg/utils"
+)
+
+// withdrawPollDelay 提交打款后超过该时长仍处理中的，由定时任务向渠道查询结果
+const withdrawPollDelay = time.Minute
+
+// withdrawMethodProviders 提现方式对应的打款渠道
+var withdrawMethodProviders = map[string]string{
+	models.WithdrawMethodAlipay: payment.ProviderAlipay,
+	models.WithdrawMethodWechat: payment.ProviderWechat,
+	models.WithdrawMethodBank:   payment.ProviderShouqianba,
+}
+
+// WithdrawAccount 收款账户，保存在提现申请的账户信息中
+type WithdrawAccount struct {
+	Account  string `json:"account"`             // 支付宝账号或用户ID、微信OpenID、银行卡号
+	RealName string `json:"real_name,omitempty"` // 收款人姓名
+	BankCode string `json:"bank_code,omitempty"` // 银行代码，银行卡提现必填
+}
+
+// CreateWithdrawRequest 提现请求
+type CreateWithdrawRequest struct {
+	UserID  uint64          `json:"user_id"`
+	Amount  money.Money     `json:"amount"`
+	Method  string          `json:"method"`
+	Account WithdrawAccount `json:"account"`
+}
+
+// WithdrawListRequest 提现列表查询条件
+type WithdrawListRequest struct {
+	UserID   *uint64
+	Status   *int8
+	Page     int
+	PageSize int
+}
+
+// WithdrawService 提现服务，申请时冻结金额，审核通过后通过渠道转账打款
+type WithdrawService struct {
+	db       *gorm.DB
+	cfg      config.RiskControlConfig
+	provider string
+	gateways *payment.Registry
+}
+
+// NewWithdrawService 创建提现服务，provider为统一的打款渠道，为空时按提现方式选择
+func NewWithdrawService(db *gorm.DB, cfg config.RiskControlConfig, provider string, gateways *payment.Registry) *WithdrawService {
+	return &WithdrawService{
+		db:       db,
+		cfg:      cfg,
+		provider: provider,
+		gateways: gateways,
+	}
+}
+
+// CreateWithdraw 申请提现：校验限额后冻结提现金额，大额或有风险的申请转人工审核，其余直接提交渠道打款
+func (s *WithdrawService) CreateWithdraw(ctx context.Context, req *CreateWithdrawRequest) (*models.WithdrawRequest, error) {
+	minAmount := s.cfg.MinWithdrawAmount
+	if !req.Amount.IsPositive() || req.Amount < minAmount {
+		return nil, ErrWithdrawAmountTooSmall.WithMessage(fmt.Sprintf("单笔最低提现%s元", minAmount))
+	}
+	if strings.TrimSpace(req.Account.Account) == "" {
+		return nil, ErrInvalidParam.WithMessage("收款账户不能为空")
+	}
+	if req.Method == models.WithdrawMethodBank && req.Account.BankCode == "" {
+		return nil, ErrInvalidParam.WithMessage("银行卡提现须提供银行代码")
+	}
+	if _, err := s.gatewayFor(req.Method); err != nil {
+		return nil, err
+	}
+	accountInfo, err := json.Marshal(req.Account)
+	if err != nil {
+		return nil, fmt.Errorf("序列化收款账户失败: %w", err)
+	}
+
+	var withdraw *models.WithdrawRequest
+	var reviewReasons []string
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 先锁钱包，同一用户的提现申请串行执行，保证当日限额统计准确
+		if _, err := lockWallet(tx, req.UserID); err != nil {
+			return err
+		}
+		if err := s.checkDailyLimit(tx, req.UserID, req.Amount); err != nil {
+			return err
+		}
+		var err error
+		reviewReasons, err = s.reviewReasons(tx, req)
+		if err != nil {
+			return err
+		}
+
+		withdraw = &models.WithdrawRequest{
+			UserID:         req.UserID,
+			Amount:         req.Amount,
+			WithdrawMethod: req.Method,
+			AccountInfo:    string(accountInfo),
+			Status:         models.WithdrawStatusPending,
+			RequestNo:      utils.GenerateOrderNo(),
+		}
+		if err := tx.Create(withdraw).Error; err != nil {
+			return fmt.Errorf("创建提现申请失败: %w", err)
+		}
+
+		description := fmt.Sprintf("提现%s冻结", withdraw.RequestNo)
+		if _, err := postJournal(tx, ledgerBizWithdraw, withdraw.ID, description,
+			debit(userAvailableAccount(req.UserID), req.Amount),
+			credit(userFrozenAccount(req.UserID), req.Amount),
+		); err != nil {
+			return err
+		}
+		if _, err := freezeWallet(tx, req.UserID, req.Amount, walletEntry{
+			RelatedID:   withdraw.ID,
+			RelatedType: "withdraw",
+			Description: description,
+		}); err != nil {
+			return err
+		}
+
+		if len(reviewReasons) == 0 {
+			return nil
+		}
+		riskLog := &models.RiskLog{
+			UserID:      req.UserID,
+			Action:      "withdraw",
+			RiskLevel:   1,
+			Description: fmt.Sprintf("提现%s转人工审核: %s", withdraw.RequestNo, strings.Join(reviewReasons, "；")),
+			DeviceInfo:  "{}",
+		}
+		if err := tx.Create(riskLog).Error; err != nil {
+			return fmt.Errorf("写入风控日志失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(reviewReasons) > 0 {
+		return withdraw, nil
+	}
+	return s.payout(ctx, withdraw.ID)
+}
+
+// GetWithdraw 查询当前用户的提现申请，打款中的申请会主动向渠道查询并同步结果
+func (s *WithdrawService) GetWithdraw(ctx context.Context, userID uint64, requestNo string) (*models.WithdrawRequest, error) {
+	var withdraw models.WithdrawRequest
+	if err := s.db.WithContext(ctx).Where("request_no = ? AND user_id = ?", requestNo, userID).First(&withdraw).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWithdrawNotFound
+		}
+		return nil, fmt.Errorf("查询提现申请失败: %w", err)
+	}
+	if !withdraw.IsProcessing() {
+		return &withdraw, nil
+	}
+
+	if err := s.syncTransfer(ctx, &withdraw); err != nil {
+		return nil, err
+	}
+	return &withdraw, nil
+}
+
+// ListWithdraws 分页查询提现申请
+func (s *WithdrawService) ListWithdraws(ctx context.Context, req *WithdrawListRequest) ([]models.WithdrawRequest, int64, error) {
+	var withdraws []models.WithdrawRequest
+	var total int64
+
+	db := s.db.WithContext(ctx).Model(&models.WithdrawRequest{})
+	if req.UserID != nil {
+		db = db.Where("user_id = ?", *req.UserID)
+	}
+	if req.Status != nil {
+		db = db.Where("status = ?", *req.Status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取提现申请总数失败: %w", err)
+	}
+
+	offset, limit := utils.Pagination(req.Page, req.PageSize)
+	if err := db.Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&withdraws).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询提现申请列表失败: %w", err)
+	}
+
+	return withdraws, total, nil
+}
+
+// ApproveWithdraw 审核通过待处理的提现申请并提交渠道打款
+func (s *WithdrawService) ApproveWithdraw(ctx context.Context, withdrawID uint64) (*models.WithdrawRequest, error) {
+	return s.payout(ctx, withdrawID)
+}
+
+// RejectWithdraw 审核拒绝待处理的提现申请，冻结金额解冻回可用余额
+func (s *WithdrawService) RejectWithdraw(ctx context.Context, withdrawID uint64, reason string) (*models.WithdrawRequest, error) {
+	var withdraw models.WithdrawRequest
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockWithdraw(tx, withdrawID, &withdraw); err != nil {
+			return err
+		}
+		if !withdraw.IsPending() {
+			return ErrWithdrawInvalidStatus
+		}
+		return rejectWithdraw(tx, &withdraw, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &withdraw, nil
+}
+
+// SyncProcessingWithdraws 向渠道查询超时仍处理中的提现并同步打款结果，返回已出结果的数量；
+// 单笔提现同步失败时跳过并继续，失败原因汇总后返回由调度器记录
+func (s *WithdrawService) SyncProcessingWithdraws(ctx context.Context) (int, error) {
+	var withdraws []models.WithdrawRequest
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND process_time < ?", models.WithdrawStatusProcessing, time.Now().Add(-withdrawPollDelay)).
+		Order("process_time ASC").
+		Limit(100).
+		Find(&withdraws).Error; err != nil {
+		return 0, fmt.Errorf("查询处理中提现失败: %w", err)
+	}
+
+	resolved := 0
+	var errs []error
+	for i := range withdraws {
+		withdraw := &withdraws[i]
+		if err := s.syncTransfer(ctx, withdraw); err != nil {
+			errs = append(errs, fmt.Errorf("同步提现%s失败: %w", withdraw.RequestNo, err))
+			continue
+		}
+		if !withdraw.IsProcessing() {
+			resolved++
+		}
+	}
+	return resolved, errors.Join(errs...)
+}
+
+// payout 将待处理的提现标记为处理中并提交渠道转账。
+// 渠道请求异常时结果未知，提现保持处理中，由定时任务查询确认，避免重复打款
+func (s *WithdrawService) payout(ctx context.Context, withdrawID uint64) (*models.WithdrawRequest, error) {
+	var withdraw models.WithdrawRequest
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockWithdraw(tx, withdrawID, &withdraw); err != nil {
+			return err
+		}
+		if !withdraw.IsPending() {
+			return ErrWithdrawInvalidStatus
+		}
+		now := time.Now()
+		if err := tx.Model(&withdraw).Updates(map[string]interface{}{
+			"status":       models.WithdrawStatusProcessing,
+			"process_time": now,
+		}).Error; err != nil {
+			return fmt.Errorf("更新提现状态失败: %w", err)
+		}
+		withdraw.Status = models.WithdrawStatusProcessing
+		withdraw.ProcessTime = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	gateway, err := s.gatewayFor(withdraw.WithdrawMethod)
+	if err != nil {
+		return nil, err
+	}
+	var account WithdrawAccount
+	if err := json.Unmarshal([]byte(withdraw.AccountInfo), &account); err != nil {
+		return nil, fmt.Errorf("解析收款账户失败: %w", err)
+	}
+	result, err := gateway.Transfer(&payment.TransferRequest{
+		OrderNo:   withdraw.RequestNo,
+		AccountNo: account.Account,
+		Amount:    withdraw.Amount,
+		RealName:  account.RealName,
+		BankCode:  account.BankCode,
+		Memo:      "余额提现",
+	})
+	if err != nil {
+		return nil, ErrPaymentGateway.WithMessage(fmt.Sprintf("打款请求失败，稍后将自动确认结果: %v", err))
+	}
+	if err := s.applyTransferResult(ctx, &withdraw, result); err != nil {
+		return nil, err
+	}
+	return &withdraw, nil
+}
+
+// syncTransfer 向渠道查询转账结果并更新，渠道仍在处理时原样返回
+func (s *WithdrawService) syncTransfer(ctx context.Context, withdraw *models.WithdrawRequest) error {
+	gateway, err := s.gatewayFor(withdraw.WithdrawMethod)
+	if err != nil {
+		return err
+	}
+	result, err := gateway.QueryTransfer(&payment.TransferQueryRequest{OrderNo: withdraw.RequestNo})
+	if err != nil {
+		return ErrPaymentGateway.WithMessage(fmt.Sprintf("查询打款状态失败: %v", err))
+	}
+	return s.applyTransferResult(ctx, withdraw, result)
+}
+
+// applyTransferResult 在事务内将转账结果同步到提现申请和钱包：成功时扣减冻结金额，失败时标记拒绝并解冻。
+// 只处理打款中的申请，重复同步直接返回成功
+func (s *WithdrawService) applyTransferResult(ctx context.Context, withdraw *models.WithdrawRequest, result *payment.TransferData) error {
+	if result.Status != payment.PayStatusSuccess && result.Status != payment.PayStatusFailed {
+		return nil
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockWithdraw(tx, withdraw.ID, withdraw); err != nil {
+			return err
+		}
+		if !withdraw.IsProcessing() {
+			return nil
+		}
+
+		if result.Status != payment.PayStatusSuccess {
+			reason := "打款失败"
+			if result.FailReason != "" {
+				reason += ": " + result.FailReason
+			}
+			return rejectWithdraw(tx, withdraw, reason)
+		}
+
+		if !result.Amount.IsZero() && result.Amount != withdraw.Amount {
+			return ErrPaymentAmountMismatch.WithMessage(fmt.Sprintf("打款金额不匹配: 预期%s, 实际%s", withdraw.Amount, result.Amount))
+		}
+		processTime := result.TransferTime
+		if processTime.IsZero() {
+			processTime = time.Now()
+		}
+		if err := tx.Model(withdraw).Updates(map[string]interface{}{
+			"status":       models.WithdrawStatusCompleted,
+			"process_time": processTime,
+		}).Error; err != nil {
+			return fmt.Errorf("更新提现状态失败: %w", err)
+		}
+		withdraw.Status = models.WithdrawStatusCompleted
+		withdraw.ProcessTime = &processTime
+
+		description := fmt.Sprintf("提现%s到账", withdraw.RequestNo)
+		if _, err := postJournal(tx, ledgerBizWithdraw, withdraw.ID, description,
+			debit(userFrozenAccount(withdraw.UserID), withdraw.Amount),
+			credit(gatewayAccount, withdraw.Amount),
+		); err != nil {
+			return err
+		}
+		_, err := payoutWallet(tx, withdraw.UserID, withdraw.Amount, walletEntry{
+			RelatedID:   withdraw.ID,
+			RelatedType: "withdraw",
+			Description: description,
+		})
+		return err
+	})
+}
+
+// checkDailyLimit 校验当日提现次数和累计金额，已拒绝的申请不计入
+func (s *WithdrawService) checkDailyLimit(tx *gorm.DB, userID uint64, amount money.Money) error {
+	if s.cfg.MaxWithdrawCountPerDay <= 0 && !s.cfg.MaxWithdrawPerDay.IsPositive() {
+		return nil
+	}
+
+	now := time.Now()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var withdraws []models.WithdrawRequest
+	if err := tx.Select("amount").
+		Where("user_id = ? AND status <> ? AND created_at >= ?", userID, models.WithdrawStatusRejected, startOfDay).
+		Find(&withdraws).Error; err != nil {
+		return fmt.Errorf("查询当日提现失败: %w", err)
+	}
+
+	if s.cfg.MaxWithdrawCountPerDay > 0 && len(withdraws) >= s.cfg.MaxWithdrawCountPerDay {
+		return ErrWithdrawLimitExceeded.WithMessage(fmt.Sprintf("每日最多提现%d次", s.cfg.MaxWithdrawCountPerDay))
+	}
+	if s.cfg.MaxWithdrawPerDay.IsPositive() {
+		total := amount
+		for _, w := range withdraws {
+			total += w.Amount
+		}
+		if total > s.cfg.MaxWithdrawPerDay {
+			return ErrWithdrawLimitExceeded.WithMessage(fmt.Sprintf("每日累计提现不能超过%s元", s.cfg.MaxWithdrawPerDay))
+		}
+	}
+	return nil
+}
+
+// reviewReasons 返回提现需要人工审核的原因，为空表示可自动打款
+func (s *WithdrawService) reviewReasons(tx *gorm.DB, req *CreateWithdrawRequest) ([]string, error) {
+	var reasons []string
+	if s.cfg.WithdrawReviewAmount.IsPositive() && req.Amount > s.cfg.WithdrawReviewAmount {
+		reasons = append(reasons, fmt.Sprintf("单笔金额超过%s元", s.cfg.WithdrawReviewAmount))
+	}
+
+	if s.cfg.WithdrawReviewNewUserDays > 0 {
+		var user models.User
+		if err := tx.Select("user_id", "create_time").First(&user, req.UserID).Error; err != nil {
+			return nil, fmt.Errorf("查询用户失败: %w", err)
+		}
+		if time.Since(user.CreatedAt) < time.Duration(s.cfg.WithdrawReviewNewUserDays)*24*time.Hour {
+			reasons = append(reasons, fmt.Sprintf("注册未满%d天", s.cfg.WithdrawReviewNewUserDays))
+		}
+	}
+
+	var violations int64
+	if err := tx.Model(&models.Violation{}).Where("user_id = ? AND status = ?", req.UserID, models.ViolationStatusPending).Count(&violations).Error; err != nil {
+		return nil, fmt.Errorf("查询违规记录失败: %w", err)
+	}
+	if violations > 0 {
+		reasons = append(reasons, "存在待处理的违规记录")
+	}
+	return reasons, nil
+}
+
+// gatewayFor 选择提现打款渠道，配置了统一打款渠道时优先使用
+func (s *WithdrawService) gatewayFor(method string) (payment.PaymentGateway, error) {
+	provider := s.provider
+	if provider == "" {
+		var ok bool
+		if provider, ok = withdrawMethodProviders[method]; !ok {
+			return nil, ErrPaymentMethodUnsupported.WithMessage(fmt.Sprintf("不支持的提现方式: %s", method))
+		}
+	}
+	gateway, ok := s.gateways.Get(provider)
+	if !ok {
+		return nil, ErrPaymentMethodUnsupported.WithMessage(fmt.Sprintf("提现打款渠道未启用: %s", provider))
+	}
+	return gateway, nil
+}
+
+// lockWithdraw 加行锁读取提现申请
+func lockWithdraw(tx *gorm.DB, withdrawID uint64, withdraw *models.WithdrawRequest) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(withdraw, withdrawID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrWithdrawNotFound
+		}
+		return fmt.Errorf("查询提现申请失败: %w", err)
+	}
+	return nil
+}
+
+// rejectWithdraw 将提现标记为已拒绝并解冻提现金额，须在事务内调用且已锁定提现申请
+func rejectWithdraw(tx *gorm.DB, withdraw *models.WithdrawRequest, reason string) error {
+	now := time.Now()
+	if err := tx.Model(withdraw).Updates(map[string]interface{}{
+		"status":        models.WithdrawStatusRejected,
+		"reject_reason": reason,
+		"process_time":  now,
+	}).Error; err != nil {
+		return fmt.Errorf("更新提现状态失败: %w", err)
+	}
+	withdraw.Status = models.WithdrawStatusRejected
+	withdraw.RejectReason = reason
+	withdraw.ProcessTime = &now
+
+	description := fmt.Sprintf("提现%s未完成解冻", withdraw.RequestNo)
+	if _, err := postJournal(tx, ledgerBizWithdraw, withdraw.ID, description,
+		debit(userFrozenAccount(withdraw.UserID), withdraw.Amount),
+		credit(userAvailableAccount(withdraw.UserID), withdraw.Amount),
+	); err != nil {
+		return err
+	}
+	_, err := unfreezeWallet(tx, withdraw.UserID, withdraw.Amount, walletEntry{
+		RelatedID:   withdraw.ID,
+		RelatedType: "withdraw",
+		Description: description,
+	})
+	return err
+}
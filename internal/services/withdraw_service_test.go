@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/internal/models"
+	"task-platform-api/internal/testutil"
+)
+
+// newTestWithdrawService 创建银行卡提现经收钱吧打款的提现服务，收钱吧接口对任意请求返回body
+func newTestWithdrawService(t *testing.T, body string) (*WithdrawService, *testutil.StubDB) {
+	t.Helper()
+	processTime := time.Now().Add(-time.Hour)
+	db, stub := testutil.NewGorm(t,
+		testutil.StubRows{
+			Match:   "FROM `withdraw_requests`",
+			Columns: []string{"id", "user_id", "amount", "withdraw_method", "account_info", "status", "request_no", "process_time"},
+			Values:  [][]driver.Value{{int64(9), int64(3), []byte("50.00"), models.WithdrawMethodBank, `{"account":"6222000000000000"}`, int64(models.WithdrawStatusProcessing), "WD001", processTime}},
+		},
+		testutil.StubRows{
+			Match:   "FROM `wallets`",
+			Columns: []string{"id", "user_id", "balance", "frozen_balance", "version"},
+			Values:  [][]driver.Value{{int64(1), int64(3), []byte("0.00"), []byte("50.00"), int64(4)}},
+		},
+		anyLedgerAccountRows(),
+	)
+	return NewWithdrawService(db, config.RiskControlConfig{}, "", newShouqianbaGateways(t, body)), stub
+}
+
+func TestSyncProcessingWithdrawsRejectsUnknownTransfer(t *testing.T) {
+	svc, stub := newTestWithdrawService(t, `{"code":"404","message":"订单不存在"}`)
+
+	resolved, err := svc.SyncProcessingWithdraws(context.Background())
+	if err != nil {
+		t.Fatalf("同步提现失败: %v", err)
+	}
+	if resolved != 1 {
+		t.Fatalf("渠道查无转账单的提现应出结果, 实际: %d", resolved)
+	}
+
+	rejects := stub.Executed("UPDATE `withdraw_requests`")
+	if len(rejects) != 1 || !rejects[0].HasArgs(int64(models.WithdrawStatusRejected), "打款失败: 转账单不存在") {
+		t.Fatalf("提现应标记为已拒绝: %+v", rejects)
+	}
+	wallets := stub.Executed("UPDATE `wallets`")
+	if len(wallets) != 1 || !wallets[0].HasArgs("50.00", "0.00", int64(5)) {
+		t.Fatalf("提现金额应从冻结余额解冻回可用余额: %+v", wallets)
+	}
+	if len(stub.Executed("INSERT INTO `ledger_entries`")) != 1 {
+		t.Fatal("解冻应记一张账本凭证")
+	}
+}
+
+func TestSyncProcessingWithdrawsKeepsUnknownResult(t *testing.T) {
+	svc, stub := newTestWithdrawService(t, `{"code":"500","message":"系统繁忙"}`)
+
+	resolved, err := svc.SyncProcessingWithdraws(context.Background())
+	if err == nil || resolved != 0 {
+		t.Fatalf("渠道系统异常时应返回错误且不出结果: %d, %v", resolved, err)
+	}
+	if len(stub.Executed("UPDATE `")) > 0 {
+		t.Fatal("结果未知的提现应保持处理中")
+	}
+}
@@ -0,0 +1,225 @@
+// Package testutil 单元测试共用的桩实现
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// StubRows 查询语句包含Match且参数包含全部Args时返回的结果集，Once为true时只匹配一次
+type StubRows struct {
+	Match   string
+	Args    []driver.Value
+	Once    bool
+	Columns []string
+	Values  [][]driver.Value
+}
+
+// StubStmt 执行过的语句及参数
+type StubStmt struct {
+	Query string
+	Args  []driver.Value
+}
+
+// HasArgs 判断语句参数是否包含全部指定值
+func (s StubStmt) HasArgs(values ...driver.Value) bool {
+	for _, v := range values {
+		found := false
+		for _, arg := range s.Args {
+			if arg == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// StubDB 按SQL片段和参数返回预设结果的数据库桩，按顺序取第一个匹配的结果集，
+// 未匹配的查询返回空结果集，写语句默认影响一行并返回递增的自增ID
+type StubDB struct {
+	mu       sync.Mutex
+	rows     []StubRows
+	affected []stubAffected
+	stmts    []StubStmt
+	nextID   int64
+}
+
+// stubAffected 包含match的写语句依次返回的影响行数
+type stubAffected struct {
+	match  string
+	counts []int64
+}
+
+// NewGorm 创建连接到数据库桩的gorm实例，使用MySQL方言生成SQL
+func NewGorm(t *testing.T, rows ...StubRows) (*gorm.DB, *StubDB) {
+	t.Helper()
+	stub := &StubDB{rows: rows, nextID: 100}
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(stub),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("打开数据库桩失败: %v", err)
+	}
+	return db, stub
+}
+
+// Executed 返回包含指定片段的已执行语句，事务的开始、提交和回滚分别记录为BEGIN、COMMIT、ROLLBACK
+func (s *StubDB) Executed(fragment string) []StubStmt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var matched []StubStmt
+	for _, stmt := range s.stmts {
+		if strings.Contains(stmt.Query, fragment) {
+			matched = append(matched, stmt)
+		}
+	}
+	return matched
+}
+
+// AffectRows 设置包含match的写语句依次返回的影响行数，用完后恢复为影响一行
+func (s *StubDB) AffectRows(match string, counts ...int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.affected = append(s.affected, stubAffected{match: match, counts: counts})
+}
+
+// Connect 实现driver.Connector
+func (s *StubDB) Connect(context.Context) (driver.Conn, error) {
+	return &stubConn{db: s}, nil
+}
+
+// Driver 实现driver.Connector
+func (s *StubDB) Driver() driver.Driver {
+	return nil
+}
+
+func (s *StubDB) record(query string, args []driver.NamedValue) StubStmt {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	stmt := StubStmt{Query: query, Args: values}
+	s.mu.Lock()
+	s.stmts = append(s.stmts, stmt)
+	s.mu.Unlock()
+	return stmt
+}
+
+type stubConn struct {
+	db *StubDB
+}
+
+func (c *stubConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("不支持预编译语句")
+}
+
+func (c *stubConn) Close() error {
+	return nil
+}
+
+func (c *stubConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *stubConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.db.record("BEGIN", nil)
+	return stubTx{db: c.db}, nil
+}
+
+func (c *stubConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	stmt := c.db.record(query, args)
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	for i, r := range c.db.rows {
+		if !strings.Contains(query, r.Match) || !stmt.HasArgs(r.Args...) {
+			continue
+		}
+		if r.Once {
+			c.db.rows = append(c.db.rows[:i:i], c.db.rows[i+1:]...)
+		}
+		return &stubResultRows{columns: r.Columns, values: r.Values}, nil
+	}
+	return &stubResultRows{}, nil
+}
+
+func (c *stubConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.record(query, args)
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	id := c.db.nextID
+	c.db.nextID++
+	affected := int64(1)
+	for i, a := range c.db.affected {
+		if len(a.counts) > 0 && strings.Contains(query, a.match) {
+			affected = a.counts[0]
+			c.db.affected[i].counts = a.counts[1:]
+			break
+		}
+	}
+	return stubResult{lastID: id, affected: affected}, nil
+}
+
+type stubTx struct {
+	db *StubDB
+}
+
+func (t stubTx) Commit() error {
+	t.db.record("COMMIT", nil)
+	return nil
+}
+
+func (t stubTx) Rollback() error {
+	t.db.record("ROLLBACK", nil)
+	return nil
+}
+
+type stubResult struct {
+	lastID   int64
+	affected int64
+}
+
+func (r stubResult) LastInsertId() (int64, error) {
+	return r.lastID, nil
+}
+
+func (r stubResult) RowsAffected() (int64, error) {
+	return r.affected, nil
+}
+
+type stubResultRows struct {
+	columns []string
+	values  [][]driver.Value
+	next    int
+}
+
+func (r *stubResultRows) Columns() []string {
+	return r.columns
+}
+
+func (r *stubResultRows) Close() error {
+	return nil
+}
+
+func (r *stubResultRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,294 @@
+package money
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money 金额，以分为单位的整数，避免浮点运算误差
+type Money int64
+
+// Zero 零金额
+const Zero Money = 0
+
+// RoundingMode 舍入方式
+type RoundingMode int
+
+const (
+	RoundHalfUp   RoundingMode = iota // 四舍五入（远离零）
+	RoundHalfEven                     // 银行家舍入
+	RoundDown                         // 向零截断
+	RoundUp                           // 远离零进位
+)
+
+// rateScale 费率精度，费率按万分之一换算为整数参与运算
+const rateScale = 10000
+
+// FromCents 由分创建金额
+func FromCents(cents int64) Money {
+	return Money(cents)
+}
+
+// FromYuan 由元创建金额，按四舍五入到分，仅用于边界处的浮点输入
+func FromYuan(yuan float64) Money {
+	return Money(math.Round(yuan * 100))
+}
+
+// Parse 解析以元为单位的十进制金额字符串，如"12.34"，最多两位小数
+func Parse(s string) (Money, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	if s == "" {
+		return Zero, fmt.Errorf("金额不能为空")
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return Zero, fmt.Errorf("无效的金额格式: %q", s)
+	}
+	if len(fracPart) > 2 {
+		// 允许多余的0，如MySQL返回的"12.3400"
+		if strings.Trim(fracPart[2:], "0") != "" {
+			return Zero, fmt.Errorf("金额精度超过分: %q", s)
+		}
+		fracPart = fracPart[:2]
+	}
+	for len(fracPart) < 2 {
+		fracPart += "0"
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+
+	yuan, err := strconv.ParseUint(intPart, 10, 63)
+	if err != nil {
+		return Zero, fmt.Errorf("无效的金额格式: %q", s)
+	}
+	cents, err := strconv.ParseUint(fracPart, 10, 8)
+	if err != nil {
+		return Zero, fmt.Errorf("无效的金额格式: %q", s)
+	}
+	if yuan > math.MaxInt64/100-1 {
+		return Zero, fmt.Errorf("金额超出范围: %q", s)
+	}
+
+	m := Money(int64(yuan)*100 + int64(cents))
+	if negative {
+		m = -m
+	}
+	return m, nil
+}
+
+// MustParse 解析金额字符串，失败时panic，仅用于常量初始化
+func MustParse(s string) Money {
+	m, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Cents 金额的分值
+func (m Money) Cents() int64 {
+	return int64(m)
+}
+
+// Yuan 金额的元值，仅用于展示或与外部浮点接口交互
+func (m Money) Yuan() float64 {
+	return float64(m) / 100
+}
+
+// String 格式化为两位小数的元字符串
+func (m Money) String() string {
+	sign := ""
+	cents := int64(m)
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// IsZero 是否为零
+func (m Money) IsZero() bool {
+	return m == 0
+}
+
+// IsPositive 是否大于零
+func (m Money) IsPositive() bool {
+	return m > 0
+}
+
+// IsNegative 是否小于零
+func (m Money) IsNegative() bool {
+	return m < 0
+}
+
+// Abs 绝对值
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// MulRate 按费率计算金额并舍入到分，费率精确到万分之一，如0.06表示6%
+func (m Money) MulRate(rate float64, mode RoundingMode) Money {
+	scaledRate := int64(math.Round(rate * rateScale))
+	return Money(divRound(int64(m)*scaledRate, rateScale, mode))
+}
+
+// Allocate 按权重拆分金额，分配结果之和严格等于原金额；
+// 按比例向下取整后剩余的分依次补给靠前的份额
+func (m Money) Allocate(weights ...int64) []Money {
+	parts := make([]Money, len(weights))
+	var total int64
+	for _, w := range weights {
+		if w < 0 {
+			panic("money: 拆分权重不能为负")
+		}
+		total += w
+	}
+	if total == 0 {
+		return parts
+	}
+
+	var allocated Money
+	for i, w := range weights {
+		parts[i] = Money(int64(m) * w / total)
+		allocated += parts[i]
+	}
+
+	remainder := m - allocated
+	step := Money(1)
+	if remainder < 0 {
+		step = -1
+	}
+	for i := 0; remainder != 0; i = (i + 1) % len(parts) {
+		if weights[i] == 0 {
+			continue
+		}
+		parts[i] += step
+		remainder -= step
+	}
+	return parts
+}
+
+// Split 平均拆分为n份，分配结果之和严格等于原金额
+func (m Money) Split(n int) []Money {
+	if n <= 0 {
+		return nil
+	}
+	weights := make([]int64, n)
+	for i := range weights {
+		weights[i] = 1
+	}
+	return m.Allocate(weights...)
+}
+
+// MarshalJSON 序列化为两位小数的JSON数字
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON 解析JSON数字或字符串形式的金额
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" || s == "" {
+		*m = Zero
+		return nil
+	}
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value 实现driver.Valuer，以十进制字符串写入DECIMAL字段
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan 实现sql.Scanner，读取DECIMAL字段。数据库中的金额均以元存储，
+// 整数值（如DECIMAL(10,0)列或整数聚合结果）同样按元解释，不是分
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = Zero
+	case []byte:
+		parsed, err := Parse(string(v))
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	case string:
+		parsed, err := Parse(v)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	case int64:
+		if v > math.MaxInt64/100 || v < math.MinInt64/100 {
+			return fmt.Errorf("money: 金额超出范围 %d", v)
+		}
+		*m = Money(v * 100)
+	case float64:
+		*m = FromYuan(v)
+	default:
+		return fmt.Errorf("money: 不支持的数据库类型 %T", src)
+	}
+	return nil
+}
+
+// divRound 整数除法并按舍入方式处理余数
+func divRound(num, den int64, mode RoundingMode) int64 {
+	q, r := num/den, num%den
+	if r == 0 {
+		return q
+	}
+
+	sign := int64(1)
+	if (num < 0) != (den < 0) {
+		sign = -1
+	}
+	absR, absDen := r, den
+	if absR < 0 {
+		absR = -absR
+	}
+	if absDen < 0 {
+		absDen = -absDen
+	}
+
+	switch mode {
+	case RoundDown:
+		return q
+	case RoundUp:
+		return q + sign
+	case RoundHalfEven:
+		if 2*absR > absDen || (2*absR == absDen && q%2 != 0) {
+			return q + sign
+		}
+		return q
+	default:
+		if 2*absR >= absDen {
+			return q + sign
+		}
+		return q
+	}
+}
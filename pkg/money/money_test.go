@@ -0,0 +1,230 @@
+package money
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: "12.34", want: 1234},
+		{in: "12", want: 1200},
+		{in: "12.3", want: 1230},
+		{in: ".5", want: 50},
+		{in: "5.", want: 500},
+		{in: "+1.50", want: 150},
+		{in: "-0.01", want: -1},
+		{in: "-12.34", want: -1234},
+		{in: " 1,234.56 ", want: 123456},
+		{in: "12.3400", want: 1234},
+		{in: "92233720368547757.99", want: 9223372036854775799},
+		{in: "-92233720368547757.99", want: -9223372036854775799},
+		{in: "12.345", wantErr: true},
+		{in: "0.001", wantErr: true},
+		{in: "92233720368547758", wantErr: true},
+		{in: "99999999999999999999", wantErr: true},
+		{in: "", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: ".", wantErr: true},
+		{in: "+-1", wantErr: true},
+		{in: "1.-5", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "1e3", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q) = %d, 应返回错误", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %d, %v, 期望 %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	for _, m := range []Money{0, 1, -1, 99, 100, -1234, 123456789} {
+		parsed, err := Parse(m.String())
+		if err != nil || parsed != m {
+			t.Errorf("Parse(%q) = %d, %v, 期望 %d", m.String(), parsed, err, m)
+		}
+	}
+	if got := Money(-5).String(); got != "-0.05" {
+		t.Errorf("Money(-5).String() = %q, 期望 \"-0.05\"", got)
+	}
+}
+
+func TestDivRound(t *testing.T) {
+	tests := []struct {
+		num, den                   int64
+		halfUp, halfEven, down, up int64
+	}{
+		{num: 25, den: 10, halfUp: 3, halfEven: 2, down: 2, up: 3},
+		{num: -25, den: 10, halfUp: -3, halfEven: -2, down: -2, up: -3},
+		{num: 15, den: 10, halfUp: 2, halfEven: 2, down: 1, up: 2},
+		{num: -15, den: 10, halfUp: -2, halfEven: -2, down: -1, up: -2},
+		{num: 24, den: 10, halfUp: 2, halfEven: 2, down: 2, up: 3},
+		{num: -24, den: 10, halfUp: -2, halfEven: -2, down: -2, up: -3},
+		{num: 26, den: 10, halfUp: 3, halfEven: 3, down: 2, up: 3},
+		{num: -26, den: 10, halfUp: -3, halfEven: -3, down: -2, up: -3},
+		{num: 25, den: -10, halfUp: -3, halfEven: -2, down: -2, up: -3},
+		{num: -25, den: -10, halfUp: 3, halfEven: 2, down: 2, up: 3},
+		{num: -5, den: 10, halfUp: -1, halfEven: 0, down: 0, up: -1},
+		{num: -20, den: 10, halfUp: -2, halfEven: -2, down: -2, up: -2},
+	}
+	for _, tt := range tests {
+		for mode, want := range map[RoundingMode]int64{
+			RoundHalfUp:   tt.halfUp,
+			RoundHalfEven: tt.halfEven,
+			RoundDown:     tt.down,
+			RoundUp:       tt.up,
+		} {
+			if got := divRound(tt.num, tt.den, mode); got != want {
+				t.Errorf("divRound(%d, %d, %d) = %d, 期望 %d", tt.num, tt.den, mode, got, want)
+			}
+		}
+	}
+}
+
+func TestMulRate(t *testing.T) {
+	tests := []struct {
+		m    Money
+		rate float64
+		mode RoundingMode
+		want Money
+	}{
+		{m: 1050, rate: 0.05, mode: RoundHalfUp, want: 53},
+		{m: 1050, rate: 0.05, mode: RoundHalfEven, want: 52},
+		{m: -1050, rate: 0.05, mode: RoundHalfUp, want: -53},
+		{m: -1050, rate: 0.05, mode: RoundHalfEven, want: -52},
+		{m: -1050, rate: 0.05, mode: RoundDown, want: -52},
+		{m: 1001, rate: 0.06, mode: RoundUp, want: 61},
+		{m: 10000, rate: 0.0625, mode: RoundHalfUp, want: 625},
+	}
+	for _, tt := range tests {
+		if got := tt.m.MulRate(tt.rate, tt.mode); got != tt.want {
+			t.Errorf("%s.MulRate(%v, %d) = %s, 期望 %s", tt.m, tt.rate, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		m       Money
+		weights []int64
+		want    []Money
+	}{
+		{m: 100, weights: []int64{1, 1, 1}, want: []Money{34, 33, 33}},
+		{m: -100, weights: []int64{1, 1, 1}, want: []Money{-34, -33, -33}},
+		{m: 101, weights: []int64{1, 0, 1}, want: []Money{51, 0, 50}},
+		{m: 3, weights: []int64{0, 1, 1}, want: []Money{0, 2, 1}},
+		{m: 1000, weights: []int64{7, 3}, want: []Money{700, 300}},
+		{m: 1, weights: []int64{1, 1, 1}, want: []Money{1, 0, 0}},
+		{m: 100, weights: []int64{0, 0}, want: []Money{0, 0}},
+		{m: 100, weights: nil, want: []Money{}},
+	}
+	for _, tt := range tests {
+		got := tt.m.Allocate(tt.weights...)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s.Allocate(%v) = %v, 期望 %v", tt.m, tt.weights, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s.Allocate(%v) = %v, 期望 %v", tt.m, tt.weights, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestAllocateSumsToTotal(t *testing.T) {
+	for _, m := range []Money{0, 1, 7, 9999, -9999, 123456789} {
+		for _, weights := range [][]int64{{1}, {1, 2, 3}, {5, 0, 5, 0}, {3, 3, 3, 3, 3, 3, 3}} {
+			var sum Money
+			for _, part := range m.Allocate(weights...) {
+				sum += part
+			}
+			if sum != m {
+				t.Errorf("%s.Allocate(%v) 合计 %s, 应等于原金额", m, weights, sum)
+			}
+		}
+	}
+}
+
+func TestAllocateRejectsNegativeWeight(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("负权重应panic")
+		}
+	}()
+	Money(100).Allocate(1, -1)
+}
+
+func TestSplit(t *testing.T) {
+	got := Money(10).Split(3)
+	if len(got) != 3 || got[0] != 4 || got[1] != 3 || got[2] != 3 {
+		t.Errorf("Split(3) = %v, 期望 [4 3 3]", got)
+	}
+	if Money(10).Split(0) != nil {
+		t.Error("Split(0)应返回nil")
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		src     interface{}
+		want    Money
+		wantErr bool
+	}{
+		{src: nil, want: 0},
+		{src: []byte("12.34"), want: 1234},
+		{src: []byte("-0.50"), want: -50},
+		{src: "99.00", want: 9900},
+		// 整数按元解释，与DECIMAL字段的单位一致
+		{src: int64(12), want: 1200},
+		{src: int64(-3), want: -300},
+		{src: float64(12.34), want: 1234},
+		{src: int64(1 << 62), wantErr: true},
+		{src: []byte("1.234"), wantErr: true},
+		{src: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		var m Money = 1
+		err := m.Scan(tt.src)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Scan(%#v) = %d, 应返回错误", tt.src, m)
+			}
+			continue
+		}
+		if err != nil || m != tt.want {
+			t.Errorf("Scan(%#v) = %d, %v, 期望 %d", tt.src, m, err, tt.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := Money(-1234).MarshalJSON()
+	if err != nil || string(data) != "-12.34" {
+		t.Errorf("MarshalJSON = %s, %v", data, err)
+	}
+
+	for in, want := range map[string]Money{`12.5`: 1250, `"12.50"`: 1250, `null`: 0, `""`: 0} {
+		var m Money = 1
+		if err := m.UnmarshalJSON([]byte(in)); err != nil || m != want {
+			t.Errorf("UnmarshalJSON(%s) = %d, %v, 期望 %d", in, m, err, want)
+		}
+	}
+	var m Money
+	if err := m.UnmarshalJSON([]byte(`12.345`)); err == nil {
+		t.Error("超过两位小数的JSON金额应返回错误")
+	}
+}
@@ -0,0 +1,662 @@
+package payment
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/pkg/money"
+)
+
+const (
+	alipayDefaultGatewayURL = "https://openapi.alipay.com/gateway.do"
+	alipayTimeLayout        = "2006-01-02 15:04:05"
+)
+
+// alipayLocation 支付宝接口时间使用北京时间
+var alipayLocation = time.FixedZone("CST", 8*3600)
+
+// 支付场景
+const (
+	PayScenePage = "page" // 电脑网站支付
+	PaySceneApp  = "app"  // APP支付
+)
+
+// 支付宝交易状态
+const (
+	alipayTradeWaitBuyerPay = "WAIT_BUYER_PAY"
+	alipayTradeClosed       = "TRADE_CLOSED"
+	alipayTradeSuccess      = "TRADE_SUCCESS"
+	alipayTradeFinished     = "TRADE_FINISHED"
+)
+
+// AlipayClient 支付宝开放平台客户端，提供授权登录和支付能力，请求使用RSA2签名
+type AlipayClient struct {
+	config     *config.AlipayConfig
+	client     *http.Client
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
+}
+
+// AlipayOAuthToken 授权令牌
+type AlipayOAuthToken struct {
+	UserID       string `json:"user_id"`
+	OpenID       string `json:"open_id"`
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	ReExpiresIn  int64  `json:"re_expires_in"`
+}
+
+// AlipayUserInfo 支付宝会员信息
+type AlipayUserInfo struct {
+	UserID   string `json:"user_id"`
+	OpenID   string `json:"open_id"`
+	NickName string `json:"nick_name"`
+	Avatar   string `json:"avatar"`
+}
+
+// alipayResponseStatus 接口公共响应参数
+type alipayResponseStatus struct {
+	Code    string `json:"code"`
+	Msg     string `json:"msg"`
+	SubCode string `json:"sub_code"`
+	SubMsg  string `json:"sub_msg"`
+}
+
+// alipayTradeQueryResponse 交易查询响应
+type alipayTradeQueryResponse struct {
+	alipayResponseStatus
+	TradeNo     string `json:"trade_no"`
+	OutTradeNo  string `json:"out_trade_no"`
+	TradeStatus string `json:"trade_status"`
+	TotalAmount string `json:"total_amount"`
+	SendPayDate string `json:"send_pay_date"`
+}
+
+// alipayTradeRefundResponse 退款响应
+type alipayTradeRefundResponse struct {
+	alipayResponseStatus
+	TradeNo      string `json:"trade_no"`
+	OutTradeNo   string `json:"out_trade_no"`
+	RefundFee    string `json:"refund_fee"`
+	FundChange   string `json:"fund_change"`
+	GmtRefundPay string `json:"gmt_refund_pay"`
+}
+
+// alipayRefundQueryResponse 退款查询响应
+type alipayRefundQueryResponse struct {
+	alipayResponseStatus
+	OutRequestNo string `json:"out_request_no"`
+	RefundAmount string `json:"refund_amount"`
+	RefundStatus string `json:"refund_status"`
+	GmtRefundPay string `json:"gmt_refund_pay"`
+}
+
+// alipayTransferResponse 单笔转账响应
+type alipayTransferResponse struct {
+	alipayResponseStatus
+	OutBizNo  string `json:"out_biz_no"`
+	OrderID   string `json:"order_id"`
+	Status    string `json:"status"`
+	TransDate string `json:"trans_date"`
+}
+
+// alipayTransferQueryResponse 转账查询响应
+type alipayTransferQueryResponse struct {
+	alipayResponseStatus
+	OrderID     string `json:"order_id"`
+	OutBizNo    string `json:"out_biz_no"`
+	Status      string `json:"status"`
+	TransAmount string `json:"trans_amount"`
+	PayDate     string `json:"pay_date"`
+	FailReason  string `json:"fail_reason"`
+}
+
+// NewAlipayClient 创建支付宝客户端，加载应用私钥和支付宝公钥
+func NewAlipayClient(cfg *config.AlipayConfig) (*AlipayClient, error) {
+	if cfg.AppID == "" {
+		return nil, fmt.Errorf("支付宝应用ID不能为空")
+	}
+	privateKey, err := loadRSAPrivateKey(cfg.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("加载应用私钥失败: %w", err)
+	}
+	publicKey, err := loadRSAPublicKey(cfg.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("加载支付宝公钥失败: %w", err)
+	}
+
+	return &AlipayClient{
+		config:     cfg,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		privateKey: privateKey,
+		publicKey:  publicKey,
+	}, nil
+}
+
+// Name 渠道标识
+func (c *AlipayClient) Name() string {
+	return ProviderAlipay
+}
+
+// SystemOAuthToken 使用授权码换取访问令牌
+func (c *AlipayClient) SystemOAuthToken(code string) (*AlipayOAuthToken, error) {
+	var token struct {
+		alipayResponseStatus
+		AlipayOAuthToken
+	}
+	params := map[string]string{
+		"grant_type": "authorization_code",
+		"code":       code,
+	}
+	if err := c.call("alipay.system.oauth.token", params, nil, &token); err != nil {
+		return nil, fmt.Errorf("换取授权令牌失败: %w", err)
+	}
+	return &token.AlipayOAuthToken, nil
+}
+
+// UserInfoShare 使用访问令牌获取会员信息，需用户授权auth_user
+func (c *AlipayClient) UserInfoShare(accessToken string) (*AlipayUserInfo, error) {
+	var info struct {
+		alipayResponseStatus
+		AlipayUserInfo
+	}
+	params := map[string]string{
+		"auth_token": accessToken,
+	}
+	if err := c.call("alipay.user.info.share", params, nil, &info); err != nil {
+		return nil, fmt.Errorf("获取会员信息失败: %w", err)
+	}
+	return &info.AlipayUserInfo, nil
+}
+
+// PrePay 生成支付链接，电脑网站支付返回跳转地址，APP支付在PayParams中返回签名后的订单串
+func (c *AlipayClient) PrePay(req *PrePayRequest) (*PrePayResponseData, error) {
+	if req.ExpireTime == 0 {
+		req.ExpireTime = 900
+	}
+	if req.NotifyURL == "" {
+		req.NotifyURL = c.config.NotifyURL
+	}
+	if req.ReturnURL == "" {
+		req.ReturnURL = c.config.ReturnURL
+	}
+	expireAt := time.Now().Add(time.Duration(req.ExpireTime) * time.Second)
+
+	method, productCode := "alipay.trade.page.pay", "FAST_INSTANT_TRADE_PAY"
+	if req.Scene == PaySceneApp {
+		method, productCode = "alipay.trade.app.pay", "QUICK_MSECURITY_PAY"
+	}
+	subject := req.Subject
+	if subject == "" {
+		subject = req.Description
+	}
+	bizContent := map[string]string{
+		"out_trade_no": req.OrderNo,
+		"total_amount": req.Amount.String(),
+		"subject":      subject,
+		"body":         req.Description,
+		"product_code": productCode,
+		"time_expire":  expireAt.In(alipayLocation).Format(alipayTimeLayout),
+	}
+	if req.Extra != "" {
+		bizContent["passback_params"] = url.QueryEscape(req.Extra)
+	}
+
+	params := map[string]string{
+		"notify_url": req.NotifyURL,
+	}
+	if req.Scene != PaySceneApp {
+		params["return_url"] = req.ReturnURL
+	}
+	values, err := c.signedValues(method, params, bizContent)
+	if err != nil {
+		return nil, fmt.Errorf("预支付请求失败: %w", err)
+	}
+
+	data := &PrePayResponseData{
+		OrderNo:    req.OrderNo,
+		ExpireTime: expireAt.Unix(),
+	}
+	if req.Scene == PaySceneApp {
+		data.PayParams = map[string]string{"order_string": values.Encode()}
+	} else {
+		data.PayURL = c.gatewayURL() + "?" + values.Encode()
+	}
+	return data, nil
+}
+
+// QueryPayStatus 查询交易，交易尚未创建时视为待支付
+func (c *AlipayClient) QueryPayStatus(req *PayStatusRequest) (*PayStatusData, error) {
+	bizContent := map[string]string{
+		"out_trade_no": req.OrderNo,
+	}
+	var resp alipayTradeQueryResponse
+	if err := c.call("alipay.trade.query", nil, bizContent, &resp); err != nil {
+		if resp.SubCode == "ACQ.TRADE_NOT_EXIST" {
+			return &PayStatusData{OrderNo: req.OrderNo, Status: PayStatusPending}, nil
+		}
+		return nil, fmt.Errorf("查询支付状态失败: %w", err)
+	}
+
+	amount, err := money.Parse(resp.TotalAmount)
+	if err != nil {
+		return nil, fmt.Errorf("交易金额格式错误: %w", err)
+	}
+	status := &PayStatusData{
+		OrderNo:       resp.OutTradeNo,
+		TradeNo:       resp.TradeNo,
+		Status:        alipayPayStatus(resp.TradeStatus),
+		Amount:        amount,
+		PayMethod:     ProviderAlipay,
+		TransactionID: resp.TradeNo,
+	}
+	if resp.SendPayDate != "" {
+		status.PayTime, _ = time.ParseInLocation(alipayTimeLayout, resp.SendPayDate, alipayLocation)
+	}
+	return status, nil
+}
+
+// Refund 申请退款，退款单号作为out_request_no以支持部分退款
+func (c *AlipayClient) Refund(req *RefundRequest) (*RefundData, error) {
+	bizContent := map[string]string{
+		"out_trade_no":   req.OrderNo,
+		"refund_amount":  req.Amount.String(),
+		"refund_reason":  req.Reason,
+		"out_request_no": req.RefundNo,
+	}
+	var resp alipayTradeRefundResponse
+	if err := c.call("alipay.trade.refund", nil, bizContent, &resp); err != nil {
+		return nil, fmt.Errorf("退款请求失败: %w", err)
+	}
+
+	refund := &RefundData{
+		RefundNo: req.RefundNo,
+		OrderNo:  resp.OutTradeNo,
+		Amount:   req.Amount,
+		Status:   PayStatusPending,
+	}
+	// 支付宝退款同步返回，fund_change为Y表示本次请求发生了资金变动
+	if resp.FundChange == "Y" {
+		refund.Status = PayStatusSuccess
+	}
+	if resp.GmtRefundPay != "" {
+		refund.RefundTime, _ = time.ParseInLocation(alipayTimeLayout, resp.GmtRefundPay, alipayLocation)
+	}
+	return refund, nil
+}
+
+// QueryRefund 查询退款，未查到退款记录时返回失败
+func (c *AlipayClient) QueryRefund(req *RefundQueryRequest) (*RefundData, error) {
+	bizContent := map[string]string{
+		"out_trade_no":   req.OrderNo,
+		"out_request_no": req.RefundNo,
+	}
+	var resp alipayRefundQueryResponse
+	if err := c.call("alipay.trade.fastpay.refund.query", nil, bizContent, &resp); err != nil {
+		return nil, fmt.Errorf("查询退款失败: %w", err)
+	}
+
+	refund := &RefundData{
+		RefundNo: req.RefundNo,
+		OrderNo:  req.OrderNo,
+		Status:   PayStatusFailed,
+	}
+	if resp.RefundAmount != "" {
+		amount, err := money.Parse(resp.RefundAmount)
+		if err != nil {
+			return nil, fmt.Errorf("退款金额格式错误: %w", err)
+		}
+		refund.Amount = amount
+	}
+	// 退款成功时返回REFUND_SUCCESS，有退款金额但无状态表示仍在处理
+	switch {
+	case resp.RefundStatus == "REFUND_SUCCESS":
+		refund.Status = PayStatusSuccess
+	case resp.RefundAmount != "":
+		refund.Status = PayStatusPending
+	}
+	if resp.GmtRefundPay != "" {
+		refund.RefundTime, _ = time.ParseInLocation(alipayTimeLayout, resp.GmtRefundPay, alipayLocation)
+	}
+	return refund, nil
+}
+
+// Transfer 单笔转账到支付宝账户，收款账户为支付宝用户ID或登录账号
+func (c *AlipayClient) Transfer(req *TransferRequest) (*TransferData, error) {
+	// 支付宝用户ID为纯数字，其余按登录账号处理，登录账号转账须提供真实姓名
+	identityType := "ALIPAY_USER_ID"
+	if !isDigits(req.AccountNo) {
+		identityType = "ALIPAY_LOGON_ID"
+	}
+	payee := map[string]string{
+		"identity":      req.AccountNo,
+		"identity_type": identityType,
+	}
+	if req.RealName != "" {
+		payee["name"] = req.RealName
+	}
+	bizContent := map[string]interface{}{
+		"out_biz_no":   req.OrderNo,
+		"trans_amount": req.Amount.String(),
+		"product_code": "TRANS_ACCOUNT_NO_PWD",
+		"biz_scene":    "DIRECT_TRANSFER",
+		"order_title":  req.Memo,
+		"payee_info":   payee,
+	}
+	var resp alipayTransferResponse
+	if err := c.call("alipay.fund.trans.uni.transfer", nil, bizContent, &resp); err != nil {
+		return nil, fmt.Errorf("转账请求失败: %w", err)
+	}
+
+	transfer := &TransferData{
+		OrderNo:    resp.OutBizNo,
+		TransferNo: resp.OrderID,
+		Amount:     req.Amount,
+	}
+	switch resp.Status {
+	case "SUCCESS":
+		transfer.Status = PayStatusSuccess
+	case "FAIL", "CLOSED":
+		transfer.Status = PayStatusFailed
+	default:
+		transfer.Status = PayStatusPending
+	}
+	if resp.TransDate != "" {
+		transfer.TransferTime, _ = time.ParseInLocation(alipayTimeLayout, resp.TransDate, alipayLocation)
+	}
+	return transfer, nil
+}
+
+// QueryTransfer 查询单笔转账结果，转账单不存在时返回失败
+func (c *AlipayClient) QueryTransfer(req *TransferQueryRequest) (*TransferData, error) {
+	bizContent := map[string]string{
+		"out_biz_no":   req.OrderNo,
+		"product_code": "TRANS_ACCOUNT_NO_PWD",
+		"biz_scene":    "DIRECT_TRANSFER",
+	}
+	var resp alipayTransferQueryResponse
+	if err := c.call("alipay.fund.trans.common.query", nil, bizContent, &resp); err != nil {
+		if resp.SubCode == "ORDER_NOT_EXIST" {
+			return &TransferData{OrderNo: req.OrderNo, Status: PayStatusFailed, FailReason: "转账单不存在"}, nil
+		}
+		return nil, fmt.Errorf("查询转账失败: %w", err)
+	}
+
+	transfer := &TransferData{
+		OrderNo:    req.OrderNo,
+		TransferNo: resp.OrderID,
+		Status:     PayStatusPending,
+		FailReason: resp.FailReason,
+	}
+	if resp.TransAmount != "" {
+		amount, err := money.Parse(resp.TransAmount)
+		if err != nil {
+			return nil, fmt.Errorf("转账金额格式错误: %w", err)
+		}
+		transfer.Amount = amount
+	}
+	// REFUND表示转账成功后被退票，资金已退回
+	switch resp.Status {
+	case "SUCCESS":
+		transfer.Status = PayStatusSuccess
+	case "FAIL", "CLOSED", "REFUND":
+		transfer.Status = PayStatusFailed
+	}
+	if resp.PayDate != "" {
+		transfer.TransferTime, _ = time.ParseInLocation(alipayTimeLayout, resp.PayDate, alipayLocation)
+	}
+	return transfer, nil
+}
+
+// ParseNotification 验证异步通知签名并解析交易结果
+func (c *AlipayClient) ParseNotification(header http.Header, body []byte) (*NotificationData, error) {
+	data, err := formParams(body)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.verifyParams(data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if data["app_id"] != c.config.AppID {
+		return nil, fmt.Errorf("%w: 应用ID不匹配", ErrInvalidSignature)
+	}
+
+	amount, err := money.Parse(data["total_amount"])
+	if err != nil {
+		return nil, fmt.Errorf("通知金额格式错误: %w", err)
+	}
+	extra, _ := url.QueryUnescape(data["passback_params"])
+	notification := &NotificationData{
+		OrderNo:       data["out_trade_no"],
+		TradeNo:       data["trade_no"],
+		Status:        alipayPayStatus(data["trade_status"]),
+		Amount:        amount,
+		PayMethod:     ProviderAlipay,
+		TransactionID: data["trade_no"],
+		Signature:     data["sign"],
+		Extra:         extra,
+	}
+	if notification.OrderNo == "" {
+		return nil, fmt.Errorf("通知缺少商户订单号")
+	}
+	if payTime := data["gmt_payment"]; payTime != "" {
+		notification.PayTime, _ = time.ParseInLocation(alipayTimeLayout, payTime, alipayLocation)
+	}
+	return notification, nil
+}
+
+// ParseRefundNotification 支付宝退款结果由退款接口同步返回或通过查询获取，不单独发送退款通知
+func (c *AlipayClient) ParseRefundNotification(header http.Header, body []byte) (*RefundData, error) {
+	return nil, fmt.Errorf("支付宝不支持退款异步通知")
+}
+
+// alipayPayStatus 将支付宝交易状态转换为统一的支付状态
+func alipayPayStatus(tradeStatus string) string {
+	switch tradeStatus {
+	case alipayTradeSuccess, alipayTradeFinished:
+		return PayStatusSuccess
+	case alipayTradeClosed:
+		return PayStatusClosed
+	case alipayTradeWaitBuyerPay:
+		return PayStatusPending
+	default:
+		return PayStatusPending
+	}
+}
+
+// call 调用开放平台接口，验证响应签名后解析响应节点
+func (c *AlipayClient) call(method string, params map[string]string, bizContent interface{}, out interface{}) error {
+	values, err := c.signedValues(method, params, bizContent)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.PostForm(c.gatewayURL(), values)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, body)
+	}
+
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	nodeName := strings.ReplaceAll(method, ".", "_") + "_response"
+	node, ok := envelope[nodeName]
+	if !ok {
+		node, ok = envelope["error_response"]
+		if !ok {
+			return fmt.Errorf("响应缺少%s节点", nodeName)
+		}
+	}
+
+	// 签名覆盖响应节点的原始报文，错误响应可能不带签名
+	var sign string
+	if raw, ok := envelope["sign"]; ok {
+		if err := json.Unmarshal(raw, &sign); err != nil {
+			return fmt.Errorf("解析响应签名失败: %w", err)
+		}
+	}
+	var status alipayResponseStatus
+	if err := json.Unmarshal(node, &status); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	if sign != "" || status.Code == "" || status.Code == "10000" {
+		if err := c.verify(node, sign); err != nil {
+			return fmt.Errorf("响应签名验证失败: %w", err)
+		}
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(node, out); err != nil {
+			return fmt.Errorf("解析响应失败: %w", err)
+		}
+	}
+	// 授权类接口成功时不返回code
+	if status.Code != "" && status.Code != "10000" {
+		return fmt.Errorf("%s %s: %s", status.Code, status.SubCode, firstNonEmpty(status.SubMsg, status.Msg))
+	}
+	return nil
+}
+
+// signedValues 组装公共请求参数并签名
+func (c *AlipayClient) signedValues(method string, params map[string]string, bizContent interface{}) (url.Values, error) {
+	all := map[string]string{
+		"app_id":    c.config.AppID,
+		"method":    method,
+		"format":    "JSON",
+		"charset":   "utf-8",
+		"sign_type": "RSA2",
+		"timestamp": time.Now().In(alipayLocation).Format(alipayTimeLayout),
+		"version":   "1.0",
+	}
+	for k, v := range params {
+		if v != "" {
+			all[k] = v
+		}
+	}
+	if bizContent != nil {
+		content, err := json.Marshal(bizContent)
+		if err != nil {
+			return nil, fmt.Errorf("序列化业务参数失败: %w", err)
+		}
+		all["biz_content"] = string(content)
+	}
+
+	sign, err := c.sign(alipaySignContent(all))
+	if err != nil {
+		return nil, err
+	}
+	values := url.Values{}
+	for k, v := range all {
+		values.Set(k, v)
+	}
+	values.Set("sign", sign)
+	return values, nil
+}
+
+// sign 使用应用私钥进行SHA256WithRSA签名
+func (c *AlipayClient) sign(content string) (string, error) {
+	hashed := sha256.Sum256([]byte(content))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, c.privateKey, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", fmt.Errorf("签名失败: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// verify 使用支付宝公钥验证签名
+func (c *AlipayClient) verify(content []byte, sign string) error {
+	if sign == "" {
+		return fmt.Errorf("缺少签名")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(sign)
+	if err != nil {
+		return fmt.Errorf("签名格式错误: %w", err)
+	}
+	hashed := sha256.Sum256(content)
+	return rsa.VerifyPKCS1v15(c.publicKey, crypto.SHA256, hashed[:], decoded)
+}
+
+// verifyParams 验证异步通知签名，sign和sign_type不参与签名
+func (c *AlipayClient) verifyParams(data map[string]string) error {
+	if data["sign_type"] != "" && data["sign_type"] != "RSA2" {
+		return fmt.Errorf("不支持的签名类型: %s", data["sign_type"])
+	}
+	params := make(map[string]string, len(data))
+	for k, v := range data {
+		if k != "sign" && k != "sign_type" {
+			params[k] = v
+		}
+	}
+	return c.verify([]byte(alipaySignContent(params)), data["sign"])
+}
+
+// gatewayURL 开放平台网关地址，测试时可指向本地桩服务
+func (c *AlipayClient) gatewayURL() string {
+	if c.config.GatewayURL != "" {
+		return c.config.GatewayURL
+	}
+	return alipayDefaultGatewayURL
+}
+
+// alipaySignContent 按键名排序拼接非空参数，生成待签名字符串
+func alipaySignContent(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k, v := range params {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + params[k]
+	}
+	return strings.Join(pairs, "&")
+}
+
+// isDigits 是否全为数字
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
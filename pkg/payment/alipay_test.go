@@ -0,0 +1,208 @@
+package payment
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/pkg/money"
+)
+
+const testAlipayAppID = "2021000000000001"
+
+// newTestAlipayClient 生成应用密钥和支付宝密钥并创建客户端，返回应用私钥和支付宝私钥
+func newTestAlipayClient(t *testing.T, gatewayURL string) (*AlipayClient, *rsa.PrivateKey, *rsa.PrivateKey) {
+	t.Helper()
+	appKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("生成应用密钥失败: %v", err)
+	}
+	alipayKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("生成支付宝密钥失败: %v", err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(appKey)
+	if err != nil {
+		t.Fatalf("编码应用私钥失败: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&alipayKey.PublicKey)
+	if err != nil {
+		t.Fatalf("编码支付宝公钥失败: %v", err)
+	}
+
+	client, err := NewAlipayClient(&config.AlipayConfig{
+		AppID: testAlipayAppID,
+		// 开放平台下发的应用私钥为不含PEM头的Base64字符串
+		PrivateKey: base64.StdEncoding.EncodeToString(privateDER),
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+		GatewayURL: gatewayURL,
+	})
+	if err != nil {
+		t.Fatalf("创建支付宝客户端失败: %v", err)
+	}
+	return client, appKey, alipayKey
+}
+
+// signAlipay 以SHA256WithRSA签名，模拟支付宝对应答和通知签名
+func signAlipay(t *testing.T, key *rsa.PrivateKey, content string) string {
+	t.Helper()
+	hashed := sha256.Sum256([]byte(content))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatalf("签名失败: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(signature)
+}
+
+// verifyAlipayRequest 以应用公钥校验请求签名，sign不参与签名
+func verifyAlipayRequest(key *rsa.PublicKey, form url.Values) error {
+	params := make(map[string]string)
+	for k := range form {
+		if k != "sign" {
+			params[k] = form.Get(k)
+		}
+	}
+	signature, err := base64.StdEncoding.DecodeString(form.Get("sign"))
+	if err != nil {
+		return err
+	}
+	hashed := sha256.Sum256([]byte(alipaySignContent(params)))
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature)
+}
+
+func TestAlipaySignAndVerify(t *testing.T) {
+	client, appKey, alipayKey := newTestAlipayClient(t, "")
+	content := alipaySignContent(map[string]string{"out_trade_no": "T001", "total_amount": "100.00", "empty": ""})
+	if content != "out_trade_no=T001&total_amount=100.00" {
+		t.Fatalf("待签名字符串应按键名排序并忽略空值: %s", content)
+	}
+
+	sign, err := client.sign(content)
+	if err != nil {
+		t.Fatalf("签名失败: %v", err)
+	}
+	decoded, _ := base64.StdEncoding.DecodeString(sign)
+	hashed := sha256.Sum256([]byte(content))
+	if err := rsa.VerifyPKCS1v15(&appKey.PublicKey, crypto.SHA256, hashed[:], decoded); err != nil {
+		t.Fatalf("应使用应用私钥签名: %v", err)
+	}
+
+	if err := client.verify([]byte(content), signAlipay(t, alipayKey, content)); err != nil {
+		t.Fatalf("支付宝签名应验证通过: %v", err)
+	}
+	if err := client.verify([]byte(content+"&x=1"), signAlipay(t, alipayKey, content)); err == nil {
+		t.Fatal("内容被篡改时应验证失败")
+	}
+	if err := client.verify([]byte(content), sign); err == nil {
+		t.Fatal("非支付宝私钥的签名应验证失败")
+	}
+}
+
+func TestAlipayQueryPayStatus(t *testing.T) {
+	var client *AlipayClient
+	var appKey, alipayKey *rsa.PrivateKey
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("解析请求失败: %v", err)
+		}
+		if err := verifyAlipayRequest(&appKey.PublicKey, r.PostForm); err != nil {
+			t.Errorf("请求签名验证失败: %v", err)
+		}
+		if r.PostForm.Get("method") != "alipay.trade.query" || r.PostForm.Get("app_id") != testAlipayAppID {
+			t.Errorf("请求参数错误: %v", r.PostForm)
+		}
+		// 签名覆盖响应节点的原始报文
+		node := `{"code":"10000","msg":"Success","trade_no":"2024001","out_trade_no":"T001","trade_status":"TRADE_SUCCESS","total_amount":"100.00","send_pay_date":"2024-05-01 12:00:00"}`
+		w.Write([]byte(`{"alipay_trade_query_response":` + node + `,"sign":"` + signAlipay(t, alipayKey, node) + `"}`))
+	}))
+	t.Cleanup(server.Close)
+	client, appKey, alipayKey = newTestAlipayClient(t, server.URL)
+
+	status, err := client.QueryPayStatus(&PayStatusRequest{OrderNo: "T001"})
+	if err != nil {
+		t.Fatalf("查询支付状态失败: %v", err)
+	}
+	if status.Status != PayStatusSuccess || status.Amount != money.FromCents(10000) || status.TradeNo != "2024001" || status.PayTime.IsZero() {
+		t.Fatalf("查询结果错误: %+v", status)
+	}
+}
+
+func TestAlipayRejectsTamperedResponse(t *testing.T) {
+	var alipayKey *rsa.PrivateKey
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signed := `{"code":"10000","msg":"Success","out_trade_no":"T001","trade_status":"WAIT_BUYER_PAY","total_amount":"100.00"}`
+		node := `{"code":"10000","msg":"Success","out_trade_no":"T001","trade_status":"TRADE_SUCCESS","total_amount":"100.00"}`
+		w.Write([]byte(`{"alipay_trade_query_response":` + node + `,"sign":"` + signAlipay(t, alipayKey, signed) + `"}`))
+	}))
+	t.Cleanup(server.Close)
+	client, _, key := newTestAlipayClient(t, server.URL)
+	alipayKey = key
+
+	if status, err := client.QueryPayStatus(&PayStatusRequest{OrderNo: "T001"}); err == nil {
+		t.Fatalf("响应被篡改时应返回错误: %+v", status)
+	}
+}
+
+func TestAlipayParseNotification(t *testing.T) {
+	client, _, alipayKey := newTestAlipayClient(t, "")
+	notification := func(tamper func(params map[string]string)) []byte {
+		params := map[string]string{
+			"app_id":       testAlipayAppID,
+			"out_trade_no": "T001",
+			"trade_no":     "2024001",
+			"trade_status": "TRADE_SUCCESS",
+			"total_amount": "100.00",
+			"gmt_payment":  "2024-05-01 12:00:00",
+		}
+		params["sign"] = signAlipay(t, alipayKey, alipaySignContent(params))
+		params["sign_type"] = "RSA2"
+		if tamper != nil {
+			tamper(params)
+		}
+		form := url.Values{}
+		for k, v := range params {
+			form.Set(k, v)
+		}
+		return []byte(form.Encode())
+	}
+
+	data, err := client.ParseNotification(http.Header{}, notification(nil))
+	if err != nil {
+		t.Fatalf("解析通知失败: %v", err)
+	}
+	if data.OrderNo != "T001" || data.Status != PayStatusSuccess || data.Amount != money.FromCents(10000) || data.PayTime.IsZero() {
+		t.Fatalf("通知解析结果错误: %+v", data)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(params map[string]string)
+	}{
+		{name: "篡改金额", tamper: func(p map[string]string) { p["total_amount"] = "0.01" }},
+		{name: "篡改交易状态", tamper: func(p map[string]string) { p["trade_status"] = "TRADE_FINISHED" }},
+		{name: "追加参数", tamper: func(p map[string]string) { p["passback_params"] = "x" }},
+		{name: "缺少签名", tamper: func(p map[string]string) { delete(p, "sign") }},
+		{name: "不支持的签名类型", tamper: func(p map[string]string) { p["sign_type"] = "RSA" }},
+		{name: "其他应用的通知", tamper: func(p map[string]string) {
+			p["app_id"] = "2021000000000002"
+			delete(p, "sign")
+			delete(p, "sign_type")
+			p["sign"] = signAlipay(t, alipayKey, alipaySignContent(p))
+		}},
+	}
+	for _, tt := range tests {
+		if _, err := client.ParseNotification(http.Header{}, notification(tt.tamper)); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: 应返回ErrInvalidSignature, 实际: %v", tt.name, err)
+		}
+	}
+}
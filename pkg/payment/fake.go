@@ -0,0 +1,353 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/pkg/money"
+	"task-platform-api/pkg/utils"
+)
+
+// PayMethodFake 模拟渠道的支付方式
+const PayMethodFake = "fake"
+
+// ErrFakeRequestFailed 模拟渠道按配置概率注入的接口失败
+var ErrFakeRequestFailed = errors.New("模拟渠道请求失败")
+
+// fakeOrder 模拟渠道的订单
+type fakeOrder struct {
+	status    PayStatusData
+	notifyURL string
+	extra     string
+	refunded  money.Money
+}
+
+// FakeGateway 进程内模拟支付渠道，按配置注入延迟和失败，并向回调地址发送签名通知
+type FakeGateway struct {
+	config *config.FakePaymentConfig
+	client *http.Client
+	logger *zap.Logger
+
+	mu        sync.Mutex
+	orders    map[string]*fakeOrder
+	refunds   map[string]*RefundData
+	transfers map[string]*TransferData
+}
+
+// NewFakeGateway 创建模拟支付渠道
+func NewFakeGateway(cfg *config.FakePaymentConfig, logger *zap.Logger) *FakeGateway {
+	return &FakeGateway{
+		config:    cfg,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		logger:    logger,
+		orders:    make(map[string]*fakeOrder),
+		refunds:   make(map[string]*RefundData),
+		transfers: make(map[string]*TransferData),
+	}
+}
+
+// Name 渠道标识
+func (g *FakeGateway) Name() string {
+	return ProviderFake
+}
+
+// PrePay 创建待支付订单，开启自动通知时延迟后完成支付并回调
+func (g *FakeGateway) PrePay(req *PrePayRequest) (*PrePayResponseData, error) {
+	if err := g.simulate(); err != nil {
+		return nil, err
+	}
+	if !req.Amount.IsPositive() {
+		return nil, fmt.Errorf("预支付失败: 金额必须大于0")
+	}
+	if req.ExpireTime == 0 {
+		req.ExpireTime = 900
+	}
+	if req.NotifyURL == "" {
+		req.NotifyURL = g.config.NotifyURL
+	}
+
+	g.mu.Lock()
+	order, exists := g.orders[req.OrderNo]
+	if !exists {
+		order = &fakeOrder{
+			status: PayStatusData{
+				OrderNo: req.OrderNo,
+				TradeNo: "FAKE" + utils.GenerateOrderNo(),
+				Status:  PayStatusPending,
+				Amount:  req.Amount,
+			},
+			notifyURL: req.NotifyURL,
+			extra:     req.Extra,
+		}
+		g.orders[req.OrderNo] = order
+	}
+	tradeNo := order.status.TradeNo
+	g.mu.Unlock()
+
+	if !exists && g.config.AutoNotify {
+		go g.autoComplete(req.OrderNo)
+	}
+
+	return &PrePayResponseData{
+		OrderNo:    req.OrderNo,
+		TradeNo:    tradeNo,
+		PayURL:     "fake://pay/" + tradeNo,
+		QRCode:     "fake://pay/" + tradeNo,
+		ExpireTime: time.Now().Add(time.Duration(req.ExpireTime) * time.Second).Unix(),
+	}, nil
+}
+
+// QueryPayStatus 查询订单支付状态
+func (g *FakeGateway) QueryPayStatus(req *PayStatusRequest) (*PayStatusData, error) {
+	if err := g.simulate(); err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	order, ok := g.orders[req.OrderNo]
+	if !ok {
+		return nil, fmt.Errorf("查询失败: 订单不存在")
+	}
+	status := order.status
+	return &status, nil
+}
+
+// Refund 对已支付订单退款，累计退款不超过支付金额，相同退款单号重复请求返回原结果
+func (g *FakeGateway) Refund(req *RefundRequest) (*RefundData, error) {
+	if err := g.simulate(); err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if refund, ok := g.refunds[req.RefundNo]; ok {
+		result := *refund
+		return &result, nil
+	}
+	order, ok := g.orders[req.OrderNo]
+	if !ok {
+		return nil, fmt.Errorf("退款失败: 订单不存在")
+	}
+	if order.status.Status != PayStatusSuccess {
+		return nil, fmt.Errorf("退款失败: 订单未支付成功")
+	}
+	if !req.Amount.IsPositive() || order.refunded+req.Amount > order.status.Amount {
+		return nil, fmt.Errorf("退款失败: 退款金额超出可退金额")
+	}
+
+	order.refunded += req.Amount
+	refund := &RefundData{
+		RefundNo:   req.RefundNo,
+		OrderNo:    req.OrderNo,
+		Amount:     req.Amount,
+		Status:     PayStatusSuccess,
+		RefundTime: time.Now(),
+	}
+	g.refunds[req.RefundNo] = refund
+	result := *refund
+	return &result, nil
+}
+
+// QueryRefund 查询退款结果，退款单不存在时返回失败
+func (g *FakeGateway) QueryRefund(req *RefundQueryRequest) (*RefundData, error) {
+	if err := g.simulate(); err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	refund, ok := g.refunds[req.RefundNo]
+	if !ok {
+		return &RefundData{RefundNo: req.RefundNo, OrderNo: req.OrderNo, Status: PayStatusFailed}, nil
+	}
+	result := *refund
+	return &result, nil
+}
+
+// Transfer 模拟转账，相同商户订单号重复请求返回原结果
+func (g *FakeGateway) Transfer(req *TransferRequest) (*TransferData, error) {
+	if err := g.simulate(); err != nil {
+		return nil, err
+	}
+	if !req.Amount.IsPositive() {
+		return nil, fmt.Errorf("转账失败: 金额必须大于0")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	transfer, ok := g.transfers[req.OrderNo]
+	if !ok {
+		transfer = &TransferData{
+			OrderNo:      req.OrderNo,
+			TransferNo:   "FAKE" + utils.GenerateOrderNo(),
+			Status:       PayStatusSuccess,
+			Amount:       req.Amount,
+			TransferTime: time.Now(),
+		}
+		g.transfers[req.OrderNo] = transfer
+	}
+	result := *transfer
+	return &result, nil
+}
+
+// QueryTransfer 查询转账结果，转账单不存在时返回失败
+func (g *FakeGateway) QueryTransfer(req *TransferQueryRequest) (*TransferData, error) {
+	if err := g.simulate(); err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	transfer, ok := g.transfers[req.OrderNo]
+	if !ok {
+		return &TransferData{OrderNo: req.OrderNo, Status: PayStatusFailed, FailReason: "转账单不存在"}, nil
+	}
+	result := *transfer
+	return &result, nil
+}
+
+// ParseNotification 校验签名并解析表单形式的回调通知
+func (g *FakeGateway) ParseNotification(header http.Header, body []byte) (*NotificationData, error) {
+	data, err := formParams(body)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyParams(data, g.config.SecretKey) {
+		return nil, ErrInvalidSignature
+	}
+	return parseNotificationParams(data)
+}
+
+// ParseRefundNotification 校验签名并解析表单形式的退款通知
+func (g *FakeGateway) ParseRefundNotification(header http.Header, body []byte) (*RefundData, error) {
+	data, err := formParams(body)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyParams(data, g.config.SecretKey) {
+		return nil, ErrInvalidSignature
+	}
+	return parseRefundParams(data)
+}
+
+// Complete 将待支付订单置为指定的最终状态并同步发送回调通知，供测试主动驱动支付结果
+func (g *FakeGateway) Complete(orderNo, status string) error {
+	if status != PayStatusSuccess && status != PayStatusFailed && status != PayStatusClosed {
+		return fmt.Errorf("无效的支付状态: %s", status)
+	}
+
+	g.mu.Lock()
+	order, ok := g.orders[orderNo]
+	if !ok {
+		g.mu.Unlock()
+		return fmt.Errorf("订单不存在: %s", orderNo)
+	}
+	if order.status.Status != PayStatusPending {
+		g.mu.Unlock()
+		return fmt.Errorf("订单已完成: %s", orderNo)
+	}
+	order.status.Status = status
+	order.status.PayTime = time.Now()
+	order.status.PayMethod = PayMethodFake
+	order.status.TransactionID = "FAKETX" + utils.GenerateOrderNo()
+	params := g.notificationParams(order)
+	notifyURL := order.notifyURL
+	g.mu.Unlock()
+
+	return g.notify(notifyURL, params)
+}
+
+// autoComplete 延迟后按失败概率决定支付结果并发送通知
+func (g *FakeGateway) autoComplete(orderNo string) {
+	time.Sleep(time.Duration(g.config.NotifyDelay) * time.Millisecond)
+
+	status := PayStatusSuccess
+	if rand.Float64() < g.config.PayFailRate {
+		status = PayStatusFailed
+	}
+	if err := g.Complete(orderNo, status); err != nil {
+		g.logger.Error("模拟渠道发送支付通知失败", zap.String("order_no", orderNo), zap.Error(err))
+	}
+}
+
+// notificationParams 构造签名后的通知参数，调用方须持有锁
+func (g *FakeGateway) notificationParams(order *fakeOrder) map[string]string {
+	params := map[string]string{
+		"order_no":       order.status.OrderNo,
+		"trade_no":       order.status.TradeNo,
+		"status":         order.status.Status,
+		"amount":         order.status.Amount.String(),
+		"pay_time":       strconv.FormatInt(order.status.PayTime.Unix(), 10),
+		"pay_method":     order.status.PayMethod,
+		"transaction_id": order.status.TransactionID,
+		"extra":          order.extra,
+		"nonce_str":      utils.GenerateNonce(32),
+	}
+	params["signature"] = signParams(params, g.config.SecretKey)
+	return params
+}
+
+// notify 以表单POST发送通知，未收到success时按配置次数重试
+func (g *FakeGateway) notify(notifyURL string, params map[string]string) error {
+	if notifyURL == "" {
+		return nil
+	}
+
+	values := url.Values{}
+	for k, v := range params {
+		values.Set(k, v)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= g.config.NotifyRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+		lastErr = g.postNotification(notifyURL, values)
+		if lastErr == nil {
+			return nil
+		}
+	}
+	return lastErr
+}
+
+// postNotification 发送一次通知，响应须为200且内容为success
+func (g *FakeGateway) postNotification(notifyURL string, values url.Values) error {
+	resp, err := g.client.PostForm(notifyURL, values)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK || strings.TrimSpace(string(body)) != "success" {
+		return fmt.Errorf("通知未被接收: HTTP %d %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// simulate 按配置注入接口延迟和失败
+func (g *FakeGateway) simulate() error {
+	if g.config.ResponseDelay > 0 {
+		time.Sleep(time.Duration(g.config.ResponseDelay) * time.Millisecond)
+	}
+	if rand.Float64() < g.config.RequestFailRate {
+		return ErrFakeRequestFailed
+	}
+	return nil
+}
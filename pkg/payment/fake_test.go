@@ -0,0 +1,103 @@
+package payment
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/pkg/money"
+)
+
+func TestFakeGatewayNotifyRoundTrip(t *testing.T) {
+	gateway := NewFakeGateway(&config.FakePaymentConfig{SecretKey: "fake-secret"}, zap.NewNop())
+
+	received := make(chan *NotificationData, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("读取通知失败: %v", err)
+			return
+		}
+		notification, err := gateway.ParseNotification(r.Header, body)
+		if err != nil {
+			t.Errorf("解析通知失败: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- notification
+		io.WriteString(w, "success")
+	}))
+	defer server.Close()
+
+	prepay, err := gateway.PrePay(&PrePayRequest{
+		OrderNo:   "T001",
+		Amount:    money.FromCents(12345),
+		NotifyURL: server.URL,
+		Extra:     "task=1",
+	})
+	if err != nil {
+		t.Fatalf("预支付失败: %v", err)
+	}
+	if err := gateway.Complete("T001", PayStatusSuccess); err != nil {
+		t.Fatalf("完成支付失败: %v", err)
+	}
+
+	notification := <-received
+	if notification.OrderNo != "T001" || notification.TradeNo != prepay.TradeNo {
+		t.Errorf("通知订单号错误: %+v", notification)
+	}
+	if notification.Status != PayStatusSuccess || notification.Amount != money.FromCents(12345) {
+		t.Errorf("通知状态或金额错误: status=%s amount=%s", notification.Status, notification.Amount)
+	}
+	if notification.Extra != "task=1" || notification.PayMethod != PayMethodFake || notification.PayTime.IsZero() {
+		t.Errorf("通知附加信息错误: %+v", notification)
+	}
+
+	status, err := gateway.QueryPayStatus(&PayStatusRequest{OrderNo: "T001"})
+	if err != nil {
+		t.Fatalf("查询支付状态失败: %v", err)
+	}
+	if status.Status != PayStatusSuccess {
+		t.Errorf("支付后查询状态应为成功, 实际: %s", status.Status)
+	}
+	if err := gateway.Complete("T001", PayStatusFailed); err == nil {
+		t.Error("已完成的订单不应再次完成")
+	}
+}
+
+func TestFakeGatewayRejectsTamperedNotification(t *testing.T) {
+	gateway := NewFakeGateway(&config.FakePaymentConfig{SecretKey: "fake-secret"}, zap.NewNop())
+
+	params := map[string]string{
+		"order_no": "T002",
+		"trade_no": "FAKE002",
+		"status":   PayStatusSuccess,
+		"amount":   "1.00",
+	}
+	params["signature"] = signParams(params, "fake-secret")
+
+	values := url.Values{}
+	for k, v := range params {
+		values.Set(k, v)
+	}
+	if _, err := gateway.ParseNotification(nil, []byte(values.Encode())); err != nil {
+		t.Fatalf("签名正确的通知应解析成功: %v", err)
+	}
+
+	values.Set("amount", "100.00")
+	if _, err := gateway.ParseNotification(nil, []byte(values.Encode())); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("篡改金额后应返回ErrInvalidSignature, 实际: %v", err)
+	}
+
+	wrongKey := NewFakeGateway(&config.FakePaymentConfig{SecretKey: "other-secret"}, zap.NewNop())
+	values.Set("amount", "1.00")
+	if _, err := wrongKey.ParseNotification(nil, []byte(values.Encode())); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("密钥不一致时应返回ErrInvalidSignature, 实际: %v", err)
+	}
+}
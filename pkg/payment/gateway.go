@@ -0,0 +1,259 @@
+package payment
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/pkg/money"
+)
+
+// 支付渠道标识
+const (
+	ProviderShouqianba = "shouqianba" // 收钱吧
+	ProviderWechat     = "wechat"     // 微信支付
+	ProviderAlipay     = "alipay"     // 支付宝
+	ProviderFake       = "fake"       // 本地模拟渠道
+)
+
+// PayStatusPending 待支付，渠道尚未返回最终结果
+const PayStatusPending = "PENDING"
+
+// ErrInvalidSignature 回调通知签名校验失败
+var ErrInvalidSignature = errors.New("回调通知签名验证失败")
+
+// PaymentGateway 支付渠道，封装预支付、查询、退款、转账及回调通知解析
+type PaymentGateway interface {
+	// Name 渠道标识
+	Name() string
+	// PrePay 创建预支付订单
+	PrePay(req *PrePayRequest) (*PrePayResponseData, error)
+	// QueryPayStatus 查询订单支付状态
+	QueryPayStatus(req *PayStatusRequest) (*PayStatusData, error)
+	// Refund 申请退款，返回的状态为SUCCESS、PENDING或FAILED
+	Refund(req *RefundRequest) (*RefundData, error)
+	// QueryRefund 查询退款结果，退款单不存在时返回FAILED
+	QueryRefund(req *RefundQueryRequest) (*RefundData, error)
+	// Transfer 向收款账户转账，返回的状态为SUCCESS、PENDING或FAILED
+	Transfer(req *TransferRequest) (*TransferData, error)
+	// QueryTransfer 查询转账结果，转账单不存在时返回FAILED
+	QueryTransfer(req *TransferQueryRequest) (*TransferData, error)
+	// ParseNotification 校验签名并解析异步通知的请求头和原始报文，签名错误时返回ErrInvalidSignature
+	ParseNotification(header http.Header, body []byte) (*NotificationData, error)
+	// ParseRefundNotification 校验签名并解析退款结果通知
+	ParseRefundNotification(header http.Header, body []byte) (*RefundData, error)
+}
+
+var (
+	_ PaymentGateway = (*ShouqianbaClient)(nil)
+	_ PaymentGateway = (*WechatPayClient)(nil)
+	_ PaymentGateway = (*AlipayClient)(nil)
+	_ PaymentGateway = (*FakeGateway)(nil)
+)
+
+// Registry 已启用的支付渠道，按交易的支付方式选择渠道
+type Registry struct {
+	gateways    map[string]PaymentGateway
+	defaultName string
+}
+
+// NewRegistry 按配置创建支付渠道，payment.provider为默认渠道，payment.providers为额外启用的渠道
+func NewRegistry(cfg *config.Config, logger *zap.Logger) (*Registry, error) {
+	r := &Registry{
+		gateways:    make(map[string]PaymentGateway),
+		defaultName: cfg.Payment.Provider,
+	}
+	if r.defaultName == "" {
+		r.defaultName = ProviderShouqianba
+	}
+
+	names := append([]string{r.defaultName}, cfg.Payment.Providers...)
+	for _, name := range names {
+		if _, ok := r.gateways[name]; ok {
+			continue
+		}
+		switch name {
+		case ProviderShouqianba:
+			r.Register(NewShouqianbaClient(&cfg.Shouqianba))
+		case ProviderWechat:
+			wechat, err := NewWechatPayClient(&cfg.Wechat)
+			if err != nil {
+				return nil, fmt.Errorf("初始化微信支付失败: %w", err)
+			}
+			r.Register(wechat)
+		case ProviderAlipay:
+			alipay, err := NewAlipayClient(&cfg.Alipay)
+			if err != nil {
+				return nil, fmt.Errorf("初始化支付宝失败: %w", err)
+			}
+			r.Register(alipay)
+		case ProviderFake:
+			r.Register(NewFakeGateway(&cfg.Payment.Fake, logger))
+		default:
+			return nil, fmt.Errorf("不支持的支付渠道: %s", name)
+		}
+	}
+	return r, nil
+}
+
+// Register 注册支付渠道，同名渠道会被覆盖
+func (r *Registry) Register(gateway PaymentGateway) {
+	r.gateways[gateway.Name()] = gateway
+}
+
+// Get 按渠道标识获取支付渠道，标识为空时返回默认渠道
+func (r *Registry) Get(name string) (PaymentGateway, bool) {
+	if name == "" {
+		name = r.defaultName
+	}
+	gateway, ok := r.gateways[name]
+	return gateway, ok
+}
+
+// Names 已启用的渠道标识，按名称排序
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.gateways))
+	for name := range r.gateways {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultName 默认渠道标识
+func (r *Registry) DefaultName() string {
+	return r.defaultName
+}
+
+// signParams 按键名排序拼接非空参数并追加密钥，MD5后转大写
+func signParams(params map[string]string, secretKey string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	for _, key := range keys {
+		if params[key] != "" {
+			builder.WriteString(key)
+			builder.WriteString("=")
+			builder.WriteString(params[key])
+			builder.WriteString("&")
+		}
+	}
+	builder.WriteString("key=")
+	builder.WriteString(secretKey)
+
+	hash := md5.Sum([]byte(builder.String()))
+	return strings.ToUpper(hex.EncodeToString(hash[:]))
+}
+
+// verifyParams 校验带signature字段的参数签名
+func verifyParams(data map[string]string, secretKey string) bool {
+	signature := data["signature"]
+	if signature == "" {
+		return false
+	}
+
+	params := make(map[string]string, len(data))
+	for k, v := range data {
+		if k != "signature" {
+			params[k] = v
+		}
+	}
+	return signParams(params, secretKey) == signature
+}
+
+// formParams 将表单报文解析为参数表，同名参数取第一个值
+func formParams(body []byte) (map[string]string, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("解析通知报文失败: %w", err)
+	}
+	data := make(map[string]string, len(values))
+	for key, v := range values {
+		if len(v) > 0 {
+			data[key] = v[0]
+		}
+	}
+	return data, nil
+}
+
+// resultStatus 将渠道退款、转账状态归一为SUCCESS、PENDING或FAILED
+func resultStatus(status string) string {
+	switch status {
+	case PayStatusSuccess:
+		return PayStatusSuccess
+	case PayStatusFailed, PayStatusClosed:
+		return PayStatusFailed
+	default:
+		return PayStatusPending
+	}
+}
+
+// parseRefundParams 将表单形式的退款通知解析为退款数据，签名须事先校验
+func parseRefundParams(data map[string]string) (*RefundData, error) {
+	amount, err := money.Parse(data["amount"])
+	if err != nil {
+		return nil, fmt.Errorf("通知金额格式错误: %w", err)
+	}
+
+	refund := &RefundData{
+		RefundNo: data["refund_no"],
+		OrderNo:  data["order_no"],
+		Amount:   amount,
+		Status:   resultStatus(data["status"]),
+	}
+	if refund.RefundNo == "" {
+		return nil, fmt.Errorf("通知缺少退款单号")
+	}
+	if refundTime := data["refund_time"]; refundTime != "" {
+		ts, err := strconv.ParseInt(refundTime, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("通知退款时间格式错误: %w", err)
+		}
+		refund.RefundTime = time.Unix(ts, 0)
+	}
+	return refund, nil
+}
+
+// parseNotificationParams 将表单形式的通知参数解析为通知数据，签名须事先校验
+func parseNotificationParams(data map[string]string) (*NotificationData, error) {
+	amount, err := money.Parse(data["amount"])
+	if err != nil {
+		return nil, fmt.Errorf("通知金额格式错误: %w", err)
+	}
+
+	notification := &NotificationData{
+		OrderNo:       data["order_no"],
+		TradeNo:       data["trade_no"],
+		Status:        data["status"],
+		Amount:        amount,
+		PayMethod:     data["pay_method"],
+		TransactionID: data["transaction_id"],
+		Signature:     data["signature"],
+		Extra:         data["extra"],
+	}
+	if notification.OrderNo == "" {
+		return nil, fmt.Errorf("通知缺少商户订单号")
+	}
+	if payTime := data["pay_time"]; payTime != "" {
+		ts, err := strconv.ParseInt(payTime, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("通知支付时间格式错误: %w", err)
+		}
+		notification.PayTime = time.Unix(ts, 0)
+	}
+	return notification, nil
+}
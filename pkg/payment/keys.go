@@ -0,0 +1,89 @@
+package payment
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// readPEM 读取PEM内容，配置值可以是PEM内容、文件路径或不含PEM头的Base64密钥
+func readPEM(value, blockType string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, fmt.Errorf("密钥未配置")
+	}
+	if strings.Contains(value, "-----BEGIN") {
+		return []byte(value), nil
+	}
+	if data, err := os.ReadFile(value); err == nil {
+		return data, nil
+	}
+
+	// 支付宝开放平台下发的密钥通常为不含PEM头的Base64字符串
+	der, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("密钥既不是PEM内容也不是可读取的文件")
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), nil
+}
+
+// loadRSAPrivateKey 加载PKCS8或PKCS1格式的RSA私钥
+func loadRSAPrivateKey(value string) (*rsa.PrivateKey, error) {
+	data, err := readPEM(value, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("私钥不是有效的PEM格式")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("私钥不是RSA密钥")
+		}
+		return rsaKey, nil
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析私钥失败: %w", err)
+	}
+	return key, nil
+}
+
+// loadRSAPublicKey 加载RSA公钥，支持PKIX公钥和X.509证书
+func loadRSAPublicKey(value string) (*rsa.PublicKey, error) {
+	data, err := readPEM(value, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("公钥不是有效的PEM格式")
+	}
+
+	var key interface{}
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析证书失败: %w", err)
+		}
+		key = cert.PublicKey
+	} else {
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析公钥失败: %w", err)
+		}
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("公钥不是RSA密钥")
+	}
+	return rsaKey, nil
+}
@@ -1,22 +1,32 @@
 package payment
 
 import (
-	"crypto/md5"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"sort"
 	"strconv"
-	"strings"
 	"time"
 
 	"task-platform-api/internal/config"
+	"task-platform-api/pkg/money"
 	"task-platform-api/pkg/utils"
 )
 
+// 渠道交易状态
+const (
+	PayStatusSuccess = "SUCCESS" // 支付成功
+	PayStatusFailed  = "FAILED"  // 支付失败
+	PayStatusClosed  = "CLOSED"  // 已关闭
+)
+
+// 收钱吧业务码，4xx表示请求被业务拒绝未受理，5xx等其余非200业务码表示处理结果未知
+const (
+	shouqianbaCodeSuccess  = "200"
+	shouqianbaCodeNotFound = "404"
+)
+
 // ShouqianbaClient 收钱吧客户端
 type ShouqianbaClient struct {
 	config *config.ShouqianbaConfig
@@ -26,7 +36,7 @@ type ShouqianbaClient struct {
 // PrePayRequest 预支付请求
 type PrePayRequest struct {
 	OrderNo     string  `json:"order_no"`     // 商户订单号
-	Amount      float64 `json:"amount"`       // 支付金额，单位：元
+	Amount      money.Money `json:"amount"`       // 支付金额，单位：元
 	Subject     string  `json:"subject"`      // 支付主题
 	Description string  `json:"description"`  // 支付描述
 	NotifyURL   string  `json:"notify_url"`   // 异步通知地址
@@ -34,6 +44,8 @@ type PrePayRequest struct {
 	ExpireTime  int     `json:"expire_time"`  // 订单过期时间，秒
 	ClientIP    string  `json:"client_ip"`    // 客户端IP
 	Extra       string  `json:"extra"`        // 附加参数
+	OpenID      string  `json:"openid"`       // 付款用户标识，微信JSAPI支付必填，为空时使用扫码支付
+	Scene       string  `json:"scene"`        // 支付场景，支付宝区分电脑网站支付和APP支付
 }
 
 // PrePayResponse 预支付响应
@@ -50,6 +62,7 @@ type PrePayResponseData struct {
 	PayURL     string `json:"pay_url"`     // 支付链接
 	QRCode     string `json:"qrcode"`      // 二维码内容
 	ExpireTime int64  `json:"expire_time"` // 过期时间
+	PayParams  map[string]string `json:"pay_params,omitempty"` // 客户端调起支付的签名参数
 }
 
 // PayStatusRequest 支付状态查询请求
@@ -70,7 +83,7 @@ type PayStatusData struct {
 	OrderNo     string    `json:"order_no"`      // 商户订单号
 	TradeNo     string    `json:"trade_no"`      // 平台交易号
 	Status      string    `json:"status"`        // 支付状态
-	Amount      float64   `json:"amount"`        // 支付金额
+	Amount      money.Money `json:"amount"`        // 支付金额
 	PayTime     time.Time `json:"pay_time"`      // 支付时间
 	PayMethod   string    `json:"pay_method"`    // 支付方式
 	TransactionID string  `json:"transaction_id"` // 第三方交易号
@@ -80,11 +93,18 @@ type PayStatusData struct {
 type RefundRequest struct {
 	OrderNo   string  `json:"order_no"`   // 原订单号
 	RefundNo  string  `json:"refund_no"`  // 退款订单号
-	Amount    float64 `json:"amount"`     // 退款金额
+	Amount    money.Money `json:"amount"`     // 退款金额
+	TotalAmount money.Money `json:"total_amount"` // 原订单金额
 	Reason    string  `json:"reason"`     // 退款原因
 	NotifyURL string  `json:"notify_url"` // 退款通知地址
 }
 
+// RefundQueryRequest 退款查询请求
+type RefundQueryRequest struct {
+	OrderNo  string `json:"order_no"`  // 原订单号
+	RefundNo string `json:"refund_no"` // 退款订单号
+}
+
 // RefundResponse 退款响应
 type RefundResponse struct {
 	Code    string         `json:"code"`
@@ -96,7 +116,7 @@ type RefundResponse struct {
 type RefundData struct {
 	RefundNo   string    `json:"refund_no"`   // 退款订单号
 	OrderNo    string    `json:"order_no"`    // 原订单号
-	Amount     float64   `json:"amount"`      // 退款金额
+	Amount     money.Money `json:"amount"`      // 退款金额
 	Status     string    `json:"status"`      // 退款状态
 	RefundTime time.Time `json:"refund_time"` // 退款时间
 }
@@ -105,13 +125,18 @@ type RefundData struct {
 type TransferRequest struct {
 	OrderNo   string  `json:"order_no"`   // 商户订单号
 	AccountNo string  `json:"account_no"`  // 收款账户
-	Amount    float64 `json:"amount"`     // 转账金额
+	Amount    money.Money `json:"amount"`     // 转账金额
 	RealName  string  `json:"real_name"`  // 真实姓名
 	BankCode  string  `json:"bank_code"`  // 银行代码
 	Memo      string  `json:"memo"`       // 转账备注
 	NotifyURL string  `json:"notify_url"` // 异步通知地址
 }
 
+// TransferQueryRequest 转账查询请求
+type TransferQueryRequest struct {
+	OrderNo string `json:"order_no"` // 商户订单号
+}
+
 // TransferResponse 转账响应
 type TransferResponse struct {
 	Code    string          `json:"code"`
@@ -124,8 +149,9 @@ type TransferData struct {
 	OrderNo     string    `json:"order_no"`     // 商户订单号
 	TransferNo  string    `json:"transfer_no"`  // 平台转账单号
 	Status      string    `json:"status"`       // 转账状态
-	Amount      float64   `json:"amount"`       // 转账金额
+	Amount      money.Money `json:"amount"`       // 转账金额
 	TransferTime time.Time `json:"transfer_time"` // 转账时间
+	FailReason  string    `json:"fail_reason"`   // 失败原因
 }
 
 // NotificationData 回调通知数据
@@ -133,7 +159,7 @@ type NotificationData struct {
 	OrderNo       string    `json:"order_no"`       // 商户订单号
 	TradeNo       string    `json:"trade_no"`       // 平台交易号
 	Status        string    `json:"status"`         // 交易状态
-	Amount        float64   `json:"amount"`         // 交易金额
+	Amount        money.Money `json:"amount"`         // 交易金额
 	PayTime       time.Time `json:"pay_time"`       // 支付时间
 	PayMethod     string    `json:"pay_method"`     // 支付方式
 	TransactionID string    `json:"transaction_id"`  // 第三方交易号
@@ -157,13 +183,16 @@ func (c *ShouqianbaClient) PrePay(req *PrePayRequest) (*PrePayResponseData, erro
 	if req.ExpireTime == 0 {
 		req.ExpireTime = 900 // 15分钟
 	}
+	if req.NotifyURL == "" {
+		req.NotifyURL = c.config.NotifyURL
+	}
 
 	// 构建请求参数
 	params := map[string]string{
 		"appid":       c.config.AppID,
 		"mch_no":      c.config.MerchantNo,
 		"order_no":    req.OrderNo,
-		"amount":      fmt.Sprintf("%.2f", req.Amount),
+		"amount":      req.Amount.String(),
 		"subject":     req.Subject,
 		"description": req.Description,
 		"notify_url":  req.NotifyURL,
@@ -230,14 +259,14 @@ func (c *ShouqianbaClient) QueryPayStatus(req *PayStatusRequest) (*PayStatusData
 	return result.Data, nil
 }
 
-// Refund 退款
+// Refund 退款，渠道拒绝受理时返回失败
 func (c *ShouqianbaClient) Refund(req *RefundRequest) (*RefundData, error) {
 	params := map[string]string{
 		"appid":       c.config.AppID,
 		"mch_no":      c.config.MerchantNo,
 		"order_no":    req.OrderNo,
 		"refund_no":   req.RefundNo,
-		"amount":      fmt.Sprintf("%.2f", req.Amount),
+		"amount":      req.Amount.String(),
 		"reason":      req.Reason,
 		"notify_url":  req.NotifyURL,
 		"timestamp":   strconv.FormatInt(time.Now().Unix(), 10),
@@ -259,21 +288,63 @@ func (c *ShouqianbaClient) Refund(req *RefundRequest) (*RefundData, error) {
 		return nil, fmt.Errorf("解析响应失败: %w", err)
 	}
 
-	if result.Code != "200" {
+	if result.Code != shouqianbaCodeSuccess {
+		// 渠道拒绝受理时退款未发起，按失败处理以便解冻退款金额
+		if shouqianbaRejected(result.Code) {
+			return &RefundData{RefundNo: req.RefundNo, OrderNo: req.OrderNo, Status: PayStatusFailed}, nil
+		}
 		return nil, fmt.Errorf("退款失败: %s", result.Message)
 	}
 
+	result.Data.Status = resultStatus(result.Data.Status)
+	return result.Data, nil
+}
+
+// QueryRefund 查询退款结果，退款单不存在时返回失败
+func (c *ShouqianbaClient) QueryRefund(req *RefundQueryRequest) (*RefundData, error) {
+	params := map[string]string{
+		"appid":     c.config.AppID,
+		"mch_no":    c.config.MerchantNo,
+		"order_no":  req.OrderNo,
+		"refund_no": req.RefundNo,
+		"timestamp": strconv.FormatInt(time.Now().Unix(), 10),
+		"nonce_str": utils.GenerateNonce(32),
+	}
+
+	// 生成签名
+	signature := c.generateSignature(params)
+	params["sign"] = signature
+
+	// 发送请求
+	resp, err := c.postRequest("/api/pay/refund/query", params)
+	if err != nil {
+		return nil, fmt.Errorf("查询退款失败: %w", err)
+	}
+
+	var result RefundResponse
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	if result.Code == shouqianbaCodeNotFound {
+		return &RefundData{RefundNo: req.RefundNo, OrderNo: req.OrderNo, Status: PayStatusFailed}, nil
+	}
+	if result.Code != shouqianbaCodeSuccess {
+		return nil, fmt.Errorf("查询退款失败: %s", result.Message)
+	}
+
+	result.Data.Status = resultStatus(result.Data.Status)
 	return result.Data, nil
 }
 
-// Transfer 转账（用于任务结算）
+// Transfer 转账（用于任务结算），渠道拒绝受理时返回失败
 func (c *ShouqianbaClient) Transfer(req *TransferRequest) (*TransferData, error) {
 	params := map[string]string{
 		"appid":       c.config.AppID,
 		"mch_no":      c.config.MerchantNo,
 		"order_no":    req.OrderNo,
 		"account_no":  req.AccountNo,
-		"amount":      fmt.Sprintf("%.2f", req.Amount),
+		"amount":      req.Amount.String(),
 		"real_name":   req.RealName,
 		"bank_code":   req.BankCode,
 		"memo":        req.Memo,
@@ -297,57 +368,96 @@ func (c *ShouqianbaClient) Transfer(req *TransferRequest) (*TransferData, error)
 		return nil, fmt.Errorf("解析响应失败: %w", err)
 	}
 
-	if result.Code != "200" {
+	if result.Code != shouqianbaCodeSuccess {
+		// 渠道拒绝受理时转账未发起，按失败处理以便解冻提现金额
+		if shouqianbaRejected(result.Code) {
+			return &TransferData{OrderNo: req.OrderNo, Status: PayStatusFailed, FailReason: result.Message}, nil
+		}
 		return nil, fmt.Errorf("转账失败: %s", result.Message)
 	}
 
+	result.Data.Status = resultStatus(result.Data.Status)
+	return result.Data, nil
+}
+
+// QueryTransfer 查询转账结果，转账单不存在时返回失败
+func (c *ShouqianbaClient) QueryTransfer(req *TransferQueryRequest) (*TransferData, error) {
+	params := map[string]string{
+		"appid":     c.config.AppID,
+		"mch_no":    c.config.MerchantNo,
+		"order_no":  req.OrderNo,
+		"timestamp": strconv.FormatInt(time.Now().Unix(), 10),
+		"nonce_str": utils.GenerateNonce(32),
+	}
+
+	// 生成签名
+	signature := c.generateSignature(params)
+	params["sign"] = signature
+
+	// 发送请求
+	resp, err := c.postRequest("/api/pay/transfer/query", params)
+	if err != nil {
+		return nil, fmt.Errorf("查询转账失败: %w", err)
+	}
+
+	var result TransferResponse
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	if result.Code == shouqianbaCodeNotFound {
+		return &TransferData{OrderNo: req.OrderNo, Status: PayStatusFailed, FailReason: "转账单不存在"}, nil
+	}
+	if result.Code != shouqianbaCodeSuccess {
+		return nil, fmt.Errorf("查询转账失败: %s", result.Message)
+	}
+
+	result.Data.Status = resultStatus(result.Data.Status)
 	return result.Data, nil
 }
 
+// Name 渠道标识
+func (c *ShouqianbaClient) Name() string {
+	return ProviderShouqianba
+}
+
 // VerifyNotification 验证回调通知签名
 func (c *ShouqianbaClient) VerifyNotification(data map[string]string) bool {
-	signature := data["signature"]
-	if signature == "" {
-		return false
+	return verifyParams(data, c.config.SecretKey)
+}
+
+// ParseNotification 校验签名并解析表单形式的回调通知
+func (c *ShouqianbaClient) ParseNotification(header http.Header, body []byte) (*NotificationData, error) {
+	data, err := formParams(body)
+	if err != nil {
+		return nil, err
 	}
+	if !c.VerifyNotification(data) {
+		return nil, ErrInvalidSignature
+	}
+	return parseNotificationParams(data)
+}
 
-	// 删除签名参数
-	params := make(map[string]string)
-	for k, v := range data {
-		if k != "signature" {
-			params[k] = v
-		}
+// ParseRefundNotification 校验签名并解析表单形式的退款通知
+func (c *ShouqianbaClient) ParseRefundNotification(header http.Header, body []byte) (*RefundData, error) {
+	data, err := formParams(body)
+	if err != nil {
+		return nil, err
 	}
+	if !c.VerifyNotification(data) {
+		return nil, ErrInvalidSignature
+	}
+	return parseRefundParams(data)
+}
 
-	// 生成签名并对比
-	return c.generateSignature(params) == signature
+// shouqianbaRejected 业务码是否表示渠道明确拒绝受理，未受理的请求可直接按失败处理
+func shouqianbaRejected(code string) bool {
+	return len(code) == 3 && code[0] == '4'
 }
 
 // generateSignature 生成签名
 func (c *ShouqianbaClient) generateSignature(params map[string]string) string {
-	// 按键名排序
-	var keys []string
-	for k := range params {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-
-	// 构建签名字符串
-	var builder strings.Builder
-	for _, key := range keys {
-		if params[key] != "" {
-			builder.WriteString(key)
-			builder.WriteString("=")
-			builder.WriteString(params[key])
-			builder.WriteString("&")
-		}
-	}
-	builder.WriteString("key=")
-	builder.WriteString(c.config.SecretKey)
-
-	// MD5加密并转为大写
-	hash := md5.Sum([]byte(builder.String()))
-	return strings.ToUpper(hex.EncodeToString(hash[:]))
+	return signParams(params, c.config.SecretKey)
 }
 
 // postRequest 发送POST请求
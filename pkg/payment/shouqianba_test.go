@@ -0,0 +1,152 @@
+package payment
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/pkg/money"
+)
+
+const testShouqianbaSecret = "shouqianba-secret"
+
+// newStubShouqianbaClient 启动按路径返回预设报文的收钱吧接口桩服务，校验请求签名
+func newStubShouqianbaClient(t *testing.T, responses map[string]interface{}) *ShouqianbaClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("解析请求失败: %v", err)
+		}
+		params := make(map[string]string)
+		for key := range r.PostForm {
+			if key != "sign" {
+				params[key] = r.PostForm.Get(key)
+			}
+		}
+		if signParams(params, testShouqianbaSecret) != r.PostForm.Get("sign") {
+			t.Errorf("请求签名错误: %s", r.URL)
+		}
+
+		resp, ok := responses[r.URL.Path]
+		if !ok {
+			t.Errorf("未预期的请求: %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	return NewShouqianbaClient(&config.ShouqianbaConfig{
+		AppID:      "sqb-app",
+		MerchantNo: "M001",
+		SecretKey:  testShouqianbaSecret,
+		APIURL:     server.URL,
+	})
+}
+
+func TestShouqianbaTransfer(t *testing.T) {
+	client := newStubShouqianbaClient(t, map[string]interface{}{
+		"/api/pay/transfer": map[string]interface{}{
+			"code": "200",
+			"data": map[string]string{"order_no": "WD001", "transfer_no": "T001", "status": "PROCESSING", "amount": "50.00"},
+		},
+		"/api/pay/transfer/query": map[string]interface{}{
+			"code": "200",
+			"data": map[string]string{"order_no": "WD001", "transfer_no": "T001", "status": "SUCCESS", "amount": "50.00"},
+		},
+	})
+
+	transfer, err := client.Transfer(&TransferRequest{OrderNo: "WD001", AccountNo: "6222000000000000", Amount: money.FromCents(5000)})
+	if err != nil {
+		t.Fatalf("转账失败: %v", err)
+	}
+	if transfer.Status != PayStatusPending || transfer.TransferNo != "T001" {
+		t.Errorf("受理中的转账应返回PENDING: %+v", transfer)
+	}
+
+	transfer, err = client.QueryTransfer(&TransferQueryRequest{OrderNo: "WD001"})
+	if err != nil {
+		t.Fatalf("查询转账失败: %v", err)
+	}
+	if transfer.Status != PayStatusSuccess || transfer.Amount != money.FromCents(5000) {
+		t.Errorf("查询结果错误: %+v", transfer)
+	}
+}
+
+func TestShouqianbaTransferRejected(t *testing.T) {
+	client := newStubShouqianbaClient(t, map[string]interface{}{
+		"/api/pay/transfer":       map[string]string{"code": "400", "message": "收款账户户名不符"},
+		"/api/pay/transfer/query": map[string]string{"code": "404", "message": "订单不存在"},
+	})
+
+	transfer, err := client.Transfer(&TransferRequest{OrderNo: "WD002", AccountNo: "6222000000000000", Amount: money.FromCents(5000)})
+	if err != nil {
+		t.Fatalf("渠道拒绝受理时不应返回错误: %v", err)
+	}
+	if transfer.Status != PayStatusFailed || transfer.OrderNo != "WD002" || transfer.FailReason != "收款账户户名不符" {
+		t.Errorf("渠道拒绝受理的转账应返回失败: %+v", transfer)
+	}
+
+	transfer, err = client.QueryTransfer(&TransferQueryRequest{OrderNo: "WD002"})
+	if err != nil {
+		t.Fatalf("转账单不存在时不应返回错误: %v", err)
+	}
+	if transfer.Status != PayStatusFailed || transfer.OrderNo != "WD002" {
+		t.Errorf("转账单不存在时应返回失败: %+v", transfer)
+	}
+}
+
+func TestShouqianbaTransferUnknownResult(t *testing.T) {
+	client := newStubShouqianbaClient(t, map[string]interface{}{
+		"/api/pay/transfer":       map[string]string{"code": "500", "message": "系统繁忙"},
+		"/api/pay/transfer/query": map[string]string{"code": "500", "message": "系统繁忙"},
+	})
+
+	// 系统异常时转账结果未知，须返回错误由调用方保持处理中
+	if _, err := client.Transfer(&TransferRequest{OrderNo: "WD003", Amount: money.FromCents(100)}); err == nil {
+		t.Error("渠道系统异常时转账应返回错误")
+	}
+	if _, err := client.QueryTransfer(&TransferQueryRequest{OrderNo: "WD003"}); err == nil {
+		t.Error("渠道系统异常时查询转账应返回错误")
+	}
+}
+
+func TestShouqianbaRefundRejected(t *testing.T) {
+	client := newStubShouqianbaClient(t, map[string]interface{}{
+		"/api/pay/refund":       map[string]string{"code": "400", "message": "退款金额超过可退金额"},
+		"/api/pay/refund/query": map[string]string{"code": "404", "message": "退款单不存在"},
+	})
+
+	refund, err := client.Refund(&RefundRequest{OrderNo: "T001", RefundNo: "R001", Amount: money.FromCents(100)})
+	if err != nil {
+		t.Fatalf("渠道拒绝受理时不应返回错误: %v", err)
+	}
+	if refund.Status != PayStatusFailed || refund.RefundNo != "R001" || refund.OrderNo != "T001" {
+		t.Errorf("渠道拒绝受理的退款应返回失败: %+v", refund)
+	}
+
+	refund, err = client.QueryRefund(&RefundQueryRequest{OrderNo: "T001", RefundNo: "R001"})
+	if err != nil {
+		t.Fatalf("退款单不存在时不应返回错误: %v", err)
+	}
+	if refund.Status != PayStatusFailed || refund.RefundNo != "R001" {
+		t.Errorf("退款单不存在时应返回失败: %+v", refund)
+	}
+}
+
+func TestShouqianbaRefundUnknownResult(t *testing.T) {
+	client := newStubShouqianbaClient(t, map[string]interface{}{
+		"/api/pay/refund":       map[string]string{"code": "500", "message": "系统繁忙"},
+		"/api/pay/refund/query": map[string]string{"code": "500", "message": "系统繁忙"},
+	})
+
+	if _, err := client.Refund(&RefundRequest{OrderNo: "T002", RefundNo: "R002", Amount: money.FromCents(100)}); err == nil {
+		t.Error("渠道系统异常时退款应返回错误")
+	}
+	if _, err := client.QueryRefund(&RefundQueryRequest{OrderNo: "T002", RefundNo: "R002"}); err == nil {
+		t.Error("渠道系统异常时查询退款应返回错误")
+	}
+}
@@ -0,0 +1,190 @@
+package payment
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-platform-api/pkg/money"
+	"task-platform-api/pkg/utils"
+)
+
+// statementTimeLayout 对账单中交易时间的格式
+const statementTimeLayout = "2006-01-02 15:04:05"
+
+// StatementEntry 渠道对账单中的一笔支付
+type StatementEntry struct {
+	OrderNo   string      `json:"order_no"`   // 商户订单号
+	TradeNo   string      `json:"trade_no"`   // 平台交易号
+	Amount    money.Money `json:"amount"`     // 支付金额
+	Status    string      `json:"status"`     // 支付状态，归一为SUCCESS、PENDING或FAILED
+	TradeTime time.Time   `json:"trade_time"` // 交易时间
+}
+
+// StatementDownloader 支持按日下载对账单的渠道
+type StatementDownloader interface {
+	// DownloadStatement 下载指定日期的支付对账单
+	DownloadStatement(date time.Time) ([]StatementEntry, error)
+}
+
+var (
+	_ StatementDownloader = (*ShouqianbaClient)(nil)
+	_ StatementDownloader = (*FakeGateway)(nil)
+)
+
+// statementColumns 对账单CSV表头别名，兼容渠道导出的中文表头
+var statementColumns = map[string][]string{
+	"order_no":   {"order_no", "商户订单号"},
+	"trade_no":   {"trade_no", "平台交易号", "收钱吧订单号"},
+	"amount":     {"amount", "交易金额", "金额"},
+	"status":     {"status", "交易状态", "状态"},
+	"trade_time": {"trade_time", "交易时间"},
+}
+
+// ParseStatementCSV 解析渠道导出的CSV对账单，首行为表头，须包含交易金额列及商户订单号或平台交易号列
+func ParseStatementCSV(r io.Reader) ([]StatementEntry, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("读取对账单表头失败: %w", err)
+	}
+	index := make(map[string]int)
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		for column, aliases := range statementColumns {
+			for _, alias := range aliases {
+				if strings.EqualFold(name, alias) {
+					index[column] = i
+				}
+			}
+		}
+	}
+	_, hasOrderNo := index["order_no"]
+	_, hasTradeNo := index["trade_no"]
+	if !hasOrderNo && !hasTradeNo {
+		return nil, fmt.Errorf("对账单缺少商户订单号或平台交易号列")
+	}
+	if _, ok := index["amount"]; !ok {
+		return nil, fmt.Errorf("对账单缺少交易金额列")
+	}
+
+	field := func(record []string, column string) string {
+		i, ok := index[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var entries []StatementEntry
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取对账单第%d行失败: %w", line, err)
+		}
+
+		entry := StatementEntry{
+			OrderNo: field(record, "order_no"),
+			TradeNo: field(record, "trade_no"),
+			Status:  PayStatusSuccess,
+		}
+		if entry.OrderNo == "" && entry.TradeNo == "" {
+			continue
+		}
+		entry.Amount, err = money.Parse(field(record, "amount"))
+		if err != nil {
+			return nil, fmt.Errorf("对账单第%d行金额格式错误: %w", line, err)
+		}
+		if status := field(record, "status"); status != "" {
+			entry.Status = resultStatus(strings.ToUpper(status))
+		}
+		if tradeTime := field(record, "trade_time"); tradeTime != "" {
+			entry.TradeTime, err = time.ParseInLocation(statementTimeLayout, tradeTime, time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("对账单第%d行交易时间格式错误: %w", line, err)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// StatementResponse 对账单下载响应
+type StatementResponse struct {
+	Code    string           `json:"code"`
+	Message string           `json:"message"`
+	Data    []StatementEntry `json:"data"`
+}
+
+// DownloadStatement 下载指定日期的支付对账单
+func (c *ShouqianbaClient) DownloadStatement(date time.Time) ([]StatementEntry, error) {
+	params := map[string]string{
+		"appid":     c.config.AppID,
+		"mch_no":    c.config.MerchantNo,
+		"bill_date": date.Format("2006-01-02"),
+		"timestamp": strconv.FormatInt(time.Now().Unix(), 10),
+		"nonce_str": utils.GenerateNonce(32),
+	}
+
+	// 生成签名
+	signature := c.generateSignature(params)
+	params["sign"] = signature
+
+	// 发送请求
+	resp, err := c.postRequest("/api/pay/statement", params)
+	if err != nil {
+		return nil, fmt.Errorf("下载对账单失败: %w", err)
+	}
+
+	var result StatementResponse
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	if result.Code != "200" {
+		return nil, fmt.Errorf("下载对账单失败: %s", result.Message)
+	}
+
+	for i := range result.Data {
+		result.Data[i].Status = resultStatus(result.Data[i].Status)
+	}
+	return result.Data, nil
+}
+
+// DownloadStatement 返回指定日期内已完成支付的模拟订单
+func (g *FakeGateway) DownloadStatement(date time.Time) ([]StatementEntry, error) {
+	if err := g.simulate(); err != nil {
+		return nil, err
+	}
+
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	end := start.AddDate(0, 0, 1)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var entries []StatementEntry
+	for _, order := range g.orders {
+		s := order.status
+		if s.Status != PayStatusSuccess || s.PayTime.Before(start) || !s.PayTime.Before(end) {
+			continue
+		}
+		entries = append(entries, StatementEntry{
+			OrderNo:   s.OrderNo,
+			TradeNo:   s.TradeNo,
+			Amount:    s.Amount,
+			Status:    s.Status,
+			TradeTime: s.PayTime,
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,720 @@
+package payment
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/pkg/money"
+	"task-platform-api/pkg/utils"
+)
+
+const (
+	wechatDefaultBaseURL = "https://api.mch.weixin.qq.com"
+	wechatAuthSchema     = "WECHATPAY2-SHA256-RSA2048"
+	wechatMaxClockSkew   = 5 * time.Minute
+)
+
+// 微信支付交易状态
+const (
+	wechatTradeSuccess    = "SUCCESS"
+	wechatTradeRefund     = "REFUND"
+	wechatTradeNotPay     = "NOTPAY"
+	wechatTradeClosed     = "CLOSED"
+	wechatTradeRevoked    = "REVOKED"
+	wechatTradeUserPaying = "USERPAYING"
+	wechatTradePayError   = "PAYERROR"
+)
+
+// WechatPayClient 微信支付v3客户端，支持JSAPI和Native下单
+type WechatPayClient struct {
+	config      *config.WechatConfig
+	client      *http.Client
+	privateKey  *rsa.PrivateKey
+	platformKey *rsa.PublicKey
+}
+
+// wechatAmount 微信支付金额，单位为分
+type wechatAmount struct {
+	Total      int64  `json:"total"`
+	PayerTotal int64  `json:"payer_total,omitempty"`
+	Currency   string `json:"currency,omitempty"`
+}
+
+// wechatPrepayRequest 下单请求
+type wechatPrepayRequest struct {
+	AppID       string           `json:"appid"`
+	MchID       string           `json:"mchid"`
+	Description string           `json:"description"`
+	OutTradeNo  string           `json:"out_trade_no"`
+	TimeExpire  string           `json:"time_expire,omitempty"`
+	Attach      string           `json:"attach,omitempty"`
+	NotifyURL   string           `json:"notify_url"`
+	Amount      wechatAmount     `json:"amount"`
+	Payer       *wechatPayer     `json:"payer,omitempty"`
+	SceneInfo   *wechatSceneInfo `json:"scene_info,omitempty"`
+}
+
+// wechatPayer 支付者
+type wechatPayer struct {
+	OpenID string `json:"openid"`
+}
+
+// wechatSceneInfo 支付场景
+type wechatSceneInfo struct {
+	PayerClientIP string `json:"payer_client_ip"`
+}
+
+// wechatPrepayResponse 下单响应，JSAPI返回prepay_id，Native返回code_url
+type wechatPrepayResponse struct {
+	PrepayID string `json:"prepay_id"`
+	CodeURL  string `json:"code_url"`
+}
+
+// wechatTransaction 订单查询结果及支付通知解密后的内容
+type wechatTransaction struct {
+	OutTradeNo    string       `json:"out_trade_no"`
+	TransactionID string       `json:"transaction_id"`
+	TradeType     string       `json:"trade_type"`
+	TradeState    string       `json:"trade_state"`
+	SuccessTime   string       `json:"success_time"`
+	Attach        string       `json:"attach"`
+	Amount        wechatAmount `json:"amount"`
+}
+
+// wechatRefundRequest 退款请求
+type wechatRefundRequest struct {
+	OutTradeNo  string             `json:"out_trade_no"`
+	OutRefundNo string             `json:"out_refund_no"`
+	Reason      string             `json:"reason,omitempty"`
+	NotifyURL   string             `json:"notify_url,omitempty"`
+	Amount      wechatRefundAmount `json:"amount"`
+}
+
+// wechatRefundAmount 退款金额，单位为分
+type wechatRefundAmount struct {
+	Refund   int64  `json:"refund"`
+	Total    int64  `json:"total"`
+	Currency string `json:"currency"`
+}
+
+// wechatRefundResponse 退款响应
+type wechatRefundResponse struct {
+	RefundID     string             `json:"refund_id"`
+	OutRefundNo  string             `json:"out_refund_no"`
+	OutTradeNo   string             `json:"out_trade_no"`
+	Status       string             `json:"status"`
+	RefundStatus string             `json:"refund_status"`
+	SuccessTime  string             `json:"success_time"`
+	Amount       wechatRefundAmount `json:"amount"`
+}
+
+// wechatTransferRequest 商家转账请求，每批次只转一笔
+type wechatTransferRequest struct {
+	AppID              string                 `json:"appid"`
+	OutBatchNo         string                 `json:"out_batch_no"`
+	BatchName          string                 `json:"batch_name"`
+	BatchRemark        string                 `json:"batch_remark"`
+	TotalAmount        int64                  `json:"total_amount"`
+	TotalNum           int                    `json:"total_num"`
+	TransferDetailList []wechatTransferDetail `json:"transfer_detail_list"`
+}
+
+// wechatTransferDetail 转账明细
+type wechatTransferDetail struct {
+	OutDetailNo    string `json:"out_detail_no"`
+	TransferAmount int64  `json:"transfer_amount"`
+	TransferRemark string `json:"transfer_remark"`
+	OpenID         string `json:"openid"`
+	UserName       string `json:"user_name,omitempty"`
+}
+
+// wechatTransferResponse 商家转账响应
+type wechatTransferResponse struct {
+	OutBatchNo  string `json:"out_batch_no"`
+	BatchID     string `json:"batch_id"`
+	CreateTime  string `json:"create_time"`
+	BatchStatus string `json:"batch_status"`
+}
+
+// wechatTransferBatchResponse 转账批次查询响应
+type wechatTransferBatchResponse struct {
+	TransferBatch struct {
+		OutBatchNo  string `json:"out_batch_no"`
+		BatchID     string `json:"batch_id"`
+		BatchStatus string `json:"batch_status"`
+		CloseReason string `json:"close_reason"`
+	} `json:"transfer_batch"`
+}
+
+// wechatTransferDetailResponse 转账明细查询响应
+type wechatTransferDetailResponse struct {
+	OutDetailNo    string `json:"out_detail_no"`
+	DetailStatus   string `json:"detail_status"`
+	TransferAmount int64  `json:"transfer_amount"`
+	FailReason     string `json:"fail_reason"`
+	UpdateTime     string `json:"update_time"`
+}
+
+// wechatNotification 回调通知报文
+type wechatNotification struct {
+	ID           string         `json:"id"`
+	CreateTime   string         `json:"create_time"`
+	EventType    string         `json:"event_type"`
+	ResourceType string         `json:"resource_type"`
+	Resource     wechatResource `json:"resource"`
+}
+
+// wechatResource 回调通知的加密数据
+type wechatResource struct {
+	Algorithm      string `json:"algorithm"`
+	Ciphertext     string `json:"ciphertext"`
+	AssociatedData string `json:"associated_data"`
+	Nonce          string `json:"nonce"`
+}
+
+// wechatError 接口错误响应
+type wechatError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error 实现error接口
+func (e *wechatError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// NewWechatPayClient 创建微信支付客户端，加载商户私钥和平台公钥
+func NewWechatPayClient(cfg *config.WechatConfig) (*WechatPayClient, error) {
+	if cfg.MchID == "" || cfg.MchSerialNo == "" {
+		return nil, fmt.Errorf("商户号和商户证书序列号不能为空")
+	}
+	if len(cfg.APIKey) != 32 {
+		return nil, fmt.Errorf("APIv3密钥长度必须为32字节")
+	}
+
+	privateKey, err := loadRSAPrivateKey(cfg.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("加载商户私钥失败: %w", err)
+	}
+	platformKey, err := loadRSAPublicKey(cfg.PlatformPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("加载平台公钥失败: %w", err)
+	}
+
+	return &WechatPayClient{
+		config:      cfg,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		privateKey:  privateKey,
+		platformKey: platformKey,
+	}, nil
+}
+
+// Name 渠道标识
+func (c *WechatPayClient) Name() string {
+	return ProviderWechat
+}
+
+// PrePay 下单，传入OpenID时使用JSAPI支付并返回调起支付参数，否则使用Native扫码支付
+func (c *WechatPayClient) PrePay(req *PrePayRequest) (*PrePayResponseData, error) {
+	if req.ExpireTime == 0 {
+		req.ExpireTime = 900
+	}
+	if req.NotifyURL == "" {
+		req.NotifyURL = c.config.PayNotifyURL
+	}
+	expireAt := time.Now().Add(time.Duration(req.ExpireTime) * time.Second)
+
+	description := req.Subject
+	if description == "" {
+		description = req.Description
+	}
+	body := &wechatPrepayRequest{
+		AppID:       c.config.AppID,
+		MchID:       c.config.MchID,
+		Description: description,
+		OutTradeNo:  req.OrderNo,
+		TimeExpire:  expireAt.Format(time.RFC3339),
+		Attach:      req.Extra,
+		NotifyURL:   req.NotifyURL,
+		Amount:      wechatAmount{Total: req.Amount.Cents(), Currency: "CNY"},
+	}
+	if req.ClientIP != "" {
+		body.SceneInfo = &wechatSceneInfo{PayerClientIP: req.ClientIP}
+	}
+
+	path := "/v3/pay/transactions/native"
+	if req.OpenID != "" {
+		path = "/v3/pay/transactions/jsapi"
+		body.Payer = &wechatPayer{OpenID: req.OpenID}
+	}
+
+	var resp wechatPrepayResponse
+	if err := c.do(http.MethodPost, path, body, &resp); err != nil {
+		return nil, fmt.Errorf("预支付请求失败: %w", err)
+	}
+
+	data := &PrePayResponseData{
+		OrderNo:    req.OrderNo,
+		PayURL:     resp.CodeURL,
+		QRCode:     resp.CodeURL,
+		ExpireTime: expireAt.Unix(),
+	}
+	if resp.PrepayID != "" {
+		params, err := c.jsapiPayParams(resp.PrepayID)
+		if err != nil {
+			return nil, err
+		}
+		data.PayParams = params
+	}
+	return data, nil
+}
+
+// QueryPayStatus 按商户订单号查询订单
+func (c *WechatPayClient) QueryPayStatus(req *PayStatusRequest) (*PayStatusData, error) {
+	path := "/v3/pay/transactions/out-trade-no/" + url.PathEscape(req.OrderNo) + "?mchid=" + url.QueryEscape(c.config.MchID)
+
+	var tx wechatTransaction
+	if err := c.do(http.MethodGet, path, nil, &tx); err != nil {
+		return nil, fmt.Errorf("查询支付状态失败: %w", err)
+	}
+	return tx.payStatus()
+}
+
+// Refund 申请退款，微信要求同时提供原订单金额
+func (c *WechatPayClient) Refund(req *RefundRequest) (*RefundData, error) {
+	total := req.TotalAmount
+	if total.IsZero() {
+		total = req.Amount
+	}
+	body := &wechatRefundRequest{
+		OutTradeNo:  req.OrderNo,
+		OutRefundNo: req.RefundNo,
+		Reason:      req.Reason,
+		NotifyURL:   req.NotifyURL,
+		Amount: wechatRefundAmount{
+			Refund:   req.Amount.Cents(),
+			Total:    total.Cents(),
+			Currency: "CNY",
+		},
+	}
+
+	var resp wechatRefundResponse
+	if err := c.do(http.MethodPost, "/v3/refund/domestic/refunds", body, &resp); err != nil {
+		return nil, fmt.Errorf("退款请求失败: %w", err)
+	}
+	return resp.refundData(), nil
+}
+
+// QueryRefund 按商户退款单号查询退款，退款单不存在时返回失败
+func (c *WechatPayClient) QueryRefund(req *RefundQueryRequest) (*RefundData, error) {
+	var resp wechatRefundResponse
+	err := c.do(http.MethodGet, "/v3/refund/domestic/refunds/"+url.PathEscape(req.RefundNo), nil, &resp)
+	if err != nil {
+		var apiErr *wechatError
+		if errors.As(err, &apiErr) && apiErr.Code == "RESOURCE_NOT_EXISTS" {
+			return &RefundData{RefundNo: req.RefundNo, OrderNo: req.OrderNo, Status: PayStatusFailed}, nil
+		}
+		return nil, fmt.Errorf("查询退款失败: %w", err)
+	}
+	return resp.refundData(), nil
+}
+
+// ParseRefundNotification 验证回调签名并解密退款结果通知
+func (c *WechatPayClient) ParseRefundNotification(header http.Header, body []byte) (*RefundData, error) {
+	if err := c.verifySignature(header, body); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	var notification wechatNotification
+	if err := json.Unmarshal(body, &notification); err != nil {
+		return nil, fmt.Errorf("解析通知报文失败: %w", err)
+	}
+	if !strings.HasPrefix(notification.EventType, "REFUND.") {
+		return nil, fmt.Errorf("不支持的通知类型: %s", notification.EventType)
+	}
+
+	plaintext, err := c.decryptResource(&notification.Resource)
+	if err != nil {
+		return nil, err
+	}
+	var resp wechatRefundResponse
+	if err := json.Unmarshal(plaintext, &resp); err != nil {
+		return nil, fmt.Errorf("解析通知数据失败: %w", err)
+	}
+	return resp.refundData(), nil
+}
+
+// refundData 将微信退款状态转换为统一的退款数据，查询接口返回status，通知返回refund_status
+func (r *wechatRefundResponse) refundData() *RefundData {
+	refund := &RefundData{
+		RefundNo: r.OutRefundNo,
+		OrderNo:  r.OutTradeNo,
+		Amount:   money.FromCents(r.Amount.Refund),
+	}
+	status := r.Status
+	if status == "" {
+		status = r.RefundStatus
+	}
+	// ABNORMAL为退款异常（如用户账户冻结），需在商户平台人工处理后才有结果，
+	// 与未知状态一样按处理中返回，退款资金保持冻结，不能按失败解冻
+	switch status {
+	case "SUCCESS":
+		refund.Status = PayStatusSuccess
+	case "CLOSED":
+		refund.Status = PayStatusFailed
+	default:
+		refund.Status = PayStatusPending
+	}
+	if r.SuccessTime != "" {
+		refund.RefundTime, _ = time.Parse(time.RFC3339, r.SuccessTime)
+	}
+	return refund
+}
+
+// Transfer 商家转账到零钱，收款账户为用户OpenID，姓名使用平台公钥加密
+func (c *WechatPayClient) Transfer(req *TransferRequest) (*TransferData, error) {
+	detail := wechatTransferDetail{
+		OutDetailNo:    req.OrderNo,
+		TransferAmount: req.Amount.Cents(),
+		TransferRemark: req.Memo,
+		OpenID:         req.AccountNo,
+	}
+	if req.RealName != "" {
+		encrypted, err := c.encryptSensitive(req.RealName)
+		if err != nil {
+			return nil, err
+		}
+		detail.UserName = encrypted
+	}
+	body := &wechatTransferRequest{
+		AppID:              c.config.AppID,
+		OutBatchNo:         req.OrderNo,
+		BatchName:          req.Memo,
+		BatchRemark:        req.Memo,
+		TotalAmount:        req.Amount.Cents(),
+		TotalNum:           1,
+		TransferDetailList: []wechatTransferDetail{detail},
+	}
+
+	var resp wechatTransferResponse
+	if err := c.do(http.MethodPost, "/v3/transfer/batches", body, &resp); err != nil {
+		return nil, fmt.Errorf("转账请求失败: %w", err)
+	}
+
+	transfer := &TransferData{
+		OrderNo:    resp.OutBatchNo,
+		TransferNo: resp.BatchID,
+		Amount:     req.Amount,
+	}
+	// 批次受理后明细仍可能失败，最终结果须通过QueryTransfer查询明细确认
+	transfer.Status = PayStatusPending
+	if resp.BatchStatus == "CLOSED" {
+		transfer.Status = PayStatusFailed
+	}
+	if resp.CreateTime != "" {
+		transfer.TransferTime, _ = time.Parse(time.RFC3339, resp.CreateTime)
+	}
+	return transfer, nil
+}
+
+// QueryTransfer 查询转账结果，批次未受理时返回失败，批次完成后以明细状态为准
+func (c *WechatPayClient) QueryTransfer(req *TransferQueryRequest) (*TransferData, error) {
+	batchNo := url.PathEscape(req.OrderNo)
+	var batch wechatTransferBatchResponse
+	err := c.do(http.MethodGet, "/v3/transfer/batches/out-batch-no/"+batchNo+"?need_query_detail=false", nil, &batch)
+	if err != nil {
+		var apiErr *wechatError
+		if errors.As(err, &apiErr) && (apiErr.Code == "NOT_FOUND" || apiErr.Code == "RESOURCE_NOT_EXISTS") {
+			return &TransferData{OrderNo: req.OrderNo, Status: PayStatusFailed, FailReason: "转账批次不存在"}, nil
+		}
+		return nil, fmt.Errorf("查询转账批次失败: %w", err)
+	}
+
+	transfer := &TransferData{
+		OrderNo:    req.OrderNo,
+		TransferNo: batch.TransferBatch.BatchID,
+		Status:     PayStatusPending,
+	}
+	switch batch.TransferBatch.BatchStatus {
+	case "CLOSED":
+		transfer.Status = PayStatusFailed
+		transfer.FailReason = batch.TransferBatch.CloseReason
+		return transfer, nil
+	case "FINISHED":
+	default:
+		return transfer, nil
+	}
+
+	// 每批次只有一笔明细，明细单号与批次单号相同
+	var detail wechatTransferDetailResponse
+	if err := c.do(http.MethodGet, "/v3/transfer/batches/out-batch-no/"+batchNo+"/details/out-detail-no/"+batchNo, nil, &detail); err != nil {
+		return nil, fmt.Errorf("查询转账明细失败: %w", err)
+	}
+	transfer.Amount = money.FromCents(detail.TransferAmount)
+	switch detail.DetailStatus {
+	case "SUCCESS":
+		transfer.Status = PayStatusSuccess
+	case "FAIL":
+		transfer.Status = PayStatusFailed
+		transfer.FailReason = detail.FailReason
+	}
+	if detail.UpdateTime != "" {
+		transfer.TransferTime, _ = time.Parse(time.RFC3339, detail.UpdateTime)
+	}
+	return transfer, nil
+}
+
+// ParseNotification 验证回调签名并用APIv3密钥解密支付通知
+func (c *WechatPayClient) ParseNotification(header http.Header, body []byte) (*NotificationData, error) {
+	if err := c.verifySignature(header, body); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	var notification wechatNotification
+	if err := json.Unmarshal(body, &notification); err != nil {
+		return nil, fmt.Errorf("解析通知报文失败: %w", err)
+	}
+	if !strings.HasPrefix(notification.EventType, "TRANSACTION.") {
+		return nil, fmt.Errorf("不支持的通知类型: %s", notification.EventType)
+	}
+
+	plaintext, err := c.decryptResource(&notification.Resource)
+	if err != nil {
+		return nil, err
+	}
+	var tx wechatTransaction
+	if err := json.Unmarshal(plaintext, &tx); err != nil {
+		return nil, fmt.Errorf("解析通知数据失败: %w", err)
+	}
+	status, err := tx.payStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	return &NotificationData{
+		OrderNo:       status.OrderNo,
+		TradeNo:       status.TradeNo,
+		Status:        status.Status,
+		Amount:        status.Amount,
+		PayTime:       status.PayTime,
+		PayMethod:     status.PayMethod,
+		TransactionID: status.TransactionID,
+		Extra:         tx.Attach,
+	}, nil
+}
+
+// payStatus 将微信订单状态转换为统一的支付状态
+func (tx *wechatTransaction) payStatus() (*PayStatusData, error) {
+	status := &PayStatusData{
+		OrderNo:       tx.OutTradeNo,
+		TradeNo:       tx.TransactionID,
+		Amount:        money.FromCents(tx.Amount.Total),
+		PayMethod:     tx.TradeType,
+		TransactionID: tx.TransactionID,
+	}
+	switch tx.TradeState {
+	case wechatTradeSuccess, wechatTradeRefund:
+		status.Status = PayStatusSuccess
+	case wechatTradeClosed, wechatTradeRevoked:
+		status.Status = PayStatusClosed
+	case wechatTradePayError:
+		status.Status = PayStatusFailed
+	case wechatTradeNotPay, wechatTradeUserPaying:
+		status.Status = PayStatusPending
+	default:
+		return nil, fmt.Errorf("未知的交易状态: %s", tx.TradeState)
+	}
+	if tx.SuccessTime != "" {
+		payTime, err := time.Parse(time.RFC3339, tx.SuccessTime)
+		if err != nil {
+			return nil, fmt.Errorf("支付时间格式错误: %w", err)
+		}
+		status.PayTime = payTime
+	}
+	return status, nil
+}
+
+// jsapiPayParams 生成JSAPI调起支付的签名参数
+func (c *WechatPayClient) jsapiPayParams(prepayID string) (map[string]string, error) {
+	params := map[string]string{
+		"appId":     c.config.AppID,
+		"timeStamp": strconv.FormatInt(time.Now().Unix(), 10),
+		"nonceStr":  utils.GenerateNonce(32),
+		"package":   "prepay_id=" + prepayID,
+		"signType":  "RSA",
+	}
+	message := params["appId"] + "\n" + params["timeStamp"] + "\n" + params["nonceStr"] + "\n" + params["package"] + "\n"
+	signature, err := c.sign(message)
+	if err != nil {
+		return nil, err
+	}
+	params["paySign"] = signature
+	return params, nil
+}
+
+// do 发送签名请求，校验响应签名后解析响应报文
+func (c *WechatPayClient) do(method, path string, reqBody interface{}, out interface{}) error {
+	var payload []byte
+	if reqBody != nil {
+		var err error
+		if payload, err = json.Marshal(reqBody); err != nil {
+			return fmt.Errorf("序列化请求失败: %w", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, c.baseURL()+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	authorization, err := c.authorization(method, path, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if c.config.PlatformSerialNo != "" {
+		req.Header.Set("Wechatpay-Serial", c.config.PlatformSerialNo)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &wechatError{}
+		if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Code == "" {
+			return fmt.Errorf("HTTP %d: %s", resp.StatusCode, body)
+		}
+		return apiErr
+	}
+	if err := c.verifySignature(resp.Header, body); err != nil {
+		return fmt.Errorf("响应签名验证失败: %w", err)
+	}
+
+	if out != nil && len(body) > 0 {
+		if err := json.Unmarshal(body, out); err != nil {
+			return fmt.Errorf("解析响应失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// authorization 生成请求签名的Authorization头
+func (c *WechatPayClient) authorization(method, path string, body []byte) (string, error) {
+	nonce := utils.GenerateNonce(32)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	message := method + "\n" + path + "\n" + timestamp + "\n" + nonce + "\n" + string(body) + "\n"
+
+	signature, err := c.sign(message)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`%s mchid="%s",nonce_str="%s",signature="%s",timestamp="%s",serial_no="%s"`,
+		wechatAuthSchema, c.config.MchID, nonce, signature, timestamp, c.config.MchSerialNo), nil
+}
+
+// sign 使用商户私钥进行SHA256-RSA签名
+func (c *WechatPayClient) sign(message string) (string, error) {
+	hashed := sha256.Sum256([]byte(message))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, c.privateKey, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", fmt.Errorf("签名失败: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// verifySignature 使用平台公钥验证应答或回调的签名，并拒绝时间偏差过大的报文
+func (c *WechatPayClient) verifySignature(header http.Header, body []byte) error {
+	timestamp := header.Get("Wechatpay-Timestamp")
+	nonce := header.Get("Wechatpay-Nonce")
+	signature := header.Get("Wechatpay-Signature")
+	serial := header.Get("Wechatpay-Serial")
+	if timestamp == "" || nonce == "" || signature == "" {
+		return fmt.Errorf("缺少签名信息")
+	}
+	if c.config.PlatformSerialNo != "" && serial != c.config.PlatformSerialNo {
+		return fmt.Errorf("平台证书序列号不匹配: %s", serial)
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("时间戳格式错误: %w", err)
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > wechatMaxClockSkew || skew < -wechatMaxClockSkew {
+		return fmt.Errorf("签名时间戳已过期")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("签名格式错误: %w", err)
+	}
+	message := timestamp + "\n" + nonce + "\n" + string(body) + "\n"
+	hashed := sha256.Sum256([]byte(message))
+	return rsa.VerifyPKCS1v15(c.platformKey, crypto.SHA256, hashed[:], decoded)
+}
+
+// decryptResource 使用APIv3密钥以AEAD_AES_256_GCM解密回调数据
+func (c *WechatPayClient) decryptResource(resource *wechatResource) ([]byte, error) {
+	if resource.Algorithm != "AEAD_AES_256_GCM" {
+		return nil, fmt.Errorf("不支持的加密算法: %s", resource.Algorithm)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(resource.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("密文格式错误: %w", err)
+	}
+
+	block, err := aes.NewCipher([]byte(c.config.APIKey))
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, []byte(resource.Nonce), ciphertext, []byte(resource.AssociatedData))
+	if err != nil {
+		return nil, fmt.Errorf("解密通知数据失败: %w", err)
+	}
+	return plaintext, nil
+}
+
+// encryptSensitive 使用平台公钥以RSA-OAEP加密敏感字段
+func (c *WechatPayClient) encryptSensitive(plaintext string) (string, error) {
+	ciphertext, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, c.platformKey, []byte(plaintext), nil)
+	if err != nil {
+		return "", fmt.Errorf("加密敏感信息失败: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// baseURL 支付接口地址，未配置时使用微信支付正式地址
+func (c *WechatPayClient) baseURL() string {
+	if c.config.PayBaseURL != "" {
+		return strings.TrimRight(c.config.PayBaseURL, "/")
+	}
+	return wechatDefaultBaseURL
+}
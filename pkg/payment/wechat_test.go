@@ -0,0 +1,264 @@
+package payment
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"task-platform-api/internal/config"
+	"task-platform-api/pkg/money"
+)
+
+const (
+	testWechatAPIKey       = "0123456789abcdef0123456789abcdef"
+	testWechatPlatformSN   = "PUB_KEY_ID_TEST"
+	testWechatMerchantSN   = "MCH_SERIAL_TEST"
+	testWechatMerchantID   = "1900000001"
+	testWechatAuthPattern  = `^WECHATPAY2-SHA256-RSA2048 mchid="(\w+)",nonce_str="(\w+)",signature="([^"]+)",timestamp="(\d+)",serial_no="(\w+)"$`
+	testWechatNotification = "TRANSACTION.SUCCESS"
+)
+
+// wechatStub 模拟微信支付v3接口，校验商户签名并以平台私钥签名应答
+type wechatStub struct {
+	t           *testing.T
+	merchantKey *rsa.PublicKey
+	platformKey *rsa.PrivateKey
+	handler     func(w http.ResponseWriter, r *http.Request, body []byte) interface{}
+}
+
+func (s *wechatStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if err := verifyMerchantAuthorization(s.merchantKey, r.Method, r.URL.RequestURI(), body, r.Header.Get("Authorization")); err != nil {
+		s.t.Errorf("商户请求签名验证失败: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	payload, _ := json.Marshal(s.handler(w, r, body))
+	signWechatHeader(s.t, s.platformKey, w.Header(), payload, time.Now())
+	w.Write(payload)
+}
+
+// verifyMerchantAuthorization 按微信支付的规则校验请求的Authorization头
+func verifyMerchantAuthorization(key *rsa.PublicKey, method, uri string, body []byte, authorization string) error {
+	matches := regexp.MustCompile(testWechatAuthPattern).FindStringSubmatch(authorization)
+	if matches == nil {
+		return fmt.Errorf("Authorization格式错误: %s", authorization)
+	}
+	if matches[1] != testWechatMerchantID || matches[5] != testWechatMerchantSN {
+		return fmt.Errorf("商户号或证书序列号错误: %s", authorization)
+	}
+	signature, err := base64.StdEncoding.DecodeString(matches[3])
+	if err != nil {
+		return err
+	}
+	message := method + "\n" + uri + "\n" + matches[4] + "\n" + matches[2] + "\n" + string(body) + "\n"
+	hashed := sha256.Sum256([]byte(message))
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature)
+}
+
+// signWechatHeader 以平台私钥对报文签名并写入应答或回调的签名头
+func signWechatHeader(t *testing.T, key *rsa.PrivateKey, header http.Header, body []byte, at time.Time) {
+	t.Helper()
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	nonce := "stubnonce"
+	hashed := sha256.Sum256([]byte(timestamp + "\n" + nonce + "\n" + string(body) + "\n"))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatalf("平台签名失败: %v", err)
+	}
+	header.Set("Wechatpay-Timestamp", timestamp)
+	header.Set("Wechatpay-Nonce", nonce)
+	header.Set("Wechatpay-Signature", base64.StdEncoding.EncodeToString(signature))
+	header.Set("Wechatpay-Serial", testWechatPlatformSN)
+}
+
+// newTestWechatClient 生成商户和平台密钥对，返回指向baseURL的客户端
+func newTestWechatClient(t *testing.T, baseURL string) (*WechatPayClient, *rsa.PrivateKey, *rsa.PrivateKey) {
+	t.Helper()
+	merchantKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("生成商户密钥失败: %v", err)
+	}
+	platformKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("生成平台密钥失败: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&platformKey.PublicKey)
+	if err != nil {
+		t.Fatalf("编码平台公钥失败: %v", err)
+	}
+
+	client, err := NewWechatPayClient(&config.WechatConfig{
+		AppID:             "wx-test-app",
+		MchID:             testWechatMerchantID,
+		APIKey:            testWechatAPIKey,
+		MchSerialNo:       testWechatMerchantSN,
+		PrivateKey:        string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(merchantKey)})),
+		PlatformSerialNo:  testWechatPlatformSN,
+		PlatformPublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+		PayBaseURL:        baseURL,
+		PayNotifyURL:      "https://example.com/notify/wechat",
+	})
+	if err != nil {
+		t.Fatalf("创建微信支付客户端失败: %v", err)
+	}
+	return client, merchantKey, platformKey
+}
+
+// newWechatStubServer 启动微信支付桩服务并创建与之互相签名的客户端
+func newWechatStubServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, body []byte) interface{}) (*WechatPayClient, *wechatStub) {
+	t.Helper()
+	stub := &wechatStub{t: t, handler: handler}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	client, merchantKey, platformKey := newTestWechatClient(t, server.URL)
+	stub.merchantKey = &merchantKey.PublicKey
+	stub.platformKey = platformKey
+	return client, stub
+}
+
+func TestWechatPayPrePayAndQuery(t *testing.T) {
+	client, _ := newWechatStubServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) interface{} {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v3/pay/transactions/native":
+			var req wechatPrepayRequest
+			if err := json.Unmarshal(body, &req); err != nil {
+				t.Errorf("解析下单请求失败: %v", err)
+			}
+			if req.OutTradeNo != "W001" || req.Amount.Total != 2550 || req.MchID != testWechatMerchantID {
+				t.Errorf("下单请求内容错误: %+v", req)
+			}
+			return wechatPrepayResponse{CodeURL: "weixin://wxpay/bizpayurl?pr=test"}
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/pay/transactions/out-trade-no/W001":
+			if r.URL.Query().Get("mchid") != testWechatMerchantID {
+				t.Errorf("查询请求缺少商户号: %s", r.URL.RawQuery)
+			}
+			return wechatTransaction{
+				OutTradeNo:    "W001",
+				TransactionID: "4200000001",
+				TradeType:     "NATIVE",
+				TradeState:    wechatTradeSuccess,
+				SuccessTime:   "2026-01-02T15:04:05+08:00",
+				Amount:        wechatAmount{Total: 2550},
+			}
+		}
+		t.Errorf("未预期的请求: %s %s", r.Method, r.URL)
+		return nil
+	})
+
+	prepay, err := client.PrePay(&PrePayRequest{OrderNo: "W001", Amount: money.FromCents(2550), Subject: "任务预付款"})
+	if err != nil {
+		t.Fatalf("下单失败: %v", err)
+	}
+	if prepay.PayURL != "weixin://wxpay/bizpayurl?pr=test" {
+		t.Errorf("下单返回的支付链接错误: %s", prepay.PayURL)
+	}
+
+	status, err := client.QueryPayStatus(&PayStatusRequest{OrderNo: "W001"})
+	if err != nil {
+		t.Fatalf("查询支付状态失败: %v", err)
+	}
+	if status.Status != PayStatusSuccess || status.Amount != money.FromCents(2550) || status.TradeNo != "4200000001" {
+		t.Errorf("查询结果错误: %+v", status)
+	}
+}
+
+func TestWechatPayRejectsUnsignedResponse(t *testing.T) {
+	client, stub := newWechatStubServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) interface{} {
+		return wechatTransaction{OutTradeNo: "W002", TradeState: wechatTradeSuccess, Amount: wechatAmount{Total: 100}}
+	})
+	// 应答改用其他私钥签名，模拟伪造的平台应答
+	forged, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	stub.platformKey = forged
+
+	_, err = client.QueryPayStatus(&PayStatusRequest{OrderNo: "W002"})
+	if err == nil || !strings.Contains(err.Error(), "响应签名验证失败") {
+		t.Fatalf("伪造签名的应答应被拒绝, 实际: %v", err)
+	}
+}
+
+// encryptedNotification 按微信支付规则加密交易数据并生成回调报文
+func encryptedNotification(t *testing.T, tx wechatTransaction) []byte {
+	t.Helper()
+	plaintext, _ := json.Marshal(tx)
+	block, _ := aes.NewCipher([]byte(testWechatAPIKey))
+	gcm, _ := cipher.NewGCM(block)
+	nonce := "0123456789ab"
+	ciphertext := gcm.Seal(nil, []byte(nonce), plaintext, []byte("transaction"))
+
+	body, _ := json.Marshal(wechatNotification{
+		ID:           "EV-001",
+		EventType:    testWechatNotification,
+		ResourceType: "encrypt-resource",
+		Resource: wechatResource{
+			Algorithm:      "AEAD_AES_256_GCM",
+			Ciphertext:     base64.StdEncoding.EncodeToString(ciphertext),
+			AssociatedData: "transaction",
+			Nonce:          nonce,
+		},
+	})
+	return body
+}
+
+func TestWechatPayParseNotification(t *testing.T) {
+	client, _, platformKey := newTestWechatClient(t, "")
+	body := encryptedNotification(t, wechatTransaction{
+		OutTradeNo:    "W003",
+		TransactionID: "4200000003",
+		TradeType:     "JSAPI",
+		TradeState:    wechatTradeSuccess,
+		SuccessTime:   "2026-01-02T15:04:05+08:00",
+		Attach:        "task=3",
+		Amount:        wechatAmount{Total: 990},
+	})
+
+	header := http.Header{}
+	signWechatHeader(t, platformKey, header, body, time.Now())
+	notification, err := client.ParseNotification(header, body)
+	if err != nil {
+		t.Fatalf("解析回调失败: %v", err)
+	}
+	if notification.OrderNo != "W003" || notification.Status != PayStatusSuccess ||
+		notification.Amount != money.FromCents(990) || notification.Extra != "task=3" {
+		t.Errorf("回调内容错误: %+v", notification)
+	}
+
+	tampered := []byte(strings.Replace(string(body), "EV-001", "EV-002", 1))
+	if _, err := client.ParseNotification(header, tampered); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("篡改报文后应返回ErrInvalidSignature, 实际: %v", err)
+	}
+
+	stale := http.Header{}
+	signWechatHeader(t, platformKey, stale, body, time.Now().Add(-10*time.Minute))
+	if _, err := client.ParseNotification(stale, body); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("过期的回调应返回ErrInvalidSignature, 实际: %v", err)
+	}
+
+	wrongSerial := header.Clone()
+	wrongSerial.Set("Wechatpay-Serial", "OTHER_SERIAL")
+	if _, err := client.ParseNotification(wrongSerial, body); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("平台证书序列号不匹配时应返回ErrInvalidSignature, 实际: %v", err)
+	}
+}
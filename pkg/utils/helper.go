@@ -6,11 +6,12 @@ import (
 	"fmt"
 	mrand "math/rand"
 	"net"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"task-platform-api/pkg/money"
 )
 
 // GenerateOrderNo 生成订单号
@@ -114,40 +115,47 @@ func IsValidPhone(phone string) bool {
 }
 
 // FormatAmount 格式化金额显示
-func FormatAmount(amount float64) string {
-	return fmt.Sprintf("%.2f", amount)
+//
+// Deprecated: 使用money.Money的String方法
+func FormatAmount(amount money.Money) string {
+    return amount.String()
 }
 
-// ParseAmount 解析金额字符串
-func ParseAmount(amountStr string) (float64, error) {
-	amountStr = strings.TrimSpace(amountStr)
-	amountStr = strings.ReplaceAll(amountStr, ",", "")
-	
-	amount, err := strconv.ParseFloat(amountStr, 64)
-	if err != nil {
-		return 0, fmt.Errorf("无效的金额格式: %w", err)
-	}
-	
-	if amount < 0 {
-		return 0, fmt.Errorf("金额不能为负数")
-	}
-	
-	return amount, nil
+// ParseAmount 解析金额字符串，金额不能为负数
+//
+// Deprecated: 使用money.Parse
+func ParseAmount(amountStr string) (money.Money, error) {
+    amount, err := money.Parse(amountStr)
+    if err != nil {
+        return 0, fmt.Errorf("无效的金额格式: %w", err)
+    }
+    
+    if amount.IsNegative() {
+        return 0, fmt.Errorf("金额不能为负数")
+    }
+    
+    return amount, nil
 }
 
-// CalculateServiceFee 计算服务费
-func CalculateServiceFee(amount float64, rate float64) float64 {
-	return amount * rate
+// CalculateServiceFee 计算服务费，四舍五入到分
+//
+// Deprecated: 服务费按规则由FeeEngine计算
+func CalculateServiceFee(amount money.Money, rate float64) money.Money {
+    return amount.MulRate(rate, money.RoundHalfUp)
 }
 
-// CalculateDeposit 计算保证金
-func CalculateDeposit(amount float64, rate float64) float64 {
-	return amount * rate
+// CalculateDeposit 计算保证金，四舍五入到分
+//
+// Deprecated: 使用models.Task的GetDepositAmount方法
+func CalculateDeposit(amount money.Money, rate float64) money.Money {
+    return amount.MulRate(rate, money.RoundHalfUp)
 }
 
 // RoundToMoney 四舍五入到分
-func RoundToMoney(amount float64) float64 {
-	return float64(int64(amount*100+0.5)) / 100
+//
+// Deprecated: 使用money.FromYuan
+func RoundToMoney(amount float64) money.Money {
+    return money.FromYuan(amount)
 }
 
 // IsExpired 检查是否过期
@@ -11,6 +11,7 @@ type Response struct {
     Code      int         `json:"code"`      // 状态码
     Message   string      `json:"message"`   // 消息
     Data      interface{} `json:"data"`      // 数据
+    ErrorCode string      `json:"error_code,omitempty"` // 业务错误码
     Timestamp int64       `json:"timestamp"` // 时间戳
 }
 
@@ -20,6 +21,13 @@ type PageResponse struct {
     Pagination PaginationInfo  `json:"pagination"` // 分页信息
 }
 
+// CursorResponse 游标分页响应结构
+type CursorResponse struct {
+    List       interface{} `json:"list"`        // 数据列表
+    NextCursor uint64      `json:"next_cursor"` // 下一页游标，传入cursor参数获取下一页，0表示没有更多
+    HasMore    bool        `json:"has_more"`    // 是否还有更多数据
+}
+
 // PaginationInfo 分页信息
 type PaginationInfo struct {
     Page       int `json:"page"`        // 当前页码
@@ -50,6 +58,18 @@ func ErrorResponse(c *gin.Context, code int, message string) {
     c.JSON(code, response)
 }
 
+// ErrorCodeResponse 带业务错误码的错误响应
+func ErrorCodeResponse(c *gin.Context, code int, errorCode string, message string) {
+    response := Response{
+        Code:      code,
+        Message:   message,
+        Data:      nil,
+        ErrorCode: errorCode,
+        Timestamp: getCurrentTimestamp(),
+    }
+    c.JSON(code, response)
+}
+
 // SuccessPageResponse 分页成功响应
 func SuccessPageResponse(c *gin.Context, list interface{}, pagination PaginationInfo) {
     data := PageResponse{
@@ -66,6 +86,23 @@ func SuccessPageResponse(c *gin.Context, list interface{}, pagination Pagination
     c.JSON(http.StatusOK, response)
 }
 
+// SuccessCursorResponse 游标分页成功响应
+func SuccessCursorResponse(c *gin.Context, list interface{}, nextCursor uint64) {
+    data := CursorResponse{
+        List:       list,
+        NextCursor: nextCursor,
+        HasMore:    nextCursor > 0,
+    }
+
+    response := Response{
+        Code:      http.StatusOK,
+        Message:   "success",
+        Data:      data,
+        Timestamp: getCurrentTimestamp(),
+    }
+    c.JSON(http.StatusOK, response)
+}
+
 // CreatedResponse 创建成功响应
 func CreatedResponse(c *gin.Context, data interface{}) {
     response := Response{
@@ -0,0 +1,156 @@
+package wechat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"task-platform-api/internal/config"
+)
+
+const defaultOAuthBaseURL = "https://api.weixin.qq.com"
+
+// 登录渠道
+const (
+	ChannelMiniProgram = "miniprogram" // 小程序
+	ChannelOfficial    = "official"    // 公众号网页授权
+)
+
+// OAuthClient 微信登录客户端，支持小程序code2Session和公众号网页授权
+type OAuthClient struct {
+	config *config.WechatConfig
+	client *http.Client
+}
+
+// Session 登录凭证校验结果
+type Session struct {
+	OpenID     string `json:"openid"`
+	UnionID    string `json:"unionid"`
+	SessionKey string `json:"session_key"`
+}
+
+// AccessToken 网页授权令牌
+type AccessToken struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	OpenID       string `json:"openid"`
+	UnionID      string `json:"unionid"`
+	Scope        string `json:"scope"`
+}
+
+// UserInfo 网页授权用户信息
+type UserInfo struct {
+	OpenID     string `json:"openid"`
+	UnionID    string `json:"unionid"`
+	Nickname   string `json:"nickname"`
+	HeadImgURL string `json:"headimgurl"`
+}
+
+// apiError 接口错误码
+type apiError struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// NewOAuthClient 创建微信登录客户端
+func NewOAuthClient(cfg *config.WechatConfig) *OAuthClient {
+	return &OAuthClient{
+		config: cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Code2Session 小程序登录凭证校验
+func (c *OAuthClient) Code2Session(code string) (*Session, error) {
+	if c.config.MiniProgramAppID == "" {
+		return nil, fmt.Errorf("小程序AppID未配置")
+	}
+	query := url.Values{
+		"appid":      {c.config.MiniProgramAppID},
+		"secret":     {c.config.MiniProgramAppSecret},
+		"js_code":    {code},
+		"grant_type": {"authorization_code"},
+	}
+
+	var session Session
+	if err := c.get("/sns/jscode2session", query, &session); err != nil {
+		return nil, fmt.Errorf("小程序登录凭证校验失败: %w", err)
+	}
+	if session.OpenID == "" {
+		return nil, fmt.Errorf("小程序登录凭证校验失败: 未返回openid")
+	}
+	return &session, nil
+}
+
+// OAuth2AccessToken 公众号网页授权code换取令牌
+func (c *OAuthClient) OAuth2AccessToken(code string) (*AccessToken, error) {
+	query := url.Values{
+		"appid":      {c.config.AppID},
+		"secret":     {c.config.AppSecret},
+		"code":       {code},
+		"grant_type": {"authorization_code"},
+	}
+
+	var token AccessToken
+	if err := c.get("/sns/oauth2/access_token", query, &token); err != nil {
+		return nil, fmt.Errorf("网页授权换取令牌失败: %w", err)
+	}
+	if token.OpenID == "" {
+		return nil, fmt.Errorf("网页授权换取令牌失败: 未返回openid")
+	}
+	return &token, nil
+}
+
+// UserInfo 拉取网页授权用户信息，需snsapi_userinfo授权
+func (c *OAuthClient) UserInfo(accessToken, openID string) (*UserInfo, error) {
+	query := url.Values{
+		"access_token": {accessToken},
+		"openid":       {openID},
+		"lang":         {"zh_CN"},
+	}
+
+	var info UserInfo
+	if err := c.get("/sns/userinfo", query, &info); err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	return &info, nil
+}
+
+// get 发送GET请求，errcode非0时返回错误
+func (c *OAuthClient) get(path string, query url.Values, out interface{}) error {
+	resp, err := c.client.Get(c.baseURL() + path + "?" + query.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, body)
+	}
+
+	var apiErr apiError
+	if err := json.Unmarshal(body, &apiErr); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	if apiErr.ErrCode != 0 {
+		return fmt.Errorf("errcode=%d errmsg=%s", apiErr.ErrCode, apiErr.ErrMsg)
+	}
+	return json.Unmarshal(body, out)
+}
+
+// baseURL 开放接口地址，测试时可指向本地桩服务
+func (c *OAuthClient) baseURL() string {
+	if c.config.OAuthBaseURL != "" {
+		return strings.TrimRight(c.config.OAuthBaseURL, "/")
+	}
+	return defaultOAuthBaseURL
+}
@@ -0,0 +1,84 @@
+package wechat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"task-platform-api/internal/config"
+)
+
+// newStubOAuthClient 启动按路径返回预设报文的登录接口桩服务
+func newStubOAuthClient(t *testing.T, responses map[string]interface{}) *OAuthClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, ok := responses[r.URL.Path]
+		if !ok {
+			t.Errorf("未预期的请求: %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	return NewOAuthClient(&config.WechatConfig{
+		AppID:            "wx-official",
+		AppSecret:        "official-secret",
+		MiniProgramAppID: "wx-mini",
+		OAuthBaseURL:     server.URL,
+	})
+}
+
+func TestOAuthClientReturnsUnionID(t *testing.T) {
+	client := newStubOAuthClient(t, map[string]interface{}{
+		"/sns/jscode2session":      map[string]string{"openid": "mini-openid", "unionid": "union-1", "session_key": "key"},
+		"/sns/oauth2/access_token": map[string]string{"access_token": "token", "openid": "official-openid", "unionid": "union-1", "scope": "snsapi_userinfo"},
+		"/sns/userinfo":            map[string]string{"openid": "official-openid", "unionid": "union-1", "nickname": "张三"},
+	})
+
+	session, err := client.Code2Session("mini-code")
+	if err != nil {
+		t.Fatalf("小程序登录失败: %v", err)
+	}
+	token, err := client.OAuth2AccessToken("official-code")
+	if err != nil {
+		t.Fatalf("网页授权失败: %v", err)
+	}
+	info, err := client.UserInfo(token.AccessToken, token.OpenID)
+	if err != nil {
+		t.Fatalf("获取用户信息失败: %v", err)
+	}
+
+	// 同一开放平台下不同渠道的openid不同，unionid相同
+	if session.OpenID == token.OpenID {
+		t.Fatalf("不同渠道的openid应不同: %s", session.OpenID)
+	}
+	if session.UnionID != "union-1" || token.UnionID != "union-1" || info.UnionID != "union-1" {
+		t.Errorf("各渠道应返回相同的unionid: session=%s token=%s info=%s", session.UnionID, token.UnionID, info.UnionID)
+	}
+	if info.Nickname != "张三" {
+		t.Errorf("用户昵称错误: %s", info.Nickname)
+	}
+}
+
+func TestOAuthClientErrors(t *testing.T) {
+	client := newStubOAuthClient(t, map[string]interface{}{
+		"/sns/jscode2session":      map[string]interface{}{"errcode": 40029, "errmsg": "invalid code"},
+		"/sns/oauth2/access_token": map[string]string{"access_token": "token"},
+	})
+
+	if _, err := client.Code2Session("bad-code"); err == nil || !strings.Contains(err.Error(), "errcode=40029") {
+		t.Errorf("errcode非0时应返回错误, 实际: %v", err)
+	}
+	if _, err := client.OAuth2AccessToken("code"); err == nil || !strings.Contains(err.Error(), "未返回openid") {
+		t.Errorf("未返回openid时应返回错误, 实际: %v", err)
+	}
+
+	unconfigured := NewOAuthClient(&config.WechatConfig{})
+	if _, err := unconfigured.Code2Session("code"); err == nil {
+		t.Error("未配置小程序AppID时应返回错误")
+	}
+}
@@ -46,6 +46,22 @@ CREATE TABLE IF NOT EXISTS user_sessions (
     INDEX idx_expire_time (expire_time)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户会话表';
 
+-- 用户登录身份表
+CREATE TABLE IF NOT EXISTS user_auths (
+    auth_id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    user_id BIGINT NOT NULL COMMENT '用户ID',
+    auth_type ENUM('wechat', 'alipay') NOT NULL COMMENT '授权类型',
+    channel VARCHAR(20) NOT NULL COMMENT '登录渠道:miniprogram,official,alipay',
+    openid VARCHAR(128) NOT NULL COMMENT '渠道用户标识',
+    unionid VARCHAR(128) DEFAULT NULL COMMENT '跨渠道用户标识',
+    create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    update_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_channel_openid (channel, openid),
+    INDEX idx_user_id (user_id),
+    INDEX idx_unionid (unionid),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户登录身份表';
+
 -- 用户信誉表
 CREATE TABLE IF NOT EXISTS user_credits (
     credit_id BIGINT PRIMARY KEY AUTO_INCREMENT,
@@ -84,8 +100,12 @@ CREATE TABLE IF NOT EXISTS tasks (
     amount DECIMAL(10,2) NOT NULL COMMENT '任务金额',
     service_fee_ratio DECIMAL(3,2) DEFAULT 0.06 COMMENT '服务费比例',
     deposit_ratio DECIMAL(3,2) DEFAULT 0.10 COMMENT '保证金比例',
+    publisher_fee DECIMAL(10,2) DEFAULT 0 COMMENT '发布者服务费',
+    taker_fee DECIMAL(10,2) DEFAULT 0 COMMENT '接取者服务费',
+    fee_breakdown JSON DEFAULT NULL COMMENT '服务费计算明细,支付预付款时按规则计算',
+    max_revisions INT DEFAULT 3 COMMENT '最大整改轮次',
     deadline TIMESTAMP NOT NULL COMMENT '截止时间',
-    status TINYINT DEFAULT 0 COMMENT '状态:0-草稿,1-待接取,2-进行中,3-待验收,4-已完成,5-已取消',
+    status TINYINT DEFAULT 0 COMMENT '状态:0-草稿,1-待接取,2-进行中,3-待验收,4-已完成,5-已取消,6-已逾期',
     view_count INT DEFAULT 0 COMMENT '浏览次数',
     apply_count INT DEFAULT 0 COMMENT '申请次数',
     category_id BIGINT DEFAULT NULL COMMENT '分类ID',
@@ -98,6 +118,7 @@ CREATE TABLE IF NOT EXISTS tasks (
     INDEX idx_taker_id (taker_id),
     INDEX idx_status (status),
     INDEX idx_deadline (deadline),
+    INDEX idx_status_deadline (status, deadline),
     INDEX idx_category_id (category_id),
     INDEX idx_create_time (create_time),
     FOREIGN KEY (publisher_id) REFERENCES users(user_id) ON DELETE CASCADE,
@@ -105,6 +126,67 @@ CREATE TABLE IF NOT EXISTS tasks (
     FOREIGN KEY (category_id) REFERENCES task_categories(id) ON DELETE SET NULL
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='任务表';
 
+-- 服务费规则表
+CREATE TABLE IF NOT EXISTS fee_rules (
+    rule_id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(100) NOT NULL COMMENT '规则名称',
+    rule_type ENUM('base','promotion') NOT NULL COMMENT '规则类型',
+    category_id BIGINT DEFAULT NULL COMMENT '任务分类ID,为空匹配全部分类',
+    min_level INT DEFAULT 0 COMMENT '发布者最低用户等级,0-不限',
+    min_credit_score DECIMAL(3,1) DEFAULT 0 COMMENT '发布者最低信用评分,0-不限',
+    publisher_rate DECIMAL(5,4) DEFAULT 0 COMMENT '发布者服务费率',
+    taker_rate DECIMAL(5,4) DEFAULT 0 COMMENT '接取者服务费率',
+    min_publisher_fee DECIMAL(10,2) DEFAULT 0 COMMENT '发布者最低服务费',
+    min_taker_fee DECIMAL(10,2) DEFAULT 0 COMMENT '接取者最低服务费',
+    discount DECIMAL(3,2) DEFAULT 1 COMMENT '促销折扣,0.8表示服务费八折',
+    priority INT DEFAULT 0 COMMENT '优先级,越大越优先',
+    start_time TIMESTAMP NULL DEFAULT NULL COMMENT '生效时间',
+    end_time TIMESTAMP NULL DEFAULT NULL COMMENT '失效时间',
+    status TINYINT DEFAULT 1 COMMENT '状态:0-停用,1-启用',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_type_status (rule_type, status),
+    INDEX idx_category_id (category_id),
+    FOREIGN KEY (category_id) REFERENCES task_categories(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='服务费规则表';
+
+-- 优惠券表
+CREATE TABLE IF NOT EXISTS coupons (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    code VARCHAR(32) UNIQUE NOT NULL COMMENT '优惠码',
+    name VARCHAR(100) NOT NULL COMMENT '优惠券名称',
+    coupon_type ENUM('first_task_fee','fee_percent','fixed') NOT NULL COMMENT '优惠券类型',
+    amount DECIMAL(10,2) DEFAULT 0 COMMENT '立减金额,fixed类型使用',
+    fee_discount_rate DECIMAL(3,2) DEFAULT 0 COMMENT '服务费减免比例,fee_percent类型使用,0.5表示减免一半',
+    max_discount DECIMAL(10,2) DEFAULT 0 COMMENT '单次最高优惠金额,0-不限',
+    min_task_amount DECIMAL(10,2) DEFAULT 0 COMMENT '任务赏金门槛,0-不限',
+    stackable TINYINT(1) DEFAULT 0 COMMENT '是否可与其他类型的优惠券叠加',
+    total_limit INT DEFAULT 0 COMMENT '全局可用次数,0-不限',
+    per_user_limit INT DEFAULT 1 COMMENT '每个用户可用次数,0-不限',
+    used_count INT DEFAULT 0 COMMENT '已占用次数,含待支付订单',
+    start_time TIMESTAMP NULL DEFAULT NULL COMMENT '生效时间',
+    end_time TIMESTAMP NULL DEFAULT NULL COMMENT '失效时间',
+    status TINYINT DEFAULT 1 COMMENT '状态:0-停用,1-启用',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='优惠券表';
+
+-- 任务状态变更记录表
+CREATE TABLE IF NOT EXISTS task_status_logs (
+    log_id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    task_id BIGINT NOT NULL COMMENT '任务ID',
+    from_status TINYINT NOT NULL COMMENT '变更前状态',
+    to_status TINYINT NOT NULL COMMENT '变更后状态',
+    event VARCHAR(32) NOT NULL COMMENT '触发事件',
+    operator_id BIGINT DEFAULT 0 COMMENT '操作人ID,0表示系统',
+    reason VARCHAR(500) DEFAULT NULL COMMENT '变更原因',
+    create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_task_id (task_id, create_time),
+    INDEX idx_operator_id (operator_id),
+    FOREIGN KEY (task_id) REFERENCES tasks(task_id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='任务状态变更记录表';
+
 -- 任务阶段表
 CREATE TABLE IF NOT EXISTS task_stages (
     stage_id BIGINT PRIMARY KEY AUTO_INCREMENT,
@@ -129,7 +211,7 @@ CREATE TABLE IF NOT EXISTS task_applications (
     message TEXT DEFAULT NULL COMMENT '申请留言',
     quoted_price DECIMAL(10,2) DEFAULT NULL COMMENT '报价',
     attachments JSON DEFAULT NULL COMMENT '附件',
-    status TINYINT DEFAULT 0 COMMENT '状态:0-待审核,1-已接受,2-已拒绝',
+    status TINYINT DEFAULT 0 COMMENT '状态:0-待审核,1-已接受,2-已拒绝,3-已入围',
     review_note TEXT DEFAULT NULL COMMENT '审核备注',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
@@ -155,6 +237,7 @@ CREATE TABLE IF NOT EXISTS task_deliveries (
     INDEX idx_task_id (task_id),
     INDEX idx_taker_id (taker_id),
     INDEX idx_stage_id (stage_id),
+    INDEX idx_status_create_time (status, create_time),
     FOREIGN KEY (task_id) REFERENCES tasks(task_id) ON DELETE CASCADE,
     FOREIGN KEY (taker_id) REFERENCES users(user_id) ON DELETE CASCADE,
     FOREIGN KEY (stage_id) REFERENCES task_stages(stage_id) ON DELETE CASCADE
@@ -180,15 +263,18 @@ CREATE TABLE IF NOT EXISTS trades (
     trade_id BIGINT PRIMARY KEY AUTO_INCREMENT,
     user_id BIGINT NOT NULL COMMENT '用户ID',
     task_id BIGINT DEFAULT NULL COMMENT '关联任务ID',
-    trade_type ENUM('prepay','settle','refund','penalty') NOT NULL COMMENT '交易类型',
+    trade_type ENUM('prepay','settle','refund','penalty','deposit','deposit_return') NOT NULL COMMENT '交易类型',
     amount DECIMAL(10,2) NOT NULL COMMENT '交易金额',
+    discount DECIMAL(10,2) DEFAULT 0 COMMENT '优惠券抵扣金额,由平台营销费用承担',
     third_party_no VARCHAR(64) DEFAULT NULL COMMENT '第三方交易号',
     internal_no VARCHAR(64) UNIQUE NOT NULL COMMENT '内部交易号',
-    status TINYINT DEFAULT 0 COMMENT '状态:0-待支付,1-已支付,2-已失败,3-已退款',
+    status TINYINT DEFAULT 0 COMMENT '状态:0-待支付,1-已支付,2-已失败,3-已退款,4-已关闭',
     payment_method VARCHAR(20) DEFAULT NULL COMMENT '支付方式',
     description VARCHAR(500) DEFAULT NULL COMMENT '交易描述',
     pay_time TIMESTAMP DEFAULT NULL COMMENT '支付时间',
     expire_time TIMESTAMP DEFAULT NULL COMMENT '过期时间',
+    query_count INT DEFAULT 0 COMMENT '主动查询渠道次数',
+    next_query_time TIMESTAMP DEFAULT NULL COMMENT '下次允许主动查询渠道的时间',
     create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     update_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     INDEX idx_user_id (user_id),
@@ -197,6 +283,8 @@ CREATE TABLE IF NOT EXISTS trades (
     INDEX idx_internal_no (internal_no),
     INDEX idx_trade_type (trade_type),
     INDEX idx_status (status),
+    INDEX idx_status_expire_time (status, expire_time),
+    INDEX idx_pay_time (pay_time),
     FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
     FOREIGN KEY (task_id) REFERENCES tasks(task_id) ON DELETE SET NULL
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='交易表';
@@ -205,6 +293,7 @@ CREATE TABLE IF NOT EXISTS trades (
 CREATE TABLE IF NOT EXISTS settlements (
     settle_id BIGINT PRIMARY KEY AUTO_INCREMENT,
     task_id BIGINT NOT NULL COMMENT '任务ID',
+    stage_id BIGINT DEFAULT NULL COMMENT '阶段ID,为空表示整单结算',
     publisher_amount DECIMAL(10,2) NOT NULL COMMENT '发布方收入',
     taker_amount DECIMAL(10,2) NOT NULL COMMENT '接取方收入',
     platform_fee DECIMAL(10,2) NOT NULL COMMENT '平台费用',
@@ -215,7 +304,9 @@ CREATE TABLE IF NOT EXISTS settlements (
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     INDEX idx_task_id (task_id),
-    FOREIGN KEY (task_id) REFERENCES tasks(task_id) ON DELETE CASCADE
+    UNIQUE INDEX uk_task_stage (task_id, stage_id),
+    FOREIGN KEY (task_id) REFERENCES tasks(task_id) ON DELETE CASCADE,
+    FOREIGN KEY (stage_id) REFERENCES task_stages(stage_id) ON DELETE SET NULL
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='结算表';
 
 -- 退款表
@@ -231,9 +322,30 @@ CREATE TABLE IF NOT EXISTS refunds (
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     INDEX idx_trade_id (trade_id),
     INDEX idx_refund_no (refund_no),
+    INDEX idx_status_created (status, created_at),
     FOREIGN KEY (trade_id) REFERENCES trades(trade_id) ON DELETE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='退款表';
 
+-- 优惠券使用记录表
+CREATE TABLE IF NOT EXISTS coupon_redemptions (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    coupon_id BIGINT NOT NULL COMMENT '优惠券ID',
+    user_id BIGINT NOT NULL COMMENT '用户ID',
+    task_id BIGINT NOT NULL COMMENT '任务ID',
+    trade_id BIGINT NOT NULL COMMENT '预付款交易ID',
+    discount DECIMAL(10,2) NOT NULL COMMENT '优惠金额',
+    status TINYINT DEFAULT 0 COMMENT '状态:0-已占用,1-已使用,2-已释放',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_coupon_user (coupon_id, user_id),
+    INDEX idx_task_id (task_id),
+    INDEX idx_trade_id (trade_id),
+    FOREIGN KEY (coupon_id) REFERENCES coupons(id) ON DELETE RESTRICT,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (task_id) REFERENCES tasks(task_id) ON DELETE CASCADE,
+    FOREIGN KEY (trade_id) REFERENCES trades(trade_id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='优惠券使用记录表';
+
 -- 钱包交易记录表
 CREATE TABLE IF NOT EXISTS wallet_transactions (
     id BIGINT PRIMARY KEY AUTO_INCREMENT,
@@ -250,10 +362,51 @@ CREATE TABLE IF NOT EXISTS wallet_transactions (
     INDEX idx_user_id (user_id),
     INDEX idx_trade_id (trade_id),
     INDEX idx_related (related_id, related_type),
+    INDEX idx_user_created (user_id, created_at),
     FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
     FOREIGN KEY (trade_id) REFERENCES trades(trade_id) ON DELETE SET NULL
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='钱包交易记录表';
 
+-- 账本账户表
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    account_id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    code VARCHAR(64) UNIQUE NOT NULL COMMENT '账户编码',
+    name VARCHAR(100) NOT NULL COMMENT '账户名称',
+    account_type ENUM('asset','liability','revenue','expense') NOT NULL COMMENT '账户类型',
+    user_id BIGINT DEFAULT NULL COMMENT '所属用户ID,平台账户为空',
+    balance DECIMAL(12,2) DEFAULT 0.00 COMMENT '余额(按账户正常方向)',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_user_id (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='账本账户表';
+
+-- 记账凭证表
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    entry_id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    biz_type VARCHAR(32) NOT NULL COMMENT '业务类型',
+    biz_id BIGINT DEFAULT NULL COMMENT '业务ID',
+    amount DECIMAL(12,2) NOT NULL COMMENT '凭证金额(借方合计)',
+    description VARCHAR(500) DEFAULT NULL COMMENT '摘要',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_biz (biz_type, biz_id),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='记账凭证表';
+
+-- 记账分录表
+CREATE TABLE IF NOT EXISTS ledger_postings (
+    posting_id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    entry_id BIGINT NOT NULL COMMENT '凭证ID',
+    account_id BIGINT NOT NULL COMMENT '账户ID',
+    direction ENUM('debit','credit') NOT NULL COMMENT '借贷方向',
+    amount DECIMAL(12,2) NOT NULL COMMENT '金额',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_entry_id (entry_id),
+    INDEX idx_account_id (account_id),
+    FOREIGN KEY (entry_id) REFERENCES ledger_entries(entry_id) ON DELETE RESTRICT,
+    FOREIGN KEY (account_id) REFERENCES ledger_accounts(account_id) ON DELETE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='记账分录表';
+
 -- 提现申请表
 CREATE TABLE IF NOT EXISTS withdraw_requests (
     id BIGINT PRIMARY KEY AUTO_INCREMENT,
@@ -269,9 +422,101 @@ CREATE TABLE IF NOT EXISTS withdraw_requests (
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     INDEX idx_user_id (user_id),
     INDEX idx_request_no (request_no),
+    INDEX idx_user_created (user_id, created_at),
+    INDEX idx_status_process (status, process_time),
     FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='提现申请表';
 
+-- 开票信息表
+CREATE TABLE IF NOT EXISTS invoice_profiles (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    user_id BIGINT UNIQUE NOT NULL COMMENT '用户ID',
+    title_type ENUM('personal','company') NOT NULL COMMENT '抬头类型',
+    title VARCHAR(200) NOT NULL COMMENT '发票抬头',
+    tax_no VARCHAR(32) DEFAULT NULL COMMENT '纳税人识别号',
+    address VARCHAR(255) DEFAULT NULL COMMENT '注册地址',
+    phone VARCHAR(32) DEFAULT NULL COMMENT '注册电话',
+    bank_name VARCHAR(100) DEFAULT NULL COMMENT '开户银行',
+    bank_account VARCHAR(64) DEFAULT NULL COMMENT '银行账号',
+    email VARCHAR(100) DEFAULT NULL COMMENT '接收邮箱',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='开票信息表';
+
+-- 发票表
+CREATE TABLE IF NOT EXISTS invoices (
+    invoice_id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    invoice_no VARCHAR(32) UNIQUE NOT NULL COMMENT '发票号码',
+    user_id BIGINT NOT NULL COMMENT '购买方用户ID',
+    source_type ENUM('prepay','platform_fee') NOT NULL COMMENT '开票来源',
+    trade_id BIGINT DEFAULT NULL COMMENT '预付款交易ID',
+    settlement_id BIGINT DEFAULT NULL COMMENT '结算ID',
+    task_id BIGINT DEFAULT NULL COMMENT '关联任务ID',
+    amount DECIMAL(10,2) NOT NULL COMMENT '开票金额',
+    buyer_title VARCHAR(200) NOT NULL COMMENT '购买方抬头',
+    buyer_info JSON DEFAULT NULL COMMENT '购买方信息快照',
+    seller_info JSON DEFAULT NULL COMMENT '销售方信息快照',
+    items JSON DEFAULT NULL COMMENT '开票明细',
+    status TINYINT DEFAULT 0 COMMENT '状态:0-已开具,1-已作废',
+    issue_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '开具时间',
+    void_time TIMESTAMP DEFAULT NULL COMMENT '作废时间',
+    void_reason VARCHAR(500) DEFAULT NULL COMMENT '作废原因',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_user_created (user_id, created_at),
+    INDEX idx_trade_id (trade_id),
+    INDEX idx_settlement_id (settlement_id),
+    INDEX idx_task_id (task_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (trade_id) REFERENCES trades(trade_id) ON DELETE SET NULL,
+    FOREIGN KEY (settlement_id) REFERENCES settlements(settle_id) ON DELETE SET NULL,
+    FOREIGN KEY (task_id) REFERENCES tasks(task_id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='发票表';
+
+-- 发票号码序列表
+CREATE TABLE IF NOT EXISTS invoice_sequences (
+    period VARCHAR(16) PRIMARY KEY COMMENT '编号期间,如202401',
+    last_no BIGINT NOT NULL DEFAULT 0 COMMENT '已使用的最大序号',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='发票号码序列表';
+
+-- 对账报告表
+CREATE TABLE IF NOT EXISTS reconciliation_reports (
+    report_id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    provider VARCHAR(20) NOT NULL COMMENT '支付渠道',
+    bill_date DATE NOT NULL COMMENT '账单日',
+    source VARCHAR(20) NOT NULL COMMENT '对账单来源:api-渠道接口,csv-文件导入',
+    remote_count INT DEFAULT 0 COMMENT '渠道成功笔数',
+    remote_amount DECIMAL(12,2) DEFAULT 0 COMMENT '渠道成功金额',
+    local_count INT DEFAULT 0 COMMENT '本地已支付笔数',
+    local_amount DECIMAL(12,2) DEFAULT 0 COMMENT '本地已支付金额',
+    matched_count INT DEFAULT 0 COMMENT '核对一致笔数',
+    discrepancy_count INT DEFAULT 0 COMMENT '差异笔数',
+    status TINYINT DEFAULT 0 COMMENT '状态:0-账平,1-存在差异',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_provider_bill_date (provider, bill_date)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='对账报告表';
+
+-- 对账差异明细表
+CREATE TABLE IF NOT EXISTS reconciliation_discrepancies (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    report_id BIGINT NOT NULL COMMENT '对账报告ID',
+    type ENUM('missing_local','missing_remote','amount_mismatch','status_mismatch') NOT NULL COMMENT '差异类型',
+    trade_id BIGINT DEFAULT NULL COMMENT '本地交易ID',
+    internal_no VARCHAR(64) DEFAULT NULL COMMENT '内部交易号',
+    third_party_no VARCHAR(64) DEFAULT NULL COMMENT '第三方交易号',
+    local_amount DECIMAL(10,2) DEFAULT 0 COMMENT '本地金额',
+    remote_amount DECIMAL(10,2) DEFAULT 0 COMMENT '渠道金额',
+    local_status VARCHAR(20) DEFAULT NULL COMMENT '本地状态',
+    remote_status VARCHAR(20) DEFAULT NULL COMMENT '渠道状态',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_report_id (report_id),
+    INDEX idx_trade_id (trade_id),
+    FOREIGN KEY (report_id) REFERENCES reconciliation_reports(report_id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='对账差异明细表';
+
 -- 违规表
 CREATE TABLE IF NOT EXISTS violations (
     violate_id BIGINT PRIMARY KEY AUTO_INCREMENT,
@@ -281,7 +526,7 @@ CREATE TABLE IF NOT EXISTS violations (
     penalty DECIMAL(10,2) DEFAULT 0 COMMENT '处罚金额',
     description TEXT DEFAULT NULL COMMENT '违规描述',
     evidence JSON DEFAULT NULL COMMENT '违规证据',
-    status TINYINT DEFAULT 0 COMMENT '状态:0-待处理,1-已处理,2-已申诉',
+    status TINYINT DEFAULT 0 COMMENT '状态:0-待处理,1-已处理,2-已申诉,3-已撤销',
     handle_time TIMESTAMP DEFAULT NULL COMMENT '处理时间',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
@@ -382,4 +627,9 @@ INSERT INTO task_categories (name, description, sort_order) VALUES
 ('生活服务', '家政服务、跑腿代办、维修等', 5),
 ('教育培训', '在线辅导、技能培训、咨询服务等', 6);
 
+INSERT INTO ledger_accounts (code, name, account_type) VALUES
+('platform:gateway', '支付渠道资金', 'asset'),
+('platform:escrow', '平台托管资金', 'liability'),
+('platform:fee_revenue', '平台服务费收入', 'revenue');
+
 SET FOREIGN_KEY_CHECKS = 1;
\ No newline at end of file